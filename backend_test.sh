@@ -53,7 +53,7 @@ if [ -n "$PREBUILT_SERVER" ] && [ -f "$PREBUILT_SERVER" ]; then
     chmod +x /tmp/fls-backend-server
 else
     log_info "Building server..."
-    go build -o /tmp/fls-backend-server ./cmd/server
+    go build -tags sqlite_fts5 -o /tmp/fls-backend-server ./cmd/server
     if [ $? -ne 0 ]; then
         log_error "Failed to build server"
         exit 1
@@ -185,6 +185,9 @@ if [ "$FETCHED_TITLE" == "Backend Test Benchmark" ]; then
     # Verify user info is populated
     USERNAME_IN_BENCH=$(echo "$RESPONSE" | jq -r '.user.username')
     log_info "  Benchmark owner: $USERNAME_IN_BENCH"
+    # Mutations require the revision as If-Match (also returned as the ETag header)
+    REVISION=$(echo "$RESPONSE" | jq -r '.revision')
+    log_info "  Revision: $REVISION"
 else
     log_error "✗ Get benchmark failed: $RESPONSE"
     exit 1
@@ -219,16 +222,31 @@ fi
 log_info "Test 8: Update benchmark"
 RESPONSE=$(curl -s -b "$SESSION_COOKIE" -X PUT \
     -H "Content-Type: application/json" \
+    -H "If-Match: \"$REVISION\"" \
     -d '{"title":"Updated Backend Benchmark","description":"Updated description"}' \
     "${BASE_URL}/api/benchmarks/${BENCHMARK_ID}")
 UPDATED_TITLE=$(echo "$RESPONSE" | jq -r '.title')
 if [ "$UPDATED_TITLE" == "Updated Backend Benchmark" ]; then
     log_info "✓ Update benchmark passed"
+    log_info "  Revision: $(echo "$RESPONSE" | jq -r '.revision')"
 else
     log_error "✗ Update benchmark failed: $RESPONSE"
     exit 1
 fi
 
+# The revision read in Test 6 is stale after the update
+HTTP_CODE=$(curl -s -o /dev/null -w "%{http_code}" -b "$SESSION_COOKIE" -X PUT \
+    -H "Content-Type: application/json" \
+    -H "If-Match: \"$REVISION\"" \
+    -d '{"title":"Stale Backend Benchmark"}' \
+    "${BASE_URL}/api/benchmarks/${BENCHMARK_ID}")
+if [ "$HTTP_CODE" == "412" ]; then
+    log_info "✓ Update with a stale revision rejected"
+else
+    log_error "✗ Expected HTTP 412 for a stale revision, got $HTTP_CODE"
+    exit 1
+fi
+
 # Test 9: List benchmarks (with data)
 log_info "Test 9: List benchmarks (with data)"
 RESPONSE=$(curl -s "${BASE_URL}/api/benchmarks")
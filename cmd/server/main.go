@@ -30,6 +30,19 @@ func main() {
 	}
 	debug.SetGCPercent(gogc)
 
+	// Storage integrity checker: flightlesssomething fsck [--repair] [--json]
+	if len(os.Args) > 1 && os.Args[1] == "fsck" {
+		os.Exit(app.RunFsck(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	// Backup and restore: flightlesssomething backup --output FILE, flightlesssomething restore --input FILE
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		os.Exit(app.RunBackup(os.Args[2:], version, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		os.Exit(app.RunRestore(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	config, err := app.NewConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
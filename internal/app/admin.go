@@ -84,6 +84,10 @@ func HandleListUsers(db *DBInstance) gin.HandlerFunc {
 				users[i].BenchmarkCount = int(benchCountMap[users[i].ID])
 				users[i].APITokenCount = int(tokenCountMap[users[i].ID])
 			}
+
+			if err := loadStorageUsage(db, users); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
 		}
 
 		// Sort by benchmark count (top uploaders first)
@@ -140,24 +144,27 @@ func HandleDeleteUser(db *DBInstance) gin.HandlerFunc {
 		username := user.Username
 
 		if deleteData {
-			// Get all user's benchmarks to delete their data files
+			// Get all user's benchmarks, including those in the trash, to delete their data files
 			var benchmarks []Benchmark
-			if err := db.DB.Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
+			if err := db.DB.Unscoped().Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find user benchmarks"})
 				return
 			}
 
-			// Delete all benchmark data files
+			// Delete all benchmarks permanently, bypassing the trash
 			for i := range benchmarks {
-				if err := DeleteBenchmarkData(benchmarks[i].ID); err != nil {
+				if err := purgeUserBenchmark(db, benchmarks[i].ID); err != nil {
 					// Log but continue
-					fmt.Printf("Warning: failed to delete data for benchmark %d\n", benchmarks[i].ID)
+					fmt.Printf("Warning: failed to delete benchmark %d: %v\n", benchmarks[i].ID, err)
 				}
 			}
 		}
 
 		// Delete user (cascade will handle benchmarks)
-		if err := db.DB.Delete(&user).Error; err != nil {
+		release := beginStorageMutation()
+		err = db.DB.Delete(&user).Error
+		release()
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
 			return
 		}
@@ -191,6 +198,8 @@ func HandleDeleteUserBenchmarks(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		defer beginStorageMutation()()
+
 		// Get all user's benchmarks
 		var benchmarks []Benchmark
 		if err := db.DB.Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
@@ -198,14 +207,7 @@ func HandleDeleteUserBenchmarks(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
-		// Delete all benchmark data files
-		for i := range benchmarks {
-			if err := DeleteBenchmarkData(benchmarks[i].ID); err != nil {
-				fmt.Printf("Warning: failed to delete data for benchmark %d\n", benchmarks[i].ID)
-			}
-		}
-
-		// Delete all benchmarks from database
+		// Move all benchmarks to the trash; their files are kept until it is purged
 		if err := db.DB.Where("user_id = ?", user.ID).Delete(&Benchmark{}).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete benchmarks"})
 			return
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -801,3 +802,51 @@ func TestSystemAdminAdminCannotBeRevoked(t *testing.T) {
 		t.Error("System admin should still have admin privileges")
 	}
 }
+
+func TestDeleteUserWaitsForBenchmarkWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	admin := createTestUser(db, "purgeadmin", true)
+	target := createTestUser(db, "purgetarget", false)
+	benchmark := Benchmark{UserID: target.ID, Title: "Locked"}
+	if err := db.DB.Create(&benchmark).Error; err != nil {
+		t.Fatalf("Failed to create benchmark: %v", err)
+	}
+
+	_, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.DELETE("/api/admin/users/:id", func(ctx *gin.Context) {
+		ctx.Set("UserID", admin.ID)
+		HandleDeleteUser(db)(ctx)
+	})
+
+	// A mutation of the benchmark is in progress
+	unlock := lockBenchmarkWrites(benchmark.ID)
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d?delete_data=true", target.ID), http.NoBody))
+		done <- w
+	}()
+
+	select {
+	case <-done:
+		unlock()
+		t.Fatal("benchmark was purged while its write lock was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+
+	if w := <-done; w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var count int64
+	db.DB.Unscoped().Model(&Benchmark{}).Where("id = ?", benchmark.ID).Count(&count)
+	if count != 0 {
+		t.Error("Expected the benchmark to be purged")
+	}
+}
@@ -292,6 +292,16 @@ func LogUserUnbanned(adminUserID uint, adminUsername string, targetUserID uint,
 		})
 }
 
+// LogUserQuotaUpdated logs when an admin changes a user's storage quota
+func LogUserQuotaUpdated(adminUserID uint, adminUsername string, targetUserID uint, targetUsername string, quota StorageQuota) {
+	writeAuditLog(adminUserID, adminUsername, "user_quota_updated",
+		fmt.Sprintf("Admin %s (ID %d) changed the storage quota of user %s (ID %d)", adminUsername, adminUserID, targetUsername, targetUserID),
+		"user", targetUserID, map[string]interface{}{
+			"target_username": targetUsername,
+			"quota":           quota,
+		})
+}
+
 // LogUserDeleted logs when a user is deleted
 func LogUserDeleted(adminUserID uint, adminUsername string, targetUserID uint, targetUsername string) {
 	writeAuditLog(adminUserID, adminUsername, "user_deleted",
@@ -310,3 +320,131 @@ func LogUserBenchmarksDeleted(adminUserID uint, adminUsername string, targetUser
 			"benchmark_count": benchmarkCount,
 		})
 }
+
+// LogStatsRecomputeRequested logs when an admin queues pre-calculated stats for recompute.
+// benchmarkID is 0 when all benchmarks were queued.
+func LogStatsRecomputeRequested(adminUserID uint, adminUsername string, benchmarkID uint, queued int) {
+	description := fmt.Sprintf("Admin %s (ID %d) queued stats recompute for all benchmarks (%d queued)", adminUsername, adminUserID, queued)
+	if benchmarkID != 0 {
+		description = fmt.Sprintf("Admin %s (ID %d) queued stats recompute for benchmark %d", adminUsername, adminUserID, benchmarkID)
+	}
+	writeAuditLog(adminUserID, adminUsername, "stats_recompute_requested", description,
+		"benchmark", benchmarkID, map[string]interface{}{
+			"queued":            queued,
+			"algorithm_version": statsAlgorithmVersion,
+		})
+}
+
+// LogStorageChecked logs when an admin runs the storage integrity checker.
+func LogStorageChecked(adminUserID uint, adminUsername string, report *StorageCheckReport) {
+	action := "checked"
+	if report.Repair {
+		action = "checked and repaired"
+	}
+	description := fmt.Sprintf("Admin %s (ID %d) %s benchmark storage (%d issues, %d unresolved)", adminUsername, adminUserID, action, len(report.Issues), report.Unresolved)
+	writeAuditLog(adminUserID, adminUsername, "storage_checked", description,
+		"storage", 0, map[string]interface{}{
+			"repair":     report.Repair,
+			"issues":     len(report.Issues),
+			"unresolved": report.Unresolved,
+		})
+}
+
+// LogBackupCreated logs when an admin downloads a backup.
+func LogBackupCreated(adminUserID uint, adminUsername string, manifest *BackupManifest) {
+	description := fmt.Sprintf("Admin %s (ID %d) downloaded a backup (%d benchmarks, %d files)", adminUsername, adminUserID, manifest.Benchmarks, len(manifest.Files))
+	writeAuditLog(adminUserID, adminUsername, "backup_created", description,
+		"storage", 0, map[string]interface{}{
+			"benchmarks":     manifest.Benchmarks,
+			"files":          len(manifest.Files),
+			"schema_version": manifest.SchemaVersion,
+			"audit_logs":     manifest.AuditLogs,
+		})
+}
+
+// LogBenchmarkRestored logs when a benchmark is restored from the trash
+func LogBenchmarkRestored(userID uint, username string, benchmarkID uint, title string) {
+	writeAuditLog(userID, username, "benchmark_restored",
+		fmt.Sprintf("User %s (ID %d) restored benchmark #%d: %s from the trash", username, userID, benchmarkID, title),
+		"benchmark", benchmarkID, map[string]interface{}{
+			"benchmark_title": title,
+		})
+}
+
+// LogBenchmarkRunRestored logs when a run is restored from the trash
+func LogBenchmarkRunRestored(userID uint, username string, benchmarkID uint, title string, runIndex int, runLabel string) {
+	writeAuditLog(userID, username, "benchmark_run_restored",
+		fmt.Sprintf("User %s (ID %d) restored run %d (%s) of benchmark #%d: %s from the trash", username, userID, runIndex, runLabel, benchmarkID, title),
+		"benchmark", benchmarkID, map[string]interface{}{
+			"benchmark_title": title,
+			"run_index":       runIndex,
+			"run_label":       runLabel,
+		})
+}
+
+// LogBenchmarkReverted logs when a benchmark's metadata is reverted to an earlier revision
+func LogBenchmarkReverted(userID uint, username string, benchmarkID uint, title string, revision uint, changes []string) {
+	writeAuditLog(userID, username, "benchmark_reverted",
+		fmt.Sprintf("User %s (ID %d) reverted benchmark #%d: %s to revision %d (changed: %s)", username, userID, benchmarkID, title, revision, strings.Join(changes, ", ")),
+		"benchmark", benchmarkID, map[string]interface{}{
+			"benchmark_title": title,
+			"revision":        revision,
+			"changed_fields":  changes,
+		})
+}
+
+// LogBenchmarkPurged logs when a deleted benchmark is permanently removed after the trash retention period
+func LogBenchmarkPurged(benchmarkID uint, title string, ownerID uint, deletedAt time.Time) {
+	writeAuditLog(0, "system", "benchmark_purged",
+		fmt.Sprintf("Purged benchmark #%d: %s of user ID %d from the trash (deleted %s)", benchmarkID, title, ownerID, deletedAt.UTC().Format(time.RFC3339)),
+		"benchmark", benchmarkID, map[string]interface{}{
+			"benchmark_title": title,
+			"owner_id":        ownerID,
+			"deleted_at":      deletedAt.UTC().Format(time.RFC3339),
+		})
+}
+
+// LogBenchmarkRunPurged logs when a deleted run is permanently removed after the trash retention period
+func LogBenchmarkRunPurged(trashed *TrashedRun) {
+	writeAuditLog(0, "system", "benchmark_run_purged",
+		fmt.Sprintf("Purged run %d (%s) of benchmark #%d: %s from the trash (deleted %s)", trashed.RunIndex, trashed.Label, trashed.BenchmarkID, trashed.BenchmarkTitle, trashed.TrashedAt.UTC().Format(time.RFC3339)),
+		"benchmark", trashed.BenchmarkID, map[string]interface{}{
+			"benchmark_title": trashed.BenchmarkTitle,
+			"owner_id":        trashed.UserID,
+			"run_index":       trashed.RunIndex,
+			"run_label":       trashed.Label,
+			"deleted_at":      trashed.TrashedAt.UTC().Format(time.RFC3339),
+		})
+}
+
+// LogGameCreated logs when an admin creates a game.
+func LogGameCreated(adminUserID uint, adminUsername string, game *Game) {
+	writeAuditLog(adminUserID, adminUsername, "game_created",
+		fmt.Sprintf("Admin %s (ID %d) created game %s (ID %d)", adminUsername, adminUserID, game.Name, game.ID),
+		"game", game.ID, map[string]interface{}{
+			"name":         game.Name,
+			"aliases":      game.Aliases,
+			"steam_app_id": game.SteamAppID,
+		})
+}
+
+// LogGameUpdated logs when an admin changes a game.
+func LogGameUpdated(adminUserID uint, adminUsername string, game *Game) {
+	writeAuditLog(adminUserID, adminUsername, "game_updated",
+		fmt.Sprintf("Admin %s (ID %d) updated game %s (ID %d)", adminUsername, adminUserID, game.Name, game.ID),
+		"game", game.ID, map[string]interface{}{
+			"name":         game.Name,
+			"aliases":      game.Aliases,
+			"steam_app_id": game.SteamAppID,
+		})
+}
+
+// LogGameDeleted logs when an admin deletes a game. unlinked is the number of benchmarks it was linked to.
+func LogGameDeleted(adminUserID uint, adminUsername string, gameID uint, name string, unlinked int64) {
+	writeAuditLog(adminUserID, adminUsername, "game_deleted",
+		fmt.Sprintf("Admin %s (ID %d) deleted game %s (ID %d)", adminUsername, adminUserID, name, gameID),
+		"game", gameID, map[string]interface{}{
+			"name":                name,
+			"unlinked_benchmarks": unlinked,
+		})
+}
@@ -299,10 +299,16 @@ func HandleGetCurrentUser(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		users := []User{user}
+		if err := loadStorageUsage(db, users); err != nil {
+			fmt.Printf("Warning: failed to load storage usage of user %d: %v\n", user.ID, err)
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"user_id":  user.ID,
 			"username": user.Username,
 			"is_admin": user.IsAdmin,
+			"usage":    users[0].Usage,
 		})
 	}
 }
@@ -449,6 +449,7 @@ func TestBenchmarkOwnershipEnforcement(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/benchmarks/%d", benchmark.ID), strings.NewReader(body))
 		req.Header.Set("Authorization", "Bearer "+ownerToken.Token)
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", benchmarkETag(1))
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -508,6 +509,7 @@ func TestAdminCanAccessOtherUsersBenchmarks(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/benchmarks/%d", benchmark.ID), strings.NewReader(body))
 	req.Header.Set("Authorization", "Bearer "+adminToken.Token)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", benchmarkETag(1))
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
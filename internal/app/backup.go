@@ -0,0 +1,954 @@
+package app
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/peterbourgon/ff/v3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// A backup is a single tar.zst archive: manifest.json first, then an online copy of the database
+// (VACUUM INTO), the benchmark files of every benchmark in that copy and, optionally, the audit
+// logs. The manifest records the size and SHA-256 of every file, so an archive can be verified
+// before it is trusted and restore never leaves a half-extracted data directory behind.
+
+const (
+	backupFormatVersion = 1
+	backupManifestName  = "manifest.json"
+	backupDatabaseName  = "flightlesssomething.db"
+	backupBenchmarksDir = "benchmarks"
+	backupLogsDir       = "logs"
+)
+
+// BackupFile describes one file in a backup archive.
+type BackupFile struct {
+	Path   string `json:"path"` // Slash-separated path inside the archive
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BackupManifest is the first entry of a backup archive.
+type BackupManifest struct {
+	FormatVersion int          `json:"format_version"`
+	AppVersion    string       `json:"app_version"`
+	SchemaVersion int          `json:"schema_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Benchmarks    int64        `json:"benchmarks"`
+	AuditLogs     bool         `json:"audit_logs"`
+	Files         []BackupFile `json:"files"`
+}
+
+// storageSnapshotMu lets a backup capture the database and the benchmark files at the same point.
+// Anything that changes benchmark rows together with their files holds it for reading; the
+// snapshot holds it exclusively while it copies the database and the files (hard links with the
+// local blob store, so that is quick).
+var storageSnapshotMu sync.RWMutex
+
+// beginStorageMutation marks the start of a change to benchmark rows and files. Returns the
+// function that ends it. Must not be nested.
+func beginStorageMutation() func() {
+	storageSnapshotMu.RLock()
+	return storageSnapshotMu.RUnlock
+}
+
+// backupSnapshot is a consistent copy of the data directory in a staging directory.
+type backupSnapshot struct {
+	dir      string
+	manifest *BackupManifest
+}
+
+// createBackupSnapshot copies the database and the current benchmark files into a staging
+// directory in the data directory, then checks the copied database and builds the manifest.
+func createBackupSnapshot(db *DBInstance, appVersion string, auditLogs bool) (*backupSnapshot, error) {
+	dir, err := os.MkdirTemp(storageDataDir, ".backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	snapshot := &backupSnapshot{dir: dir}
+	if err := snapshot.capture(db, auditLogs); err != nil {
+		snapshot.Close()
+		return nil, err
+	}
+
+	schemaVersion, benchmarks, err := inspectBackupDatabase(filepath.Join(dir, backupDatabaseName))
+	if err != nil {
+		snapshot.Close()
+		return nil, err
+	}
+	if schemaVersion > currentSchemaVersion {
+		snapshot.Close()
+		return nil, fmt.Errorf("database schema version %d is newer than this release supports (%d)", schemaVersion, currentSchemaVersion)
+	}
+	files, err := backupFiles(dir)
+	if err != nil {
+		snapshot.Close()
+		return nil, err
+	}
+	snapshot.manifest = &BackupManifest{
+		FormatVersion: backupFormatVersion,
+		AppVersion:    appVersion,
+		SchemaVersion: schemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Benchmarks:    benchmarks,
+		AuditLogs:     auditLogs,
+		Files:         files,
+	}
+	return snapshot, nil
+}
+
+// capture copies the database and the files of every benchmark in it while mutations are held off.
+func (s *backupSnapshot) capture(db *DBInstance, auditLogs bool) error {
+	filesDir := filepath.Join(s.dir, backupBenchmarksDir)
+	if err := os.Mkdir(filesDir, 0o750); err != nil {
+		return err
+	}
+
+	storageSnapshotMu.Lock()
+	defer storageSnapshotMu.Unlock()
+
+	if err := db.DB.Exec("VACUUM INTO ?", filepath.Join(s.dir, backupDatabaseName)).Error; err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	var ids []uint
+	if err := db.DB.Unscoped().Model(&Benchmark{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for _, id := range ids {
+		if err := snapshotBenchmarkFiles(id, filesDir); err != nil {
+			return fmt.Errorf("failed to snapshot files of benchmark %d: %w", id, err)
+		}
+	}
+	if err := snapshotSharedRuns(db, filesDir); err != nil {
+		return fmt.Errorf("failed to snapshot shared runs: %w", err)
+	}
+	if err := snapshotTrashedRuns(db, filesDir); err != nil {
+		return fmt.Errorf("failed to snapshot deleted runs: %w", err)
+	}
+
+	if auditLogs {
+		if err := copyAuditLogs(filepath.Join(s.dir, backupLogsDir)); err != nil {
+			return fmt.Errorf("failed to copy audit logs: %w", err)
+		}
+	}
+	return nil
+}
+
+// snapshotBenchmarkFiles copies the current generation of a benchmark's files (or its files
+// without a manifest) into dir. Missing files are left for the storage integrity check to report.
+func snapshotBenchmarkFiles(benchmarkID uint, dir string) error {
+	// Stats recomputation writes generations outside of benchmark mutations
+	unlock := lockBenchmarkFiles(benchmarkID)
+	defer unlock()
+
+	m, err := readManifest(benchmarkID)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if m == nil {
+		for _, kind := range benchmarkFileKinds {
+			names = append(names, legacyFileName(benchmarkID, kind))
+		}
+	} else {
+		names = append(names, manifestName(benchmarkID))
+		for _, kind := range m.Files {
+			names = append(names, generationFileName(benchmarkID, m.Generation, kind))
+		}
+	}
+	for _, name := range names {
+		if err := saveBlob(name, filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotSharedRuns copies the shared run blobs referenced by run records into dir.
+func snapshotSharedRuns(db *DBInstance, dir string) error {
+	blobs, err := blobStore.List()
+	if err != nil {
+		return err
+	}
+	var hashes []string
+	if err := db.DB.Model(&BenchmarkRun{}).Distinct("content_hash").Pluck("content_hash", &hashes).Error; err != nil {
+		return err
+	}
+	referenced := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		referenced[hash] = true
+	}
+	for _, blob := range blobs {
+		match := sharedRunFilePattern.FindStringSubmatch(blob.Name)
+		if match == nil || !referenced[match[1]] {
+			continue
+		}
+		if err := saveBlob(blob.Name, filepath.Join(dir, blob.Name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotTrashedRuns copies the trash blobs of the runs in the trash into dir.
+func snapshotTrashedRuns(db *DBInstance, dir string) error {
+	var ids []uint
+	if err := db.DB.Model(&TrashedRun{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		name := trashRunName(id)
+		if err := saveBlob(name, filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAuditLogs copies the audit log and its rotated files into dir. The active log is copied
+// rather than linked because it is appended to in place.
+func copyAuditLogs(dir string) error {
+	if auditLogsDir == "" {
+		return errors.New("audit log is not initialized")
+	}
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		return err
+	}
+
+	auditLogMu.Lock()
+	defer auditLogMu.Unlock()
+
+	entries, err := os.ReadDir(auditLogsDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isAuditLogFileName(entry.Name()) {
+			continue
+		}
+		if err := copyFile(filepath.Join(auditLogsDir, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isAuditLogFileName(name string) bool {
+	matched, _ := path.Match("audit-*.json.gz", name) //nolint:errcheck // The pattern is valid
+	return name == "audit.json" || matched
+}
+
+// inspectBackupDatabase checks the integrity of a database copy and returns its schema version
+// and number of benchmarks.
+func inspectBackupDatabase(dbPath string) (schemaVersion int, benchmarks int64, err error) {
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open database copy: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	defer func() {
+		if closeErr := sqlDB.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close database copy: %v\n", closeErr)
+		}
+	}()
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to check database copy: %w", err)
+	}
+	if result != "ok" {
+		return 0, 0, fmt.Errorf("database copy failed the integrity check: %s", result)
+	}
+	schemaVersion, err = detectSchemaVersion(db)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to detect schema version: %w", err)
+	}
+	if err := db.Model(&Benchmark{}).Count(&benchmarks).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to count benchmarks: %w", err)
+	}
+	return schemaVersion, benchmarks, nil
+}
+
+// backupFiles lists the files of a staging directory with their checksums: the database first,
+// then benchmark files and audit logs in name order.
+func backupFiles(dir string) ([]BackupFile, error) {
+	names := []string{backupDatabaseName}
+	for _, sub := range []string{backupBenchmarksDir, backupLogsDir} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			names = append(names, sub+"/"+entry.Name())
+		}
+	}
+
+	files := make([]BackupFile, 0, len(names))
+	for _, name := range names {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		size, err := io.Copy(h, f)
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		files = append(files, BackupFile{Path: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+	}
+	return files, nil
+}
+
+// WriteTo writes the snapshot as a tar.zst archive.
+func (s *backupSnapshot) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	zw, err := zstd.NewWriter(counter)
+	if err != nil {
+		return 0, err
+	}
+	tw := tar.NewWriter(zw)
+
+	manifest, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return counter.n, err
+	}
+	if err := tw.WriteHeader(s.tarHeader(backupManifestName, int64(len(manifest)))); err != nil {
+		return counter.n, err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return counter.n, err
+	}
+	for _, file := range s.manifest.Files {
+		if err := s.writeFile(tw, file); err != nil {
+			return counter.n, fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return counter.n, err
+	}
+	err = zw.Close()
+	return counter.n, err
+}
+
+func (s *backupSnapshot) writeFile(tw *tar.Writer, file BackupFile) error {
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+	if err := tw.WriteHeader(s.tarHeader(file.Path, file.Size)); err != nil {
+		return err
+	}
+	// The checksums in the manifest cover exactly file.Size bytes
+	_, err = io.CopyN(tw, f, file.Size)
+	return err
+}
+
+func (s *backupSnapshot) tarHeader(name string, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o600,
+		Size:     size,
+		ModTime:  s.manifest.CreatedAt,
+	}
+}
+
+// Close removes the staging directory.
+func (s *backupSnapshot) Close() {
+	if err := os.RemoveAll(s.dir); err != nil {
+		fmt.Printf("Warning: failed to remove backup staging directory %s: %v\n", s.dir, err)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteBackup writes a consistent backup of the database, the benchmark files and, optionally,
+// the audit logs to w.
+func WriteBackup(db *DBInstance, w io.Writer, appVersion string, auditLogs bool) (*BackupManifest, error) {
+	snapshot, err := createBackupSnapshot(db, appVersion, auditLogs)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Close()
+	if _, err := snapshot.WriteTo(w); err != nil {
+		return nil, err
+	}
+	return snapshot.manifest, nil
+}
+
+// VerifyBackup reads a whole backup archive and checks every file against the manifest.
+func VerifyBackup(r io.Reader) (*BackupManifest, error) {
+	return readBackupArchive(r, "")
+}
+
+// readBackupArchive reads a backup archive, verifying its manifest and the size and checksum of
+// every file. Files are extracted into dest unless it is empty.
+func readBackupArchive(r io.Reader, dest string) (*BackupManifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	if hdr.Name != backupManifestName {
+		return nil, fmt.Errorf("invalid backup archive: expected %s first, found %s", backupManifestName, hdr.Name)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	if err := checkBackupManifest(&manifest); err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]BackupFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		pending[file.Path] = file
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		file, ok := pending[hdr.Name]
+		if !ok {
+			return nil, fmt.Errorf("backup archive contains %s, which is not in the manifest or appears twice", hdr.Name)
+		}
+		delete(pending, hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || hdr.Size != file.Size {
+			return nil, fmt.Errorf("%s: expected a regular file of %d bytes", file.Path, file.Size)
+		}
+		if err := readBackupFile(tr, file, dest); err != nil {
+			return nil, err
+		}
+	}
+	if len(pending) > 0 {
+		missing := make([]string, 0, len(pending))
+		for name := range pending {
+			missing = append(missing, name)
+		}
+		slices.Sort(missing)
+		return nil, fmt.Errorf("backup archive is missing %d file(s): %s", len(missing), strings.Join(missing, ", "))
+	}
+	return &manifest, nil
+}
+
+// readBackupFile reads one archived file, writing it below dest if set, and verifies its checksum.
+func readBackupFile(r io.Reader, file BackupFile, dest string) error {
+	h := sha256.New()
+	var out *os.File
+	w := io.Writer(h)
+	if dest != "" {
+		target := filepath.Join(dest, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return err
+		}
+		var err error
+		if out, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600); err != nil {
+			return err
+		}
+		w = io.MultiWriter(out, h)
+	}
+	_, err := io.Copy(w, r)
+	if out != nil {
+		if err == nil {
+			err = out.Sync()
+		}
+		if closeErr := out.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != file.SHA256 {
+		return fmt.Errorf("%s: checksum mismatch (expected %s, got %s)", file.Path, file.SHA256, sum)
+	}
+	return nil
+}
+
+// checkBackupManifest validates a manifest before any file is read: the format, the schema
+// version and that every path is one a backup can contain.
+func checkBackupManifest(m *BackupManifest) error {
+	if m.FormatVersion != backupFormatVersion {
+		return fmt.Errorf("unsupported backup format version %d", m.FormatVersion)
+	}
+	if m.SchemaVersion > currentSchemaVersion {
+		return fmt.Errorf("backup has schema version %d, newer than this release supports (%d); use a newer release", m.SchemaVersion, currentSchemaVersion)
+	}
+	if m.SchemaVersion < 1 {
+		return fmt.Errorf("invalid schema version %d in backup manifest", m.SchemaVersion)
+	}
+	seen := make(map[string]bool, len(m.Files))
+	for _, file := range m.Files {
+		if !validBackupPath(file.Path) || seen[file.Path] || file.Size < 0 {
+			return fmt.Errorf("invalid file entry %q in backup manifest", file.Path)
+		}
+		seen[file.Path] = true
+	}
+	if !seen[backupDatabaseName] {
+		return fmt.Errorf("backup manifest has no %s", backupDatabaseName)
+	}
+	return nil
+}
+
+// validBackupPath reports whether name is a path a backup can contain. It rules out anything
+// that could be extracted outside the staging directory.
+func validBackupPath(name string) bool {
+	if name == backupDatabaseName {
+		return true
+	}
+	dir, base, found := strings.Cut(name, "/")
+	if !found {
+		return false
+	}
+	switch dir {
+	case backupBenchmarksDir:
+		_, _, _, ok := parseBenchmarkFileName(base)
+		return ok && !strings.HasSuffix(base, ".tmp") || sharedRunFilePattern.MatchString(base) || trashRunFilePattern.MatchString(base)
+	case backupLogsDir:
+		return isAuditLogFileName(base)
+	}
+	return false
+}
+
+// RestoreBackup verifies a backup archive and restores it into dataDir. Files are extracted into a
+// staging directory first and only moved into place once everything checked out. Existing data
+// is only replaced if force is set; it is moved to the returned directory rather than deleted.
+// With a blob store other than the local one, benchmark files are uploaded to it instead, and
+// the existing ones are moved to a key prefix named like the returned directory.
+// The server must not be running.
+func RestoreBackup(r io.Reader, dataDir string, force bool) (manifest *BackupManifest, previousDir string, err error) {
+	dataDir = filepath.Clean(dataDir)
+	if err := os.MkdirAll(dataDir, 0o750); err != nil {
+		return nil, "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	dbPath := filepath.Join(dataDir, backupDatabaseName)
+	filesDir := filepath.Join(dataDir, backupBenchmarksDir)
+	logsDir := filepath.Join(filepath.Dir(dataDir), backupLogsDir)
+	remote := remoteBlobStore()
+	hasFiles := !dirEmpty(filesDir)
+	if remote {
+		blobs, err := blobStore.List()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list benchmark files: %w", err)
+		}
+		hasFiles = len(blobs) > 0
+	}
+	if !force && (pathExists(dbPath) || hasFiles) {
+		return nil, "", fmt.Errorf("data directory %s already contains data; use -force to replace it", dataDir)
+	}
+
+	staging, err := os.MkdirTemp(dataDir, ".restore-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		if removeErr := os.RemoveAll(staging); removeErr != nil {
+			fmt.Printf("Warning: failed to remove restore staging directory %s: %v\n", staging, removeErr)
+		}
+	}()
+
+	manifest, err = readBackupArchive(r, staging)
+	if err != nil {
+		return nil, "", err
+	}
+	schemaVersion, _, err := inspectBackupDatabase(filepath.Join(staging, backupDatabaseName))
+	if err != nil {
+		return nil, "", err
+	}
+	if schemaVersion != manifest.SchemaVersion {
+		return nil, "", fmt.Errorf("restored database has schema version %d, but the manifest says %d", schemaVersion, manifest.SchemaVersion)
+	}
+	if err := os.MkdirAll(filepath.Join(staging, backupBenchmarksDir), 0o750); err != nil {
+		return nil, "", err
+	}
+
+	// Move the current data aside, then move the restored data into place
+	suffix := time.Now().UTC().Format("20060102-150405")
+	previousDir = filepath.Join(dataDir, "pre-restore-"+suffix)
+	moveAside := func(src, dst string) error {
+		if !pathExists(src) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+			return err
+		}
+		return os.Rename(src, dst)
+	}
+	for _, name := range []string{backupDatabaseName, backupDatabaseName + "-wal", backupDatabaseName + "-shm", backupBenchmarksDir} {
+		if err := moveAside(filepath.Join(dataDir, name), filepath.Join(previousDir, name)); err != nil {
+			return nil, "", fmt.Errorf("failed to move existing %s aside: %w", name, err)
+		}
+	}
+	movedBlobs := false
+	if remote {
+		if movedBlobs, err = restoreBlobs(filepath.Join(staging, backupBenchmarksDir), filepath.Base(previousDir)); err != nil {
+			return nil, "", fmt.Errorf("failed to restore benchmark files: %w", err)
+		}
+	}
+	if err := os.Rename(filepath.Join(staging, backupDatabaseName), dbPath); err != nil {
+		return nil, "", fmt.Errorf("failed to restore database: %w", err)
+	}
+	if !remote {
+		if err := os.Rename(filepath.Join(staging, backupBenchmarksDir), filesDir); err != nil {
+			return nil, "", fmt.Errorf("failed to restore benchmark files: %w", err)
+		}
+	}
+	if manifest.AuditLogs {
+		if err := moveAside(logsDir, logsDir+".pre-restore-"+suffix); err != nil {
+			return nil, "", fmt.Errorf("failed to move existing audit logs aside: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Join(staging, backupLogsDir), 0o750); err != nil {
+			return nil, "", err
+		}
+		if err := os.Rename(filepath.Join(staging, backupLogsDir), logsDir); err != nil {
+			return nil, "", fmt.Errorf("failed to restore audit logs: %w", err)
+		}
+	}
+	if err := syncDir(dataDir); err != nil {
+		fmt.Printf("Warning: failed to sync data directory: %v\n", err)
+	}
+
+	if !pathExists(previousDir) && !movedBlobs {
+		previousDir = ""
+	}
+	return manifest, previousDir, nil
+}
+
+// restoreBlobs moves the blobs in the store below the key prefix aside/ and uploads the files
+// of dir in their place. Reports whether there was anything to move aside.
+func restoreBlobs(dir, aside string) (bool, error) {
+	blobs, err := blobStore.List()
+	if err != nil {
+		return false, err
+	}
+	for _, blob := range blobs {
+		if err := blobStore.Copy(blob.Name, aside+"/"+blob.Name); err != nil {
+			return false, fmt.Errorf("failed to move %s aside: %w", blob.Name, err)
+		}
+		if err := blobStore.Remove(blob.Name); err != nil {
+			return false, fmt.Errorf("failed to move %s aside: %w", blob.Name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return len(blobs) > 0, err
+	}
+	for _, entry := range entries {
+		if err := uploadBlob(filepath.Join(dir, entry.Name()), entry.Name()); err != nil {
+			return len(blobs) > 0, fmt.Errorf("failed to upload %s: %w", entry.Name(), err)
+		}
+	}
+	resetManifestCache()
+	return len(blobs) > 0, nil
+}
+
+// uploadBlob writes a local file to the blob store.
+func uploadBlob(path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+	return writeBlob(name, func(out io.Writer) error {
+		_, err := io.Copy(out, f)
+		return err
+	})
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// dirEmpty reports whether dir is missing or has no entries.
+func dirEmpty(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err != nil || len(entries) == 0
+}
+
+// HandleBackup streams a consistent backup archive (admin only)
+func HandleBackup(db *DBInstance, version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auditLogs := c.Query("audit_logs") == "true"
+
+		snapshot, err := createBackupSnapshot(db, version, auditLogs)
+		if err != nil {
+			fmt.Printf("Warning: failed to create backup: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create backup"})
+			return
+		}
+		defer snapshot.Close()
+
+		// Large archives take longer than the server's write timeout
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			fmt.Printf("Warning: failed to extend write deadline for backup: %v\n", err)
+		}
+
+		name := fmt.Sprintf("flightlesssomething-backup-%s.tar.zst", snapshot.manifest.CreatedAt.Format("20060102-150405"))
+		c.Header("Content-Type", "application/zstd")
+		c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+		c.Status(http.StatusOK)
+		if _, err := snapshot.WriteTo(c.Writer); err != nil {
+			// The status is already sent; the client detects the truncated archive when verifying it
+			fmt.Printf("Warning: failed to stream backup: %v\n", err)
+			return
+		}
+
+		if adminUserID, exists := c.Get("UserID"); exists {
+			if uid, ok := adminUserID.(uint); ok {
+				LogBackupCreated(uid, GetUsernameFromContext(c), snapshot.manifest)
+			}
+		}
+	}
+}
+
+// RunBackup implements the backup subcommand. It writes a backup of a stopped server's data
+// directory, or downloads one from a running server, and verifies it before moving it into place.
+// Returns the process exit code.
+func RunBackup(args []string, appVersion string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("flightlesssomething backup", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dataDir := fs.String("data-dir", "/data", "Path where data would be stored (server must be stopped)")
+	output := fs.String("output", "", "Path of the backup archive to write (required)")
+	auditLogs := fs.Bool("audit-logs", false, "Include the audit logs")
+	server := fs.String("server", "", "Download the backup from this running server instead (e.g. https://example.com)")
+	token := fs.String("token", "", "Admin API token for -server")
+	var blobCfg BlobStoreConfig
+	registerBlobStoreFlags(fs, &blobCfg)
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("FS")); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output == "" {
+		fmt.Fprintln(stderr, "-output is required")
+		return 2
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".backup-*.tar.zst")
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to create backup file: %v\n", err)
+		return 1
+	}
+	defer removeIfExists(tmp.Name())
+	if *server != "" {
+		err = downloadBackup(tmp, *server, *token, *auditLogs)
+	} else {
+		err = writeLocalBackup(tmp, *dataDir, blobCfg, appVersion, *auditLogs)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Backup failed: %v\n", err)
+		return 1
+	}
+
+	manifest, err := verifyBackupFile(tmp.Name())
+	if err != nil {
+		fmt.Fprintf(stderr, "Backup verification failed: %v\n", err)
+		return 1
+	}
+	if err := os.Rename(tmp.Name(), *output); err != nil {
+		fmt.Fprintf(stderr, "Failed to move backup into place: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote verified backup %s: %d benchmarks, %d files, schema version %d\n",
+		*output, manifest.Benchmarks, len(manifest.Files), manifest.SchemaVersion)
+	return 0
+}
+
+// writeLocalBackup backs up a data directory directly. The database is opened as is; migrations
+// are left to the next server start.
+func writeLocalBackup(w io.Writer, dataDir string, blobCfg BlobStoreConfig, appVersion string, auditLogs bool) error {
+	dbPath := filepath.Join(dataDir, backupDatabaseName)
+	if !pathExists(dbPath) {
+		return fmt.Errorf("no database at %s", dbPath)
+	}
+	if err := InitBlobStore(dataDir, blobCfg); err != nil {
+		return fmt.Errorf("failed to initialize blob store: %w", err)
+	}
+	if auditLogs {
+		if err := InitAuditLog(dataDir); err != nil {
+			return fmt.Errorf("failed to initialize audit log directory: %w", err)
+		}
+	}
+	gormDB, err := gorm.Open(sqlite.Open(dbPath+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+	defer func() {
+		if closeErr := sqlDB.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close database: %v\n", closeErr)
+		}
+	}()
+	_, err = WriteBackup(&DBInstance{DB: gormDB}, w, appVersion, auditLogs)
+	return err
+}
+
+// downloadBackup fetches a backup from a running server's admin endpoint.
+func downloadBackup(w io.Writer, server, token string, auditLogs bool) error {
+	endpoint, err := url.JoinPath(server, "/api/admin/backup")
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+	if auditLogs {
+		endpoint += "?audit_logs=true"
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close response body: %v\n", closeErr)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)) //nolint:errcheck // Best effort error detail
+		return fmt.Errorf("server responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func verifyBackupFile(path string) (*BackupManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close backup: %v\n", closeErr)
+		}
+	}()
+	return VerifyBackup(f)
+}
+
+// RunRestore implements the restore subcommand. Returns the process exit code.
+func RunRestore(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("flightlesssomething restore", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dataDir := fs.String("data-dir", "/data", "Path where data would be stored (server must be stopped)")
+	input := fs.String("input", "", "Path of the backup archive to restore (required)")
+	force := fs.Bool("force", false, "Replace existing data, moving it to a pre-restore directory")
+	var blobCfg BlobStoreConfig
+	registerBlobStoreFlags(fs, &blobCfg)
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("FS")); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	// The local store is restored by moving the extracted directory into place
+	if blobCfg.Backend != "" && blobCfg.Backend != blobStoreLocal {
+		if err := InitBlobStore(*dataDir, blobCfg); err != nil {
+			fmt.Fprintf(stderr, "Failed to initialize blob store: %v\n", err)
+			return 2
+		}
+	}
+	if *input == "" {
+		fmt.Fprintln(stderr, "-input is required")
+		return 2
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open backup: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close backup: %v\n", closeErr)
+		}
+	}()
+	manifest, previousDir, err := RestoreBackup(f, *dataDir, *force)
+	if err != nil {
+		fmt.Fprintf(stderr, "Restore failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Restored backup from %s: %d benchmarks, %d files, schema version %d\n",
+		manifest.CreatedAt.Format(time.RFC3339), manifest.Benchmarks, len(manifest.Files), manifest.SchemaVersion)
+	if manifest.SchemaVersion < currentSchemaVersion {
+		fmt.Fprintf(stdout, "The database will be migrated to schema version %d on the next server start\n", currentSchemaVersion)
+	}
+	if previousDir != "" {
+		fmt.Fprintf(stdout, "Previous data moved to %s\n", previousDir)
+		if remoteBlobStore() {
+			fmt.Fprintf(stdout, "Previous benchmark files moved below the key prefix %s/\n", filepath.Base(previousDir))
+		}
+	}
+	return 0
+}
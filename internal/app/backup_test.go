@@ -0,0 +1,241 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupBackupTest stores two benchmarks in a fresh data directory and points the audit log at it.
+func setupBackupTest(t *testing.T) *DBInstance {
+	t.Helper()
+	dataDir := t.TempDir()
+	db, err := InitDB(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+	t.Cleanup(func() { cleanupTestDB(t, db) })
+	if err := InitBenchmarksDir(dataDir); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	prevLogsDir, prevLogPath := auditLogsDir, auditLogPath
+	t.Cleanup(func() { auditLogsDir, auditLogPath = prevLogsDir, prevLogPath })
+	if err := InitAuditLog(dataDir); err != nil {
+		t.Fatalf("Failed to init audit log: %v", err)
+	}
+
+	user := createTestUser(db, "backupuser", false)
+	for _, title := range []string{"First", "Second"} {
+		benchmark := &Benchmark{UserID: user.ID, Title: title, Revision: 1}
+		db.DB.Create(benchmark)
+		if err := StoreBenchmarkData(columnarRuns(), benchmark.ID); err != nil {
+			t.Fatalf("Failed to store benchmark %d: %v", benchmark.ID, err)
+		}
+		LogBenchmarkCreated(user.ID, user.Username, benchmark.ID, title, 2)
+	}
+	return db
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	db := setupBackupTest(t)
+
+	var archive bytes.Buffer
+	manifest, err := WriteBackup(db, &archive, "test", true)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if manifest.Benchmarks != 2 || manifest.SchemaVersion != currentSchemaVersion {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	paths := make(map[string]bool)
+	for _, file := range manifest.Files {
+		paths[file.Path] = true
+	}
+	for _, want := range []string{backupDatabaseName, "benchmarks/1.manifest", "benchmarks/1-g1.bin", "benchmarks/2-g1.series", "logs/audit.json"} {
+		if !paths[want] {
+			t.Errorf("expected %s in backup, got %v", want, paths)
+		}
+	}
+	if _, err := VerifyBackup(bytes.NewReader(archive.Bytes())); err != nil {
+		t.Fatalf("Verification failed: %v", err)
+	}
+
+	restoreDir := filepath.Join(t.TempDir(), "data")
+	if _, _, err := RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, false); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(restoreDir), "logs", "audit.json")); err != nil {
+		t.Errorf("audit log not restored: %v", err)
+	}
+	restored, err := InitDB(restoreDir)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer cleanupTestDB(t, restored)
+	var count int64
+	restored.DB.Model(&Benchmark{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 restored benchmarks, got %d", count)
+	}
+	if err := InitBenchmarksDir(restoreDir); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	if data, err := RetrieveBenchmarkData(2); err != nil || len(data) != 2 {
+		t.Errorf("expected 2 restored runs, got %d (%v)", len(data), err)
+	}
+
+	t.Run("existing data needs force", func(t *testing.T) {
+		if _, _, err := RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, false); err == nil || !strings.Contains(err.Error(), "already contains data") {
+			t.Fatalf("expected refusal, got %v", err)
+		}
+		_, previousDir, err := RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, true)
+		if err != nil {
+			t.Fatalf("Forced restore failed: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(previousDir, "benchmarks", "1.manifest")); err != nil {
+			t.Errorf("previous data not kept: %v", err)
+		}
+	})
+}
+
+func TestBackupVerificationFailures(t *testing.T) {
+	db := setupBackupTest(t)
+
+	tests := []struct {
+		name    string
+		modify  func(m *BackupManifest)
+		wantErr string
+	}{
+		{"checksum mismatch", func(m *BackupManifest) { m.Files[1].SHA256 = strings.Repeat("0", 64) }, "checksum mismatch"},
+		{"newer schema", func(m *BackupManifest) { m.SchemaVersion = currentSchemaVersion + 1 }, "newer than this release supports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, err := createBackupSnapshot(db, "test", false)
+			if err != nil {
+				t.Fatalf("Snapshot failed: %v", err)
+			}
+			defer snapshot.Close()
+			tt.modify(snapshot.manifest)
+			var archive bytes.Buffer
+			if _, err := snapshot.WriteTo(&archive); err != nil {
+				t.Fatalf("Failed to write archive: %v", err)
+			}
+
+			restoreDir := t.TempDir()
+			_, _, err = RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if entries, _ := os.ReadDir(restoreDir); len(entries) != 0 {
+				t.Errorf("failed restore left files behind: %v", entries)
+			}
+		})
+	}
+
+	t.Run("truncated archive", func(t *testing.T) {
+		var archive bytes.Buffer
+		if _, err := WriteBackup(db, &archive, "test", false); err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		if _, err := VerifyBackup(bytes.NewReader(archive.Bytes()[:archive.Len()/2])); err == nil {
+			t.Error("expected truncated archive to fail verification")
+		}
+	})
+}
+
+func TestValidBackupPath(t *testing.T) {
+	tests := map[string]bool{
+		"flightlesssomething.db":                              true,
+		"benchmarks/1.manifest":                               true,
+		"benchmarks/12-g3.stats":                              true,
+		"benchmarks/run-" + strings.Repeat("ab", 32) + ".bin": true,
+		"benchmarks/trash-run-12.bin":                         true,
+		"benchmarks/trash-run-x.bin":                          false,
+		"logs/audit.json":                                     true,
+		"logs/audit-20260101.json.gz":                         true,
+		"benchmarks/1-g3.stats.tmp":                           false,
+		"benchmarks/../../etc/passwd":                         false,
+		"benchmarks/sub/1.bin":                                false,
+		"../flightlesssomething.db":                           false,
+		"/flightlesssomething.db":                             false,
+		"logs/notes.txt":                                      false,
+		"quarantine/1.bin":                                    false,
+	}
+	for name, want := range tests {
+		if got := validBackupPath(name); got != want {
+			t.Errorf("validBackupPath(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestHandleBackup(t *testing.T) {
+	db := setupBackupTest(t)
+	router := setupTestRouter()
+	router.GET("/api/admin/backup", HandleBackup(db, "test"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/backup?audit_logs=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Disposition"), `attachment; filename="flightlesssomething-backup-`) {
+		t.Errorf("unexpected Content-Disposition: %q", w.Header().Get("Content-Disposition"))
+	}
+	manifest, err := VerifyBackup(w.Body)
+	if err != nil {
+		t.Fatalf("Verification failed: %v", err)
+	}
+	if !manifest.AuditLogs || manifest.AppVersion != "test" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+}
+
+func TestBackupWaitsForMutations(t *testing.T) {
+	db := setupBackupTest(t)
+
+	unlock := lockBenchmarkWrites(1)
+	done := make(chan error, 1)
+	go func() {
+		var archive bytes.Buffer
+		_, err := WriteBackup(db, &archive, "test", false)
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("backup finished while a mutation was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+}
+
+func TestRunBackupAndRestore(t *testing.T) {
+	setupBackupTest(t)
+	dataDir := filepath.Dir(benchmarksDir)
+	output := filepath.Join(t.TempDir(), "backup.tar.zst")
+
+	var out, errOut bytes.Buffer
+	if code := RunBackup([]string{"--data-dir", dataDir, "--output", output}, "test", &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "Wrote verified backup") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	out.Reset()
+	restoreDir := filepath.Join(t.TempDir(), "data")
+	if code := RunRestore([]string{"--data-dir", restoreDir, "--input", output}, &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if code := RunRestore([]string{"--data-dir", restoreDir, "--input", output}, &out, &errOut); code != 1 {
+		t.Errorf("expected exit code 1 restoring over existing data, got %d", code)
+	}
+}
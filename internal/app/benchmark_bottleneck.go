@@ -0,0 +1,302 @@
+package app
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	bottleneckWindowMs       = 1000.0 // Elapsed time covered by one classification window
+	bottleneckMinSamples     = 30     // Minimum samples needed to analyze a run
+	gpuBoundLoadThreshold    = 90.0   // Mean GPU load (%) at or above which a window is GPU-bound
+	gpuBoundLoadWithMaxClock = 75.0   // Lower GPU load threshold when the GPU runs at full clock
+	gpuMaxClockRatio         = 0.95   // Core clock ratio (vs. the run's p95) considered "full clock"
+	frameCapTolerance        = 0.02   // Relative FPS distance from the cap counted as "at the cap"
+	frameCapMinShare         = 0.50   // Minimum share of frames at the cap to detect a limiter
+	frameCapMaxAbove         = 0.02   // Maximum share of frames allowed above the cap
+	cappedWindowShare        = 0.50   // Share of frames at the cap for a window to count as capped
+	cpuBoundLoadThreshold    = 80.0   // Mean CPU load (%) at or above which a window is CPU-bound
+	cpuMainThreadLoadRatio   = 0.85   // CPU load ratio (vs. the run's p95) of a busy main thread
+	cpuMinBusyLoad           = 10.0   // Mean CPU load (%) below which the CPU is never the limit
+)
+
+// Bottleneck states
+const (
+	BottleneckGPU     = "gpu"     // GPU-bound
+	BottleneckCPU     = "cpu"     // CPU/engine-bound (GPU waiting on the CPU or engine)
+	BottleneckCapped  = "capped"  // Frame-capped by vsync or a frame limiter
+	BottleneckUnknown = "unknown" // Neither component saturated, e.g. loading screens, menus or I/O stalls
+)
+
+// BottleneckSegment is a contiguous part of a run with a single bottleneck state.
+type BottleneckSegment struct {
+	State   string  `json:"state"`
+	Start   int     `json:"start"` // First sample index
+	End     int     `json:"end"`   // Sample index after the last sample
+	StartMs float64 `json:"startMs"`
+	EndMs   float64 `json:"endMs"`
+}
+
+// BottleneckAnalysis holds the CPU/GPU-bound classification of a run.
+// Percentages are shares of the classified elapsed time and add up to 100.
+type BottleneckAnalysis struct {
+	GPUBound float64             `json:"gpuBound"`
+	CPUBound float64             `json:"cpuBound"`
+	Capped   float64             `json:"capped"`
+	Unknown  float64             `json:"unknown"`
+	CapFPS   float64             `json:"capFps,omitempty"` // Detected frame cap (0 if none)
+	Segments []BottleneckSegment `json:"segments"`
+}
+
+// computeBottleneckAnalysis classifies fixed-duration windows of a run as GPU-bound,
+// CPU/engine-bound, frame-capped or unknown. Returns nil if the run lacks GPU load or frame
+// timing data.
+func computeBottleneckAnalysis(run *BenchmarkData) *BottleneckAnalysis {
+	n := len(run.DataFrameTime)
+	if n == 0 {
+		n = len(run.DataFPS)
+	}
+	if n < bottleneckMinSamples || !sensorAvailable(run.DataGPULoad) {
+		return nil
+	}
+
+	frametimes := runFrametimes(run, n)
+	capFPS := detectFrameCap(frametimes)
+	maxClock := clockReference(run.DataGPUCoreClock)
+	cpuReference := nonZeroPercentile(run.DataCPULoad, 95)
+
+	analysis := &BottleneckAnalysis{CapFPS: capFPS}
+	durations := make(map[string]float64)
+
+	for _, w := range splitTimeWindows(frametimes, bottleneckWindowMs) {
+		state := classifyBottleneckWindow(run, frametimes, w.start, w.end, capFPS, maxClock, cpuReference)
+		if state == "" {
+			continue
+		}
+		durations[state] += w.endMs - w.startMs
+		last := len(analysis.Segments) - 1
+		if last >= 0 && analysis.Segments[last].State == state && analysis.Segments[last].End == w.start {
+			analysis.Segments[last].End = w.end
+			analysis.Segments[last].EndMs = math.Round(w.endMs*100) / 100
+		} else {
+			analysis.Segments = append(analysis.Segments, BottleneckSegment{
+				State:   state,
+				Start:   w.start,
+				End:     w.end,
+				StartMs: math.Round(w.startMs*100) / 100,
+				EndMs:   math.Round(w.endMs*100) / 100,
+			})
+		}
+	}
+
+	total := durations[BottleneckGPU] + durations[BottleneckCPU] + durations[BottleneckCapped] + durations[BottleneckUnknown]
+	if total <= 0 {
+		return nil
+	}
+	analysis.GPUBound = math.Round(durations[BottleneckGPU]/total*10000) / 100
+	analysis.CPUBound = math.Round(durations[BottleneckCPU]/total*10000) / 100
+	analysis.Capped = math.Round(durations[BottleneckCapped]/total*10000) / 100
+	analysis.Unknown = math.Round(durations[BottleneckUnknown]/total*10000) / 100
+
+	return analysis
+}
+
+// classifyBottleneckWindow returns the bottleneck state of samples [start, end),
+// or "" if the window has no GPU load readings. cpuReference is the run's p95 CPU load.
+func classifyBottleneckWindow(run *BenchmarkData, frametimes []float64, start, end int, capFPS, maxClock, cpuReference float64) string {
+	gpuLoad, ok := windowMean(run.DataGPULoad, start, end)
+	if !ok {
+		return ""
+	}
+	if gpuLoad >= gpuBoundLoadThreshold {
+		// A saturated GPU is the limit even when frames sit at the cap; this also keeps
+		// very steady GPU-bound runs from being mistaken for a limiter
+		return BottleneckGPU
+	}
+
+	if capFPS > 0 {
+		atCap := 0
+		for i := start; i < end; i++ {
+			if isAtFrameCap(frametimes[i], capFPS) {
+				atCap++
+			}
+		}
+		if float64(atCap) >= cappedWindowShare*float64(end-start) {
+			return BottleneckCapped
+		}
+	}
+
+	// A GPU running at full clock with moderately high load is still the limiting factor
+	if maxClock > 0 && gpuLoad >= gpuBoundLoadWithMaxClock {
+		if clock, ok := windowMean(run.DataGPUCoreClock, start, end); ok && clock >= gpuMaxClockRatio*maxClock {
+			return BottleneckGPU
+		}
+	}
+
+	if cpuBusy(run, start, end, cpuReference) {
+		return BottleneckCPU
+	}
+	return BottleneckUnknown
+}
+
+// cpuBusy reports whether the CPU load of samples [start, end) shows the CPU as the limit: a high
+// total load, or a load near the run's p95. Per-core loads are not recorded, so a saturated main
+// thread is recognized by the total load sitting at the level the game's busiest threads reach.
+func cpuBusy(run *BenchmarkData, start, end int, cpuReference float64) bool {
+	if !sensorAvailable(run.DataCPULoad) {
+		return false
+	}
+	cpuLoad, ok := windowMean(run.DataCPULoad, start, end)
+	if !ok || cpuLoad < cpuMinBusyLoad {
+		return false
+	}
+	return cpuLoad >= cpuBoundLoadThreshold || cpuLoad >= cpuMainThreadLoadRatio*cpuReference
+}
+
+// detectFrameCap looks for a vsync/limiter ceiling: a cluster of frames at one FPS value
+// with (almost) no frames faster than it. Returns the cap in FPS, or 0 if none is found.
+func detectFrameCap(frametimes []float64) float64 {
+	counts := make(map[int]int)
+	valid := 0
+	for _, ft := range frametimes {
+		if ft <= 0 {
+			continue
+		}
+		counts[int(math.Round(1000/ft))]++
+		valid++
+	}
+	if valid < bottleneckMinSamples {
+		return 0
+	}
+
+	// Most common integer FPS value is the cap candidate
+	var mode, modeCount int
+	for fps, count := range counts {
+		if count > modeCount || (count == modeCount && fps > mode) {
+			mode, modeCount = fps, count
+		}
+	}
+	if mode <= 0 {
+		return 0
+	}
+
+	capFPS := float64(mode)
+	atCap, above := 0, 0
+	for _, ft := range frametimes {
+		if ft <= 0 {
+			continue
+		}
+		switch {
+		case isAtFrameCap(ft, capFPS):
+			atCap++
+		case 1000/ft > capFPS*(1+frameCapTolerance):
+			above++
+		}
+	}
+
+	if float64(atCap) < frameCapMinShare*float64(valid) || float64(above) > frameCapMaxAbove*float64(valid) {
+		return 0
+	}
+	return capFPS
+}
+
+// isAtFrameCap reports whether a frame's FPS is within tolerance of the cap.
+func isAtFrameCap(frametime, capFPS float64) bool {
+	if frametime <= 0 {
+		return false
+	}
+	return math.Abs(1000/frametime-capFPS) <= capFPS*frameCapTolerance
+}
+
+// clockReference returns the p95 of non-zero clock readings, used as the run's "full clock".
+func clockReference(clocks []float64) float64 {
+	return nonZeroPercentile(clocks, 95)
+}
+
+// nonZeroPercentile returns the p-th percentile of the non-zero readings of a sensor column.
+func nonZeroPercentile(data []float64, p float64) float64 {
+	values := make([]float64, 0, len(data))
+	for _, v := range data {
+		if v > 0 {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	return percentileLinear(values, p)
+}
+
+// timeWindow is a range of samples [start, end) covering a span of elapsed time.
+type timeWindow struct {
+	start, end     int
+	startMs, endMs float64
+}
+
+// runFrametimes returns the frametime of each of the first n samples (see sampleFrametime).
+func runFrametimes(run *BenchmarkData, n int) []float64 {
+	frametimes := make([]float64, n)
+	for i := range frametimes {
+		frametimes[i] = sampleFrametime(run, i)
+	}
+	return frametimes
+}
+
+// splitTimeWindows splits samples into consecutive windows of roughly windowMs elapsed time,
+// using cumulative frametime as the clock. Every window holds at least one sample.
+func splitTimeWindows(frametimes []float64, windowMs float64) []timeWindow {
+	var windows []timeWindow
+	var elapsed float64
+	for start := 0; start < len(frametimes); {
+		w := timeWindow{start: start, end: start, startMs: elapsed}
+		for w.end < len(frametimes) && (w.end == start || elapsed-w.startMs < windowMs) {
+			elapsed += frametimes[w.end]
+			w.end++
+		}
+		w.endMs = elapsed
+		windows = append(windows, w)
+		start = w.end
+	}
+	return windows
+}
+
+// windowMean returns the mean of data[start:end], clipped to the data length.
+func windowMean(data []float64, start, end int) (float64, bool) {
+	if end > len(data) {
+		end = len(data)
+	}
+	if start >= end {
+		return 0, false
+	}
+	var sum float64
+	for _, v := range data[start:end] {
+		sum += v
+	}
+	return sum / float64(end-start), true
+}
+
+// bottleneckToMCP converts a bottleneck analysis to the MCP BottleneckSummary format.
+func bottleneckToMCP(analysis *BottleneckAnalysis) *BottleneckSummary {
+	if analysis == nil {
+		return nil
+	}
+	summary := &BottleneckSummary{
+		GPUBoundPct: analysis.GPUBound,
+		CPUBoundPct: analysis.CPUBound,
+		CappedPct:   analysis.Capped,
+		UnknownPct:  analysis.Unknown,
+		CapFPS:      analysis.CapFPS,
+		Segments:    make([]BottleneckSegmentEntry, len(analysis.Segments)),
+	}
+	for i, seg := range analysis.Segments {
+		summary.Segments[i] = BottleneckSegmentEntry{
+			State:      seg.State,
+			StartIndex: seg.Start,
+			EndIndex:   seg.End,
+			StartMs:    seg.StartMs,
+			EndMs:      seg.EndMs,
+		}
+	}
+	return summary
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"testing"
+)
+
+// repeatValue returns a slice of n copies of v.
+func repeatValue(v float64, n int) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = v
+	}
+	return data
+}
+
+func TestDetectFrameCap(t *testing.T) {
+	t.Run("limiter at 60 FPS", func(t *testing.T) {
+		frametimes := repeatValue(16.67, 100)
+		// A few slower frames below the cap
+		for i := 0; i < 20; i++ {
+			frametimes[i*5] = 25
+		}
+		if got := detectFrameCap(frametimes); got != 60 {
+			t.Errorf("expected cap at 60, got %v", got)
+		}
+	})
+
+	t.Run("uncapped run", func(t *testing.T) {
+		frametimes := make([]float64, 100)
+		for i := range frametimes {
+			frametimes[i] = 8 + float64(i%10)
+		}
+		if got := detectFrameCap(frametimes); got != 0 {
+			t.Errorf("expected no cap, got %v", got)
+		}
+	})
+
+	t.Run("too few samples", func(t *testing.T) {
+		if got := detectFrameCap(repeatValue(16.67, 5)); got != 0 {
+			t.Errorf("expected no cap for short run, got %v", got)
+		}
+	})
+}
+
+func TestComputeBottleneckAnalysis(t *testing.T) {
+	t.Run("requires GPU load", func(t *testing.T) {
+		run := &BenchmarkData{DataFrameTime: repeatValue(10, 200)}
+		if got := computeBottleneckAnalysis(run); got != nil {
+			t.Errorf("expected nil without GPU load, got %+v", got)
+		}
+	})
+
+	t.Run("GPU then CPU bound", func(t *testing.T) {
+		// 200 frames at 10 ms = 2 s GPU-bound, then 200 frames at 10 ms with low GPU load
+		frametimes := make([]float64, 400)
+		gpuLoad := make([]float64, 400)
+		cpuLoad := make([]float64, 400)
+		for i := range frametimes {
+			frametimes[i] = 9 + float64(i%3) // no single FPS cluster
+			if i < 200 {
+				gpuLoad[i], cpuLoad[i] = 98, 30
+			} else {
+				gpuLoad[i], cpuLoad[i] = 55, 45 // Main thread saturated on a many-core CPU
+			}
+		}
+		analysis := computeBottleneckAnalysis(&BenchmarkData{DataFrameTime: frametimes, DataGPULoad: gpuLoad, DataCPULoad: cpuLoad})
+		if analysis == nil {
+			t.Fatal("expected analysis")
+		}
+		if analysis.CapFPS != 0 || analysis.Capped != 0 {
+			t.Errorf("unexpected cap: %v FPS, %v%%", analysis.CapFPS, analysis.Capped)
+		}
+		if !approxEqual(analysis.GPUBound, 50, 1) || !approxEqual(analysis.CPUBound, 50, 1) {
+			t.Errorf("expected ~50/50 split, got GPU %v%% CPU %v%%", analysis.GPUBound, analysis.CPUBound)
+		}
+		if len(analysis.Segments) != 2 || analysis.Segments[0].State != BottleneckGPU || analysis.Segments[1].State != BottleneckCPU {
+			t.Fatalf("unexpected segments: %+v", analysis.Segments)
+		}
+		if analysis.Segments[0].Start != 0 || analysis.Segments[1].End != 400 || analysis.Segments[0].End != analysis.Segments[1].Start {
+			t.Errorf("segments should cover the run contiguously: %+v", analysis.Segments)
+		}
+	})
+
+	t.Run("idle GPU and CPU is unknown", func(t *testing.T) {
+		// 2 s CPU-bound, then 2 s with both GPU and CPU load low (a loading screen)
+		frametimes := make([]float64, 400)
+		gpuLoad := make([]float64, 400)
+		cpuLoad := make([]float64, 400)
+		for i := range frametimes {
+			frametimes[i] = 9 + float64(i%3)
+			if i < 200 {
+				gpuLoad[i], cpuLoad[i] = 55, 60
+			} else {
+				gpuLoad[i], cpuLoad[i] = 40, 15
+			}
+		}
+		analysis := computeBottleneckAnalysis(&BenchmarkData{DataFrameTime: frametimes, DataGPULoad: gpuLoad, DataCPULoad: cpuLoad})
+		if analysis == nil {
+			t.Fatal("expected analysis")
+		}
+		if !approxEqual(analysis.CPUBound, 50, 1) || !approxEqual(analysis.Unknown, 50, 1) || analysis.GPUBound != 0 {
+			t.Errorf("expected ~50%% CPU-bound and ~50%% unknown, got %+v", analysis)
+		}
+		if len(analysis.Segments) != 2 || analysis.Segments[1].State != BottleneckUnknown {
+			t.Errorf("unexpected segments: %+v", analysis.Segments)
+		}
+
+		// Without CPU load readings the CPU can't be confirmed as the limit
+		analysis = computeBottleneckAnalysis(&BenchmarkData{DataFrameTime: frametimes, DataGPULoad: gpuLoad})
+		if analysis == nil || analysis.Unknown != 100 {
+			t.Errorf("expected unknown without CPU load, got %+v", analysis)
+		}
+	})
+
+	t.Run("frame capped", func(t *testing.T) {
+		n := 180 // 3 s at 60 FPS
+		analysis := computeBottleneckAnalysis(&BenchmarkData{
+			DataFrameTime: repeatValue(16.67, n),
+			DataGPULoad:   repeatValue(40, n),
+		})
+		if analysis == nil {
+			t.Fatal("expected analysis")
+		}
+		if analysis.CapFPS != 60 || analysis.Capped != 100 {
+			t.Errorf("expected fully capped at 60 FPS, got %v%% at %v", analysis.Capped, analysis.CapFPS)
+		}
+	})
+
+	t.Run("saturated GPU at the cap is GPU-bound", func(t *testing.T) {
+		n := 180
+		analysis := computeBottleneckAnalysis(&BenchmarkData{
+			DataFrameTime: repeatValue(16.67, n),
+			DataGPULoad:   repeatValue(97, n),
+		})
+		if analysis == nil || analysis.GPUBound != 100 {
+			t.Errorf("expected GPU-bound, got %+v", analysis)
+		}
+	})
+
+	t.Run("full clock with moderate load", func(t *testing.T) {
+		frametimes := make([]float64, 200)
+		for i := range frametimes {
+			frametimes[i] = 9 + float64(i%3)
+		}
+		analysis := computeBottleneckAnalysis(&BenchmarkData{
+			DataFrameTime:    frametimes,
+			DataGPULoad:      repeatValue(80, 200),
+			DataGPUCoreClock: repeatValue(2500, 200),
+		})
+		if analysis == nil || analysis.GPUBound != 100 {
+			t.Errorf("expected GPU-bound at full clock, got %+v", analysis)
+		}
+	})
+}
+
+func TestPreCalculatedRunBottleneckMCP(t *testing.T) {
+	n := 180
+	result := computePreCalculatedRun(&BenchmarkData{
+		DataFrameTime: repeatValue(16.67, n),
+		DataGPULoad:   repeatValue(40, n),
+	})
+	if result.Bottleneck == nil {
+		t.Fatal("expected bottleneck analysis on pre-calculated run")
+	}
+
+	summary := PreCalculatedRunToMCPSummary(result, 0)
+	if summary.Bottleneck == nil || summary.Bottleneck.CappedPct != 100 || summary.Bottleneck.CapFPS != 60 {
+		t.Fatalf("unexpected MCP bottleneck: %+v", summary.Bottleneck)
+	}
+	if len(summary.Bottleneck.Segments) != 1 || summary.Bottleneck.Segments[0].EndIndex != n {
+		t.Errorf("unexpected MCP segments: %+v", summary.Bottleneck.Segments)
+	}
+}
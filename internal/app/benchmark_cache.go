@@ -0,0 +1,224 @@
+package app
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Decoded .meta payloads and serialized /data responses are kept in a size-bounded LRU cache, so
+// popular benchmarks aren't decoded from the blob store on every request. Every write to a
+// benchmark's files (a committed generation or a deletion) invalidates its entries. Readers take
+// a token before reading the files and the cache drops their result if any benchmark was
+// invalidated in the meantime, so a write racing a read can't leave a stale entry behind. A single
+// generation counter keeps that check free of per-benchmark state; a write elsewhere only costs
+// the in-flight reads their cache store.
+
+// Cached payload kinds
+const (
+	benchmarkCacheMeta  = "meta"
+	benchmarkCacheStats = "stats"
+)
+
+// defaultBenchmarkCacheBytes is the cache size unless configured otherwise.
+const defaultBenchmarkCacheBytes = 64 << 20
+
+// benchmarkCache is the process-wide cache, resized from the config at startup.
+var benchmarkCache = NewBenchmarkCache(defaultBenchmarkCacheBytes)
+
+type benchmarkCacheKey struct {
+	benchmarkID uint
+	kind        string
+	variant     string // Distinguishes payloads of the same kind, e.g. stats selections
+}
+
+type benchmarkCacheEntry struct {
+	key   benchmarkCacheKey
+	value any
+	size  int64
+}
+
+// BenchmarkCacheCounters counts lookups of one payload kind.
+type BenchmarkCacheCounters struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// BenchmarkCacheStats reports the cache's size and counters.
+type BenchmarkCacheStats struct {
+	CapacityBytes int64                             `json:"capacity_bytes"`
+	SizeBytes     int64                             `json:"size_bytes"`
+	Entries       int                               `json:"entries"`
+	Evictions     int64                             `json:"evictions"`
+	Invalidations int64                             `json:"invalidations"`
+	Kinds         map[string]BenchmarkCacheCounters `json:"kinds"`
+}
+
+// BenchmarkCache is an LRU cache of benchmark payloads bounded by their total size in bytes.
+type BenchmarkCache struct {
+	mu          sync.Mutex
+	capacity    int64
+	size        int64
+	order       *list.List // Front is the most recently used entry
+	entries     map[benchmarkCacheKey]*list.Element
+	byBenchmark map[uint]map[benchmarkCacheKey]struct{}
+
+	// Invalidation token: grows on every invalidation and reset
+	generation uint64
+
+	evictions     int64
+	invalidations int64
+	counters      map[string]*BenchmarkCacheCounters
+}
+
+// NewBenchmarkCache creates a cache holding up to capacity bytes. A capacity of 0 disables caching.
+func NewBenchmarkCache(capacity int64) *BenchmarkCache {
+	c := &BenchmarkCache{capacity: capacity, counters: make(map[string]*BenchmarkCacheCounters)}
+	c.clear()
+	return c
+}
+
+// clear drops every entry. Callers hold mu, except the constructor.
+func (c *BenchmarkCache) clear() {
+	c.order = list.New()
+	c.entries = make(map[benchmarkCacheKey]*list.Element)
+	c.byBenchmark = make(map[uint]map[benchmarkCacheKey]struct{})
+	c.size = 0
+}
+
+// SetCapacity changes the cache size, evicting entries that no longer fit.
+func (c *BenchmarkCache) SetCapacity(capacity int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+// lookup returns a cached payload. On a miss, the returned token must be passed to store along
+// with the payload read from the files.
+func (c *BenchmarkCache) lookup(benchmarkID uint, kind, variant string) (any, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counters := c.counters[kind]
+	if counters == nil {
+		counters = &BenchmarkCacheCounters{}
+		c.counters[kind] = counters
+	}
+	if elem, ok := c.entries[benchmarkCacheKey{benchmarkID, kind, variant}]; ok {
+		counters.Hits++
+		c.order.MoveToFront(elem)
+		return elem.Value.(*benchmarkCacheEntry).value, 0, true
+	}
+	counters.Misses++
+	return nil, c.generation, false
+}
+
+// store caches a payload of the given size, unless a benchmark was invalidated since the lookup
+// that returned token. Payloads larger than a quarter of the cache are not cached, so a
+// single huge benchmark can't flush everything else.
+func (c *BenchmarkCache) store(benchmarkID uint, kind, variant string, token uint64, value any, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size > c.capacity/4 || c.generation != token {
+		return
+	}
+	key := benchmarkCacheKey{benchmarkID, kind, variant}
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&benchmarkCacheEntry{key: key, value: value, size: size})
+	if c.byBenchmark[benchmarkID] == nil {
+		c.byBenchmark[benchmarkID] = make(map[benchmarkCacheKey]struct{})
+	}
+	c.byBenchmark[benchmarkID][key] = struct{}{}
+	c.size += size
+	c.evict()
+}
+
+// evict removes the least recently used entries until the cache fits its capacity. Callers hold mu.
+func (c *BenchmarkCache) evict() {
+	for c.size > c.capacity {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+// remove drops an entry. Callers hold mu.
+func (c *BenchmarkCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*benchmarkCacheEntry)
+	delete(c.entries, entry.key)
+	keys := c.byBenchmark[entry.key.benchmarkID]
+	delete(keys, entry.key)
+	if len(keys) == 0 {
+		delete(c.byBenchmark, entry.key.benchmarkID)
+	}
+	c.size -= entry.size
+}
+
+// invalidate drops every entry of a benchmark and any result still being read for it.
+func (c *BenchmarkCache) invalidate(benchmarkID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.invalidations++
+	for key := range c.byBenchmark[benchmarkID] {
+		c.remove(c.entries[key])
+	}
+}
+
+// reset drops every entry, e.g. when the blob store changes.
+func (c *BenchmarkCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.clear()
+}
+
+// Stats returns the cache's size and counters.
+func (c *BenchmarkCache) Stats() BenchmarkCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := BenchmarkCacheStats{
+		CapacityBytes: c.capacity,
+		SizeBytes:     c.size,
+		Entries:       len(c.entries),
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+		Kinds:         make(map[string]BenchmarkCacheCounters),
+	}
+	for _, kind := range []string{benchmarkCacheMeta, benchmarkCacheStats} {
+		stats.Kinds[kind] = BenchmarkCacheCounters{}
+	}
+	for kind, counters := range c.counters {
+		stats.Kinds[kind] = *counters
+	}
+	return stats
+}
+
+// metadataCacheSize estimates the memory a cached .meta payload takes.
+func metadataCacheSize(metadata *BenchmarkMetadata) int64 {
+	size := int64(64)
+	for _, label := range metadata.RunLabels {
+		size += int64(len(label)) + 16
+	}
+	return size
+}
+
+// statsCacheVariant identifies a stats selection, and whether run groups are included, in the cache.
+func statsCacheVariant(sel StatsSelection, groups bool) string {
+	return fmt.Sprintf("runs=%v metrics=%v methods=%v series=%t stats=%t density=%t groups=%t",
+		sel.Runs, sel.Metrics, sel.Methods, sel.Series, sel.Stats, sel.Density, groups)
+}
+
+// HandleGetBenchmarkCache returns the benchmark cache's size and hit/miss counters (admin only)
+func HandleGetBenchmarkCache(c *gin.Context) {
+	c.JSON(http.StatusOK, benchmarkCache.Stats())
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBenchmarkCacheLRU(t *testing.T) {
+	cache := NewBenchmarkCache(400)
+
+	store := func(id uint, size int64) {
+		_, token, ok := cache.lookup(id, benchmarkCacheMeta, "")
+		if ok {
+			t.Fatalf("unexpected hit for benchmark %d", id)
+		}
+		cache.store(id, benchmarkCacheMeta, "", token, id, size)
+	}
+	store(1, 100)
+	store(2, 100)
+	store(3, 100)
+	if _, _, ok := cache.lookup(1, benchmarkCacheMeta, ""); !ok {
+		t.Fatal("expected benchmark 1 cached")
+	}
+	store(4, 100)
+	store(5, 100) // Evicts 2, the least recently used entry
+
+	if _, _, ok := cache.lookup(2, benchmarkCacheMeta, ""); ok {
+		t.Error("expected benchmark 2 evicted")
+	}
+	for _, id := range []uint{1, 3, 4, 5} {
+		if value, _, ok := cache.lookup(id, benchmarkCacheMeta, ""); !ok || value != id {
+			t.Errorf("expected benchmark %d cached, got %v", id, value)
+		}
+	}
+
+	store(6, 101) // Larger than a quarter of the cache
+	if _, _, ok := cache.lookup(6, benchmarkCacheMeta, ""); ok {
+		t.Error("expected oversized payload not cached")
+	}
+
+	stats := cache.Stats()
+	if stats.SizeBytes != 400 || stats.Entries != 4 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if meta := stats.Kinds[benchmarkCacheMeta]; meta.Hits != 5 || meta.Misses != 8 {
+		t.Errorf("unexpected counters: %+v", meta)
+	}
+}
+
+func TestBenchmarkCacheInvalidation(t *testing.T) {
+	cache := NewBenchmarkCache(1 << 20)
+
+	_, token, _ := cache.lookup(1, benchmarkCacheStats, "a")
+	cache.store(1, benchmarkCacheStats, "a", token, "a", 10)
+	_, token, _ = cache.lookup(1, benchmarkCacheStats, "b")
+	cache.store(1, benchmarkCacheStats, "b", token, "b", 10)
+	_, token, _ = cache.lookup(2, benchmarkCacheStats, "a")
+	cache.store(2, benchmarkCacheStats, "a", token, "a", 10)
+
+	cache.invalidate(1)
+	for _, variant := range []string{"a", "b"} {
+		if _, _, ok := cache.lookup(1, benchmarkCacheStats, variant); ok {
+			t.Errorf("expected variant %s of benchmark 1 invalidated", variant)
+		}
+	}
+	if _, _, ok := cache.lookup(2, benchmarkCacheStats, "a"); !ok {
+		t.Error("expected benchmark 2 kept")
+	}
+
+	t.Run("read racing a write", func(t *testing.T) {
+		_, token, _ := cache.lookup(1, benchmarkCacheStats, "a")
+		cache.invalidate(1) // The files changed while the payload was being read
+		cache.store(1, benchmarkCacheStats, "a", token, "stale", 10)
+		if _, _, ok := cache.lookup(1, benchmarkCacheStats, "a"); ok {
+			t.Error("expected the stale payload dropped")
+		}
+	})
+
+	t.Run("read racing a write of another benchmark", func(t *testing.T) {
+		_, token, _ := cache.lookup(1, benchmarkCacheStats, "a")
+		cache.invalidate(5)
+		cache.store(1, benchmarkCacheStats, "a", token, "a", 10)
+		if _, _, ok := cache.lookup(1, benchmarkCacheStats, "a"); ok {
+			t.Error("expected the payload dropped, the token covers every benchmark")
+		}
+		_, token, _ = cache.lookup(1, benchmarkCacheStats, "a")
+		cache.store(1, benchmarkCacheStats, "a", token, "a", 10)
+		if _, _, ok := cache.lookup(1, benchmarkCacheStats, "a"); !ok {
+			t.Error("expected the payload cached on the next read")
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		_, token, _ := cache.lookup(3, benchmarkCacheStats, "a")
+		cache.reset()
+		cache.store(3, benchmarkCacheStats, "a", token, "stale", 10)
+		if stats := cache.Stats(); stats.Entries != 0 || stats.SizeBytes != 0 {
+			t.Errorf("expected an empty cache, got %+v", stats)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cache.SetCapacity(0)
+		_, token, _ := cache.lookup(4, benchmarkCacheStats, "a")
+		cache.store(4, benchmarkCacheStats, "a", token, "a", 1)
+		if _, _, ok := cache.lookup(4, benchmarkCacheStats, "a"); ok {
+			t.Error("expected nothing cached without capacity")
+		}
+	})
+}
+
+func TestBenchmarkDataCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "cacheuser", true)
+	router := revisionTestRouter(db, user)
+	router.POST("/api/benchmarks", func(c *gin.Context) {
+		c.Set("UserID", user.ID)
+		HandleCreateBenchmark(db)(c)
+	})
+	router.GET("/api/benchmarks/:id/data", HandleGetBenchmarkData(db))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, createBenchmarkRequest(t, "Cached", "a", "b"))
+	var benchmark Benchmark
+	if err := json.Unmarshal(w.Body.Bytes(), &benchmark); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+	}
+
+	getData := func(query string) []map[string]any {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/benchmarks/%d/data%s", benchmark.ID, query), nil))
+		var runs []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to get data: %d %s", w.Code, w.Body.String())
+		}
+		return runs
+	}
+	counters := func() BenchmarkCacheCounters {
+		return benchmarkCache.Stats().Kinds[benchmarkCacheStats]
+	}
+
+	before := counters()
+	first := getData("")
+	second := getData("")
+	if after := counters(); after.Misses != before.Misses+1 || after.Hits != before.Hits+1 {
+		t.Errorf("expected one miss and one hit, got %+v (before %+v)", after, before)
+	}
+	if len(first) != 2 || len(second) != 2 {
+		t.Errorf("expected 2 runs from both requests, got %d and %d", len(first), len(second))
+	}
+	if runs := getData("?runs=1"); len(runs) != 1 || runs[0]["label"] != "b" {
+		t.Errorf("expected a separate entry for the selection, got %v", runs)
+	}
+
+	t.Run("writes invalidate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, addRunRequest(t, benchmark.ID, "c", `"1"`))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to add run: %d %s", w.Code, w.Body.String())
+		}
+		if runs := getData(""); len(runs) != 3 {
+			t.Errorf("expected the added run served, got %d runs", len(runs))
+		}
+		count, labels, err := GetBenchmarkRunCount(benchmark.ID)
+		if err != nil || count != 3 || labels[2] != "c" {
+			t.Errorf("expected metadata updated, got %d %v %v", count, labels, err)
+		}
+	})
+}
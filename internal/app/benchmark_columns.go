@@ -0,0 +1,271 @@
+package app
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// Storage format v3 stores .bin files as sectioned files (see sectioned_file.go). Each run has a
+// gob section with its label and specs, and one section per non-empty data column. The index lets
+// readers fetch a single run, or a single column of a run, without decoding anything else.
+//
+// Columns are encoded before zstd compression with whichever float-friendly encoding applies:
+// readings parsed from CSV are decimals with at most 5 fractional digits, stored as varint deltas
+// of the scaled integers; anything else is Gorilla XOR encoded (see gorilla.go).
+const binFileMagic = "FSDATAV3" // Trailer magic (8 bytes)
+
+// Column encodings
+const (
+	columnEncodingGorilla = 0
+	columnEncodingDelta   = 1 // Zigzag varint deltas of values scaled by precisionFactor
+)
+
+// maxExactScaledValue bounds scaled integers so they convert to float64 without rounding.
+const maxExactScaledValue = 1 << 53
+
+// binIndex describes the sections of a v3 .bin file.
+type binIndex struct {
+	Version int
+	Runs    []binRunIndex
+}
+
+// binRunIndex describes the sections of one run.
+type binRunIndex struct {
+	Header  fileSection          // gob BenchmarkData without data columns
+	Columns map[string]binColumn // metric key -> column
+	Shared  string               // Content hash of the shared run blob holding the columns, if any
+}
+
+// binColumn locates an encoded data column.
+type binColumn struct {
+	Section  fileSection
+	Count    int // Number of values
+	Encoding int // columnEncodingGorilla or columnEncodingDelta
+}
+
+// dataColumn pairs a metric key with the BenchmarkData field holding its raw data.
+type dataColumn struct {
+	key  string
+	data *[]float64
+}
+
+// dataColumns returns every data column of a run, keyed like the pre-calculated stats.
+func dataColumns(run *BenchmarkData) []dataColumn {
+	return []dataColumn{
+		{"FPS", &run.DataFPS},
+		{"FrameTime", &run.DataFrameTime},
+		{"CPULoad", &run.DataCPULoad},
+		{"GPULoad", &run.DataGPULoad},
+		{"CPUTemp", &run.DataCPUTemp},
+		{"CPUPower", &run.DataCPUPower},
+		{"GPUTemp", &run.DataGPUTemp},
+		{"GPUCoreClock", &run.DataGPUCoreClock},
+		{"GPUMemClock", &run.DataGPUMemClock},
+		{"GPUVRAMUsed", &run.DataGPUVRAMUsed},
+		{"GPUPower", &run.DataGPUPower},
+		{"RAMUsed", &run.DataRAMUsed},
+		{"SwapUsed", &run.DataSwapUsed},
+		{"CPUClock", &run.DataCPUClock},
+	}
+}
+
+func binFileName(benchmarkID uint) string {
+	return benchmarkFileName(benchmarkID, benchmarkFileData)
+}
+
+// writeColumnarBenchmarkFile writes runs to a .bin file in storage format v3.
+func writeColumnarBenchmarkFile(out io.Writer, benchmarkData []*BenchmarkData) error {
+	return writeColumnarRuns(out, benchmarkData, runDedupEnabled)
+}
+
+// writeColumnarRuns writes runs in storage format v3. With dedup, data columns are stored in
+// shared run blobs (see benchmark_runs.go).
+func writeColumnarRuns(out io.Writer, benchmarkData []*BenchmarkData, dedup bool) error {
+	sw, err := newSectionWriter(out)
+	if err != nil {
+		return err
+	}
+	defer sw.Close()
+
+	index := binIndex{Version: storageFormatVersion, Runs: make([]binRunIndex, len(benchmarkData))}
+	for i, run := range benchmarkData {
+		if index.Runs[i], err = writeColumnarRun(sw, run, dedup); err != nil {
+			return fmt.Errorf("failed to encode run %d: %w", i, err)
+		}
+	}
+	return sw.finish(index, binFileMagic)
+}
+
+func writeColumnarRun(sw *sectionWriter, run *BenchmarkData, dedup bool) (binRunIndex, error) {
+	idx := binRunIndex{Columns: make(map[string]binColumn)}
+
+	header := *run
+	for _, col := range dataColumns(&header) {
+		*col.data = nil
+	}
+	var err error
+	if idx.Header, err = sw.writeGob(&header); err != nil {
+		return idx, err
+	}
+
+	if dedup {
+		idx.Shared = runContentHash(run)
+		if err := writeSharedRun(idx.Shared, run); err != nil {
+			return idx, fmt.Errorf("failed to write shared run: %w", err)
+		}
+		return idx, nil
+	}
+
+	for _, col := range dataColumns(run) {
+		if len(*col.data) == 0 {
+			continue
+		}
+		data, encoding := encodeColumn(*col.data)
+		section, err := sw.writeSection(data)
+		if err != nil {
+			return idx, fmt.Errorf("failed to write %s column: %w", col.key, err)
+		}
+		idx.Columns[col.key] = binColumn{Section: section, Count: len(*col.data), Encoding: encoding}
+	}
+	return idx, nil
+}
+
+// encodeColumn encodes values with the delta encoding when every value is an exact decimal with
+// at most 5 fractional digits, and with Gorilla XOR encoding otherwise.
+func encodeColumn(values []float64) ([]byte, int) {
+	buf := make([]byte, 0, len(values)*2)
+	var prev int64
+	for _, v := range values {
+		scaled := math.Round(v * precisionFactor)
+		// Negative zero would decode as zero, NaN never compares equal
+		if math.Abs(scaled) >= maxExactScaledValue || scaled/precisionFactor != v || math.Signbit(scaled) && scaled == 0 {
+			return encodeGorilla(values), columnEncodingGorilla
+		}
+		buf = binary.AppendVarint(buf, int64(scaled)-prev)
+		prev = int64(scaled)
+	}
+	return buf, columnEncodingDelta
+}
+
+// decodeColumn decodes count values written by encodeColumn.
+func decodeColumn(data []byte, count, encoding int) ([]float64, error) {
+	switch encoding {
+	case columnEncodingGorilla:
+		return decodeGorilla(data, count)
+	case columnEncodingDelta:
+		// Every value takes at least one byte; reject counts the data can't hold before allocating
+		if count < 0 || count > len(data) {
+			return nil, fmt.Errorf("delta column truncated")
+		}
+		values := make([]float64, count)
+		var prev int64
+		for i := range values {
+			delta, n := binary.Varint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("delta column truncated")
+			}
+			data = data[n:]
+			prev += delta
+			values[i] = float64(prev) / precisionFactor
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unknown column encoding: %d", encoding)
+	}
+}
+
+// openColumnarBenchmarkFile opens a v3 .bin file. Returns errNotSectioned for older formats.
+func openColumnarBenchmarkFile(benchmarkID uint) (*sectionedFile, *binIndex, error) {
+	index := &binIndex{}
+	sf, err := openSectionedFile(binFileName(benchmarkID), binFileMagic, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index.Version != storageFormatVersion {
+		sf.Close()
+		return nil, nil, fmt.Errorf("unsupported storage format version: %d", index.Version)
+	}
+	if len(index.Runs) > maxRunsPerBenchmark {
+		sf.Close()
+		return nil, nil, fmt.Errorf("invalid run count in file index: %d", len(index.Runs))
+	}
+	return sf, index, nil
+}
+
+// readColumnarRun reads a run's header and the given columns (all columns when keys is nil).
+func readColumnarRun(sf *sectionedFile, idx *binRunIndex, keys []string) (*BenchmarkData, error) {
+	run := &BenchmarkData{}
+	if err := sf.readGob(idx.Header, run); err != nil {
+		return nil, fmt.Errorf("failed to decode run header: %w", err)
+	}
+	var err error
+	if idx.Shared != "" {
+		err = readSharedRunColumns(idx.Shared, run, keys)
+	} else {
+		err = readColumns(sf, idx, run, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// readColumns reads the given columns of a run (all columns when keys is nil) into run.
+func readColumns(sf *sectionedFile, idx *binRunIndex, run *BenchmarkData, keys []string) error {
+	for _, col := range dataColumns(run) {
+		column, ok := idx.Columns[col.key]
+		if !ok || (keys != nil && !slices.Contains(keys, col.key)) {
+			continue
+		}
+		if column.Count > maxPerRunDataLines {
+			return fmt.Errorf("%s column too long: %d values", col.key, column.Count)
+		}
+		raw, err := sf.readSection(column.Section)
+		if err != nil {
+			return fmt.Errorf("failed to read %s column: %w", col.key, err)
+		}
+		if *col.data, err = decodeColumn(raw, column.Count, column.Encoding); err != nil {
+			return fmt.Errorf("failed to decode %s column: %w", col.key, err)
+		}
+	}
+	return nil
+}
+
+// retrieveBenchmarkRunColumns reads the given columns of one run of a v3 .bin file, touching only
+// the sections they are stored in. A nil keys reads every column.
+func retrieveBenchmarkRunColumns(benchmarkID uint, runIndex int, keys []string) (*BenchmarkData, error) {
+	sf, index, err := openColumnarBenchmarkFile(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Close()
+
+	if runIndex < 0 || runIndex >= len(index.Runs) {
+		return nil, fmt.Errorf("invalid run index %d (total runs: %d)", runIndex, len(index.Runs))
+	}
+	return readColumnarRun(sf, &index.Runs[runIndex], keys)
+}
+
+// benchmarkStorageVersion detects the storage format of a benchmark's .bin file.
+func benchmarkStorageVersion(benchmarkID uint) (int, error) {
+	sf, index, err := openColumnarBenchmarkFile(benchmarkID)
+	if err == nil {
+		sf.Close()
+		return index.Version, nil
+	}
+	if !errors.Is(err, errNotSectioned) {
+		return 0, err
+	}
+	isV2, err := isBenchmarkFormatV2(benchmarkID)
+	if err != nil {
+		return 0, err
+	}
+	if isV2 {
+		return 2, nil
+	}
+	return 1, nil
+}
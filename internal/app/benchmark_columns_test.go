@@ -0,0 +1,191 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestGorillaRoundTrip(t *testing.T) {
+	tests := map[string][]float64{
+		"empty":    nil,
+		"single":   {16.666},
+		"constant": repeatValue(60, 100),
+		"sensor":   {45.5, 45.5, 46, 46.25, 47, 46.75, 120.125, 0, -3.5, 1e-9, 1e12},
+		"special":  {math.NaN(), math.Inf(1), math.Inf(-1), 0, math.Copysign(0, -1), math.MaxFloat64, math.SmallestNonzeroFloat64},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeGorilla(encodeGorilla(values), len(values))
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if len(got) != len(values) {
+				t.Fatalf("expected %d values, got %d", len(values), len(got))
+			}
+			for i := range values {
+				if math.Float64bits(got[i]) != math.Float64bits(values[i]) {
+					t.Errorf("value %d: got %v, want %v", i, got[i], values[i])
+				}
+			}
+		})
+	}
+
+	if _, err := decodeGorilla(encodeGorilla([]float64{1, 2, 3}), 50); err != errGorillaTruncated {
+		t.Errorf("expected truncated error, got %v", err)
+	}
+}
+
+func TestEncodeColumn(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		encoding int
+	}{
+		{"csv decimals", []float64{144.92, 143.5, 16.66667, -3.25, 0, 1e9}, columnEncodingDelta},
+		{"more than 5 decimals", []float64{1, 1.0000001}, columnEncodingGorilla},
+		{"derived value", []float64{60, 1.0 / 3}, columnEncodingGorilla},
+		{"negative zero", []float64{math.Copysign(0, -1)}, columnEncodingGorilla},
+		{"NaN", []float64{1, math.NaN()}, columnEncodingGorilla},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, encoding := encodeColumn(tt.values)
+			if encoding != tt.encoding {
+				t.Errorf("expected encoding %d, got %d", tt.encoding, encoding)
+			}
+			got, err := decodeColumn(data, len(tt.values), encoding)
+			if err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			for i := range tt.values {
+				if math.Float64bits(got[i]) != math.Float64bits(tt.values[i]) {
+					t.Errorf("value %d: got %v, want %v", i, got[i], tt.values[i])
+				}
+			}
+		})
+	}
+}
+
+// columnarRuns returns two runs with different column sets.
+func columnarRuns() []*BenchmarkData {
+	return []*BenchmarkData{
+		{
+			Label: "Run A", SpecOS: "Linux", SpecGPU: "RX 7900",
+			DataFPS: []float64{60, 61.5, 59.25}, DataFrameTime: []float64{16.67, 16.26, 16.88},
+			DataGPUTemp: []float64{65, 65, 66},
+		},
+		{Label: "Run B", SpecCPU: "Ryzen", DataFrameTime: []float64{8.3, 8.4}, DataCPULoad: []float64{40, 45}},
+	}
+}
+
+// writeV2BenchmarkFile writes runs in the gob stream format used before columnar storage.
+func writeV2BenchmarkFile(t *testing.T, benchmarkID uint, runs []*BenchmarkData) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(benchmarksDir, binFileName(benchmarkID)))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	bufWriter := bufio.NewWriter(file)
+	enc, err := zstd.NewWriter(bufWriter)
+	if err != nil {
+		t.Fatalf("Failed to create zstd writer: %v", err)
+	}
+	gobEncoder := gob.NewEncoder(enc)
+	if err := gobEncoder.Encode(fileHeader{Version: storageFormatVersionV2, RunCount: len(runs)}); err != nil {
+		t.Fatalf("Failed to encode header: %v", err)
+	}
+	for _, run := range runs {
+		if err := gobEncoder.Encode(run); err != nil {
+			t.Fatalf("Failed to encode run: %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Failed to close zstd writer: %v", err)
+	}
+	if err := bufWriter.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Failed to close file: %v", err)
+	}
+}
+
+func TestColumnarStorage(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := InitBenchmarksDir(dataDir); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	runs := columnarRuns()
+	if err := StoreBenchmarkData(runs, 1); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+
+	t.Run("full read", func(t *testing.T) {
+		got, err := RetrieveBenchmarkData(1)
+		if err != nil {
+			t.Fatalf("Failed to retrieve: %v", err)
+		}
+		if !reflect.DeepEqual(got, columnarRuns()) {
+			t.Errorf("round trip mismatch: %+v", got[0])
+		}
+		if version, err := benchmarkStorageVersion(1); err != nil || version != storageFormatVersion {
+			t.Errorf("expected version %d, got %d (%v)", storageFormatVersion, version, err)
+		}
+	})
+
+	t.Run("single run and column", func(t *testing.T) {
+		run, err := RetrieveBenchmarkRun(1, 1)
+		if err != nil || !reflect.DeepEqual(run, columnarRuns()[1]) {
+			t.Fatalf("unexpected run: %+v (%v)", run, err)
+		}
+		run, err = retrieveBenchmarkRunColumns(1, 0, []string{"FPS"})
+		if err != nil {
+			t.Fatalf("Failed to read column: %v", err)
+		}
+		if run.Label != "Run A" || len(run.DataFPS) != 3 || run.DataFrameTime != nil {
+			t.Errorf("expected only the FPS column, got %+v", run)
+		}
+		if _, err := RetrieveBenchmarkRun(1, 2); err == nil {
+			t.Error("expected error for out of range run index")
+		}
+	})
+
+	t.Run("zip export", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportBenchmarkDataAsZip(1, &buf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		if buf.Len() == 0 {
+			t.Error("expected a non-empty ZIP")
+		}
+	})
+
+	t.Run("v2 migration", func(t *testing.T) {
+		writeV2BenchmarkFile(t, 2, columnarRuns())
+		if version, err := benchmarkStorageVersion(2); err != nil || version != storageFormatVersionV2 {
+			t.Fatalf("expected version 2, got %d (%v)", version, err)
+		}
+		if run, err := RetrieveBenchmarkRun(2, 1); err != nil || run.Label != "Run B" {
+			t.Fatalf("Failed to read v2 run: %v", err)
+		}
+
+		if err := MigrateBenchmarkStorageToV3(dataDir); err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if version, err := benchmarkStorageVersion(2); err != nil || version != storageFormatVersion {
+			t.Errorf("expected version %d after migration, got %d (%v)", storageFormatVersion, version, err)
+		}
+		got, err := RetrieveBenchmarkData(2)
+		if err != nil || !reflect.DeepEqual(got, columnarRuns()) {
+			t.Errorf("migrated data mismatch (%v)", err)
+		}
+	})
+}
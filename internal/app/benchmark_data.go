@@ -8,11 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,7 +37,8 @@ const (
 	maxFilesPerUpload  = 100 // Maximum number of files that can be uploaded in one request
 
 	// Storage format version for backward compatibility
-	storageFormatVersion = 2 // Version 2: Streaming-friendly format with individual run encoding
+	storageFormatVersion   = 3 // Version 3: Columnar sectioned format with delta/XOR-encoded columns
+	storageFormatVersionV2 = 2 // Version 2: Gob stream with individual run encoding (read-only)
 
 	// GC tuning constants for streaming operations
 	// These control how often runtime.GC() is called during streaming to aggressively reclaim memory
@@ -46,6 +49,7 @@ const (
 	maxRunsPerBenchmark = 10_000
 )
 
+// benchmarksDir is the directory of the local blob store; empty with other backends
 var benchmarksDir string
 
 // fileHeader is written at the beginning of the benchmark data file
@@ -54,9 +58,12 @@ type fileHeader struct {
 	RunCount int // Number of runs in this file
 }
 
-// InitBenchmarksDir initializes the directory for benchmark data
+// InitBenchmarksDir initializes the directory for benchmark data and uses it as the blob store
 func InitBenchmarksDir(dataDir string) error {
 	benchmarksDir = filepath.Join(dataDir, "benchmarks")
+	storageDataDir = dataDir
+	blobStore = newLocalBlobStore(benchmarksDir)
+	resetManifestCache()
 	return os.MkdirAll(benchmarksDir, 0o750)
 }
 
@@ -109,6 +116,7 @@ func parseData(scanner *bufio.Scanner, headerMap map[int]string, benchmarkData *
 	benchmarkData.DataGPUPower = make([]float64, 0, capacity)
 	benchmarkData.DataRAMUsed = make([]float64, 0, capacity)
 	benchmarkData.DataSwapUsed = make([]float64, 0, capacity)
+	benchmarkData.DataCPUClock = make([]float64, 0, capacity)
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -161,6 +169,8 @@ func parseData(scanner *bufio.Scanner, headerMap map[int]string, benchmarkData *
 				benchmarkData.DataRAMUsed = append(benchmarkData.DataRAMUsed, val)
 			case "swap_used":
 				benchmarkData.DataSwapUsed = append(benchmarkData.DataSwapUsed, val)
+			case "cpu_mhz", "CPU clock":
+				benchmarkData.DataCPUClock = append(benchmarkData.DataCPUClock, val)
 			}
 		}
 
@@ -183,7 +193,8 @@ func parseData(scanner *bufio.Scanner, headerMap map[int]string, benchmarkData *
 		len(benchmarkData.DataGPUVRAMUsed) == 0 &&
 		len(benchmarkData.DataGPUPower) == 0 &&
 		len(benchmarkData.DataRAMUsed) == 0 &&
-		len(benchmarkData.DataSwapUsed) == 0 {
+		len(benchmarkData.DataSwapUsed) == 0 &&
+		len(benchmarkData.DataCPUClock) == 0 {
 		return errors.New("no valid benchmark data found in file (all data columns are empty)")
 	}
 
@@ -227,6 +238,8 @@ func readBenchmarkFile(scanner *bufio.Scanner, fileType, totalLines int) (*Bench
 				}
 			case 4:
 				benchmarkData.SpecLinuxKernel = truncateString(strings.TrimSpace(v))
+			case 5:
+				benchmarkData.SpecDriver = truncateString(strings.TrimSpace(v))
 			case 6:
 				benchmarkData.SpecLinuxScheduler = truncateString(strings.TrimSpace(v))
 			}
@@ -417,77 +430,72 @@ func truncateString(s string) string {
 	return s
 }
 
-// StoreBenchmarkData stores benchmark data to disk in streaming-friendly format
-// Format: [zstd compressed: [header: version, run_count] [run1] [run2] ... [runN]]
-// This allows streaming reads without loading entire dataset into memory
+// StoreBenchmarkData stores benchmark data to disk in the columnar format (version 3)
+// See benchmark_columns.go for the layout; every run and column can be read on its own
+// The data, metadata and series files are committed together as a new generation (see benchmark_files.go)
 func StoreBenchmarkData(benchmarkData []*BenchmarkData, benchmarkID uint) error {
-	// Store the full data
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Create(filePath)
+	return StoreBenchmarkDataWithStats(benchmarkData, nil, nil, benchmarkID)
+}
+
+// StoreBenchmarkDataWithStats stores benchmark data together with its pre-calculated statistics,
+// so a crash can't leave stats that don't match the data. With nil stats, the stats file of the
+// previous generation is kept.
+func StoreBenchmarkDataWithStats(benchmarkData []*BenchmarkData, stats []*PreCalculatedRun, groups []*RunGroupStats, benchmarkID uint) error {
+	w, err := beginBenchmarkFileWrite(benchmarkID)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			// Log error but continue - this is cleanup
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-		}
-	}()
+	defer w.Close()
 
-	// Use buffered writer to reduce syscalls and improve write performance
-	// 256KB buffer is large enough for efficient I/O without excessive memory use
-	bufWriter := bufio.NewWriterSize(file, 256*1024)
-
-	// Use higher compression concurrency and better compression level for storage
-	// SpeedDefault provides good balance between compression ratio and speed
-	zstdEncoder, err := zstd.NewWriter(bufWriter,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderConcurrency(2))
-	if err != nil {
+	if err := w.write(benchmarkFileData, func(out io.Writer) error {
+		return writeColumnarBenchmarkFile(out, benchmarkData)
+	}); err != nil {
 		return err
 	}
 
-	gobEncoder := gob.NewEncoder(zstdEncoder)
-
-	// Write file header with version and run count
-	// Pre-allocated struct avoids allocation during encoding
-	header := fileHeader{
-		Version:  storageFormatVersion,
-		RunCount: len(benchmarkData),
-	}
-	if err := gobEncoder.Encode(&header); err != nil {
-		if closeErr := zstdEncoder.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close zstd encoder after header encode error: %v\n", closeErr)
-		}
-		return fmt.Errorf("failed to encode header: %w", err)
+	// Store metadata separately for fast access
+	if err := w.write(benchmarkFileMeta, func(out io.Writer) error {
+		return writeBenchmarkMetadata(out, benchmarkData)
+	}); err != nil {
+		return err
 	}
 
-	// Write each run separately (enables streaming reads)
-	for i, run := range benchmarkData {
-		if err := gobEncoder.Encode(run); err != nil {
-			if closeErr := zstdEncoder.Close(); closeErr != nil {
-				fmt.Printf("Warning: failed to close zstd encoder after encode error: %v\n", closeErr)
-			}
-			return fmt.Errorf("failed to encode run %d: %w", i, err)
+	if stats != nil {
+		if err := w.write(benchmarkFileStats, func(out io.Writer) error {
+			return writeStatsFile(out, stats, groups)
+		}); err != nil {
+			return err
 		}
 	}
 
-	// Ensure all data is flushed before metadata write
-	if err := zstdEncoder.Close(); err != nil {
-		return fmt.Errorf("failed to close zstd encoder: %w", err)
+	// Store the multi-resolution series used by zoomable charts
+	if err := w.write(benchmarkFileSeries, func(out io.Writer) error {
+		return writeSeriesFile(out, benchmarkData)
+	}); err != nil {
+		return err
 	}
 
-	// Flush buffered writer to disk
-	if err := bufWriter.Flush(); err != nil {
-		return fmt.Errorf("failed to flush buffer: %w", err)
+	return w.commit()
+}
+
+// storeBenchmarkMetadata stores lightweight metadata (run count and labels) as a new generation
+func storeBenchmarkMetadata(benchmarkData []*BenchmarkData, benchmarkID uint) error {
+	w, err := beginBenchmarkFileWrite(benchmarkID)
+	if err != nil {
+		return err
 	}
+	defer w.Close()
 
-	// Store metadata separately for fast access
-	return storeBenchmarkMetadata(benchmarkData, benchmarkID)
+	if err := w.write(benchmarkFileMeta, func(out io.Writer) error {
+		return writeBenchmarkMetadata(out, benchmarkData)
+	}); err != nil {
+		return err
+	}
+	return w.commit()
 }
 
-// storeBenchmarkMetadata stores lightweight metadata (run count and labels) separately
-func storeBenchmarkMetadata(benchmarkData []*BenchmarkData, benchmarkID uint) error {
+// writeBenchmarkMetadata writes the metadata file of the given runs
+func writeBenchmarkMetadata(out io.Writer, benchmarkData []*BenchmarkData) error {
 	labels := make([]string, len(benchmarkData))
 	for i, data := range benchmarkData {
 		labels[i] = data.Label
@@ -498,21 +506,9 @@ func storeBenchmarkMetadata(benchmarkData []*BenchmarkData, benchmarkID uint) er
 		RunLabels: labels,
 	}
 
-	metaPath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.meta", benchmarkID))
-	metaFile, err := os.Create(metaPath)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err := metaFile.Close(); err != nil {
-			// Log error but continue - this is cleanup
-			fmt.Printf("Warning: failed to close metaFile: %v\n", err)
-		}
-	}()
-
 	// Use gob encoding for metadata (no need for compression, it's tiny)
 	// Wrap in buffered writer to avoid many small syscalls from gob's framing
-	bufferedWriter := bufio.NewWriter(metaFile)
+	bufferedWriter := bufio.NewWriter(out)
 	gobEncoder := gob.NewEncoder(bufferedWriter)
 	if err := gobEncoder.Encode(metadata); err != nil {
 		return err
@@ -521,24 +517,28 @@ func storeBenchmarkMetadata(benchmarkData []*BenchmarkData, benchmarkID uint) er
 }
 
 // RetrieveBenchmarkData retrieves benchmark data from disk
-// Supports both old format (version 1: single array) and new format (version 2: streaming)
+// Supports the columnar format (version 3) and the older formats (version 1: single array, version 2: streaming)
 func RetrieveBenchmarkData(benchmarkID uint) ([]*BenchmarkData, error) {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Open(filePath)
-	if err != nil {
+	sf, index, err := openColumnarBenchmarkFile(benchmarkID)
+	if err == nil {
+		defer sf.Close()
+		benchmarkData := make([]*BenchmarkData, len(index.Runs))
+		for i := range index.Runs {
+			if benchmarkData[i], err = readColumnarRun(sf, &index.Runs[i], nil); err != nil {
+				return nil, fmt.Errorf("failed to decode run %d: %w", i, err)
+			}
+		}
+		return benchmarkData, nil
+	}
+	if !errors.Is(err, errNotSectioned) {
 		return nil, err
 	}
-	defer func() {
-
-		if closeErr := file.Close(); closeErr != nil {
-
-			// Log error but continue - this is cleanup
 
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-
-		}
-
-	}()
+	file, err := openBlob(binFileName(benchmarkID))
+	if err != nil {
+		return nil, err
+	}
+	defer closeBlob(file)
 
 	// Use concurrent decompression for better performance with large files
 	zstdDecoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(2))
@@ -561,7 +561,7 @@ func RetrieveBenchmarkData(benchmarkID uint) ([]*BenchmarkData, error) {
 	if header.Version == 1 {
 		// Old format: single array (shouldn't happen as old files don't have headers, but handle it)
 		return retrieveBenchmarkDataLegacy(benchmarkID)
-	} else if header.Version != storageFormatVersion {
+	} else if header.Version != storageFormatVersionV2 {
 		return nil, fmt.Errorf("unsupported storage format version: %d", header.Version)
 	}
 
@@ -570,7 +570,7 @@ func RetrieveBenchmarkData(benchmarkID uint) ([]*BenchmarkData, error) {
 		return nil, fmt.Errorf("invalid run count in file header: %d", header.RunCount)
 	}
 
-	// New format (version 2): read runs individually
+	// Version 2: read runs individually
 	benchmarkData := make([]*BenchmarkData, header.RunCount)
 	for i := 0; i < header.RunCount; i++ {
 		var run BenchmarkData
@@ -585,16 +585,11 @@ func RetrieveBenchmarkData(benchmarkID uint) ([]*BenchmarkData, error) {
 
 // retrieveBenchmarkDataLegacy reads data in the old format (version 1: single array)
 func retrieveBenchmarkDataLegacy(benchmarkID uint) ([]*BenchmarkData, error) {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Open(filePath)
+	file, err := openBlob(binFileName(benchmarkID))
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-		}
-	}()
+	defer closeBlob(file)
 
 	zstdDecoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(2))
 	if err != nil {
@@ -626,11 +621,17 @@ func GetBenchmarkRunCount(benchmarkID uint) (int, []string, error) {
 // GetBenchmarkMetadata returns the full metadata for a benchmark
 // This is optimized to read only metadata without loading the full benchmark data
 func GetBenchmarkMetadata(benchmarkID uint) (int, []string, *BenchmarkMetadata, error) {
-	metaPath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.meta", benchmarkID))
-	metaFile, err := os.Open(metaPath)
+	cached, token, ok := benchmarkCache.lookup(benchmarkID, benchmarkCacheMeta, "")
+	if ok {
+		metadata := *cached.(*BenchmarkMetadata)
+		metadata.RunLabels = slices.Clone(metadata.RunLabels)
+		return metadata.RunCount, metadata.RunLabels, &metadata, nil
+	}
+
+	metaFile, err := openBlob(benchmarkFileName(benchmarkID, benchmarkFileMeta))
 	if err != nil {
 		// Fallback: if metadata doesn't exist, load full data (backward compatibility)
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			benchmarkData, retrieveErr := RetrieveBenchmarkData(benchmarkID)
 			if retrieveErr != nil {
 				return 0, nil, nil, retrieveErr
@@ -651,12 +652,7 @@ func GetBenchmarkMetadata(benchmarkID uint) (int, []string, *BenchmarkMetadata,
 		}
 		return 0, nil, nil, err
 	}
-	defer func() {
-		if closeErr := metaFile.Close(); closeErr != nil {
-			// Log error but continue - this is cleanup
-			fmt.Printf("Warning: failed to close metaFile: %v\n", closeErr)
-		}
-	}()
+	defer closeBlob(metaFile)
 
 	var metadata BenchmarkMetadata
 	gobDecoder := gob.NewDecoder(metaFile)
@@ -665,6 +661,10 @@ func GetBenchmarkMetadata(benchmarkID uint) (int, []string, *BenchmarkMetadata,
 		return 0, nil, nil, err
 	}
 
+	cachedMetadata := metadata
+	cachedMetadata.RunLabels = slices.Clone(metadata.RunLabels)
+	benchmarkCache.store(benchmarkID, benchmarkCacheMeta, "", token, &cachedMetadata, metadataCacheSize(&metadata))
+
 	return metadata.RunCount, metadata.RunLabels, &metadata, nil
 }
 
@@ -712,6 +712,9 @@ func ExtractSearchableMetadata(benchmarkData []*BenchmarkData) (runNames, specif
 		if data.SpecLinuxScheduler != "" {
 			specSet[data.SpecLinuxScheduler] = true
 		}
+		if data.SpecDriver != "" {
+			specSet[data.SpecDriver] = true
+		}
 	}
 
 	// Convert set to slice and sort for deterministic output
@@ -743,6 +746,7 @@ func getRunDataPointCount(data *BenchmarkData) int {
 		data.DataGPUPower,
 		data.DataRAMUsed,
 		data.DataSwapUsed,
+		data.DataCPUClock,
 	}
 	maxLen := 0
 	for _, arr := range dataArrays {
@@ -780,129 +784,151 @@ func ValidatePerRunDataLines(benchmarkData []*BenchmarkData) error {
 	return nil
 }
 
-// StorePreCalculatedStats stores pre-calculated statistics to disk as zstd-compressed gob.
-// These are served directly by the REST API and MCP server.
-func StorePreCalculatedStats(stats []*PreCalculatedRun, benchmarkID uint) error {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.stats", benchmarkID))
-	file, err := os.Create(filePath)
+// StorePreCalculatedStats stores pre-calculated statistics and run group aggregates to disk.
+// The file is sectioned (see benchmark_stats_file.go) so selected runs, metrics and parts can
+// be read without decoding the rest. These are served directly by the REST API and MCP server.
+// The stats file is committed as a new generation that keeps the other files.
+func StorePreCalculatedStats(stats []*PreCalculatedRun, groups []*RunGroupStats, benchmarkID uint) error {
+	w, err := beginBenchmarkFileWrite(benchmarkID)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close stats file: %v\n", closeErr)
-		}
-	}()
+	defer w.Close()
 
-	bufWriter := bufio.NewWriterSize(file, 256*1024)
-	zstdEncoder, err := zstd.NewWriter(bufWriter,
-		zstd.WithEncoderLevel(zstd.SpeedDefault),
-		zstd.WithEncoderConcurrency(2))
-	if err != nil {
+	if err := w.write(benchmarkFileStats, func(out io.Writer) error {
+		return writeStatsFile(out, stats, groups)
+	}); err != nil {
 		return err
 	}
+	return w.commit()
+}
 
-	gobEncoder := gob.NewEncoder(zstdEncoder)
-	if err := gobEncoder.Encode(stats); err != nil {
-		if closeErr := zstdEncoder.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close zstd encoder after stats encode error: %v\n", closeErr)
-		}
-		return fmt.Errorf("failed to encode stats: %w", err)
-	}
+// RetrievePreCalculatedStats retrieves pre-calculated statistics from disk.
+func RetrievePreCalculatedStats(benchmarkID uint) ([]*PreCalculatedRun, error) {
+	stats, _, err := RetrievePreCalculatedStatsWithGroups(benchmarkID)
+	return stats, err
+}
 
-	if err := zstdEncoder.Close(); err != nil {
-		return fmt.Errorf("failed to close zstd encoder: %w", err)
-	}
+// RetrievePreCalculatedStatsWithGroups retrieves pre-calculated statistics and run group aggregates.
+// Stats files written before run groups existed return no groups.
+func RetrievePreCalculatedStatsWithGroups(benchmarkID uint) ([]*PreCalculatedRun, []*RunGroupStats, error) {
+	return RetrievePreCalculatedStatsSelection(benchmarkID, fullStatsSelection())
+}
 
-	if err := bufWriter.Flush(); err != nil {
-		return fmt.Errorf("failed to flush buffer: %w", err)
-	}
+// RetrievePreCalculatedStatsSelection retrieves the selected runs, metrics and parts of the
+// pre-calculated statistics, along with run group aggregates filtered to the same metrics.
+// Runs are returned in the order of sel.Runs.
+func RetrievePreCalculatedStatsSelection(benchmarkID uint, sel StatsSelection) ([]*PreCalculatedRun, []*RunGroupStats, error) {
+	return readStatsSelection(benchmarkID, sel)
+}
 
-	return nil
+// ReadStatsAlgorithmVersion returns the stats algorithm version a benchmark's .stats file was
+// computed with. Files written before versioning report version 0.
+func ReadStatsAlgorithmVersion(benchmarkID uint) (int, error) {
+	sf, index, err := openStatsFile(benchmarkID)
+	if err == nil {
+		sf.Close()
+		return index.Header.AlgorithmVersion, nil
+	}
+	if !errors.Is(err, errNotSectioned) {
+		return 0, err
+	}
+	header, _, _, err := readStatsFile(benchmarkID, true)
+	if err != nil {
+		return 0, err
+	}
+	return header.AlgorithmVersion, nil
 }
 
-// RetrievePreCalculatedStats retrieves pre-calculated statistics from disk.
-func RetrievePreCalculatedStats(benchmarkID uint) ([]*PreCalculatedRun, error) {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.stats", benchmarkID))
-	file, err := os.Open(filePath)
+// readStatsFile decodes a .stats file written as a single gob stream, before the sectioned layout.
+// With headerOnly, decoding stops after the version header. The oldest files start directly with
+// the runs; they fail to decode as a header and are re-read from the beginning with an empty
+// (version 0) header.
+func readStatsFile(benchmarkID uint, headerOnly bool) (*statsFileHeader, []*PreCalculatedRun, []*RunGroupStats, error) {
+	name := statsFileName(benchmarkID)
+	file, err := openBlob(name)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close stats file: %v\n", closeErr)
-		}
-	}()
+	defer func() { closeBlob(file) }()
 
 	zstdDecoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(2))
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	defer zstdDecoder.Close()
 
-	var stats []*PreCalculatedRun
+	header := &statsFileHeader{}
 	gobDecoder := gob.NewDecoder(zstdDecoder)
+	if err := gobDecoder.Decode(header); err != nil || header.Magic != statsFileMagic {
+		// Legacy file without a header: reopen and decode the runs from the start
+		closeBlob(file)
+		if file, err = openBlob(name); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to reopen stats file: %w", err)
+		}
+		if err := zstdDecoder.Reset(file); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to reset zstd decoder: %w", err)
+		}
+		header = &statsFileHeader{}
+		gobDecoder = gob.NewDecoder(zstdDecoder)
+	}
+	if headerOnly {
+		return header, nil, nil, nil
+	}
+
+	var stats []*PreCalculatedRun
 	if err := gobDecoder.Decode(&stats); err != nil {
-		return nil, fmt.Errorf("failed to decode stats: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to decode stats: %w", err)
 	}
 	// Guard against corrupted or tampered .stats files claiming an absurd number of runs
 	if len(stats) > maxRunsPerBenchmark {
-		return nil, fmt.Errorf("stats file contains too many runs: %d", len(stats))
+		return nil, nil, nil, fmt.Errorf("stats file contains too many runs: %d", len(stats))
 	}
 
-	return stats, nil
+	var groups statsFileGroups
+	if err := gobDecoder.Decode(&groups); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, nil, fmt.Errorf("failed to decode run groups: %w", err)
+	}
+
+	return header, stats, groups.Groups, nil
 }
 
 // RetrievePreCalculatedStatsRun retrieves a single run's pre-calculated stats.
 func RetrievePreCalculatedStatsRun(benchmarkID uint, runIndex int) (*PreCalculatedRun, error) {
-	stats, err := RetrievePreCalculatedStats(benchmarkID)
+	sel := fullStatsSelection()
+	sel.Runs = []int{runIndex}
+	stats, _, err := RetrievePreCalculatedStatsSelection(benchmarkID, sel)
 	if err != nil {
 		return nil, err
 	}
-
-	if runIndex < 0 || runIndex >= len(stats) {
-		return nil, fmt.Errorf("run index %d out of range (0-%d)", runIndex, len(stats)-1)
-	}
-
-	return stats[runIndex], nil
+	return stats[0], nil
 }
 
-// DeleteBenchmarkData deletes benchmark data file, metadata, and pre-calculated stats from disk
+// DeleteBenchmarkData deletes benchmark data file, metadata, pre-calculated stats and series from disk
 func DeleteBenchmarkData(benchmarkID uint) error {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	metaPath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.meta", benchmarkID))
-	statsPath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.stats", benchmarkID))
-
-	// Delete the main data file
-	err := os.Remove(filePath)
-
-	// Try to delete metadata file, only ignore error if file doesn't exist
-	if metaErr := os.Remove(metaPath); metaErr != nil && !os.IsNotExist(metaErr) {
-		// Log non-existence errors (but don't fail the operation)
-		fmt.Printf("Warning: failed to delete metadata file %s: %v\n", metaPath, metaErr)
-	}
-
-	// Try to delete stats file, only ignore error if file doesn't exist
-	if statsErr := os.Remove(statsPath); statsErr != nil && !os.IsNotExist(statsErr) {
-		fmt.Printf("Warning: failed to delete stats file %s: %v\n", statsPath, statsErr)
-	}
-
-	return err
+	return deleteBenchmarkFiles(benchmarkID)
 }
 
 // ExportBenchmarkDataAsZip exports benchmark data as a ZIP file containing CSV files
 // Uses streaming to minimize memory usage
 func ExportBenchmarkDataAsZip(benchmarkID uint, writer io.Writer) error {
-	// Open the data file
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Open(filePath)
+	// Columnar format: read and write one run at a time
+	sf, index, err := openColumnarBenchmarkFile(benchmarkID)
+	if err == nil {
+		defer sf.Close()
+		return exportColumnarBenchmarkData(sf, index, writer)
+	}
+	if !errors.Is(err, errNotSectioned) {
+		return err
+	}
+
+	// Version 2 and older: open the data file
+	file, err := openBlob(binFileName(benchmarkID))
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-		}
+		closeBlob(file)
 		// Hint to GC after we're done
 		runtime.GC()
 	}()
@@ -934,7 +960,7 @@ func ExportBenchmarkDataAsZip(benchmarkID uint, writer io.Writer) error {
 	}
 
 	// New format
-	if header.Version != storageFormatVersion {
+	if header.Version != storageFormatVersionV2 {
 		return fmt.Errorf("unsupported storage format version: %d", header.Version)
 	}
 
@@ -987,6 +1013,42 @@ func ExportBenchmarkDataAsZip(benchmarkID uint, writer io.Writer) error {
 	return nil
 }
 
+// exportColumnarBenchmarkData exports a columnar (version 3) benchmark file, decoding one run at a time
+func exportColumnarBenchmarkData(sf *sectionedFile, index *binIndex, writer io.Writer) error {
+	if len(index.Runs) == 0 {
+		return errors.New("no benchmark data to export")
+	}
+
+	zipWriter := zip.NewWriter(writer)
+	defer func() {
+		if err := zipWriter.Close(); err != nil {
+			fmt.Printf("Warning: failed to close zipWriter: %v\n", err)
+		}
+	}()
+
+	for i := range index.Runs {
+		run, err := readColumnarRun(sf, &index.Runs[i], nil)
+		if err != nil {
+			return fmt.Errorf("failed to decode run %d: %w", i, err)
+		}
+
+		fileWriter, err := zipWriter.Create(sanitizeFilename(run.Label) + ".csv")
+		if err != nil {
+			return err
+		}
+		if err := writeBenchmarkDataAsCSV(run, fileWriter); err != nil {
+			return err
+		}
+
+		// Trigger GC periodically to aggressively reclaim memory
+		if i%gcFrequencyExport == 0 && i > 0 {
+			runtime.GC()
+		}
+	}
+
+	return nil
+}
+
 // exportBenchmarkDataLegacy exports data in old format (for backward compatibility)
 func exportBenchmarkDataLegacy(benchmarkData []*BenchmarkData, writer io.Writer) error {
 	if len(benchmarkData) == 0 {
@@ -1100,15 +1162,20 @@ func writeBenchmarkDataAsCSV(data *BenchmarkData, writer io.Writer) error {
 		csvSafeCell(data.SpecGPU),
 		convertRAMToKB(data.SpecRAM),
 		csvSafeCell(data.SpecLinuxKernel),
-		"", // driver (we don't store this separately)
+		csvSafeCell(data.SpecDriver),
 		csvSafeCell(data.SpecLinuxScheduler),
 	}
 	if err := csvWriter.Write(specsLine); err != nil {
 		return err
 	}
 
-	// Write the column headers
-	headers := []string{"fps", "frametime", "cpu_load", "gpu_load", "cpu_temp", "cpu_power", "gpu_temp", "gpu_core_clock", "gpu_mem_clock", "gpu_vram_used", "gpu_power", "ram_used", "swap_used"}
+	// Column headers; derived efficiency columns are appended only when a power sensor is present
+	// (they are ignored by the parser, so the file can still be re-uploaded)
+	headers := []string{"fps", "frametime", "cpu_load", "gpu_load", "cpu_temp", "cpu_power", "gpu_temp", "gpu_core_clock", "gpu_mem_clock", "gpu_vram_used", "gpu_power", "ram_used", "swap_used", "cpu_mhz"}
+	eff := computeEfficiencySeries(data)
+	if eff != nil {
+		headers = append(headers, "fps_per_watt", "fps_per_watt_total", "energy_per_frame")
+	}
 	if err := csvWriter.Write(headers); err != nil {
 		return err
 	}
@@ -1129,6 +1196,10 @@ func writeBenchmarkDataAsCSV(data *BenchmarkData, writer io.Writer) error {
 		data.DataGPUPower,
 		data.DataRAMUsed,
 		data.DataSwapUsed,
+		data.DataCPUClock,
+	}
+	if eff != nil {
+		dataArrays = append(dataArrays, eff.FPSPerWatt, eff.FPSPerWattTotal, eff.EnergyPerFrame)
 	}
 	for _, arr := range dataArrays {
 		if len(arr) > maxLen {
@@ -1144,7 +1215,7 @@ func writeBenchmarkDataAsCSV(data *BenchmarkData, writer io.Writer) error {
 	// Write data rows
 	for i := 0; i < maxLen; i++ {
 		for j, arr := range dataArrays {
-			if i < len(arr) {
+			if i < len(arr) && !math.IsNaN(arr[i]) {
 				row[j] = strconv.FormatFloat(arr[i], 'f', -1, 64)
 			} else {
 				row[j] = "" // Clear previous value for shorter arrays
@@ -1199,16 +1270,17 @@ func convertRAMToKB(ramStr string) string {
 // RetrieveBenchmarkRun retrieves a single run from benchmark data
 // runIndex is 0-based
 func RetrieveBenchmarkRun(benchmarkID uint, runIndex int) (*BenchmarkData, error) {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Open(filePath)
+	// Columnar format: only the run's own sections are read
+	columnarRun, err := retrieveBenchmarkRunColumns(benchmarkID, runIndex, nil)
+	if !errors.Is(err, errNotSectioned) {
+		return columnarRun, err
+	}
+
+	file, err := openBlob(binFileName(benchmarkID))
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
-		}
-	}()
+	defer closeBlob(file)
 
 	zstdDecoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(2))
 	if err != nil {
@@ -1226,7 +1298,7 @@ func RetrieveBenchmarkRun(benchmarkID uint, runIndex int) (*BenchmarkData, error
 	}
 
 	// New format detected
-	if header.Version != storageFormatVersion {
+	if header.Version != storageFormatVersionV2 {
 		return nil, fmt.Errorf("unsupported storage format version: %d", header.Version)
 	}
 
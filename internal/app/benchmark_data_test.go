@@ -184,7 +184,7 @@ func TestMetadataOperations(t *testing.T) {
 	}
 
 	// Verify that metadata file exists
-	metaPath := filepath.Join(tmpDir, "benchmarks", "100.meta")
+	metaPath := filepath.Join(benchmarksDir, benchmarkFileName(benchmarkID, benchmarkFileMeta))
 	if _, err := os.Stat(metaPath); os.IsNotExist(err) {
 		t.Error("Metadata file does not exist")
 	}
@@ -195,7 +195,7 @@ func TestMetadataOperations(t *testing.T) {
 	}
 
 	// Verify both files are deleted
-	dataPath := filepath.Join(tmpDir, "benchmarks", "100.bin")
+	dataPath := filepath.Join(benchmarksDir, generationFileName(benchmarkID, 1, benchmarkFileData))
 	if _, err := os.Stat(dataPath); !os.IsNotExist(err) {
 		t.Error("Data file still exists after deletion")
 	}
@@ -228,7 +228,7 @@ func TestMetadataBackwardCompatibility(t *testing.T) {
 	}
 
 	// Delete the metadata file to simulate legacy data
-	metaPath := filepath.Join(tmpDir, "benchmarks", "200.meta")
+	metaPath := filepath.Join(benchmarksDir, benchmarkFileName(benchmarkID, benchmarkFileMeta))
 	if err := os.Remove(metaPath); err != nil {
 		t.Fatalf("Failed to remove metadata: %v", err)
 	}
@@ -247,8 +247,8 @@ func TestMetadataBackwardCompatibility(t *testing.T) {
 		t.Errorf("Labels = %v, want [Legacy Run]", labels)
 	}
 
-	// Verify that metadata file was created by the fallback
-	if _, err := os.Stat(metaPath); os.IsNotExist(err) {
+	// Verify that metadata file was created by the fallback (as a new generation)
+	if _, err := os.Stat(filepath.Join(benchmarksDir, benchmarkFileName(benchmarkID, benchmarkFileMeta))); os.IsNotExist(err) {
 		t.Error("Metadata file was not created by fallback mechanism")
 	}
 }
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"math"
+)
+
+// efficiencySeries holds derived efficiency data for a run, aligned with the raw sample index.
+// Samples without valid inputs (missing sensor, zero power, zero frametime) are NaN.
+type efficiencySeries struct {
+	FPSPerWatt      []float64 // FPS per watt of GPU power
+	FPSPerWattTotal []float64 // FPS per watt of GPU+CPU power (only when both sensors are present)
+	EnergyPerFrame  []float64 // Millijoules per frame (GPU+CPU power when both are present, else GPU power)
+	TotalEnergy     float64   // Estimated joules over the whole run (sum of energy per frame)
+}
+
+// sensorAvailable reports whether a sensor column carries real readings.
+// Some devices log a power column that is always zero, which must not be treated as data.
+func sensorAvailable(data []float64) bool {
+	for _, v := range data {
+		if v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleFrametime returns the frametime (ms) of sample i, falling back to 1000/FPS when the
+// run has no frametime column. Returns 0 if neither is usable.
+func sampleFrametime(run *BenchmarkData, i int) float64 {
+	if i < len(run.DataFrameTime) {
+		return run.DataFrameTime[i]
+	}
+	if len(run.DataFrameTime) == 0 && i < len(run.DataFPS) && run.DataFPS[i] > 0 {
+		return 1000 / run.DataFPS[i]
+	}
+	return 0
+}
+
+// computeEfficiencySeries derives FPS/W and energy per frame from the power and frametime columns.
+// Returns nil when the run has no usable GPU or CPU power sensor.
+func computeEfficiencySeries(run *BenchmarkData) *efficiencySeries {
+	hasGPU := sensorAvailable(run.DataGPUPower)
+	hasCPU := sensorAvailable(run.DataCPUPower)
+	if !hasGPU && !hasCPU {
+		return nil
+	}
+
+	n := len(run.DataFrameTime)
+	if n == 0 {
+		n = len(run.DataFPS)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	eff := &efficiencySeries{}
+	if hasGPU {
+		eff.FPSPerWatt = make([]float64, n)
+	}
+	if hasGPU && hasCPU {
+		eff.FPSPerWattTotal = make([]float64, n)
+	}
+	eff.EnergyPerFrame = make([]float64, n)
+
+	for i := 0; i < n; i++ {
+		ft := sampleFrametime(run, i)
+
+		var gpuPower, cpuPower float64
+		if i < len(run.DataGPUPower) {
+			gpuPower = run.DataGPUPower[i]
+		}
+		if i < len(run.DataCPUPower) {
+			cpuPower = run.DataCPUPower[i]
+		}
+
+		if eff.FPSPerWatt != nil {
+			eff.FPSPerWatt[i] = fpsPerWatt(ft, gpuPower)
+		}
+
+		power := gpuPower
+		if eff.FPSPerWattTotal != nil {
+			// A sample missing either reading would understate total power
+			if gpuPower > 0 && cpuPower > 0 {
+				power = gpuPower + cpuPower
+			} else {
+				power = 0
+			}
+			eff.FPSPerWattTotal[i] = fpsPerWatt(ft, power)
+		} else if !hasGPU {
+			power = cpuPower
+		}
+
+		if ft > 0 && power > 0 {
+			// W * ms = mJ; stats are rounded to 2 decimals so joules would lose precision
+			millijoules := power * ft
+			eff.EnergyPerFrame[i] = millijoules
+			eff.TotalEnergy += millijoules / 1000
+		} else {
+			eff.EnergyPerFrame[i] = math.NaN()
+		}
+	}
+
+	return eff
+}
+
+// fpsPerWatt returns the FPS per watt for a sample, or NaN if either input is not positive.
+func fpsPerWatt(frametime, power float64) float64 {
+	if frametime <= 0 || power <= 0 {
+		return math.NaN()
+	}
+	return 1000 / frametime / power
+}
+
+// efficiencyMetrics returns the derived efficiency metrics of a run keyed like runMetrics.
+func efficiencyMetrics(eff *efficiencySeries) []metricEntry {
+	if eff == nil {
+		return nil
+	}
+	return []metricEntry{
+		{"FPSPerWatt", eff.FPSPerWatt},
+		{"FPSPerWattTotal", eff.FPSPerWattTotal},
+		{"EnergyPerFrame", eff.EnergyPerFrame},
+	}
+}
+
+// validSamples returns the non-NaN values of a derived series along with their [index, value] points.
+func validSamples(data []float64) ([]float64, [][2]float64) {
+	values := make([]float64, 0, len(data))
+	points := make([][2]float64, 0, len(data))
+	for i, v := range data {
+		if math.IsNaN(v) {
+			continue
+		}
+		values = append(values, v)
+		points = append(points, [2]float64{float64(i), v})
+	}
+	return values, points
+}
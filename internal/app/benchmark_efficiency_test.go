@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSensorAvailable(t *testing.T) {
+	if sensorAvailable(nil) {
+		t.Error("nil column should not be available")
+	}
+	if sensorAvailable([]float64{0, 0, 0}) {
+		t.Error("all-zero column should not be available")
+	}
+	if !sensorAvailable([]float64{0, 12.5, 0}) {
+		t.Error("column with readings should be available")
+	}
+}
+
+func TestComputeEfficiencySeries(t *testing.T) {
+	t.Run("no power sensors", func(t *testing.T) {
+		run := &BenchmarkData{
+			DataFrameTime: []float64{10, 10},
+			DataGPUPower:  []float64{0, 0},
+		}
+		if eff := computeEfficiencySeries(run); eff != nil {
+			t.Errorf("expected nil for zero-power columns, got %+v", eff)
+		}
+	})
+
+	t.Run("gpu and cpu power", func(t *testing.T) {
+		run := &BenchmarkData{
+			DataFrameTime: []float64{10, 20, 10},
+			DataGPUPower:  []float64{10, 10, 0},
+			DataCPUPower:  []float64{10, 5, 10},
+		}
+		eff := computeEfficiencySeries(run)
+		if eff == nil {
+			t.Fatal("expected efficiency series")
+		}
+
+		// 100 FPS at 10 W GPU
+		if eff.FPSPerWatt[0] != 10 {
+			t.Errorf("FPSPerWatt[0]: got %v, want 10", eff.FPSPerWatt[0])
+		}
+		// 50 FPS at 15 W total
+		if !approxEqual(eff.FPSPerWattTotal[1], 3.33, 0.01) {
+			t.Errorf("FPSPerWattTotal[1]: got %v, want 3.33", eff.FPSPerWattTotal[1])
+		}
+		// Zero GPU power must not produce infinities
+		if !math.IsNaN(eff.FPSPerWatt[2]) || !math.IsNaN(eff.FPSPerWattTotal[2]) {
+			t.Errorf("expected NaN for zero-power sample, got %v / %v", eff.FPSPerWatt[2], eff.FPSPerWattTotal[2])
+		}
+		// 20 W * 10 ms = 200 mJ, 15 W * 20 ms = 300 mJ
+		if eff.EnergyPerFrame[0] != 200 || eff.EnergyPerFrame[1] != 300 {
+			t.Errorf("EnergyPerFrame: got %v", eff.EnergyPerFrame)
+		}
+		if !approxEqual(eff.TotalEnergy, 0.5, 1e-9) {
+			t.Errorf("TotalEnergy: got %v, want 0.5", eff.TotalEnergy)
+		}
+	})
+
+	t.Run("fps fallback without frametime", func(t *testing.T) {
+		run := &BenchmarkData{
+			DataFPS:      []float64{60, 30},
+			DataCPUPower: []float64{6, 6},
+		}
+		eff := computeEfficiencySeries(run)
+		if eff == nil {
+			t.Fatal("expected efficiency series")
+		}
+		if eff.FPSPerWatt != nil || eff.FPSPerWattTotal != nil {
+			t.Error("FPS/W requires GPU power")
+		}
+		// 6 W * 16.67 ms = 100 mJ
+		if !approxEqual(eff.EnergyPerFrame[0], 100, 0.01) {
+			t.Errorf("EnergyPerFrame[0]: got %v, want 100", eff.EnergyPerFrame[0])
+		}
+	})
+}
+
+func TestComputePreCalculatedRunEfficiency(t *testing.T) {
+	run := &BenchmarkData{
+		DataFrameTime: []float64{10, 10, 20, 20},
+		DataGPUPower:  []float64{10, 10, 10, 0},
+	}
+	result := computePreCalculatedRun(run)
+
+	stats := result.Stats["FPSPerWatt"]
+	if stats == nil {
+		t.Fatal("expected FPSPerWatt stats")
+	}
+	if stats.Count != 3 || stats.Max != 10 || stats.Min != 5 {
+		t.Errorf("unexpected FPSPerWatt stats: %+v", stats)
+	}
+	if len(result.Series["FPSPerWatt"]) != 3 {
+		t.Errorf("expected 3 FPSPerWatt series points, got %d", len(result.Series["FPSPerWatt"]))
+	}
+	if _, ok := result.Stats["FPSPerWattTotal"]; ok {
+		t.Error("FPSPerWattTotal requires CPU power")
+	}
+	if result.TotalEnergy != 0.4 {
+		t.Errorf("TotalEnergy: got %v, want 0.4", result.TotalEnergy)
+	}
+
+	summary := PreCalculatedRunToMCPSummary(result, 0)
+	if summary.Metrics["fps_per_watt"] == nil || summary.Metrics["energy_per_frame"] == nil {
+		t.Error("expected efficiency metrics in MCP summary")
+	}
+
+	t.Run("no power sensor", func(t *testing.T) {
+		result := computePreCalculatedRun(&BenchmarkData{DataFrameTime: []float64{10}})
+		if _, ok := result.Stats["EnergyPerFrame"]; ok || result.TotalEnergy != 0 {
+			t.Error("expected no efficiency metrics without power data")
+		}
+	})
+}
+
+func TestWriteBenchmarkDataAsCSVEfficiencyColumns(t *testing.T) {
+	var buf bytes.Buffer
+	run := &BenchmarkData{
+		DataFrameTime: []float64{10, 10},
+		DataGPUPower:  []float64{10, 0},
+	}
+	if err := writeBenchmarkDataAsCSV(run, &buf); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	reader := csv.NewReader(strings.NewReader(buf.String()))
+	reader.FieldsPerRecord = -1 // specs line and data lines differ in width
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	headers := records[2]
+	if headers[len(headers)-3] != "fps_per_watt" || headers[len(headers)-1] != "energy_per_frame" {
+		t.Fatalf("missing efficiency headers: %v", headers)
+	}
+	if records[3][len(headers)-3] != "10" || records[4][len(headers)-3] != "" {
+		t.Errorf("unexpected fps_per_watt cells: %q, %q", records[3][len(headers)-3], records[4][len(headers)-3])
+	}
+
+	t.Run("no power sensor keeps MangoHud columns", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeBenchmarkDataAsCSV(&BenchmarkData{DataFPS: []float64{60}}, &buf); err != nil {
+			t.Fatalf("Failed to write CSV: %v", err)
+		}
+		if strings.Contains(buf.String(), "fps_per_watt") {
+			t.Error("efficiency columns should be omitted without power data")
+		}
+	})
+}
@@ -0,0 +1,428 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Benchmark files are written in generations so a crash or full disk can never leave a .bin whose
+// .meta, .stats and .series don't match. Every write creates new generation files
+// (<id>-g<N>.bin, ...) as blobs that only become visible once complete (see blob_store.go); files
+// the write doesn't replace are carried over into the new generation. The per-benchmark manifest
+// (<id>.manifest) names the current generation; atomically replacing it is the commit point.
+// Readers take no lock, so the replaced generation is kept until the next commit for reads that
+// resolved it before the switch. Files of any other generation are leftovers of an interrupted
+// write (or of a replaced generation) and are removed at startup.
+//
+// Benchmarks written before manifests existed have no manifest and use plain <id>.<kind> names
+// until their next write.
+
+// Benchmark file kinds, used as file extensions
+const (
+	benchmarkFileData   = "bin"
+	benchmarkFileMeta   = "meta"
+	benchmarkFileStats  = "stats"
+	benchmarkFileSeries = "series"
+)
+
+// benchmarkFileKinds lists every file kind tied to a generation.
+var benchmarkFileKinds = []string{benchmarkFileData, benchmarkFileMeta, benchmarkFileStats, benchmarkFileSeries}
+
+// Benchmark file names: <id>-g<generation>.<kind> and, without a manifest, <id>.<kind>
+var (
+	generationFilePattern = regexp.MustCompile(`^(\d+)-g(\d+)\.(bin|meta|stats|series)$`)
+	legacyFilePattern     = regexp.MustCompile(`^(\d+)\.(bin|meta|stats|series)$`)
+)
+
+// benchmarkManifest ties a benchmark's files to one generation.
+type benchmarkManifest struct {
+	Generation  uint64    `json:"generation"`
+	Files       []string  `json:"files"`             // File kinds present in the generation
+	Retired     []string  `json:"retired,omitempty"` // Files of the replaced generation, removed by the next commit
+	CommittedAt time.Time `json:"committed_at"`
+}
+
+var (
+	manifestCacheMu sync.Mutex
+	manifestCache   = make(map[uint]*benchmarkManifest) // nil entries cache "no manifest"
+
+	benchmarkFileLocks sync.Map // uint -> *sync.Mutex
+)
+
+// resetManifestCache drops cached manifests and payloads, e.g. when the blob store changes.
+func resetManifestCache() {
+	manifestCacheMu.Lock()
+	defer manifestCacheMu.Unlock()
+	manifestCache = make(map[uint]*benchmarkManifest)
+	benchmarkCache.reset()
+}
+
+// lockBenchmarkFiles serializes generation writes of a benchmark. Returns the unlock function.
+func lockBenchmarkFiles(benchmarkID uint) func() {
+	mu, _ := benchmarkFileLocks.LoadOrStore(benchmarkID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func manifestName(benchmarkID uint) string {
+	return fmt.Sprintf("%d.manifest", benchmarkID)
+}
+
+func generationFileName(benchmarkID uint, generation uint64, kind string) string {
+	return fmt.Sprintf("%d-g%d.%s", benchmarkID, generation, kind)
+}
+
+func legacyFileName(benchmarkID uint, kind string) string {
+	return fmt.Sprintf("%d.%s", benchmarkID, kind)
+}
+
+// readManifest reads a benchmark's manifest from the blob store. Returns nil without error if
+// there is none.
+func readManifest(benchmarkID uint) (*benchmarkManifest, error) {
+	data, err := readBlob(manifestName(benchmarkID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m benchmarkManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// loadManifest returns a benchmark's manifest, or nil for benchmarks without one.
+func loadManifest(benchmarkID uint) (*benchmarkManifest, error) {
+	manifestCacheMu.Lock()
+	m, ok := manifestCache[benchmarkID]
+	manifestCacheMu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	m, err := readManifest(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	manifestCacheMu.Lock()
+	manifestCache[benchmarkID] = m
+	manifestCacheMu.Unlock()
+	return m, nil
+}
+
+// benchmarkFileName returns the blob name of a benchmark file in its current generation.
+func benchmarkFileName(benchmarkID uint, kind string) string {
+	m, err := loadManifest(benchmarkID)
+	if err != nil {
+		fmt.Printf("Warning: failed to read manifest of benchmark %d: %v\n", benchmarkID, err)
+	}
+	if m == nil {
+		return legacyFileName(benchmarkID, kind)
+	}
+	return generationFileName(benchmarkID, m.Generation, kind)
+}
+
+// benchmarkGeneration returns the current generation of a benchmark's files (0 without a manifest).
+func benchmarkGeneration(benchmarkID uint) (uint64, error) {
+	m, err := loadManifest(benchmarkID)
+	if err != nil || m == nil {
+		return 0, err
+	}
+	return m.Generation, nil
+}
+
+// benchmarkFileWrite writes a new generation of a benchmark's files. It holds the benchmark's
+// file lock until Close. Nothing is visible to readers until commit.
+type benchmarkFileWrite struct {
+	benchmarkID uint
+	prev        *benchmarkManifest // nil for benchmarks without a manifest
+	generation  uint64
+	written     []string
+	committed   bool
+	unlock      func()
+}
+
+// beginBenchmarkFileWrite starts a new generation of a benchmark's files.
+// Always Close the returned write; it discards the generation unless it was committed.
+func beginBenchmarkFileWrite(benchmarkID uint) (*benchmarkFileWrite, error) {
+	unlock := lockBenchmarkFiles(benchmarkID)
+	prev, err := readManifest(benchmarkID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	w := &benchmarkFileWrite{benchmarkID: benchmarkID, prev: prev, generation: 1, unlock: unlock}
+	if prev != nil {
+		w.generation = prev.Generation + 1
+	}
+	return w, nil
+}
+
+// prevGeneration returns the generation this write replaces (0 without a manifest).
+func (w *benchmarkFileWrite) prevGeneration() uint64 {
+	if w.prev == nil {
+		return 0
+	}
+	return w.prev.Generation
+}
+
+// write creates one file of the new generation from what writeFn writes. The file only becomes
+// visible once writeFn returned without error.
+func (w *benchmarkFileWrite) write(kind string, writeFn func(out io.Writer) error) error {
+	if err := writeBlob(generationFileName(w.benchmarkID, w.generation, kind), writeFn); err != nil {
+		return err
+	}
+	w.written = append(w.written, kind)
+	return nil
+}
+
+// commit carries over the files this write didn't replace and atomically switches the manifest to
+// the new generation. Files of the previous generation stay for readers that already resolved
+// them; the generation before that is removed afterwards.
+func (w *benchmarkFileWrite) commit() error {
+	files := append([]string(nil), w.written...)
+	var oldNames []string
+	for _, kind := range benchmarkFileKinds {
+		oldName := legacyFileName(w.benchmarkID, kind)
+		if w.prev != nil {
+			oldName = generationFileName(w.benchmarkID, w.prev.Generation, kind)
+		}
+		if !blobExists(oldName) {
+			continue
+		}
+		oldNames = append(oldNames, oldName)
+		if slices.Contains(w.written, kind) {
+			continue
+		}
+		if err := blobStore.Copy(oldName, generationFileName(w.benchmarkID, w.generation, kind)); err != nil {
+			return fmt.Errorf("failed to carry over %s file: %w", kind, err)
+		}
+		files = append(files, kind)
+	}
+
+	m := &benchmarkManifest{Generation: w.generation, Files: files, Retired: oldNames, CommittedAt: time.Now().UTC()}
+	if err := writeManifest(w.benchmarkID, m); err != nil {
+		return err
+	}
+	w.committed = true
+	manifestCacheMu.Lock()
+	manifestCache[w.benchmarkID] = m
+	manifestCacheMu.Unlock()
+	benchmarkCache.invalidate(w.benchmarkID)
+
+	if w.prev != nil {
+		for _, name := range w.prev.Retired {
+			removeBlob(name)
+		}
+	}
+	return nil
+}
+
+// Close releases the benchmark's file lock, discarding the new generation unless it was committed.
+func (w *benchmarkFileWrite) Close() {
+	if !w.committed {
+		for _, kind := range benchmarkFileKinds {
+			removeBlob(generationFileName(w.benchmarkID, w.generation, kind))
+		}
+	}
+	w.unlock()
+}
+
+// writeManifest atomically replaces a benchmark's manifest.
+func writeManifest(benchmarkID uint, m *benchmarkManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := writeBlob(manifestName(benchmarkID), func(out io.Writer) error {
+		_, err := out.Write(data)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// deleteBenchmarkFiles removes every file of a benchmark. The manifest goes first, so an
+// interrupted delete leaves only unreferenced files for startup cleanup.
+func deleteBenchmarkFiles(benchmarkID uint) error {
+	unlock := lockBenchmarkFiles(benchmarkID)
+	defer unlock()
+
+	m, err := readManifest(benchmarkID)
+	if err != nil {
+		fmt.Printf("Warning: failed to read manifest of benchmark %d: %v\n", benchmarkID, err)
+	}
+	if err := blobStore.Remove(manifestName(benchmarkID)); err != nil {
+		return err
+	}
+	manifestCacheMu.Lock()
+	manifestCache[benchmarkID] = nil
+	manifestCacheMu.Unlock()
+	benchmarkCache.invalidate(benchmarkID)
+
+	// The data file error is reported, like before generations existed; the others are derived
+	dataName := legacyFileName(benchmarkID, benchmarkFileData)
+	if m != nil {
+		dataName = generationFileName(benchmarkID, m.Generation, benchmarkFileData)
+	}
+	_, result := blobStore.Stat(dataName)
+	if result == nil {
+		result = blobStore.Remove(dataName)
+	}
+
+	for _, kind := range benchmarkFileKinds {
+		names := []string{legacyFileName(benchmarkID, kind)}
+		if m != nil {
+			names = append(names, generationFileName(benchmarkID, m.Generation, kind))
+		}
+		for _, name := range names {
+			if name == dataName {
+				continue
+			}
+			if err := blobStore.Remove(name); err != nil {
+				fmt.Printf("Warning: failed to delete %s file %s: %v\n", kind, name, err)
+			}
+		}
+	}
+	if m != nil {
+		for _, name := range m.Retired {
+			removeBlob(name)
+		}
+	}
+	return result
+}
+
+// RecoverBenchmarkFiles removes leftovers of interrupted writes: temporary files, files of
+// generations other than the committed one, and plain files superseded by a manifest.
+// Returns the number of files removed.
+func RecoverBenchmarkFiles() (int, error) {
+	blobs, err := blobStore.List()
+	if err != nil {
+		return 0, err
+	}
+	resetManifestCache()
+
+	removed := 0
+	remove := func(name, reason string) {
+		if err := blobStore.Remove(name); err != nil {
+			fmt.Printf("Warning: failed to remove %s: %v\n", name, err)
+			return
+		}
+		log.Printf("Removed %s (%s)", name, reason)
+		removed++
+	}
+
+	manifests := make(map[uint]*benchmarkManifest)
+	manifestFor := func(id uint) *benchmarkManifest {
+		if m, ok := manifests[id]; ok {
+			return m
+		}
+		m, err := readManifest(id)
+		if err != nil {
+			fmt.Printf("Warning: failed to read manifest of benchmark %d: %v\n", id, err)
+		}
+		manifests[id] = m
+		return m
+	}
+
+	for _, blob := range blobs {
+		name := blob.Name
+		if filepath.Ext(name) == ".tmp" {
+			remove(name, "interrupted write")
+			continue
+		}
+		if match := generationFilePattern.FindStringSubmatch(name); match != nil {
+			id, idErr := strconv.ParseUint(match[1], 10, 32)
+			generation, genErr := strconv.ParseUint(match[2], 10, 64)
+			if idErr != nil || genErr != nil {
+				continue
+			}
+			if m := manifestFor(uint(id)); m == nil || m.Generation != generation {
+				remove(name, "uncommitted or superseded generation")
+			}
+			continue
+		}
+		if match := legacyFilePattern.FindStringSubmatch(name); match != nil {
+			id, idErr := strconv.ParseUint(match[1], 10, 32)
+			if idErr == nil && manifestFor(uint(id)) != nil {
+				remove(name, "superseded by manifest")
+			}
+		}
+	}
+	return removed, nil
+}
+
+// syncDir fsyncs a directory so renames and new links in it are durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	syncErr := dir.Sync()
+	if err := dir.Close(); err != nil && syncErr == nil {
+		syncErr = err
+	}
+	return syncErr
+}
+
+// linkOrCopy hard-links src to dst, copying the file where links aren't supported.
+func linkOrCopy(src, dst string) error {
+	removeIfExists(dst) // Leftover of an interrupted write of the same generation
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// copyFile copies src to a new file dst and syncs it.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := in.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+	}()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close() //nolint:errcheck // Copy error takes precedence
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close() //nolint:errcheck // Sync error takes precedence
+		return err
+	}
+	return out.Close()
+}
+
+// removeBlob removes a blob, logging failures.
+func removeBlob(name string) {
+	if err := blobStore.Remove(name); err != nil {
+		fmt.Printf("Warning: failed to remove %s: %v\n", name, err)
+	}
+}
+
+func removeIfExists(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("Warning: failed to remove %s: %v\n", path, err)
+	}
+}
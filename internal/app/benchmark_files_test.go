@@ -0,0 +1,218 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// benchmarkDirFiles lists the file names in the benchmarks directory.
+func benchmarkDirFiles(t *testing.T) []string {
+	t.Helper()
+	entries, err := os.ReadDir(benchmarksDir)
+	if err != nil {
+		t.Fatalf("Failed to read benchmarks dir: %v", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBenchmarkFileGenerations(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	runs := columnarRuns()
+	preCalc, groups := ComputeBenchmarkStats(runs, "")
+	if err := StoreBenchmarkDataWithStats(runs, preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+
+	want := []string{"1-g1.bin", "1-g1.meta", "1-g1.series", "1-g1.stats", "1.manifest"}
+	if got := benchmarkDirFiles(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	t.Run("stats-only write carries over the other files", func(t *testing.T) {
+		if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+			t.Fatalf("Failed to store stats: %v", err)
+		}
+		// Generation 1 stays for readers that resolved it before the switch
+		want := []string{
+			"1-g1.bin", "1-g1.meta", "1-g1.series", "1-g1.stats",
+			"1-g2.bin", "1-g2.meta", "1-g2.series", "1-g2.stats", "1.manifest",
+		}
+		if got := benchmarkDirFiles(t); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		m, err := readManifest(1)
+		if err != nil || m.Generation != 2 || len(m.Files) != 4 || len(m.Retired) != 4 {
+			t.Fatalf("unexpected manifest: %+v (%v)", m, err)
+		}
+		data, err := RetrieveBenchmarkData(1)
+		if err != nil || !reflect.DeepEqual(data, columnarRuns()) {
+			t.Errorf("data changed by stats write (%v)", err)
+		}
+	})
+
+	t.Run("failed write leaves the committed generation", func(t *testing.T) {
+		w, err := beginBenchmarkFileWrite(1)
+		if err != nil {
+			t.Fatalf("Failed to begin write: %v", err)
+		}
+		if err := w.write(benchmarkFileData, func(out io.Writer) error {
+			return writeColumnarBenchmarkFile(out, runs[:1])
+		}); err != nil {
+			t.Fatalf("Failed to write data: %v", err)
+		}
+		diskFull := errors.New("no space left on device")
+		if err := w.write(benchmarkFileMeta, func(out io.Writer) error {
+			if _, err := out.Write([]byte("trunc")); err != nil {
+				return err
+			}
+			return diskFull
+		}); !errors.Is(err, diskFull) {
+			t.Fatalf("expected write error, got %v", err)
+		}
+		w.Close()
+
+		if gen, _ := benchmarkGeneration(1); gen != 2 {
+			t.Errorf("expected generation 2 to stay current, got %d", gen)
+		}
+		if got := benchmarkDirFiles(t); len(got) != 9 {
+			t.Errorf("expected the aborted generation to be removed, got %v", got)
+		}
+		if count, _, err := GetBenchmarkRunCount(1); err != nil || count != 2 {
+			t.Errorf("expected committed metadata with 2 runs, got %d (%v)", count, err)
+		}
+	})
+
+	t.Run("startup recovery removes leftovers", func(t *testing.T) {
+		leftovers := []string{"1-g3.bin", "1-g3.meta.tmp", "1.bin", "1.manifest.tmp", "2-g1.bin"}
+		for _, name := range leftovers {
+			if err := os.WriteFile(filepath.Join(benchmarksDir, name), []byte("partial"), 0o600); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		removed, err := RecoverBenchmarkFiles()
+		if err != nil {
+			t.Fatalf("Recovery failed: %v", err)
+		}
+		if removed != len(leftovers)+4 {
+			t.Errorf("expected %d leftovers and the 4 replaced files removed, got %d", len(leftovers), removed)
+		}
+		want := []string{"1-g2.bin", "1-g2.meta", "1-g2.series", "1-g2.stats", "1.manifest"}
+		if got := benchmarkDirFiles(t); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if _, err := RetrievePreCalculatedStats(1); err != nil {
+			t.Errorf("committed stats unreadable after recovery: %v", err)
+		}
+	})
+
+	t.Run("delete removes every generation file", func(t *testing.T) {
+		if err := DeleteBenchmarkData(1); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		if got := benchmarkDirFiles(t); len(got) != 0 {
+			t.Errorf("expected no files left, got %v", got)
+		}
+	})
+}
+
+func TestBenchmarkFilesWithoutManifest(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	writeV2BenchmarkFile(t, 7, columnarRuns())
+	if binFileName(7) != "7.bin" {
+		t.Fatalf("expected plain file name without a manifest, got %s", binFileName(7))
+	}
+	if removed, err := RecoverBenchmarkFiles(); err != nil || removed != 0 {
+		t.Fatalf("recovery should keep files without a manifest, removed %d (%v)", removed, err)
+	}
+
+	preCalc, groups := ComputeBenchmarkStats(columnarRuns(), "")
+	if err := StorePreCalculatedStats(preCalc, groups, 7); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+	want := []string{"7-g1.bin", "7-g1.stats", "7.bin", "7.manifest"}
+	if got := benchmarkDirFiles(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	data, err := RetrieveBenchmarkData(7)
+	if err != nil || !reflect.DeepEqual(data, columnarRuns()) {
+		t.Errorf("carried over v2 data unreadable (%v)", err)
+	}
+
+	// The next commit removes the plain files kept for readers
+	if err := StorePreCalculatedStats(preCalc, groups, 7); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+	want = []string{"7-g1.bin", "7-g1.stats", "7-g2.bin", "7-g2.stats", "7.manifest"}
+	if got := benchmarkDirFiles(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBenchmarkFilesConcurrentReader(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	runs := columnarRuns()
+	preCalc, groups := ComputeBenchmarkStats(runs, "")
+	if err := StoreBenchmarkDataWithStats(runs, preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+
+	t.Run("resolved files survive a commit", func(t *testing.T) {
+		statsName := statsFileName(1)
+		if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+			t.Fatalf("Failed to store stats: %v", err)
+		}
+		file, err := openBlob(statsName)
+		if err != nil {
+			t.Fatalf("file resolved before the commit is gone: %v", err)
+		}
+		closeBlob(file)
+	})
+
+	t.Run("reads during writes", func(t *testing.T) {
+		done := make(chan struct{})
+		errs := make(chan error, 1)
+		go func() {
+			defer close(errs)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := RetrievePreCalculatedStats(1); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := RetrieveBenchmarkData(1); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		for range 20 {
+			if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+				t.Fatalf("Failed to store stats: %v", err)
+			}
+		}
+		close(done)
+		if err := <-errs; err != nil {
+			t.Errorf("reader failed during writes: %v", err)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxRunGroupPatternLength caps the label pattern stored on a benchmark
+	maxRunGroupPatternLength = 200
+)
+
+// GroupMetricStats holds the spread of per-run averages for a single metric within a run group.
+// JSON tags match the frontend expectations (camelCase for WebUI consumption).
+type GroupMetricStats struct {
+	Mean   float64 `json:"mean"`   // Mean of per-run averages
+	StdDev float64 `json:"stddev"` // Sample standard deviation of per-run averages
+	Min    float64 `json:"min"`    // Lowest per-run average
+	Max    float64 `json:"max"`    // Highest per-run average
+	Runs   int     `json:"runs"`   // Number of runs that contributed to this metric
+}
+
+// RunGroupStats stores aggregates for a group of repeated runs (passes) of the same configuration.
+type RunGroupStats struct {
+	Name       string `json:"name"`
+	RunIndices []int  `json:"runIndices"`
+
+	// Mean ± spread of per-run averages (linear interpolation stats)
+	Metrics map[string]*GroupMetricStats `json:"metrics"`
+
+	// Statistics over the raw data of all runs in the group combined
+	Pooled         map[string]*MetricStats `json:"pooled"`
+	PooledMangoHud map[string]*MetricStats `json:"pooledMangoHud"`
+}
+
+// statsFileGroups wraps run group aggregates stored after the runs in a .stats file.
+// Older .stats files end after the runs, which decodes as "no groups".
+type statsFileGroups struct {
+	Groups []*RunGroupStats
+}
+
+// ValidateRunGroupPattern checks that a run group label pattern is a valid regular expression.
+// An empty pattern is valid and disables pattern-based grouping.
+func ValidateRunGroupPattern(pattern string) error {
+	if pattern == "" {
+		return nil
+	}
+	if len(pattern) > maxRunGroupPatternLength {
+		return errors.New("run group pattern exceeds maximum length of 200 characters")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return errors.New("invalid run group pattern: " + err.Error())
+	}
+	return nil
+}
+
+// assignRunGroups returns the group name of every run ("" if the run is not grouped).
+// An explicit group set on the run wins. Otherwise the label pattern is applied: if it has a
+// capture group, the first submatch is the group name, else the matched part is stripped from
+// the label (e.g. `\s*#\d+$` turns "6.17 EEVDF #2" into "6.17 EEVDF").
+func assignRunGroups(runs []*BenchmarkData, pattern string) []string {
+	names := make([]string, len(runs))
+
+	var re *regexp.Regexp
+	if pattern != "" {
+		// Invalid patterns are rejected on input; treat a stale invalid one as "no pattern"
+		re, _ = regexp.Compile(pattern) //nolint:errcheck // validated by ValidateRunGroupPattern
+	}
+
+	for i, run := range runs {
+		if run.Group != "" {
+			names[i] = run.Group
+			continue
+		}
+		if re == nil {
+			continue
+		}
+		match := re.FindStringSubmatch(run.Label)
+		if match == nil {
+			continue
+		}
+		var name string
+		if len(match) > 1 {
+			name = match[1]
+		} else {
+			name = re.ReplaceAllString(run.Label, "")
+		}
+		names[i] = truncateString(strings.TrimSpace(name))
+	}
+
+	return names
+}
+
+// ComputeBenchmarkStats computes pre-calculated data for all runs along with run group aggregates.
+func ComputeBenchmarkStats(runs []*BenchmarkData, groupPattern string) ([]*PreCalculatedRun, []*RunGroupStats) {
+	preCalc := ComputePreCalculatedRuns(runs)
+	names := assignRunGroups(runs, groupPattern)
+	for i, name := range names {
+		preCalc[i].Group = name
+	}
+	return preCalc, computeRunGroupStats(runs, preCalc, names)
+}
+
+// computeRunGroupStats aggregates runs sharing a group name. Groups are ordered by first appearance.
+func computeRunGroupStats(runs []*BenchmarkData, preCalc []*PreCalculatedRun, names []string) []*RunGroupStats {
+	var order []string
+	members := make(map[string][]int)
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := members[name]; !ok {
+			order = append(order, name)
+		}
+		members[name] = append(members[name], i)
+	}
+
+	groups := make([]*RunGroupStats, 0, len(order))
+	for _, name := range order {
+		indices := members[name]
+		group := &RunGroupStats{
+			Name:           name,
+			RunIndices:     indices,
+			Metrics:        make(map[string]*GroupMetricStats),
+			Pooled:         make(map[string]*MetricStats),
+			PooledMangoHud: make(map[string]*MetricStats),
+		}
+
+		// Mean ± spread of per-run averages
+		averages := make(map[string][]float64)
+		for _, idx := range indices {
+			for key, stats := range preCalc[idx].Stats {
+				if stats != nil {
+					averages[key] = append(averages[key], stats.Avg)
+				}
+			}
+		}
+		for key, values := range averages {
+			group.Metrics[key] = computeGroupMetricStats(values)
+		}
+
+		// Pooled stats over the combined raw data
+		for _, key := range pooledMetricKeys(runs, indices) {
+			data := pooledMetricData(runs, indices, key)
+			if len(data) == 0 {
+				continue
+			}
+			group.Pooled[key] = computeMetricStatsForMethod(data, "linear")
+			group.PooledMangoHud[key] = computeMetricStatsForMethod(data, "mangohud")
+			addHistograms(group.Pooled[key], key, data)
+		}
+
+		// Pooled FPS follows the per-run rule: derive from frametime when every run has it
+		allFrametime := true
+		for _, idx := range indices {
+			if len(runs[idx].DataFrameTime) == 0 {
+				allFrametime = false
+				break
+			}
+		}
+		if allFrametime {
+			ft := pooledMetricData(runs, indices, "FrameTime")
+			group.Pooled["FPS"] = computeFPSFromFrametimeForMethod(ft, "linear")
+			group.PooledMangoHud["FPS"] = computeFPSFromFrametimeForMethod(ft, "mangohud")
+			addHistograms(group.Pooled["FPS"], "FPS", frametimeToFPS(ft))
+		} else if fps := pooledMetricData(runs, indices, "FPS"); len(fps) > 0 {
+			group.Pooled["FPS"] = computeMetricStatsForMethod(fps, "linear")
+			group.PooledMangoHud["FPS"] = computeMetricStatsForMethod(fps, "mangohud")
+			addHistograms(group.Pooled["FPS"], "FPS", fps)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// computeGroupMetricStats computes the mean and sample standard deviation of per-run averages.
+func computeGroupMetricStats(values []float64) *GroupMetricStats {
+	n := len(values)
+	var sum float64
+	minVal, maxVal := values[0], values[0]
+	for _, v := range values {
+		sum += v
+		minVal = math.Min(minVal, v)
+		maxVal = math.Max(maxVal, v)
+	}
+	mean := sum / float64(n)
+
+	var stdDev float64
+	if n > 1 {
+		var sumSq float64
+		for _, v := range values {
+			diff := v - mean
+			sumSq += diff * diff
+		}
+		stdDev = math.Sqrt(sumSq / float64(n-1))
+	}
+
+	return &GroupMetricStats{
+		Mean:   math.Round(mean*100) / 100,
+		StdDev: math.Round(stdDev*100) / 100,
+		Min:    math.Round(minVal*100) / 100,
+		Max:    math.Round(maxVal*100) / 100,
+		Runs:   n,
+	}
+}
+
+// pooledMetricKeys returns the standard (non-FPS) metric keys present in any of the given runs.
+func pooledMetricKeys(runs []*BenchmarkData, indices []int) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, idx := range indices {
+		for _, m := range runMetrics(runs[idx]) {
+			if len(m.data) > 0 && !seen[m.key] {
+				seen[m.key] = true
+				keys = append(keys, m.key)
+			}
+		}
+	}
+	return keys
+}
+
+// pooledMetricData concatenates the raw data of a metric across the given runs.
+func pooledMetricData(runs []*BenchmarkData, indices []int, key string) []float64 {
+	total := 0
+	for _, idx := range indices {
+		total += len(runMetricData(runs[idx], key))
+	}
+	data := make([]float64, 0, total)
+	for _, idx := range indices {
+		data = append(data, runMetricData(runs[idx], key)...)
+	}
+	return data
+}
+
+// RunGroupStatsToMCP converts run group aggregates to the snake_case MCP format.
+func RunGroupStatsToMCP(groups []*RunGroupStats) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		metrics := make(map[string]interface{}, len(g.Metrics))
+		for camelKey, m := range g.Metrics {
+			snakeKey, ok := metricKeyToSnake[camelKey]
+			if !ok {
+				continue
+			}
+			entry := map[string]interface{}{
+				"mean_of_avgs":   m.Mean,
+				"stddev_of_avgs": m.StdDev,
+				"min_avg":        m.Min,
+				"max_avg":        m.Max,
+				"runs":           m.Runs,
+			}
+			if pooled := g.Pooled[camelKey]; pooled != nil {
+				entry["pooled"] = metricStatsToMCP(pooled)
+			}
+			metrics[snakeKey] = entry
+		}
+		result = append(result, map[string]interface{}{
+			"name":        g.Name,
+			"run_indices": g.RunIndices,
+			"metrics":     metrics,
+		})
+	}
+	return result
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestValidateRunGroupPattern(t *testing.T) {
+	if err := ValidateRunGroupPattern(""); err != nil {
+		t.Errorf("empty pattern should be valid, got %v", err)
+	}
+	if err := ValidateRunGroupPattern(`\s*#\d+$`); err != nil {
+		t.Errorf("valid pattern rejected: %v", err)
+	}
+	if err := ValidateRunGroupPattern(`(unclosed`); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	long := make([]byte, maxRunGroupPatternLength+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if err := ValidateRunGroupPattern(string(long)); err == nil {
+		t.Error("expected error for overlong pattern")
+	}
+}
+
+func TestAssignRunGroups(t *testing.T) {
+	runs := []*BenchmarkData{
+		{Label: "6.17 EEVDF #1"},
+		{Label: "6.17 EEVDF #2"},
+		{Label: "6.17 BORE #1"},
+		{Label: "baseline"},
+		{Label: "6.17 BORE #2", Group: "custom"},
+	}
+
+	t.Run("strip pattern", func(t *testing.T) {
+		names := assignRunGroups(runs, `\s*#\d+$`)
+		want := []string{"6.17 EEVDF", "6.17 EEVDF", "6.17 BORE", "", "custom"}
+		for i := range want {
+			if names[i] != want[i] {
+				t.Errorf("run %d: got %q, want %q", i, names[i], want[i])
+			}
+		}
+	})
+
+	t.Run("capture group", func(t *testing.T) {
+		names := assignRunGroups(runs, `^\S+ (\w+)`)
+		want := []string{"EEVDF", "EEVDF", "BORE", "", "custom"}
+		for i := range want {
+			if names[i] != want[i] {
+				t.Errorf("run %d: got %q, want %q", i, names[i], want[i])
+			}
+		}
+	})
+
+	t.Run("no pattern keeps explicit groups only", func(t *testing.T) {
+		names := assignRunGroups(runs, "")
+		for i, name := range names[:4] {
+			if name != "" {
+				t.Errorf("run %d: expected no group, got %q", i, name)
+			}
+		}
+		if names[4] != "custom" {
+			t.Errorf("explicit group lost: %q", names[4])
+		}
+	})
+}
+
+func TestComputeBenchmarkStatsGroups(t *testing.T) {
+	runs := []*BenchmarkData{
+		{Label: "A #1", DataFrameTime: []float64{10, 10, 10, 10}, DataGPULoad: []float64{90, 90, 90, 90}},
+		{Label: "A #2", DataFrameTime: []float64{20, 20, 20, 20}, DataGPULoad: []float64{80, 80, 80, 80}},
+		{Label: "B #1", DataFPS: []float64{60, 60}},
+	}
+
+	preCalc, groups := ComputeBenchmarkStats(runs, `\s*#\d+$`)
+	if preCalc[0].Group != "A" || preCalc[2].Group != "B" {
+		t.Fatalf("groups not assigned to runs: %q, %q", preCalc[0].Group, preCalc[2].Group)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	a := groups[0]
+	if a.Name != "A" || len(a.RunIndices) != 2 {
+		t.Fatalf("unexpected group A: %+v", a)
+	}
+	// Per-run FPS averages are 100 and 50
+	fps := a.Metrics["FPS"]
+	if fps == nil || fps.Mean != 75 || fps.Runs != 2 {
+		t.Fatalf("unexpected FPS mean of averages: %+v", fps)
+	}
+	if !approxEqual(fps.StdDev, 35.36, 0.01) {
+		t.Errorf("FPS stddev of averages: got %v, want 35.36", fps.StdDev)
+	}
+	if fps.Min != 50 || fps.Max != 100 {
+		t.Errorf("FPS min/max of averages: got %v/%v", fps.Min, fps.Max)
+	}
+
+	// Pooled stats cover all 8 frames of both runs
+	pooledFT := a.Pooled["FrameTime"]
+	if pooledFT == nil || pooledFT.Count != 8 || pooledFT.Avg != 15 {
+		t.Errorf("unexpected pooled frametime: %+v", pooledFT)
+	}
+	if a.Pooled["FPS"] == nil || a.PooledMangoHud["FPS"] == nil {
+		t.Error("expected pooled FPS derived from frametime")
+	}
+	if a.Pooled["GPULoad"].Avg != 85 {
+		t.Errorf("pooled GPU load avg: got %v, want 85", a.Pooled["GPULoad"].Avg)
+	}
+
+	b := groups[1]
+	if b.Metrics["FPS"].StdDev != 0 || b.Pooled["FPS"].Avg != 60 {
+		t.Errorf("unexpected single-run group: %+v", b.Metrics["FPS"])
+	}
+}
+
+func TestPreCalculatedStatsGroupsRoundTrip(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	runs := []*BenchmarkData{
+		{Label: "A #1", DataFPS: []float64{60, 61}},
+		{Label: "A #2", DataFPS: []float64{62, 63}},
+	}
+	preCalc, groups := ComputeBenchmarkStats(runs, `\s*#\d+$`)
+	if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+
+	gotRuns, gotGroups, err := RetrievePreCalculatedStatsWithGroups(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve stats: %v", err)
+	}
+	if len(gotRuns) != 2 || len(gotGroups) != 1 || gotGroups[0].Name != "A" {
+		t.Fatalf("unexpected round trip: %d runs, groups %+v", len(gotRuns), gotGroups)
+	}
+
+	t.Run("stats file without groups", func(t *testing.T) {
+		// Files written before run groups only contain the runs slice
+		file, err := os.Create(filepath.Join(benchmarksDir, "2.stats"))
+		if err != nil {
+			t.Fatalf("Failed to create stats file: %v", err)
+		}
+		bufWriter := bufio.NewWriter(file)
+		enc, err := zstd.NewWriter(bufWriter)
+		if err != nil {
+			t.Fatalf("Failed to create zstd writer: %v", err)
+		}
+		if err := gob.NewEncoder(enc).Encode(preCalc); err != nil {
+			t.Fatalf("Failed to encode: %v", err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("Failed to close zstd writer: %v", err)
+		}
+		if err := bufWriter.Flush(); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatalf("Failed to close file: %v", err)
+		}
+
+		oldRuns, oldGroups, err := RetrievePreCalculatedStatsWithGroups(2)
+		if err != nil {
+			t.Fatalf("Failed to read legacy stats file: %v", err)
+		}
+		if len(oldRuns) != 2 || len(oldGroups) != 0 {
+			t.Errorf("expected 2 runs and no groups, got %d runs and %d groups", len(oldRuns), len(oldGroups))
+		}
+	})
+}
+
+func TestHandleGetBenchmarkDataGroups(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "groupuser", false)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Groups", RunGroupPattern: `\s*#\d+$`}
+	db.DB.Create(benchmark)
+
+	runs := []*BenchmarkData{
+		{Label: "EEVDF #1", DataFPS: []float64{60, 61}},
+		{Label: "EEVDF #2", DataFPS: []float64{62, 63}},
+	}
+	preCalc, groups := ComputeBenchmarkStats(runs, benchmark.RunGroupPattern)
+	if err := StorePreCalculatedStats(preCalc, groups, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks/:id/data", HandleGetBenchmarkData(db))
+
+	t.Run("default response is an array of runs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/benchmarks/%d/data", benchmark.ID), http.NoBody)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		var runsResp []map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &runsResp); err != nil {
+			t.Fatalf("Expected JSON array: %v", err)
+		}
+		if runsResp[0]["group"] != "EEVDF" {
+			t.Errorf("expected run group in run object, got %v", runsResp[0]["group"])
+		}
+	})
+
+	t.Run("groups=true returns envelope", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/benchmarks/%d/data?groups=true", benchmark.ID), http.NoBody)
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		var resp struct {
+			Runs   []*PreCalculatedRun `json:"runs"`
+			Groups []*RunGroupStats    `json:"groups"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if len(resp.Runs) != 2 || len(resp.Groups) != 1 {
+			t.Fatalf("expected 2 runs and 1 group, got %d and %d", len(resp.Runs), len(resp.Groups))
+		}
+		if resp.Groups[0].Metrics["FPS"].Mean != 61.5 {
+			t.Errorf("unexpected group FPS mean: %v", resp.Groups[0].Metrics["FPS"].Mean)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	maxHistogramBins = 1000 // Linear bins are widened until the full-range histogram has at most this many non-empty bins
+)
+
+// Histogram holds binned counts for a metric. Bins are [[binStart, count], ...] sorted by binStart;
+// empty bins are omitted. Exactly one of BinWidth (linear bins) or BinsPerDecade (log-scale bins) is set.
+type Histogram struct {
+	BinWidth      float64      `json:"binWidth,omitempty"`
+	BinsPerDecade int          `json:"binsPerDecade,omitempty"`
+	Bins          [][2]float64 `json:"bins"`
+}
+
+// histogramConfig controls how the density of a metric is binned.
+type histogramConfig struct {
+	BinWidth      float64 // Linear bin width in the metric's unit
+	LogScale      bool    // Use logarithmic bins (BinsPerDecade per power of 10) instead of linear ones
+	BinsPerDecade int
+	IncludeTails  bool // Keep values outside P01–P97 in the density
+}
+
+// defaultHistogramConfig is used for metrics without an entry in histogramConfigs.
+var defaultHistogramConfig = histogramConfig{BinWidth: 1}
+
+// histogramConfigs holds per-metric density settings (camelCase metric keys).
+var histogramConfigs = map[string]histogramConfig{
+	// Frametime spans a few ms, so integer bins would lump everything together; keep the
+	// stutter tail since that is what frametime plots are for
+	"FrameTime":       {BinWidth: 0.1, IncludeTails: true},
+	"FPSPerWatt":      {BinWidth: 0.1},
+	"FPSPerWattTotal": {BinWidth: 0.1},
+	"EnergyPerFrame":  {LogScale: true, BinsPerDecade: 20},
+	"GPUCoreClock":    {BinWidth: 10},
+	"GPUMemClock":     {BinWidth: 10},
+	"CPUClock":        {BinWidth: 10},
+}
+
+// histogramConfigFor returns the density settings for a metric.
+func histogramConfigFor(key string) histogramConfig {
+	if cfg, ok := histogramConfigs[key]; ok {
+		return cfg
+	}
+	return defaultHistogramConfig
+}
+
+// addHistograms bins the raw values of a metric into the histograms of its stats. The histogram
+// covers P01–P97 of stats unless the metric includes tails; the full-range histogram is only stored
+// when the histogram drops values. Both share the same bins so they can be plotted on one axis.
+func addHistograms(stats *MetricStats, key string, values []float64) {
+	if stats == nil || len(values) == 0 {
+		return
+	}
+	cfg := histogramConfigFor(key)
+
+	full := computeHistogram(values, cfg)
+	if cfg.IncludeTails {
+		stats.Histogram = full
+		return
+	}
+
+	// Stats are rounded to 2 decimals; widen the bounds so edge values are not dropped
+	stats.Histogram = binHistogram(values, cfg, stats.P01-0.005, stats.P97+0.005, full.BinWidth)
+	if histogramCount(stats.Histogram) < histogramCount(full) {
+		stats.HistogramFull = full
+	}
+}
+
+// computeHistogram bins all values according to cfg, widening linear bins until there are at
+// most maxHistogramBins of them.
+func computeHistogram(values []float64, cfg histogramConfig) *Histogram {
+	if cfg.LogScale {
+		return binHistogram(values, cfg, math.Inf(-1), math.Inf(1), 0)
+	}
+
+	width := cfg.BinWidth
+	if width <= 0 {
+		width = 1
+	}
+	for {
+		hist := binHistogram(values, cfg, math.Inf(-1), math.Inf(1), width)
+		if len(hist.Bins) <= maxHistogramBins {
+			return hist
+		}
+		width *= 2
+	}
+}
+
+// histogramCount returns the number of values held by a histogram.
+func histogramCount(hist *Histogram) int {
+	var count int
+	for _, bin := range hist.Bins {
+		count += int(bin[1])
+	}
+	return count
+}
+
+// binHistogram bins the values within [lo, hi] using log-scale bins or linear bins of the given width.
+// Non-finite values and values whose bin index would overflow int64 are skipped.
+func binHistogram(values []float64, cfg histogramConfig, lo, hi, width float64) *Histogram {
+	hist := &Histogram{}
+	var binOf func(v float64) (int64, bool)
+	var binStart func(idx int64) float64
+
+	if cfg.LogScale {
+		perDecade := float64(cfg.BinsPerDecade)
+		if perDecade <= 0 {
+			perDecade = 10
+		}
+		hist.BinsPerDecade = int(perDecade)
+		binOf = func(v float64) (int64, bool) {
+			if v <= 0 {
+				return 0, false // log bins cannot hold zero or negative values
+			}
+			return histogramBinIndex(math.Log10(v) * perDecade)
+		}
+		binStart = func(idx int64) float64 {
+			return math.Pow(10, float64(idx)/perDecade)
+		}
+	} else {
+		hist.BinWidth = width
+		binOf = func(v float64) (int64, bool) {
+			return histogramBinIndex(v/width + 1e-9)
+		}
+		binStart = func(idx int64) float64 {
+			return float64(idx) * width
+		}
+	}
+
+	counts := make(map[int64]int)
+	for _, v := range values {
+		if math.IsNaN(v) || v < lo || v > hi {
+			continue
+		}
+		if idx, ok := binOf(v); ok {
+			counts[idx]++
+		}
+	}
+
+	indices := make([]int64, 0, len(counts))
+	for idx := range counts {
+		indices = append(indices, idx)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	hist.Bins = make([][2]float64, len(indices))
+	for i, idx := range indices {
+		hist.Bins[i] = [2]float64{math.Round(binStart(idx)*10000) / 10000, float64(counts[idx])}
+	}
+	return hist
+}
+
+// histogramBinIndex floors a position in bins to a bin index. Returns false if it is not finite or is
+// outside the int64 range.
+func histogramBinIndex(pos float64) (int64, bool) {
+	idx := math.Floor(pos)
+	if math.IsNaN(idx) || idx < math.MinInt64 || idx >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(idx), true
+}
+
+// frametimeToFPS converts frametime samples (ms) to FPS, skipping non-positive frametimes.
+func frametimeToFPS(frametimes []float64) []float64 {
+	fps := make([]float64, 0, len(frametimes))
+	for _, ft := range frametimes {
+		if ft > 0 {
+			fps = append(fps, 1000/ft)
+		}
+	}
+	return fps
+}
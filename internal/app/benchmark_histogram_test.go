@@ -0,0 +1,126 @@
+package app
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeHistogramLinear(t *testing.T) {
+	values := []float64{8.31, 8.34, 8.39, 16.7, 45.2}
+	hist := computeHistogram(values, histogramConfig{BinWidth: 0.1})
+	if hist.BinWidth != 0.1 || hist.BinsPerDecade != 0 {
+		t.Fatalf("unexpected bin settings: %+v", hist)
+	}
+	want := [][2]float64{{8.3, 3}, {16.7, 1}, {45.2, 1}}
+	if len(hist.Bins) != len(want) {
+		t.Fatalf("expected %d bins, got %v", len(want), hist.Bins)
+	}
+	for i, bin := range want {
+		if hist.Bins[i] != bin {
+			t.Errorf("bin %d: got %v, want %v", i, hist.Bins[i], bin)
+		}
+	}
+
+	t.Run("bounds", func(t *testing.T) {
+		hist := binHistogram(values, histogramConfig{BinWidth: 0.1}, 8, 20, 0.1)
+		if len(hist.Bins) != 2 {
+			t.Errorf("expected tail value to be dropped, got %v", hist.Bins)
+		}
+	})
+
+	t.Run("overflow values are skipped", func(t *testing.T) {
+		// Values without a finite int64 bin index must be skipped instead of producing corrupt bins
+		values := []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e300, -1e300, 42}
+		hist := computeHistogram(values, histogramConfig{BinWidth: 0.1})
+		if len(hist.Bins) != 1 || hist.Bins[0] != [2]float64{42, 1} {
+			t.Errorf("expected only 42 binned, got %v", hist.Bins)
+		}
+		hist = computeHistogram(values, histogramConfig{LogScale: true, BinsPerDecade: 10})
+		if len(hist.Bins) != 2 {
+			t.Errorf("expected only 1e300 and 42 binned, got %v", hist.Bins)
+		}
+	})
+
+	t.Run("bin width is widened when there are too many bins", func(t *testing.T) {
+		spread := make([]float64, 3000)
+		for i := range spread {
+			spread[i] = float64(i)
+		}
+		hist := computeHistogram(spread, histogramConfig{BinWidth: 1})
+		if hist.BinWidth != 4 || len(hist.Bins) != 750 {
+			t.Errorf("expected 750 bins of width 4, got %d bins of width %v", len(hist.Bins), hist.BinWidth)
+		}
+	})
+}
+
+func TestComputeHistogramLog(t *testing.T) {
+	values := []float64{0, 1, 1.05, 10, 100}
+	hist := computeHistogram(values, histogramConfig{LogScale: true, BinsPerDecade: 10})
+	if hist.BinsPerDecade != 10 || hist.BinWidth != 0 {
+		t.Fatalf("unexpected bin settings: %+v", hist)
+	}
+	// Zero cannot be placed on a log scale
+	want := [][2]float64{{1, 2}, {10, 1}, {100, 1}}
+	if len(hist.Bins) != len(want) {
+		t.Fatalf("expected %d bins, got %v", len(want), hist.Bins)
+	}
+	for i, bin := range want {
+		if hist.Bins[i] != bin {
+			t.Errorf("bin %d: got %v, want %v", i, hist.Bins[i], bin)
+		}
+	}
+}
+
+func TestPreCalculatedRunHistograms(t *testing.T) {
+	frametimes := make([]float64, 200)
+	for i := range frametimes {
+		frametimes[i] = 8 + float64(i%10)*0.1
+	}
+	frametimes[199] = 250 // stutter
+	result := computePreCalculatedRun(&BenchmarkData{
+		DataFrameTime: frametimes,
+		DataGPULoad:   repeatValue(50, 200),
+	})
+
+	ft := result.Stats["FrameTime"]
+	if ft.Histogram == nil || ft.Histogram.BinWidth != 0.1 {
+		t.Fatalf("expected 0.1 ms frametime histogram, got %+v", ft.Histogram)
+	}
+	if ft.HistogramFull != nil {
+		t.Error("frametime includes tails, full histogram should be omitted")
+	}
+	last := ft.Histogram.Bins[len(ft.Histogram.Bins)-1]
+	if last[0] != 250 || last[1] != 1 {
+		t.Errorf("expected stutter in frametime histogram tail, got %v", last)
+	}
+
+	// Legacy density is unchanged: integer bins trimmed to P01–P97
+	if len(ft.Density) == 0 || ft.Density[len(ft.Density)-1][0] > 9 {
+		t.Errorf("unexpected legacy density: %v", ft.Density)
+	}
+
+	fps := result.Stats["FPS"]
+	if fps.Histogram == nil || fps.HistogramFull == nil {
+		t.Fatal("expected trimmed and full FPS histograms")
+	}
+	if fps.Histogram.BinWidth != fps.HistogramFull.BinWidth {
+		t.Errorf("trimmed and full histograms should share bins, got %v and %v", fps.Histogram.BinWidth, fps.HistogramFull.BinWidth)
+	}
+	if fps.HistogramFull.Bins[0][0] != 4 {
+		t.Errorf("full FPS histogram should include the 4 FPS stutter, got %v", fps.HistogramFull.Bins[0])
+	}
+	if fps.Histogram.Bins[0][0] < 100 {
+		t.Errorf("trimmed FPS histogram should drop the stutter, got %v", fps.Histogram.Bins[0])
+	}
+
+	gpu := result.Stats["GPULoad"]
+	if gpu.Histogram == nil || gpu.HistogramFull != nil {
+		t.Errorf("expected only a trimmed histogram for a constant metric, got %+v / %+v", gpu.Histogram, gpu.HistogramFull)
+	}
+	if result.StatsMangoHud["GPULoad"].Histogram != nil {
+		t.Error("histograms don't depend on the stats method and should only be stored on the linear stats")
+	}
+	if len(result.StatsMangoHud["FPS"].Density) == 0 {
+		t.Error("expected density on MangoHud FPS stats")
+	}
+}
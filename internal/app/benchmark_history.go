@@ -0,0 +1,598 @@
+package app
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Every change to a benchmark bumps its revision (see benchmark_revision.go). The edit history
+// keeps a snapshot of the title, description, run group pattern, game and run set (labels and
+// content hashes) of each revision in benchmark_revisions, recorded after the change is saved, along
+// with its author. Benchmarks that existed before the history are recorded once, at the revision
+// they had, by the v8 → v9 migration. Deleting a game unlinks it from revisions too.
+//
+// Reverting restores the metadata of an earlier revision as a new revision. Runs are matched by
+// content hash, so labels follow their runs even if runs were added or deleted since; deleted
+// runs are not brought back (they can be restored from the trash).
+
+// Actions that produce a revision
+const (
+	revisionActionImported    = "imported" // State of the benchmark when the history was introduced
+	revisionActionCreated     = "created"
+	revisionActionUpdated     = "updated"
+	revisionActionRunsAdded   = "runs_added"
+	revisionActionRunDeleted  = "run_deleted"
+	revisionActionRunRestored = "run_restored"
+	revisionActionReverted    = "reverted"
+)
+
+// RevisionRun is one run of a benchmark revision.
+type RevisionRun struct {
+	Label       string `json:"label"`
+	ContentHash string `json:"content_hash"`
+}
+
+// RevisionRuns is the run set of a revision, stored as a JSON column.
+type RevisionRuns []RevisionRun
+
+// Value implements driver.Valuer.
+func (r RevisionRuns) Value() (driver.Value, error) {
+	if r == nil {
+		r = RevisionRuns{}
+	}
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner.
+func (r *RevisionRuns) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = RevisionRuns{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported revision runs type %T", value)
+	}
+	return json.Unmarshal(data, r)
+}
+
+// RevisionFieldChange is a changed field between two revisions. Lines holds a line diff of
+// multi-line text (the description).
+type RevisionFieldChange struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Lines []DiffLine `json:"lines,omitempty"`
+}
+
+// DiffLine is a line of a line diff: Op is "=" for unchanged, "-" for removed and "+" for added lines.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// RevisionGameChange is a changed game between two revisions (nil for none).
+type RevisionGameChange struct {
+	From *uint `json:"from"`
+	To   *uint `json:"to"`
+}
+
+// RevisionRunRef is a run of a revision, by index.
+type RevisionRunRef struct {
+	RunIndex    int    `json:"run_index"`
+	Label       string `json:"label"`
+	ContentHash string `json:"content_hash"`
+}
+
+// RevisionRunRelabel is a run present in both revisions under different labels.
+type RevisionRunRelabel struct {
+	ContentHash string `json:"content_hash"`
+	FromIndex   int    `json:"from_index"`
+	ToIndex     int    `json:"to_index"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+}
+
+// BenchmarkRevisionDiff lists the differences between two revisions of a benchmark.
+type BenchmarkRevisionDiff struct {
+	BenchmarkID     uint                 `json:"benchmark_id"`
+	From            uint                 `json:"from"`
+	To              uint                 `json:"to"`
+	Title           *RevisionFieldChange `json:"title,omitempty"`
+	Description     *RevisionFieldChange `json:"description,omitempty"`
+	RunGroupPattern *RevisionFieldChange `json:"run_group_pattern,omitempty"`
+	GameID          *RevisionGameChange  `json:"game_id,omitempty"`
+	RunsAdded       []RevisionRunRef     `json:"runs_added"`
+	RunsRemoved     []RevisionRunRef     `json:"runs_removed"`
+	RunsRelabeled   []RevisionRunRelabel `json:"runs_relabeled"`
+}
+
+// snapshotBenchmarkRuns returns the current run set of a benchmark from its run records.
+func snapshotBenchmarkRuns(db *DBInstance, benchmarkID uint) (RevisionRuns, error) {
+	var records []BenchmarkRun
+	if err := db.DB.Where("benchmark_id = ?", benchmarkID).Order("run_index ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load runs of benchmark %d: %w", benchmarkID, err)
+	}
+	runs := make(RevisionRuns, len(records))
+	for i, record := range records {
+		runs[i] = RevisionRun{Label: record.Label, ContentHash: record.ContentHash}
+	}
+	return runs, nil
+}
+
+// newBenchmarkRevision snapshots a benchmark at its current revision.
+func newBenchmarkRevision(db *DBInstance, benchmark *Benchmark, userID uint, username, action string) (*BenchmarkRevision, error) {
+	runs, err := snapshotBenchmarkRuns(db, benchmark.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &BenchmarkRevision{
+		BenchmarkID:     benchmark.ID,
+		Revision:        benchmark.Revision,
+		Title:           benchmark.Title,
+		Description:     benchmark.Description,
+		RunGroupPattern: benchmark.RunGroupPattern,
+		GameID:          benchmark.GameID,
+		Runs:            runs,
+		Action:          action,
+		UserID:          userID,
+		Username:        username,
+	}, nil
+}
+
+// recordBenchmarkRevision adds the benchmark's current revision to its edit history. It is called
+// after the change was saved, so errors are logged rather than failing the request.
+func recordBenchmarkRevision(db *DBInstance, benchmark *Benchmark, userID uint, username, action string) {
+	revision, err := newBenchmarkRevision(db, benchmark, userID, username, action)
+	if err == nil {
+		err = db.DB.Create(revision).Error
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to record revision %d of benchmark %d: %v\n", benchmark.Revision, benchmark.ID, err)
+	}
+}
+
+// MigrateBenchmarkRevisions records the current revision of every benchmark, including those in
+// the trash, as the start of its edit history.
+func MigrateBenchmarkRevisions(db *DBInstance) error {
+	var benchmarks []Benchmark
+	if err := db.DB.Unscoped().Preload("User").Order("id").Find(&benchmarks).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for i := range benchmarks {
+		revision, err := newBenchmarkRevision(db, &benchmarks[i], benchmarks[i].UserID, benchmarks[i].User.Username, revisionActionImported)
+		if err != nil {
+			return err
+		}
+		revision.CreatedAt = benchmarks[i].UpdatedAt
+		if err := db.DB.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to record revision of benchmark %d: %w", benchmarks[i].ID, err)
+		}
+	}
+	log.Printf("Recorded the current revision of %d benchmark(s)", len(benchmarks))
+	return nil
+}
+
+// findBenchmarkRevision loads one revision of a benchmark.
+func findBenchmarkRevision(db *DBInstance, benchmarkID, revision uint) (*BenchmarkRevision, error) {
+	var rev BenchmarkRevision
+	err := db.DB.Where("benchmark_id = ? AND revision = ?", benchmarkID, revision).First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// diffBenchmarkRevisions compares two revisions. Runs are matched by content hash, in order, so
+// a run keeps its identity when it is relabeled or moves to another index.
+func diffBenchmarkRevisions(from, to *BenchmarkRevision) *BenchmarkRevisionDiff {
+	diff := &BenchmarkRevisionDiff{
+		BenchmarkID:   to.BenchmarkID,
+		From:          from.Revision,
+		To:            to.Revision,
+		RunsAdded:     []RevisionRunRef{},
+		RunsRemoved:   []RevisionRunRef{},
+		RunsRelabeled: []RevisionRunRelabel{},
+	}
+	if from.Title != to.Title {
+		diff.Title = &RevisionFieldChange{From: from.Title, To: to.Title}
+	}
+	if from.Description != to.Description {
+		diff.Description = &RevisionFieldChange{From: from.Description, To: to.Description, Lines: diffLines(from.Description, to.Description)}
+	}
+	if from.RunGroupPattern != to.RunGroupPattern {
+		diff.RunGroupPattern = &RevisionFieldChange{From: from.RunGroupPattern, To: to.RunGroupPattern}
+	}
+	if !sameGame(from.GameID, to.GameID) {
+		diff.GameID = &RevisionGameChange{From: from.GameID, To: to.GameID}
+	}
+
+	matches := matchRevisionRuns(from.Runs, to.Runs)
+	matched := make([]bool, len(from.Runs))
+	for toIdx, run := range to.Runs {
+		fromIdx, ok := matches[toIdx]
+		if !ok {
+			diff.RunsAdded = append(diff.RunsAdded, RevisionRunRef{RunIndex: toIdx, Label: run.Label, ContentHash: run.ContentHash})
+			continue
+		}
+		matched[fromIdx] = true
+		if from.Runs[fromIdx].Label != run.Label {
+			diff.RunsRelabeled = append(diff.RunsRelabeled, RevisionRunRelabel{
+				ContentHash: run.ContentHash,
+				FromIndex:   fromIdx,
+				ToIndex:     toIdx,
+				From:        from.Runs[fromIdx].Label,
+				To:          run.Label,
+			})
+		}
+	}
+	for fromIdx, run := range from.Runs {
+		if !matched[fromIdx] {
+			diff.RunsRemoved = append(diff.RunsRemoved, RevisionRunRef{RunIndex: fromIdx, Label: run.Label, ContentHash: run.ContentHash})
+		}
+	}
+	return diff
+}
+
+// sameGame reports whether two game links are the same (nil for none).
+func sameGame(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// matchRevisionRuns maps indices of runs to the index of the run with the same content hash in
+// from. Runs with the same hash are matched in order.
+func matchRevisionRuns(from, to RevisionRuns) map[int]int {
+	byHash := make(map[string][]int)
+	for i, run := range from {
+		byHash[run.ContentHash] = append(byHash[run.ContentHash], i)
+	}
+	matches := make(map[int]int)
+	for i, run := range to {
+		if indices := byHash[run.ContentHash]; len(indices) > 0 {
+			matches[i] = indices[0]
+			byHash[run.ContentHash] = indices[1:]
+		}
+	}
+	return matches
+}
+
+// diffLines computes a line diff of two texts from their longest common subsequence. Descriptions
+// are limited to 5000 characters, so the quadratic table stays small.
+func diffLines(a, b string) []DiffLine {
+	linesA, linesB := strings.Split(a, "\n"), strings.Split(b, "\n")
+	n, m := len(linesA), len(linesB)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case linesA[i] == linesB[j]:
+			lines = append(lines, DiffLine{Op: "=", Text: linesA[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: "-", Text: linesA[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: "+", Text: linesB[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, DiffLine{Op: "-", Text: linesA[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, DiffLine{Op: "+", Text: linesB[j]})
+	}
+	return lines
+}
+
+// revertBenchmark restores the title, description, run group pattern, game and run labels of an
+// earlier revision. Labels are applied to the current runs with the same content hash. Returns the changed
+// fields; without changes nothing is written. The caller holds lockBenchmarkWrites.
+func revertBenchmark(db *DBInstance, benchmark *Benchmark, target *BenchmarkRevision) ([]string, error) {
+	var changes []string
+	if benchmark.Title != target.Title {
+		benchmark.Title = target.Title
+		changes = append(changes, "title")
+	}
+	if benchmark.Description != target.Description {
+		benchmark.Description = target.Description
+		changes = append(changes, "description")
+	}
+	patternChanged := benchmark.RunGroupPattern != target.RunGroupPattern
+	if patternChanged {
+		benchmark.RunGroupPattern = target.RunGroupPattern
+		changes = append(changes, "run_group_pattern")
+	}
+	if !sameGame(benchmark.GameID, target.GameID) {
+		// Deleting a game unlinks it from revisions, so the target's game exists
+		benchmark.GameID, benchmark.Game = target.GameID, nil
+		changes = append(changes, "game")
+	}
+
+	current, err := snapshotBenchmarkRuns(db, benchmark.ID)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[int]string)
+	for idx, targetIdx := range matchRevisionRuns(target.Runs, current) {
+		if current[idx].Label != target.Runs[targetIdx].Label {
+			labels[idx] = target.Runs[targetIdx].Label
+		}
+	}
+	if len(labels) > 0 {
+		changes = append(changes, "labels")
+	}
+
+	if len(labels) > 0 || patternChanged {
+		benchmarkData, err := RetrieveBenchmarkData(benchmark.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve benchmark data: %w", err)
+		}
+		if len(benchmarkData) != len(current) {
+			return nil, fmt.Errorf("run records of benchmark %d are out of date", benchmark.ID)
+		}
+		for idx, label := range labels {
+			benchmarkData[idx].Label = label
+		}
+
+		preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+		if len(labels) > 0 {
+			if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, benchmark.ID); err != nil {
+				return nil, fmt.Errorf("failed to update labels: %w", err)
+			}
+			if err := syncBenchmarkRuns(db, benchmark.ID, benchmarkData, preCalc); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		} else if storeErr := StorePreCalculatedStats(preCalc, groups, benchmark.ID); storeErr != nil {
+			fmt.Printf("Warning: failed to update pre-calculated stats for benchmark %d: %v\n", benchmark.ID, storeErr)
+		}
+
+		benchmark.RunNames, benchmark.Specifications = ExtractSearchableMetadata(benchmarkData)
+		setBenchmarkUsage(benchmark, benchmarkData)
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	benchmark.Revision++
+	if err := db.DB.Save(benchmark).Error; err != nil {
+		return nil, fmt.Errorf("failed to update benchmark: %w", err)
+	}
+	return changes, nil
+}
+
+// parseRevisionParam parses a revision number from a path or query parameter.
+func parseRevisionParam(value string) (uint, error) {
+	revision, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || revision == 0 {
+		return 0, errors.New("invalid revision")
+	}
+	return uint(revision), nil
+}
+
+// HandleListBenchmarkRevisions returns the edit history of a benchmark, newest revision first
+func HandleListBenchmarkRevisions(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		benchmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "10"))
+		if err != nil || perPage < 1 || perPage > 100 {
+			perPage = 10
+		}
+
+		var benchmark Benchmark
+		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+
+		query := db.DB.Model(&BenchmarkRevision{}).Where("benchmark_id = ?", benchmark.ID)
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		revisions := []BenchmarkRevision{}
+		if err := query.Order("revision DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&revisions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"revisions":   revisions,
+			"revision":    benchmark.Revision,
+			"page":        page,
+			"per_page":    perPage,
+			"total":       total,
+			"total_pages": int((total + int64(perPage) - 1) / int64(perPage)),
+		})
+	}
+}
+
+// HandleGetBenchmarkRevision returns one revision of a benchmark
+func HandleGetBenchmarkRevision(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		benchmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+		revision, err := parseRevisionParam(c.Param("revision"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var benchmark Benchmark
+		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+		rev, err := findBenchmarkRevision(db, benchmark.ID, revision)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+			return
+		}
+		c.JSON(http.StatusOK, rev)
+	}
+}
+
+// HandleDiffBenchmarkRevisions compares two revisions of a benchmark. to defaults to the current
+// revision and from to the one before to.
+func HandleDiffBenchmarkRevisions(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		benchmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+
+		var benchmark Benchmark
+		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+
+		diff, status, err := diffBenchmarkRevisionsQuery(db, &benchmark, c.Query("from"), c.Query("to"))
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+// diffBenchmarkRevisionsQuery resolves the from and to revisions of a diff request (empty values
+// use the defaults of HandleDiffBenchmarkRevisions) and compares them. On error it also returns the
+// HTTP status to respond with.
+func diffBenchmarkRevisionsQuery(db *DBInstance, benchmark *Benchmark, fromParam, toParam string) (*BenchmarkRevisionDiff, int, error) {
+	to := benchmark.Revision
+	if toParam != "" {
+		var err error
+		if to, err = parseRevisionParam(toParam); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid to revision")
+		}
+	}
+	from := to - 1
+	if fromParam != "" {
+		var err error
+		if from, err = parseRevisionParam(fromParam); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid from revision")
+		}
+	}
+	if from == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("revision %d has no earlier revision", to)
+	}
+
+	revisions := make([]*BenchmarkRevision, 2)
+	for i, revision := range []uint{from, to} {
+		rev, err := findBenchmarkRevision(db, benchmark.ID, revision)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, http.StatusNotFound, fmt.Errorf("revision %d not found", revision)
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("database error")
+		}
+		revisions[i] = rev
+	}
+	return diffBenchmarkRevisions(revisions[0], revisions[1]), http.StatusOK, nil
+}
+
+// HandleRevertBenchmark restores the metadata of an earlier revision as a new revision
+func HandleRevertBenchmark(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, isAdmin, ok := requestingUser(c)
+		if !ok {
+			return
+		}
+		benchmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+		revision, err := parseRevisionParam(c.Param("revision"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize with other mutations of this benchmark
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
+		var benchmark Benchmark
+		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+		if benchmark.UserID != uid && !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not authorized"})
+			return
+		}
+		if !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+
+		target, err := findBenchmarkRevision(db, benchmark.ID, revision)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+			return
+		}
+
+		changes, err := revertBenchmark(db, &benchmark, target)
+		if err != nil {
+			fmt.Printf("Warning: failed to revert benchmark %d to revision %d: %v\n", benchmark.ID, revision, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revert benchmark"})
+			return
+		}
+		if len(changes) > 0 {
+			username := GetUsernameFromContext(c)
+			recordBenchmarkRevision(db, &benchmark, uid, username, revisionActionReverted)
+			LogBenchmarkReverted(uid, username, benchmark.ID, benchmark.Title, revision, changes)
+		}
+
+		if err := db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load benchmark"})
+			return
+		}
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
+		c.JSON(http.StatusOK, benchmark)
+	}
+}
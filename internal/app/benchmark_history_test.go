@@ -0,0 +1,244 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("Setup\nKernel 6.16\nNotes", "Setup\nKernel 6.17\nNotes\nMore")
+	want := []DiffLine{
+		{"=", "Setup"},
+		{"-", "Kernel 6.16"},
+		{"+", "Kernel 6.17"},
+		{"=", "Notes"},
+		{"+", "More"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffBenchmarkRevisions(t *testing.T) {
+	gameID := uint(7)
+	from := &BenchmarkRevision{
+		BenchmarkID: 1, Revision: 1, Title: "Old", Description: "Same",
+		Runs: RevisionRuns{{"a", "h1"}, {"b", "h2"}, {"c", "h3"}},
+	}
+	to := &BenchmarkRevision{
+		BenchmarkID: 1, Revision: 3, Title: "New", Description: "Same", RunGroupPattern: `#\d+$`, GameID: &gameID,
+		Runs: RevisionRuns{{"a", "h1"}, {"c renamed", "h3"}, {"d", "h4"}},
+	}
+	diff := diffBenchmarkRevisions(from, to)
+
+	if diff.Title == nil || diff.Title.From != "Old" || diff.Title.To != "New" {
+		t.Errorf("unexpected title change: %+v", diff.Title)
+	}
+	if diff.Description != nil {
+		t.Errorf("expected no description change, got %+v", diff.Description)
+	}
+	if diff.RunGroupPattern == nil || diff.RunGroupPattern.To != `#\d+$` {
+		t.Errorf("unexpected pattern change: %+v", diff.RunGroupPattern)
+	}
+	if diff.GameID == nil || diff.GameID.From != nil || diff.GameID.To == nil || *diff.GameID.To != gameID {
+		t.Errorf("unexpected game change: %+v", diff.GameID)
+	}
+	if diff := diffBenchmarkRevisions(to, to); diff.GameID != nil {
+		t.Errorf("expected no game change, got %+v", diff.GameID)
+	}
+	if want := []RevisionRunRef{{RunIndex: 2, Label: "d", ContentHash: "h4"}}; !reflect.DeepEqual(diff.RunsAdded, want) {
+		t.Errorf("runs added: got %+v, want %+v", diff.RunsAdded, want)
+	}
+	if want := []RevisionRunRef{{RunIndex: 1, Label: "b", ContentHash: "h2"}}; !reflect.DeepEqual(diff.RunsRemoved, want) {
+		t.Errorf("runs removed: got %+v, want %+v", diff.RunsRemoved, want)
+	}
+	want := []RevisionRunRelabel{{ContentHash: "h3", FromIndex: 2, ToIndex: 1, From: "c", To: "c renamed"}}
+	if !reflect.DeepEqual(diff.RunsRelabeled, want) {
+		t.Errorf("runs relabeled: got %+v, want %+v", diff.RunsRelabeled, want)
+	}
+}
+
+func TestBenchmarkHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	owner := createTestUser(db, "historyowner", true)
+	other := createTestUser(db, "historyother", false)
+	router := revisionTestRouter(db, owner)
+	router.POST("/api/benchmarks", func(c *gin.Context) {
+		c.Set("UserID", owner.ID)
+		HandleCreateBenchmark(db)(c)
+	})
+	router.GET("/api/benchmarks/:id/revisions", HandleListBenchmarkRevisions(db))
+	router.GET("/api/benchmarks/:id/revisions/diff", HandleDiffBenchmarkRevisions(db))
+	router.GET("/api/benchmarks/:id/revisions/:revision", HandleGetBenchmarkRevision(db))
+	router.POST("/api/benchmarks/:id/revisions/:revision/revert", func(c *gin.Context) {
+		c.Set("UserID", owner.ID)
+		if c.GetHeader("X-Other-User") != "" {
+			c.Set("UserID", other.ID)
+		}
+		HandleRevertBenchmark(db)(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, createBenchmarkRequest(t, "Original", "a", "b"))
+	var benchmark Benchmark
+	if err := json.Unmarshal(w.Body.Bytes(), &benchmark); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+	}
+
+	request := func(method, path, body, ifMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, fmt.Sprintf("/api/benchmarks/%d%s", benchmark.ID, path), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	game := Game{Name: "Cyberpunk 2077", Key: gameKey("Cyberpunk 2077")}
+	db.DB.Create(&game)
+	w = request(http.MethodPut, "", fmt.Sprintf(`{"title": "Renamed", "description": "Methodology v2", "labels": {"1": "b2"}, "game_id": %d}`, game.ID), `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to update benchmark: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, addRunRequest(t, benchmark.ID, "c", `"2"`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to add run: %d %s", w.Code, w.Body.String())
+	}
+
+	t.Run("every change is recorded", func(t *testing.T) {
+		w := request(http.MethodGet, "/revisions", "", "")
+		var resp struct {
+			Revisions []BenchmarkRevision `json:"revisions"`
+			Revision  uint                `json:"revision"`
+			Total     int64               `json:"total"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to list revisions: %d %s", w.Code, w.Body.String())
+		}
+		if resp.Total != 3 || resp.Revision != 3 || len(resp.Revisions) != 3 {
+			t.Fatalf("expected 3 revisions, got %+v", resp)
+		}
+		actions := []string{resp.Revisions[0].Action, resp.Revisions[1].Action, resp.Revisions[2].Action}
+		if !reflect.DeepEqual(actions, []string{revisionActionRunsAdded, revisionActionUpdated, revisionActionCreated}) {
+			t.Errorf("unexpected actions: %v", actions)
+		}
+		first := resp.Revisions[2]
+		if first.Title != "Original" || first.UserID != owner.ID || len(first.Runs) != 2 || first.Runs[1].Label != "b" {
+			t.Errorf("unexpected first revision: %+v", first)
+		}
+
+		w = request(http.MethodGet, "/revisions/2", "", "")
+		var rev BenchmarkRevision
+		if err := json.Unmarshal(w.Body.Bytes(), &rev); err != nil || rev.Title != "Renamed" || rev.Runs[1].Label != "b2" ||
+			rev.GameID == nil || *rev.GameID != game.ID {
+			t.Errorf("unexpected revision 2: %d %s", w.Code, w.Body.String())
+		}
+		if w := request(http.MethodGet, "/revisions/9", "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a missing revision, got %d", w.Code)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		w := request(http.MethodGet, "/revisions/diff?from=1", "", "")
+		var diff BenchmarkRevisionDiff
+		if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to diff revisions: %d %s", w.Code, w.Body.String())
+		}
+		if diff.From != 1 || diff.To != 3 || diff.Title == nil || diff.Description == nil || diff.GameID == nil {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+		if len(diff.RunsAdded) != 1 || diff.RunsAdded[0].Label != "c" {
+			t.Errorf("expected run c added, got %+v", diff.RunsAdded)
+		}
+		if len(diff.RunsRelabeled) != 1 || diff.RunsRelabeled[0].From != "b" || diff.RunsRelabeled[0].To != "b2" {
+			t.Errorf("expected run b relabeled, got %+v", diff.RunsRelabeled)
+		}
+		if w := request(http.MethodGet, "/revisions/diff?to=1", "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 without an earlier revision, got %d", w.Code)
+		}
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/benchmarks/%d/revisions/1/revert", benchmark.ID), nil)
+		req.Header.Set("If-Match", `"3"`)
+		req.Header.Set("X-Other-User", "1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected other users refused, got %d", w.Code)
+		}
+		if w := request(http.MethodPost, "/revisions/1/revert", "", `"2"`); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected a stale revision refused, got %d", w.Code)
+		}
+
+		w = request(http.MethodPost, "/revisions/1/revert", "", `"3"`)
+		var reverted Benchmark
+		if err := json.Unmarshal(w.Body.Bytes(), &reverted); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to revert: %d %s", w.Code, w.Body.String())
+		}
+		if reverted.Title != "Original" || reverted.Description != benchmark.Description || reverted.Revision != 4 || reverted.GameID != nil {
+			t.Errorf("unexpected reverted benchmark: %+v", reverted)
+		}
+		_, labels, err := GetBenchmarkRunCount(benchmark.ID)
+		if err != nil || !reflect.DeepEqual(labels, []string{"a", "b", "c"}) {
+			t.Errorf("expected labels reverted with the added run kept, got %v %v", labels, err)
+		}
+
+		rev, err := findBenchmarkRevision(db, benchmark.ID, 4)
+		if err != nil || rev.Action != revisionActionReverted || rev.Title != "Original" {
+			t.Errorf("expected the revert recorded, got %+v %v", rev, err)
+		}
+
+		// Reverting to the current state changes nothing
+		w = request(http.MethodPost, "/revisions/4/revert", "", `"4"`)
+		if err := json.Unmarshal(w.Body.Bytes(), &reverted); err != nil || reverted.Revision != 4 {
+			t.Errorf("expected no new revision, got %d %s", w.Code, w.Body.String())
+		}
+
+		w = request(http.MethodPost, "/revisions/2/revert", "", `"4"`)
+		if err := json.Unmarshal(w.Body.Bytes(), &reverted); err != nil || reverted.Revision != 5 ||
+			reverted.Game == nil || reverted.Game.Name != "Cyberpunk 2077" {
+			t.Errorf("expected the game restored, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestMigrateBenchmarkRevisions(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	user := createTestUser(db, "historymigrate", false)
+	game := Game{Name: "Elden Ring", Key: gameKey("Elden Ring")}
+	db.DB.Create(&game)
+	benchmark := Benchmark{UserID: user.ID, Title: "Existing", Revision: 5, GameID: &game.ID}
+	db.DB.Create(&benchmark)
+	db.DB.Create(&BenchmarkRun{BenchmarkID: benchmark.ID, RunIndex: 0, Label: "a", ContentHash: "h1"})
+
+	if err := MigrateBenchmarkRevisions(db); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	rev, err := findBenchmarkRevision(db, benchmark.ID, 5)
+	if err != nil {
+		t.Fatalf("Expected revision 5 recorded: %v", err)
+	}
+	if rev.Action != revisionActionImported || rev.Username != "historymigrate" || len(rev.Runs) != 1 || rev.Runs[0].ContentHash != "h1" ||
+		rev.GameID == nil || *rev.GameID != game.ID {
+		t.Errorf("unexpected revision: %+v", rev)
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errEmptyRange is returned when a requested range contains no samples.
+var errEmptyRange = errors.New("range contains no samples")
+
+// StatsRange is a resolved sample range of a run.
+type StatsRange struct {
+	FromIndex int     `json:"from_index"`
+	ToIndex   int     `json:"to_index"` // Exclusive
+	FromMs    float64 `json:"from_ms"`
+	ToMs      float64 `json:"to_ms"`
+}
+
+// RangeStatsResponse holds the statistics of every metric within a section of a run.
+type RangeStatsResponse struct {
+	RunIndex int    `json:"run_index"`
+	Label    string `json:"label"`
+	StatsRange
+	Samples       int                     `json:"samples"`
+	Stats         map[string]*MetricStats `json:"stats"`
+	StatsMangoHud map[string]*MetricStats `json:"stats_mangohud"`
+}
+
+// parseRangeParams reads the from, to and unit query parameters shared by range-scoped endpoints.
+// A negative to means the end of the run.
+func parseRangeParams(c *gin.Context) (from, to float64, unit string, err error) {
+	to = -1
+	unit = c.DefaultQuery("unit", "index")
+	if unit != "index" && unit != "ms" {
+		return 0, 0, "", fmt.Errorf("unit must be index or ms")
+	}
+
+	if s := c.Query("from"); s != "" {
+		v, parseErr := strconv.ParseFloat(s, 64)
+		if parseErr != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0, 0, "", fmt.Errorf("invalid from")
+		}
+		from = v
+	}
+	if s := c.Query("to"); s != "" {
+		v, parseErr := strconv.ParseFloat(s, 64)
+		if parseErr != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) || v < from {
+			return 0, 0, "", fmt.Errorf("invalid to")
+		}
+		to = v
+	}
+	return from, to, unit, nil
+}
+
+// resolveRunRange converts a range in samples or elapsed milliseconds to sample indices.
+// Elapsed time is the cumulative frametime before each sample.
+func resolveRunRange(run *BenchmarkData, from, to float64, unit string) StatsRange {
+	n := getRunDataPointCount(run)
+	elapsed := make([]float64, n+1)
+	for i := 0; i < n; i++ {
+		elapsed[i+1] = elapsed[i] + sampleFrametime(run, i)
+	}
+
+	r := StatsRange{ToIndex: n}
+	if unit == "ms" {
+		r.FromIndex = sort.SearchFloat64s(elapsed, from)
+		if to >= 0 {
+			r.ToIndex = sort.SearchFloat64s(elapsed, to)
+		}
+	} else {
+		r.FromIndex = int(math.Ceil(from))
+		if to >= 0 {
+			r.ToIndex = int(math.Ceil(to))
+		}
+	}
+	r.FromIndex = min(max(r.FromIndex, 0), n)
+	r.ToIndex = min(max(r.ToIndex, r.FromIndex), n)
+	r.FromMs = math.Round(elapsed[r.FromIndex]*100) / 100
+	r.ToMs = math.Round(elapsed[r.ToIndex]*100) / 100
+	return r
+}
+
+// sliceRun returns a copy of the run header with every data column cut to samples [from, to).
+// Columns shorter than the range are cut to their own length.
+func sliceRun(run *BenchmarkData, from, to int) *BenchmarkData {
+	cut := func(data []float64) []float64 {
+		if from >= len(data) {
+			return nil
+		}
+		return data[from:min(to, len(data))]
+	}
+	sliced := *run
+	sliced.DataFPS = cut(run.DataFPS)
+	sliced.DataFrameTime = cut(run.DataFrameTime)
+	sliced.DataCPULoad = cut(run.DataCPULoad)
+	sliced.DataGPULoad = cut(run.DataGPULoad)
+	sliced.DataCPUTemp = cut(run.DataCPUTemp)
+	sliced.DataCPUPower = cut(run.DataCPUPower)
+	sliced.DataGPUTemp = cut(run.DataGPUTemp)
+	sliced.DataGPUCoreClock = cut(run.DataGPUCoreClock)
+	sliced.DataGPUMemClock = cut(run.DataGPUMemClock)
+	sliced.DataGPUVRAMUsed = cut(run.DataGPUVRAMUsed)
+	sliced.DataGPUPower = cut(run.DataGPUPower)
+	sliced.DataRAMUsed = cut(run.DataRAMUsed)
+	sliced.DataSwapUsed = cut(run.DataSwapUsed)
+	sliced.DataCPUClock = cut(run.DataCPUClock)
+	return &sliced
+}
+
+// ComputeRangeStats computes pre-calculated data for a section of a run from its raw data.
+// Series indices in the result are relative to the start of the range.
+func ComputeRangeStats(run *BenchmarkData, from, to float64, unit string) (*PreCalculatedRun, StatsRange, error) {
+	r := resolveRunRange(run, from, to, unit)
+	if r.FromIndex >= r.ToIndex {
+		return nil, r, errEmptyRange
+	}
+	return computePreCalculatedRun(sliceRun(run, r.FromIndex, r.ToIndex)), r, nil
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sectionedRun builds a 30 s run at 10 ms per frame that drops to 40 FPS (25 ms) between 10 s and 20 s.
+func sectionedRun() *BenchmarkData {
+	run := &BenchmarkData{Label: "Boss fight"}
+	for i := 0; i < 1000; i++ {
+		run.DataFrameTime = append(run.DataFrameTime, 10)
+	}
+	for i := 0; i < 400; i++ {
+		run.DataFrameTime = append(run.DataFrameTime, 25)
+	}
+	for i := 0; i < 1000; i++ {
+		run.DataFrameTime = append(run.DataFrameTime, 10)
+	}
+	run.DataGPULoad = repeatValue(95, len(run.DataFrameTime))
+	return run
+}
+
+func TestResolveRunRange(t *testing.T) {
+	run := sectionedRun()
+
+	tests := []struct {
+		name     string
+		from, to float64
+		unit     string
+		want     StatsRange
+	}{
+		{"whole run", 0, -1, "index", StatsRange{0, 2400, 0, 30000}},
+		{"index range", 1000, 1400, "index", StatsRange{1000, 1400, 10000, 20000}},
+		{"time range", 10000, 20000, "ms", StatsRange{1000, 1400, 10000, 20000}},
+		{"time range between samples", 10010, 19990, "ms", StatsRange{1001, 1400, 10025, 20000}},
+		{"clamped", 2000, 99999, "index", StatsRange{2000, 2400, 26000, 30000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRunRange(run, tt.from, tt.to, tt.unit); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeRangeStats(t *testing.T) {
+	result, r, err := ComputeRangeStats(sectionedRun(), 10000, 20000, "ms")
+	if err != nil {
+		t.Fatalf("ComputeRangeStats failed: %v", err)
+	}
+	if r.FromIndex != 1000 || r.ToIndex != 1400 {
+		t.Errorf("unexpected range: %+v", r)
+	}
+	if result.Stats["FPS"].Avg != 40 || result.StatsMangoHud["FPS"].Avg != 40 {
+		t.Errorf("expected 40 FPS in the section, got %v / %v", result.Stats["FPS"].Avg, result.StatsMangoHud["FPS"].Avg)
+	}
+	if result.TotalDataPoints != 400 || result.Stats["GPULoad"] == nil {
+		t.Errorf("expected all metrics over 400 samples, got %d", result.TotalDataPoints)
+	}
+
+	if _, _, err := ComputeRangeStats(sectionedRun(), 5000, 5000, "index"); err != errEmptyRange {
+		t.Errorf("expected empty range error, got %v", err)
+	}
+}
+
+func TestHandleGetBenchmarkRunStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "rangeuser", false)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Range"}
+	db.DB.Create(benchmark)
+	if err := StoreBenchmarkData([]*BenchmarkData{sectionedRun()}, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks/:id/runs/:runIndex/stats", HandleGetBenchmarkRunStats(db))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return w
+	}
+
+	w := get(fmt.Sprintf("/api/benchmarks/%d/runs/0/stats?from=10000&to=20000&unit=ms", benchmark.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp RangeStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if resp.Label != "Boss fight" || resp.Samples != 400 || resp.FromIndex != 1000 {
+		t.Errorf("unexpected response: %+v", resp.StatsRange)
+	}
+	if resp.Stats["FPS"].Avg != 40 || resp.StatsMangoHud["FrameTime"].Avg != 25 {
+		t.Errorf("unexpected section stats: FPS %v", resp.Stats["FPS"].Avg)
+	}
+
+	for path, status := range map[string]int{
+		fmt.Sprintf("/api/benchmarks/%d/runs/0/stats?from=20&to=10", benchmark.ID):     http.StatusBadRequest,
+		fmt.Sprintf("/api/benchmarks/%d/runs/0/stats?unit=frames", benchmark.ID):       http.StatusBadRequest,
+		fmt.Sprintf("/api/benchmarks/%d/runs/0/stats?from=5000&to=6000", benchmark.ID): http.StatusBadRequest,
+		fmt.Sprintf("/api/benchmarks/%d/runs/3/stats", benchmark.ID):                   http.StatusNotFound,
+		"/api/benchmarks/99999/runs/0/stats":                                           http.StatusNotFound,
+	} {
+		if w := get(path); w.Code != status {
+			t.Errorf("%s: expected %d, got %d", path, status, w.Code)
+		}
+	}
+}
+
+func TestMCPGetBenchmarkRunRange(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	router := setupMCPTestRouter(db)
+
+	user := createTestUser(db, "mcprange", false)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Range"}
+	db.DB.Create(benchmark)
+	if err := StoreBenchmarkData([]*BenchmarkData{sectionedRun()}, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_benchmark_run","arguments":{"id":%d,"run_index":0,"from":10,"to":20000,"unit":"ms"}}}`, benchmark.ID)
+	_, result := parseMCPToolResult(t, mcpRequest(t, router, body, ""))
+	if result.IsError {
+		t.Fatalf("Unexpected error: %s", result.Content[0].Text)
+	}
+	var summary BenchmarkDataSummary
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &summary); err != nil {
+		t.Fatalf("Failed to unmarshal summary: %v", err)
+	}
+	if summary.Range == nil || summary.Range.FromIndex != 1 || summary.Range.ToIndex != 1400 {
+		t.Fatalf("unexpected range: %+v", summary.Range)
+	}
+	if summary.TotalDataPoints != 1399 || summary.Metrics["fps"] == nil {
+		t.Errorf("expected section stats, got %d points", summary.TotalDataPoints)
+	}
+
+	body = fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_benchmark_run","arguments":{"id":%d,"run_index":0,"from":5,"unit":"seconds"}}}`, benchmark.ID)
+	if _, result := parseMCPToolResult(t, mcpRequest(t, router, body, "")); !result.IsError {
+		t.Error("expected error for invalid unit")
+	}
+}
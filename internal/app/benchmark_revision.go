@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Benchmark mutations read all runs, modify them and rewrite the files. benchmarkWriteLocks
+// serializes them per benchmark so concurrent requests can't silently drop each other's changes.
+// It is separate from the file lock, which is taken for each generation write inside the mutation.
+var benchmarkWriteLocks sync.Map // uint -> *sync.Mutex
+
+// lockBenchmarkWrites serializes read-modify-write mutations of a benchmark. It also holds off
+// backup snapshots until the mutation is done. Returns the unlock function.
+func lockBenchmarkWrites(benchmarkID uint) func() {
+	release := beginStorageMutation()
+	mu, _ := benchmarkWriteLocks.LoadOrStore(benchmarkID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return func() {
+		mu.(*sync.Mutex).Unlock()
+		release()
+	}
+}
+
+// benchmarkETag formats a benchmark revision as a strong entity tag.
+func benchmarkETag(revision uint) string {
+	return `"` + strconv.FormatUint(uint64(revision), 10) + `"`
+}
+
+// ifMatchSatisfied reports whether an If-Match header value matches the given ETag.
+func ifMatchSatisfied(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBenchmarkRevision enforces optimistic concurrency for a benchmark mutation. The request
+// must carry an If-Match header with the benchmark's current ETag; otherwise it responds with
+// 428 (header missing) or 412 (stale revision) and returns false.
+func checkBenchmarkRevision(c *gin.Context, benchmark *Benchmark) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the benchmark revision is required"})
+		return false
+	}
+	etag := benchmarkETag(benchmark.Revision)
+	if !ifMatchSatisfied(header, etag) {
+		c.Header("ETag", etag)
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error":    "benchmark was modified by another request",
+			"revision": benchmark.Revision,
+		})
+		return false
+	}
+	return true
+}
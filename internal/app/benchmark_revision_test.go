@@ -0,0 +1,190 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatchSatisfied(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"3"`, true},
+		{`"2", "3"`, true},
+		{`*`, true},
+		{`"2"`, false},
+		{`W/"3"`, false}, // If-Match uses strong comparison
+		{`3`, false},
+	}
+	for _, tt := range tests {
+		if got := ifMatchSatisfied(tt.header, benchmarkETag(3)); got != tt.want {
+			t.Errorf("ifMatchSatisfied(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// revisionTestRouter serves the benchmark endpoints as the given user.
+func revisionTestRouter(db *DBInstance, user *User) *gin.Engine {
+	router := setupTestRouter()
+	asUser := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("UserID", user.ID)
+			c.Set("IsAdmin", user.IsAdmin)
+			handler(c)
+		}
+	}
+	router.GET("/api/benchmarks/:id", HandleGetBenchmark(db))
+	router.PUT("/api/benchmarks/:id", asUser(HandleUpdateBenchmark(db)))
+	router.POST("/api/benchmarks/:id/runs", asUser(HandleAddBenchmarkRuns(db)))
+	router.DELETE("/api/benchmarks/:id/runs/:run_index", asUser(HandleDeleteBenchmarkRun(db)))
+	return router
+}
+
+// addRunRequest builds a request uploading one CSV run with the given label as file name.
+func addRunRequest(t *testing.T, benchmarkID uint, label, ifMatch string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("files", label+".csv")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	if _, err := part.Write([]byte("os,cpu,gpu,ram,kernel,driver,cpuscheduler\nLinux,Intel,NVIDIA,16GB,6.1,nvidia,eevdf\nfps,frametime\n60,16.6\n61,16.4\n")); err != nil {
+		t.Fatalf("Failed to write content: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/benchmarks/%d/runs", benchmarkID), body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return req
+}
+
+func TestBenchmarkRevisionPreconditions(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	// Admins skip the upload rate limit
+	user := createTestUser(db, "revisionuser", true)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Revisions", Revision: 1}
+	db.DB.Create(benchmark)
+	if err := StoreBenchmarkData(columnarRuns(), benchmark.ID); err != nil {
+		t.Fatalf("Failed to store test data: %v", err)
+	}
+	router := revisionTestRouter(db, user)
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/benchmarks/%d", benchmark.ID), strings.NewReader(`{"labels":{"0":"Renamed"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("get returns the revision as ETag", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/benchmarks/%d", benchmark.ID), nil))
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+			t.Fatalf("expected 200 with ETag \"1\", got %d %q", w.Code, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("missing If-Match is rejected", func(t *testing.T) {
+		if w := update(""); w.Code != http.StatusPreconditionRequired {
+			t.Errorf("expected 428, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("matching If-Match updates and bumps the revision", func(t *testing.T) {
+		w := update(`"1"`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != `"2"` {
+			t.Errorf("expected ETag \"2\", got %q", w.Header().Get("ETag"))
+		}
+		var got Benchmark
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Revision != 2 {
+			t.Errorf("expected revision 2 in response, got %d (%v)", got.Revision, err)
+		}
+	})
+
+	t.Run("stale If-Match is rejected without changes", func(t *testing.T) {
+		w := update(`"1"`)
+		if w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("expected 412, got %d: %s", w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != `"2"` {
+			t.Errorf("expected current ETag \"2\", got %q", w.Header().Get("ETag"))
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, addRunRequest(t, benchmark.ID, "late", `"1"`))
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected 412 for add runs, got %d", w.Code)
+		}
+		if count, _, _ := GetBenchmarkRunCount(benchmark.ID); count != 2 {
+			t.Errorf("expected 2 runs after rejected requests, got %d", count)
+		}
+	})
+}
+
+func TestConcurrentBenchmarkMutations(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	// Admins skip the upload rate limit
+	user := createTestUser(db, "concurrentuser", true)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Concurrent", Revision: 1}
+	db.DB.Create(benchmark)
+	if err := StoreBenchmarkData(columnarRuns(), benchmark.ID); err != nil {
+		t.Fatalf("Failed to store test data: %v", err)
+	}
+	router := revisionTestRouter(db, user)
+
+	const adds = 6
+	var wg sync.WaitGroup
+	for i := range adds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, addRunRequest(t, benchmark.ID, fmt.Sprintf("run-%d", i), "*"))
+			if w.Code != http.StatusOK {
+				t.Errorf("add %d: expected 200, got %d: %s", i, w.Code, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, labels, err := GetBenchmarkRunCount(benchmark.ID)
+	if err != nil || count != 2+adds {
+		t.Fatalf("expected %d runs, got %d (%v): %v", 2+adds, count, err, labels)
+	}
+	var stored Benchmark
+	if err := db.DB.First(&stored, benchmark.ID).Error; err != nil || stored.Revision != 1+adds {
+		t.Errorf("expected revision %d, got %d (%v)", 1+adds, stored.Revision, err)
+	}
+}
@@ -21,6 +21,7 @@ func TestCSVRoundTrip(t *testing.T) {
 			SpecRAM:            "32 GB",
 			SpecLinuxKernel:    "6.1.0",
 			SpecLinuxScheduler: "cfs",
+			SpecDriver:         "NVIDIA 580.82.09",
 			DataFPS:            []float64{120.5, 119.8, 121.2, 120.0},
 			DataFrameTime:      []float64{8.3, 8.35, 8.28, 8.33},
 			DataCPULoad:        []float64{55.2, 56.1, 54.8, 55.5},
@@ -141,6 +142,9 @@ func TestCSVRoundTrip(t *testing.T) {
 	if reimported.SpecGPU != original.SpecGPU {
 		t.Errorf("GPU mismatch: got %q, want %q", reimported.SpecGPU, original.SpecGPU)
 	}
+	if reimported.SpecDriver != original.SpecDriver {
+		t.Errorf("Driver mismatch: got %q, want %q", reimported.SpecDriver, original.SpecDriver)
+	}
 
 	// Verify data arrays (check lengths and some sample values)
 	if len(reimported.DataFPS) != len(original.DataFPS) {
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// The headline stats of every run (see RunStats) are recorded in its benchmark_runs row when the
+// runs are stored and whenever the stats are recomputed, so that the benchmark list can be sorted
+// and filtered by performance without reading any .stats file. Range filters (min_avg_fps=60) join
+// the spec filters: a benchmark matches when one of its runs matches all of them. Sorting by a stat
+// uses the best matching run of each benchmark: the highest value when sorting descending and the
+// lowest when sorting ascending. Benchmarks without a value sort last either way.
+
+// runStatColumns are the stats of runs, named like their benchmark_runs columns.
+var runStatColumns = []string{
+	"avg_fps",
+	"p01_fps",
+	"p05_fps",
+	"avg_frame_time",
+	"avg_cpu_load",
+	"avg_gpu_load",
+	"avg_cpu_power",
+	"avg_gpu_power",
+	"avg_fps_per_watt",
+}
+
+// runStatColumn returns the benchmark_runs column of a stat, or "" if there is none.
+func runStatColumn(name string) string {
+	for _, column := range runStatColumns {
+		if column == name {
+			return column
+		}
+	}
+	return ""
+}
+
+// runStatMetrics are the metrics of the pre-calculated stats the headline stats are taken from.
+var runStatMetrics = []string{"FPS", "FrameTime", "CPULoad", "GPULoad", "CPUPower", "GPUPower", "FPSPerWatt"}
+
+// computeRunStats takes the headline stats of a run from its pre-calculated linear stats, so they
+// match the .stats files (FPS derived from frametime when available, rounded to 2 decimals).
+func computeRunStats(run *PreCalculatedRun) RunStats {
+	var stats RunStats
+	if run == nil {
+		return stats
+	}
+	if fps := run.Stats["FPS"]; fps != nil {
+		avg, p01, p05 := fps.Avg, fps.P01, fps.P05
+		stats.AvgFPS, stats.P01FPS, stats.P05FPS = &avg, &p01, &p05
+	}
+	stats.AvgFrameTime = metricAvg(run.Stats["FrameTime"])
+	stats.AvgCPULoad = metricAvg(run.Stats["CPULoad"])
+	stats.AvgGPULoad = metricAvg(run.Stats["GPULoad"])
+	// Power columns of devices without the sensor are all zero
+	if power := run.Stats["CPUPower"]; power != nil && power.Max > 0 {
+		stats.AvgCPUPower = metricAvg(power)
+	}
+	if power := run.Stats["GPUPower"]; power != nil && power.Max > 0 {
+		stats.AvgGPUPower = metricAvg(power)
+	}
+	stats.AvgFPSPerWatt = metricAvg(run.Stats["FPSPerWatt"])
+	return stats
+}
+
+// metricAvg returns the average of a metric's stats, or nil if the metric has none.
+func metricAvg(m *MetricStats) *float64 {
+	if m == nil {
+		return nil
+	}
+	avg := m.Avg
+	return &avg
+}
+
+// statFilter restricts the benchmark list to benchmarks with a run whose stat is within a bound.
+type statFilter struct {
+	column string
+	op     string // ">=" or "<="
+	value  float64
+}
+
+// condition returns the benchmark_runs condition of the filter and its value.
+func (f statFilter) condition() (string, interface{}) {
+	// Columns come from runStatColumns and operators are fixed
+	return f.column + " " + f.op + " ?", f.value
+}
+
+// parseStatFilters reads the stat range filters from their parameters (e.g. min_avg_fps,
+// max_avg_gpu_power) with get.
+func parseStatFilters(get func(name string) string) ([]statFilter, error) {
+	bounds := map[string]map[string]float64{"min": {}, "max": {}}
+	for _, column := range runStatColumns {
+		for bound, values := range bounds {
+			param := get(bound + "_" + column)
+			if param == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(param), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_%s: must be a number", bound, column)
+			}
+			values[column] = value
+		}
+	}
+	return statRangeFilters(bounds["min"], bounds["max"])
+}
+
+// statRangeFilters validates lower and upper bounds of stats, keyed by stat name.
+func statRangeFilters(minValues, maxValues map[string]float64) ([]statFilter, error) {
+	var filters []statFilter
+	for _, bound := range []struct {
+		name   string
+		op     string
+		values map[string]float64
+	}{{"min", ">=", minValues}, {"max", "<=", maxValues}} {
+		names := make([]string, 0, len(bound.values))
+		for name := range bound.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := bound.values[name]
+			if runStatColumn(name) == "" {
+				return nil, fmt.Errorf("invalid %s stat %q (valid: %s)", bound.name, name, strings.Join(runStatColumns, ", "))
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, fmt.Errorf("invalid %s_%s: must be a finite number", bound.name, name)
+			}
+			filters = append(filters, statFilter{column: name, op: bound.op, value: value})
+		}
+	}
+	return filters, nil
+}
+
+// orderByRunStat sorts a benchmarks query by a stat of the benchmark's runs matching the filter.
+func orderByRunStat(query *gorm.DB, stat string, asc bool, filter *benchmarkListFilter) *gorm.DB {
+	column := runStatColumn(stat)
+	aggregate, direction := "MAX", "DESC"
+	if asc {
+		aggregate, direction = "MIN", "ASC"
+	}
+	conditions, values := runFilterConditions(filter.specs, filter.stats)
+	conditions = append([]string{column + " IS NOT NULL"}, conditions...)
+	return query.
+		Joins("LEFT JOIN (SELECT benchmark_id, "+aggregate+"("+column+") AS value FROM benchmark_runs WHERE "+
+			strings.Join(conditions, " AND ")+" GROUP BY benchmark_id) run_stat ON run_stat.benchmark_id = benchmarks.id", values...).
+		Order("run_stat.value IS NULL").
+		Order("run_stat.value " + direction).
+		Order("benchmarks.created_at DESC")
+}
+
+// loadRunStats sets the RunStats of benchmarks from their benchmark_runs rows.
+func loadRunStats(db *gorm.DB, benchmarks []Benchmark) error {
+	if len(benchmarks) == 0 {
+		return nil
+	}
+	positions := make(map[uint]int, len(benchmarks))
+	ids := make([]uint, len(benchmarks))
+	for i := range benchmarks {
+		positions[benchmarks[i].ID] = i
+		ids[i] = benchmarks[i].ID
+	}
+	var runs []BenchmarkRun
+	if err := db.Where("benchmark_id IN ?", ids).Order("benchmark_id, run_index").Find(&runs).Error; err != nil {
+		return fmt.Errorf("failed to load run stats: %w", err)
+	}
+	for _, run := range runs {
+		b := &benchmarks[positions[run.BenchmarkID]]
+		b.RunStats = append(b.RunStats, BenchmarkRunStats{RunIndex: run.RunIndex, Label: run.Label, RunStats: run.RunStats})
+	}
+	return nil
+}
+
+// updateBenchmarkRunStats records the headline stats of a benchmark's runs in its benchmark_runs rows.
+func updateBenchmarkRunStats(db *DBInstance, benchmarkID uint, runs []*PreCalculatedRun) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for i, run := range runs {
+			record := BenchmarkRun{RunStats: computeRunStats(run)}
+			if err := tx.Model(&BenchmarkRun{}).Where("benchmark_id = ? AND run_index = ?", benchmarkID, i).
+				Select(runStatColumns).Updates(&record).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record run stats of benchmark %d: %w", benchmarkID, err)
+	}
+	return nil
+}
+
+// MigrateBenchmarkRunStats records the headline stats of every run in benchmark_runs.
+func MigrateBenchmarkRunStats(db *DBInstance) error {
+	var ids []uint
+	if err := db.DB.Model(&BenchmarkRun{}).Distinct("benchmark_id").Order("benchmark_id").Pluck("benchmark_id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for _, id := range ids {
+		runs, _, err := RetrievePreCalculatedStatsSelection(id, StatsSelection{
+			Metrics: runStatMetrics,
+			Methods: []string{statsMethodLinear},
+			Stats:   true,
+		})
+		if err != nil {
+			log.Printf("Benchmark %d: skipping run stats, failed to read stats: %v", id, err)
+			continue
+		}
+		if err := updateBenchmarkRunStats(db, id, runs); err != nil {
+			return err
+		}
+	}
+	log.Printf("Recorded run stats of %d benchmark(s)", len(ids))
+	return nil
+}
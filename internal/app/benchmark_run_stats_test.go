@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func statsRun(label, gpu string, frametime, gpuPower float64) *BenchmarkData {
+	run := &BenchmarkData{Label: label, SpecGPU: gpu}
+	for i := 0; i < 100; i++ {
+		ft := frametime
+		if i == 0 {
+			ft = frametime * 2 // One stutter for the lows
+		}
+		run.DataFrameTime = append(run.DataFrameTime, ft)
+		run.DataGPUPower = append(run.DataGPUPower, gpuPower)
+	}
+	return run
+}
+
+func TestComputeRunStats(t *testing.T) {
+	stats := computeRunStats(computePreCalculatedRun(statsRun("run", "", 10, 0)))
+	if stats.AvgFPS == nil || *stats.AvgFPS != 99.01 || *stats.AvgFrameTime != 10.1 {
+		t.Errorf("unexpected FPS stats: %+v", stats)
+	}
+	if stats.P01FPS == nil || *stats.P01FPS >= *stats.P05FPS {
+		t.Errorf("expected the 1%% low below the 5%% low, got %v and %v", stats.P01FPS, stats.P05FPS)
+	}
+	if stats.AvgGPUPower != nil || stats.AvgFPSPerWatt != nil || stats.AvgCPULoad != nil {
+		t.Errorf("expected no stats without sensor data, got %+v", stats)
+	}
+
+	stats = computeRunStats(computePreCalculatedRun(statsRun("run", "", 10, 200)))
+	if stats.AvgGPUPower == nil || *stats.AvgGPUPower != 200 || stats.AvgFPSPerWatt == nil || *stats.AvgFPSPerWatt != 0.5 {
+		t.Errorf("unexpected power stats: %+v", stats)
+	}
+}
+
+func TestSyncBenchmarkRunsKeepsRunStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "syncuser", false)
+	runs := []*BenchmarkData{statsRun("a", "", 10, 0), statsRun("b", "", 5, 0)}
+	benchmark := storeComparisonBenchmark(t, db, user.ID, "Sync", runs)
+
+	// Without pre-calculated stats, runs of unchanged content keep their recorded stats
+	if err := syncBenchmarkRuns(db, benchmark.ID, []*BenchmarkData{runs[1], statsRun("c", "", 20, 0)}, nil); err != nil {
+		t.Fatalf("Failed to sync runs: %v", err)
+	}
+	var records []BenchmarkRun
+	db.DB.Where("benchmark_id = ?", benchmark.ID).Order("run_index").Find(&records)
+	if len(records) != 2 || records[0].AvgFPS == nil || *records[0].AvgFPS != 198.02 || records[1].AvgFPS != nil {
+		t.Errorf("unexpected run stats: %+v", records)
+	}
+}
+
+func TestBenchmarkRunStatsList(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "statsuser", false)
+	storeComparisonBenchmark(t, db, user.ID, "Mixed", []*BenchmarkData{
+		statsRun("radeon", "AMD Radeon RX 9070 XT", 10, 250),   // 99 FPS
+		statsRun("geforce", "NVIDIA GeForce RTX 5080", 5, 300), // 198 FPS
+	})
+	storeComparisonBenchmark(t, db, user.ID, "Radeon", []*BenchmarkData{
+		statsRun("radeon", "AMD Radeon RX 9070 XT", 8, 200), // 124 FPS
+	})
+	storeComparisonBenchmark(t, db, user.ID, "Steam Deck", []*BenchmarkData{
+		statsRun("deck", "AMD Custom GPU 0405", 20, 0), // 49.5 FPS, no power sensor
+	})
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks", HandleListBenchmarks(db))
+
+	list := func(query string) (titles []string, benchmarks []Benchmark) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/benchmarks?"+query, nil))
+		var response struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: failed to list benchmarks: %d %s", query, w.Code, w.Body.String())
+		}
+		for _, b := range response.Benchmarks {
+			titles = append(titles, b.Title)
+		}
+		return titles, response.Benchmarks
+	}
+
+	t.Run("sorting", func(t *testing.T) {
+		tests := map[string][]string{
+			"sort_by=avg_fps":                             {"Mixed", "Radeon", "Steam Deck"},
+			"sort_by=avg_fps&sort_order=asc":              {"Steam Deck", "Mixed", "Radeon"},
+			"sort_by=avg_fps&gpu=9070":                    {"Radeon", "Mixed"}, // By the matching run only
+			"sort_by=avg_gpu_power&sort_order=asc":        {"Radeon", "Mixed", "Steam Deck"},
+			"sort_by=avg_fps_per_watt&max_avg_fps=150":    {"Radeon", "Mixed", "Steam Deck"},
+			"sort_by=p01_fps&min_avg_gpu_power=250&gpu=9": {"Mixed"},
+		}
+		for query, want := range tests {
+			if got, _ := list(query); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %v, got %v", query, want, got)
+			}
+		}
+	})
+
+	t.Run("range filters", func(t *testing.T) {
+		tests := map[string][]string{
+			"min_avg_fps=100":                       {"Mixed", "Radeon"},
+			"min_avg_fps=100&max_avg_fps=150":       {"Radeon"},
+			"gpu=geforce&max_avg_fps=150":           nil, // Both must match the same run
+			"max_avg_gpu_power=1000":                {"Mixed", "Radeon"},
+			"min_avg_frame_time=15&sort_by=avg_fps": {"Steam Deck"},
+		}
+		for query, want := range tests {
+			if got, _ := list("sort_by=title&sort_order=asc&" + query); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %v, got %v", query, want, got)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/benchmarks?min_avg_fps=fast", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an invalid bound, got %d", w.Code)
+		}
+	})
+
+	t.Run("headline stats", func(t *testing.T) {
+		_, benchmarks := list("sort_by=title&sort_order=asc")
+		runs := benchmarks[0].RunStats
+		if len(runs) != 2 || runs[1].Label != "geforce" || runs[1].AvgFPS == nil || *runs[1].AvgFPS != 198.02 {
+			t.Fatalf("unexpected run stats: %+v", runs)
+		}
+		if deck := benchmarks[2].RunStats; len(deck) != 1 || deck[0].AvgGPUPower != nil {
+			t.Errorf("expected no GPU power without a sensor, got %+v", deck)
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		server := newMCPServer(db, "test", "")
+		out, err := server.toolListBenchmarks(json.RawMessage(`{"min": {"avg_fps": 100}, "max": {"avg_gpu_power": 220}, "sort_by": "avg_fps"}`))
+		if err != nil {
+			t.Fatalf("list_benchmarks failed: %v", err)
+		}
+		var response struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		if err := json.Unmarshal([]byte(out), &response); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		if len(response.Benchmarks) != 1 || response.Benchmarks[0].Title != "Radeon" || len(response.Benchmarks[0].RunStats) != 1 {
+			t.Errorf("unexpected result: %s", out)
+		}
+		if _, err := server.toolListBenchmarks(json.RawMessage(`{"min": {"speed": 1}}`)); err == nil {
+			t.Error("expected an unknown stat rejected")
+		}
+	})
+
+	t.Run("recompute and migration", func(t *testing.T) {
+		var radeon Benchmark
+		db.DB.Where("title = ?", "Radeon").First(&radeon)
+		clearStats := func() {
+			db.DB.Model(&BenchmarkRun{}).Where("1 = 1").Updates(map[string]interface{}{"avg_fps": nil, "p01_fps": nil})
+		}
+		avgFPS := func() *float64 {
+			var run BenchmarkRun
+			db.DB.Where("benchmark_id = ?", radeon.ID).First(&run)
+			return run.AvgFPS
+		}
+
+		clearStats()
+		if err := RecomputeBenchmarkStats(db, radeon.ID); err != nil {
+			t.Fatalf("Recompute failed: %v", err)
+		}
+		if got := avgFPS(); got == nil || *got != 123.76 {
+			t.Errorf("expected the stats recorded on recompute, got %v", got)
+		}
+
+		clearStats()
+		if err := MigrateBenchmarkRunStats(db); err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if got := avgFPS(); got == nil || *got != 123.76 {
+			t.Errorf("expected the stats recorded by the migration, got %v", got)
+		}
+	})
+}
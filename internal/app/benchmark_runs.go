@@ -0,0 +1,272 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Every run gets a content hash of its parsed data when it is stored: its specs and data columns,
+// but not its label or group, which users edit freely. MangoHud logs aren't kept after parsing,
+// so the parsed data is all there is to hash. The hashes are recorded in the benchmark_runs table,
+// one row per run, so an upload of a run that already exists can be flagged.
+//
+// With run deduplication turned on (-dedup-runs), the data columns of a run are stored once in a
+// shared run-<hash>.bin blob, and .bin files reference it instead of holding the columns. The
+// benchmark_runs rows are the references: a shared blob is removed once no row has its hash.
+// Rows are added before the files referencing a blob are written and removed after the files
+// stop referencing it, so a blob is never removed while a .bin still needs it.
+
+// runDedupEnabled stores the data columns of identical runs once (set from -dedup-runs).
+var runDedupEnabled bool
+
+// sharedRunsMu serializes creating shared run blobs with removing unreferenced ones.
+var sharedRunsMu sync.Mutex
+
+var sharedRunFilePattern = regexp.MustCompile(`^run-([0-9a-f]{64})\.bin$`)
+
+func sharedRunName(hash string) string {
+	return "run-" + hash + ".bin"
+}
+
+// DuplicateRun is an uploaded run whose data already exists in a benchmark.
+type DuplicateRun struct {
+	RunIndex               int    `json:"run_index"` // Index of the uploaded run in its benchmark
+	Label                  string `json:"label"`
+	ContentHash            string `json:"content_hash"`
+	ExistingBenchmarkID    uint   `json:"existing_benchmark_id"`
+	ExistingBenchmarkTitle string `json:"existing_benchmark_title"`
+	ExistingRunIndex       int    `json:"existing_run_index"`
+	ExistingRunLabel       string `json:"existing_run_label"`
+	URL                    string `json:"url"` // Link to the benchmark holding the existing run
+}
+
+// runContentHash returns the hex SHA-256 of a run's specs and data columns.
+func runContentHash(run *BenchmarkData) string {
+	h := sha256.New()
+	writeString := func(s string) {
+		var n [binary.MaxVarintLen64]byte
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+		io.WriteString(h, s)
+	}
+	// The driver is left out: it was not parsed from uploads at first, and leaving it out keeps the
+	// hashes of runs recorded without it comparable with new uploads
+	for _, spec := range []string{run.SpecOS, run.SpecCPU, run.SpecGPU, run.SpecRAM, run.SpecLinuxKernel, run.SpecLinuxScheduler} {
+		writeString(spec)
+	}
+	buf := make([]byte, 0, 8*1024)
+	for _, col := range dataColumns(run) {
+		writeString(col.key)
+		buf = binary.LittleEndian.AppendUint64(buf[:0], uint64(len(*col.data)))
+		for _, v := range *col.data {
+			if len(buf) == cap(buf) {
+				h.Write(buf)
+				buf = buf[:0]
+			}
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// runContentHashes hashes every run.
+func runContentHashes(runs []*BenchmarkData) []string {
+	hashes := make([]string, len(runs))
+	for i, run := range runs {
+		hashes[i] = runContentHash(run)
+	}
+	return hashes
+}
+
+// findDuplicateRuns reports the runs of an upload that already exist, either in a benchmark or
+// earlier in the same upload. firstIndex is the index the first uploaded run gets in benchmarkID.
+// Must be called before the upload's runs are recorded.
+func findDuplicateRuns(db *DBInstance, benchmarkID uint, runs []*BenchmarkData, firstIndex int) ([]DuplicateRun, error) {
+	hashes := runContentHashes(runs)
+	var existing []BenchmarkRun
+	if err := db.DB.Where("content_hash IN ?", hashes).Order("benchmark_id, run_index").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up run hashes: %w", err)
+	}
+	ids := []uint{benchmarkID}
+	for _, run := range existing {
+		ids = append(ids, run.BenchmarkID)
+	}
+	var benchmarks []Benchmark
+	if err := db.DB.Select("id", "title").Where("id IN ?", ids).Find(&benchmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up benchmarks: %w", err)
+	}
+	titles := make(map[uint]string, len(benchmarks))
+	for _, b := range benchmarks {
+		titles[b.ID] = b.Title
+	}
+
+	duplicates := []DuplicateRun{}
+	for i, hash := range hashes {
+		dup := DuplicateRun{RunIndex: firstIndex + i, Label: runs[i].Label, ContentHash: hash}
+		idx := slices.IndexFunc(existing, func(run BenchmarkRun) bool {
+			_, ok := titles[run.BenchmarkID] // Rows of deleted benchmarks don't count
+			return ok && run.ContentHash == hash
+		})
+		switch earlier := slices.Index(hashes[:i], hash); {
+		case idx >= 0:
+			dup.ExistingBenchmarkID = existing[idx].BenchmarkID
+			dup.ExistingRunIndex = existing[idx].RunIndex
+			dup.ExistingRunLabel = existing[idx].Label
+		case earlier >= 0:
+			dup.ExistingBenchmarkID = benchmarkID
+			dup.ExistingRunIndex = firstIndex + earlier
+			dup.ExistingRunLabel = runs[earlier].Label
+		default:
+			continue
+		}
+		dup.ExistingBenchmarkTitle = titles[dup.ExistingBenchmarkID]
+		dup.URL = fmt.Sprintf("/benchmarks/%d", dup.ExistingBenchmarkID)
+		duplicates = append(duplicates, dup)
+	}
+	return duplicates, nil
+}
+
+// syncBenchmarkRuns replaces the run records of a benchmark with the given runs and removes
+// shared run blobs no longer referenced by any record. The headline stats of the records come
+// from the runs' pre-calculated stats; without them (nil), they are kept from the current records
+// of the same content, since they only depend on the run's data.
+func syncBenchmarkRuns(db *DBInstance, benchmarkID uint, runs []*BenchmarkData, stats []*PreCalculatedRun) error {
+	records := make([]BenchmarkRun, len(runs))
+	for i, run := range runs {
+		records[i] = BenchmarkRun{BenchmarkID: benchmarkID, RunIndex: i, Label: run.Label, ContentHash: runContentHash(run)}
+		setRunSpecs(&records[i], run)
+		if i < len(stats) {
+			records[i].RunStats = computeRunStats(stats[i])
+		}
+	}
+
+	var previous []string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var current []BenchmarkRun
+		if err := tx.Where("benchmark_id = ?", benchmarkID).Find(&current).Error; err != nil {
+			return err
+		}
+		for _, record := range current {
+			previous = append(previous, record.ContentHash)
+			if stats != nil {
+				continue
+			}
+			for i := range records {
+				if records[i].ContentHash == record.ContentHash {
+					records[i].RunStats = record.RunStats
+				}
+			}
+		}
+		if err := tx.Where("benchmark_id = ?", benchmarkID).Delete(&BenchmarkRun{}).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record runs of benchmark %d: %w", benchmarkID, err)
+	}
+
+	var dropped []string
+	for _, hash := range previous {
+		if !slices.ContainsFunc(records, func(r BenchmarkRun) bool { return r.ContentHash == hash }) && !slices.Contains(dropped, hash) {
+			dropped = append(dropped, hash)
+		}
+	}
+	return pruneSharedRuns(db, dropped)
+}
+
+// releaseBenchmarkRuns drops the run records of a deleted benchmark. Errors are logged.
+func releaseBenchmarkRuns(db *DBInstance, benchmarkID uint) {
+	if err := syncBenchmarkRuns(db, benchmarkID, nil, nil); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// pruneSharedRuns removes the shared run blobs of hashes no run record references anymore.
+func pruneSharedRuns(db *DBInstance, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	sharedRunsMu.Lock()
+	defer sharedRunsMu.Unlock()
+
+	var referenced []string
+	if err := db.DB.Model(&BenchmarkRun{}).Distinct("content_hash").Where("content_hash IN ?", hashes).Pluck("content_hash", &referenced).Error; err != nil {
+		return fmt.Errorf("failed to count run references: %w", err)
+	}
+	for _, hash := range hashes {
+		if !slices.Contains(referenced, hash) {
+			if err := blobStore.Remove(sharedRunName(hash)); err != nil {
+				return fmt.Errorf("failed to remove shared run %s: %w", hash, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeSharedRun stores the data columns of a run in its shared blob unless it already exists.
+func writeSharedRun(hash string, run *BenchmarkData) error {
+	sharedRunsMu.Lock()
+	defer sharedRunsMu.Unlock()
+
+	name := sharedRunName(hash)
+	if blobExists(name) {
+		return nil
+	}
+	// The label and specs stay in the referencing .bin files
+	columns := &BenchmarkData{}
+	src := dataColumns(run)
+	for i, col := range dataColumns(columns) {
+		*col.data = *src[i].data
+	}
+	return writeBlob(name, func(out io.Writer) error {
+		return writeColumnarRuns(out, []*BenchmarkData{columns}, false)
+	})
+}
+
+// readSharedRunColumns reads the given columns of a shared run blob into run.
+func readSharedRunColumns(hash string, run *BenchmarkData, keys []string) error {
+	index := &binIndex{}
+	sf, err := openSectionedFile(sharedRunName(hash), binFileMagic, index)
+	if err != nil {
+		return fmt.Errorf("failed to open shared run %s: %w", hash, err)
+	}
+	defer sf.Close()
+	if len(index.Runs) != 1 {
+		return fmt.Errorf("shared run %s has %d runs", hash, len(index.Runs))
+	}
+	return readColumns(sf, &index.Runs[0], run, keys)
+}
+
+// MigrateBenchmarkRunHashes records the content hashes of the runs of every benchmark.
+func MigrateBenchmarkRunHashes(db *DBInstance) error {
+	var ids []uint
+	if err := db.DB.Model(&Benchmark{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for _, id := range ids {
+		runs, err := RetrieveBenchmarkData(id)
+		if err != nil {
+			log.Printf("Benchmark %d: skipping run hashes, failed to read data: %v", id, err)
+			continue
+		}
+		if err := syncBenchmarkRuns(db, id, runs, nil); err != nil {
+			return err
+		}
+	}
+	log.Printf("Recorded run hashes of %d benchmark(s)", len(ids))
+	return nil
+}
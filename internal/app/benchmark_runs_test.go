@@ -0,0 +1,233 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sharedRunBlobs lists the shared run blobs in the blob store.
+func sharedRunBlobs(t *testing.T) []string {
+	t.Helper()
+	blobs, err := blobStore.List()
+	if err != nil {
+		t.Fatalf("Failed to list blobs: %v", err)
+	}
+	var names []string
+	for _, blob := range blobs {
+		if sharedRunFilePattern.MatchString(blob.Name) {
+			names = append(names, blob.Name)
+		}
+	}
+	return names
+}
+
+// createBenchmarkRequest builds a request creating a benchmark with one identical CSV run per label.
+func createBenchmarkRequest(t *testing.T, title string, labels ...string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("title", title); err != nil {
+		t.Fatalf("Failed to write field: %v", err)
+	}
+	for _, label := range labels {
+		part, err := writer.CreateFormFile("files", label+".csv")
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte("os,cpu,gpu,ram,kernel,driver,cpuscheduler\nLinux,Intel,NVIDIA,16GB,6.1,nvidia,eevdf\nfps,frametime\n60,16.6\n61,16.4\n"))
+	}
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/benchmarks", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestRunContentHash(t *testing.T) {
+	run := columnarRuns()[0]
+	hash := runContentHash(run)
+	if len(hash) != 64 {
+		t.Fatalf("expected a hex SHA-256, got %q", hash)
+	}
+
+	renamed := columnarRuns()[0]
+	renamed.Label, renamed.Group = "Renamed", "Group"
+	if runContentHash(renamed) != hash {
+		t.Error("label and group should not change the hash")
+	}
+
+	changed := columnarRuns()[0]
+	changed.DataFPS[1] = 61.6
+	if runContentHash(changed) == hash {
+		t.Error("changed data should change the hash")
+	}
+
+	// Moving values between columns must not collide
+	moved := columnarRuns()[0]
+	moved.DataGPUTemp, moved.DataCPUTemp = nil, moved.DataGPUTemp
+	if runContentHash(moved) == hash {
+		t.Error("values in another column should change the hash")
+	}
+}
+
+func TestDuplicateRunWarnings(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	// Admins skip the upload rate limit
+	user := createTestUser(db, "dupuser", true)
+	router := revisionTestRouter(db, user)
+	router.POST("/api/benchmarks", func(c *gin.Context) {
+		c.Set("UserID", user.ID)
+		HandleCreateBenchmark(db)(c)
+	})
+
+	create := func(title string, labels ...string) Benchmark {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createBenchmarkRequest(t, title, labels...))
+		var benchmark Benchmark
+		if err := json.Unmarshal(w.Body.Bytes(), &benchmark); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+		}
+		return benchmark
+	}
+
+	first := create("First", "original")
+	if len(first.Duplicates) != 0 {
+		t.Errorf("expected no duplicates for a new run, got %+v", first.Duplicates)
+	}
+	var records []BenchmarkRun
+	db.DB.Where("benchmark_id = ?", first.ID).Find(&records)
+	if len(records) != 1 || records[0].Label != "original" || len(records[0].ContentHash) != 64 {
+		t.Fatalf("expected one run record, got %+v", records)
+	}
+
+	t.Run("create flags runs of other benchmarks and the same upload", func(t *testing.T) {
+		second := create("Second", "copy", "copy again")
+		want := []DuplicateRun{
+			{RunIndex: 0, Label: "copy", ExistingBenchmarkID: first.ID, ExistingBenchmarkTitle: "First", ExistingRunLabel: "original"},
+			{RunIndex: 1, Label: "copy again", ExistingBenchmarkID: first.ID, ExistingBenchmarkTitle: "First", ExistingRunLabel: "original"},
+		}
+		if len(second.Duplicates) != 2 {
+			t.Fatalf("expected 2 duplicates, got %+v", second.Duplicates)
+		}
+		for i := range want {
+			got := second.Duplicates[i]
+			want[i].ContentHash, want[i].URL = records[0].ContentHash, fmt.Sprintf("/benchmarks/%d", first.ID)
+			if !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("duplicate %d: got %+v, want %+v", i, got, want[i])
+			}
+		}
+	})
+
+	t.Run("add runs flags runs of the same benchmark", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, addRunRequest(t, first.ID, "again", `"1"`))
+		var resp struct {
+			Duplicates []DuplicateRun `json:"duplicates"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to add runs: %d %s", w.Code, w.Body.String())
+		}
+		if len(resp.Duplicates) != 1 || resp.Duplicates[0].RunIndex != 1 ||
+			resp.Duplicates[0].ExistingBenchmarkID != first.ID || resp.Duplicates[0].ExistingRunIndex != 0 {
+			t.Errorf("expected run 1 flagged as a copy of run 0, got %+v", resp.Duplicates)
+		}
+	})
+
+	t.Run("deleted benchmarks are not linked", func(t *testing.T) {
+		router.DELETE("/api/benchmarks/:id", func(c *gin.Context) {
+			c.Set("UserID", user.ID)
+			HandleDeleteBenchmark(db)(c)
+		})
+		for _, id := range []string{"1", "2"} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/benchmarks/"+id, http.NoBody))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Failed to delete benchmark %s: %d", id, w.Code)
+			}
+		}
+		if fourth := create("Fourth", "original"); len(fourth.Duplicates) != 0 {
+			t.Errorf("expected no duplicates after deleting the originals, got %+v", fourth.Duplicates)
+		}
+	})
+}
+
+func TestSharedRunDedup(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+	runDedupEnabled = true
+	defer func() { runDedupEnabled = false }()
+
+	user := createTestUser(db, "dedupuser", false)
+	var ids []uint
+	for _, title := range []string{"First", "Second"} {
+		benchmark := &Benchmark{UserID: user.ID, Title: title}
+		db.DB.Create(benchmark)
+		ids = append(ids, benchmark.ID)
+		runs := columnarRuns()
+		runs[0].Label = title
+		if err := syncBenchmarkRuns(db, benchmark.ID, runs, nil); err != nil {
+			t.Fatalf("Failed to record runs: %v", err)
+		}
+		preCalc, groups := ComputeBenchmarkStats(runs, "")
+		if err := StoreBenchmarkDataWithStats(runs, preCalc, groups, benchmark.ID); err != nil {
+			t.Fatalf("Failed to store benchmark data: %v", err)
+		}
+	}
+	if shared := sharedRunBlobs(t); len(shared) != 2 {
+		t.Fatalf("expected one shared blob per distinct run, got %v", shared)
+	}
+
+	data, err := RetrieveBenchmarkData(ids[1])
+	want := columnarRuns()
+	want[0].Label = "Second"
+	if err != nil || !reflect.DeepEqual(data, want) {
+		t.Errorf("runs not read back through shared blobs (%v)", err)
+	}
+	if run, err := RetrieveBenchmarkRun(ids[0], 0); err != nil || run.Label != "First" || len(run.DataFPS) != 3 {
+		t.Errorf("unexpected run: %+v (%v)", run, err)
+	}
+
+	t.Run("storage check accepts referenced blobs and reports orphans", func(t *testing.T) {
+		if err := writeSharedRun(runContentHash(longSeriesRun(10)), longSeriesRun(10)); err != nil {
+			t.Fatalf("Failed to write shared run: %v", err)
+		}
+		report, err := CheckStorage(db, true)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if len(report.Issues) != 1 || report.Issues[0].Type != storageIssueOrphan || !report.Issues[0].Resolved {
+			t.Errorf("expected the unreferenced blob quarantined, got %+v", report.Issues)
+		}
+	})
+
+	t.Run("blobs are removed with their last reference", func(t *testing.T) {
+		DeleteBenchmarkData(ids[0])
+		releaseBenchmarkRuns(db, ids[0])
+		if shared := sharedRunBlobs(t); len(shared) != 2 {
+			t.Fatalf("expected shared blobs kept for the second benchmark, got %v", shared)
+		}
+		if data, err := RetrieveBenchmarkData(ids[1]); err != nil || len(data) != 2 {
+			t.Errorf("second benchmark unreadable after deleting the first (%v)", err)
+		}
+		DeleteBenchmarkData(ids[1])
+		releaseBenchmarkRuns(db, ids[1])
+		if shared := sharedRunBlobs(t); len(shared) != 0 {
+			t.Errorf("expected shared blobs removed, got %v", shared)
+		}
+	})
+}
@@ -0,0 +1,386 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Benchmark search uses an SQLite FTS5 index, benchmarks_fts, with one row per benchmark (rowid is
+// the benchmark ID) holding its title, description, owner's username, run names and specifications.
+// Triggers on benchmarks and users keep it in sync with every write, including soft deletes, which
+// stay indexed and are filtered out by the benchmarks query like everywhere else.
+//
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag. Without it the index can't
+// exist, searches fall back to LIKE clauses (keywords of 3+ characters, no ranking or snippets), and
+// any triggers left by a build with FTS5 are dropped so that writes keep working. When FTS5 is back,
+// the missing triggers are recreated and the index rebuilt.
+
+// benchmarkSearchFTS is true when SQLite supports FTS5 and benchmarks_fts is in use (set by InitDB).
+var benchmarkSearchFTS bool
+
+const (
+	maxSearchLength = 200
+	maxSearchTerms  = 10
+
+	// Snippet markers around matched terms; the surrounding text is not HTML-escaped
+	searchHighlightStart = "<mark>"
+	searchHighlightEnd   = "</mark>"
+)
+
+// benchmarkSearchColumns maps search_fields names to benchmarks_fts columns, in column order.
+var benchmarkSearchColumns = []struct{ field, column string }{
+	{"title", "title"},
+	{"description", "description"},
+	{"user", "username"},
+	{"run_name", "run_names"},
+	{"specifications", "specifications"},
+}
+
+// benchmarkSearchTriggers are the triggers keeping benchmarks_fts in sync.
+var benchmarkSearchTriggers = map[string]string{
+	"benchmarks_fts_insert": `CREATE TRIGGER IF NOT EXISTS benchmarks_fts_insert AFTER INSERT ON benchmarks BEGIN
+		INSERT INTO benchmarks_fts(rowid, title, description, username, run_names, specifications)
+		VALUES (new.id, new.title, new.description, COALESCE((SELECT username FROM users WHERE id = new.user_id), ''), new.run_names, new.specifications);
+	END`,
+	"benchmarks_fts_update": `CREATE TRIGGER IF NOT EXISTS benchmarks_fts_update AFTER UPDATE OF title, description, user_id, run_names, specifications ON benchmarks BEGIN
+		DELETE FROM benchmarks_fts WHERE rowid = old.id;
+		INSERT INTO benchmarks_fts(rowid, title, description, username, run_names, specifications)
+		VALUES (new.id, new.title, new.description, COALESCE((SELECT username FROM users WHERE id = new.user_id), ''), new.run_names, new.specifications);
+	END`,
+	"benchmarks_fts_delete": `CREATE TRIGGER IF NOT EXISTS benchmarks_fts_delete AFTER DELETE ON benchmarks BEGIN
+		DELETE FROM benchmarks_fts WHERE rowid = old.id;
+	END`,
+	"benchmarks_fts_username": `CREATE TRIGGER IF NOT EXISTS benchmarks_fts_username AFTER UPDATE OF username ON users BEGIN
+		UPDATE benchmarks_fts SET username = new.username WHERE rowid IN (SELECT id FROM benchmarks WHERE user_id = new.id);
+	END`,
+}
+
+// initBenchmarkSearch creates benchmarks_fts and its triggers when SQLite supports FTS5.
+// It reports whether anything was created, in which case the index must be rebuilt.
+func initBenchmarkSearch(db *gorm.DB) (bool, error) {
+	var fts5 int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return false, fmt.Errorf("failed to check for FTS5 support: %w", err)
+	}
+	benchmarkSearchFTS = fts5 == 1
+	if !benchmarkSearchFTS {
+		for name := range benchmarkSearchTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return false, fmt.Errorf("failed to drop trigger %s: %w", name, err)
+			}
+		}
+		log.Println("Warning: SQLite was built without FTS5 (build tag sqlite_fts5), benchmark search falls back to LIKE queries")
+		return false, nil
+	}
+
+	names := make([]string, 0, len(benchmarkSearchTriggers))
+	for name := range benchmarkSearchTriggers {
+		names = append(names, name)
+	}
+	var triggers int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", names).Scan(&triggers).Error; err != nil {
+		return false, fmt.Errorf("failed to check search triggers: %w", err)
+	}
+	hasTable := db.Migrator().HasTable("benchmarks_fts")
+	if hasTable && triggers == int64(len(benchmarkSearchTriggers)) {
+		return false, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !hasTable {
+			if err := tx.Exec(`CREATE VIRTUAL TABLE benchmarks_fts USING fts5(
+				title, description, username, run_names, specifications,
+				tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3'
+			)`).Error; err != nil {
+				return fmt.Errorf("failed to create benchmarks_fts: %w", err)
+			}
+			// Rank by BM25 with matches in titles weighing most
+			if err := tx.Exec("INSERT INTO benchmarks_fts(benchmarks_fts, rank) VALUES ('rank', 'bm25(10.0, 4.0, 2.0, 3.0, 1.0)')").Error; err != nil {
+				return fmt.Errorf("failed to configure benchmarks_fts ranking: %w", err)
+			}
+		}
+		for name, sql := range benchmarkSearchTriggers {
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("failed to create trigger %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	return err == nil, err
+}
+
+// RebuildBenchmarkSearchIndex replaces the contents of benchmarks_fts with all benchmarks.
+func RebuildBenchmarkSearchIndex(db *gorm.DB) error {
+	if !benchmarkSearchFTS {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM benchmarks_fts").Error; err != nil {
+			return fmt.Errorf("failed to clear search index: %w", err)
+		}
+		if err := tx.Exec(`INSERT INTO benchmarks_fts(rowid, title, description, username, run_names, specifications)
+			SELECT b.id, b.title, b.description, COALESCE(u.username, ''), b.run_names, b.specifications
+			FROM benchmarks b LEFT JOIN users u ON u.id = b.user_id`).Error; err != nil {
+			return fmt.Errorf("failed to index benchmarks: %w", err)
+		}
+		return nil
+	})
+}
+
+// searchTerm is a word or a quoted phrase of a search query.
+type searchTerm struct {
+	text   string
+	phrase bool
+}
+
+// benchmarkSearch is a parsed search of the benchmark list.
+type benchmarkSearch struct {
+	terms  []searchTerm
+	fields []string // search_fields names, in benchmarkSearchColumns order
+}
+
+// parseBenchmarkSearch parses a search query into words and "quoted phrases", searching the
+// given search_fields (invalid names are ignored). It returns nil if nothing is left to search.
+func parseBenchmarkSearch(search string, fields []string) *benchmarkSearch {
+	if len(search) > maxSearchLength {
+		search = search[:maxSearchLength]
+	}
+
+	s := &benchmarkSearch{}
+	for _, col := range benchmarkSearchColumns {
+		for _, field := range fields {
+			if strings.TrimSpace(field) == col.field {
+				s.fields = append(s.fields, col.field)
+				break
+			}
+		}
+	}
+	if len(s.fields) == 0 {
+		return nil
+	}
+
+	for rest := search; rest != "" && len(s.terms) < maxSearchTerms; {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		var term searchTerm
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			term = searchTerm{text: strings.TrimSpace(rest[1 : end+1]), phrase: true}
+			rest = rest[min(end+2, len(rest)):]
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			term = searchTerm{text: strings.TrimRight(rest[:end], "*")}
+			rest = rest[end:]
+		}
+		// Terms without letters or digits match nothing in the index
+		if strings.IndexFunc(term.text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		// Without FTS5, short words would need a full table scan
+		if !benchmarkSearchFTS && len(term.text) < 3 {
+			continue
+		}
+		s.terms = append(s.terms, term)
+	}
+	if len(s.terms) == 0 {
+		return nil
+	}
+	return s
+}
+
+// matchExpression returns the FTS5 query of the search: all terms must match, words as prefixes
+// and phrases exactly. Terms are quoted, so no input is interpreted as FTS5 syntax.
+func (s *benchmarkSearch) matchExpression() string {
+	terms := make([]string, len(s.terms))
+	for i, term := range s.terms {
+		terms[i] = `"` + strings.ReplaceAll(term.text, `"`, `""`) + `"`
+		if !term.phrase {
+			terms[i] += "*"
+		}
+	}
+	expr := strings.Join(terms, " ")
+	if len(s.fields) == len(benchmarkSearchColumns) {
+		return expr
+	}
+	columns := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		for _, col := range benchmarkSearchColumns {
+			if col.field == field {
+				columns[i] = col.column
+			}
+		}
+	}
+	return "{" + strings.Join(columns, " ") + "} : (" + expr + ")"
+}
+
+// apply restricts a benchmarks query to the search's matches.
+func (s *benchmarkSearch) apply(query *gorm.DB) *gorm.DB {
+	if benchmarkSearchFTS {
+		return query.Joins("JOIN benchmarks_fts ON benchmarks_fts.rowid = benchmarks.id").
+			Where("benchmarks_fts MATCH ?", s.matchExpression())
+	}
+
+	// Without FTS5: every term must appear in any of the fields (AND of ORs)
+	likeClauses := map[string]string{
+		"title":          "benchmarks.title LIKE ? ESCAPE '\\'",
+		"description":    "benchmarks.description LIKE ? ESCAPE '\\'",
+		"user":           "EXISTS (SELECT 1 FROM users WHERE users.id = benchmarks.user_id AND users.username LIKE ? ESCAPE '\\')",
+		"run_name":       "benchmarks.run_names LIKE ? ESCAPE '\\'",
+		"specifications": "benchmarks.specifications LIKE ? ESCAPE '\\'",
+	}
+	for _, term := range s.terms {
+		pattern := likeContainsPattern(term.text)
+		orConditions := make([]string, len(s.fields))
+		orValues := make([]interface{}, len(s.fields))
+		for i, field := range s.fields {
+			orConditions[i] = likeClauses[field]
+			orValues[i] = pattern
+		}
+		query = query.Where(strings.Join(orConditions, " OR "), orValues...)
+	}
+	return query
+}
+
+// likeContainsPattern returns a LIKE pattern (used with ESCAPE '\') matching values that contain
+// text. LIKE wildcards in text are escaped to prevent pattern injection.
+func likeContainsPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// benchmarkListFilter selects the benchmarks of the list by owner, game, search, run specs and run stats.
+type benchmarkListFilter struct {
+	userID uint // 0 for all users
+	gameID uint // 0 for all games
+	none   bool // Matches nothing, e.g. for an unknown username or game
+	search *benchmarkSearch
+	specs  []specFilter
+	stats  []statFilter
+}
+
+// apply restricts a benchmarks query to the benchmarks selected by the filter.
+func (f *benchmarkListFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.none {
+		return query.Where("1 = 0")
+	}
+	if f.userID > 0 {
+		query = query.Where("benchmarks.user_id = ?", f.userID)
+	}
+	if f.gameID > 0 {
+		query = query.Where("benchmarks.game_id = ?", f.gameID)
+	}
+	if f.search != nil {
+		query = f.search.apply(query)
+	}
+	return applyRunFilters(query, f.specs, f.stats)
+}
+
+// orderBenchmarks sorts a benchmarks query selected by filter by sort_by and sort_order. Relevance
+// applies to FTS5 searches only and always puts the best matches first; run stats sort by the
+// runs matching the filter (see orderByRunStat); anything else falls back to created_at.
+func orderBenchmarks(query *gorm.DB, sortBy, sortOrder string, filter *benchmarkListFilter) *gorm.DB {
+	if sortBy == "relevance" && filter.search != nil && benchmarkSearchFTS {
+		return query.Order("benchmarks_fts.rank").Order("benchmarks.created_at DESC")
+	}
+
+	if runStatColumn(sortBy) != "" {
+		return orderByRunStat(query, sortBy, sortOrder == "asc", filter)
+	}
+
+	// Use explicit column names to avoid any possibility of injection
+	var orderClause string
+	switch sortBy {
+	case "title":
+		orderClause = "benchmarks.title"
+	case "updated_at":
+		orderClause = "benchmarks.updated_at"
+	default:
+		orderClause = "benchmarks.created_at"
+	}
+	if sortOrder == "asc" {
+		return query.Order(orderClause + " ASC")
+	}
+	return query.Order(orderClause + " DESC")
+}
+
+// highlight sets the Highlights of benchmarks matched by the search: a snippet of each searched
+// field containing matches, with matched terms wrapped in <mark> and </mark>.
+func (s *benchmarkSearch) highlight(db *gorm.DB, benchmarks []Benchmark) error {
+	if !benchmarkSearchFTS || len(benchmarks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(benchmarks))
+	for i := range benchmarks {
+		ids[i] = benchmarks[i].ID
+	}
+	columns := []string{"rowid"}
+	for i, col := range benchmarkSearchColumns {
+		columns = append(columns, fmt.Sprintf("snippet(benchmarks_fts, %d, '%s', '%s', '…', 16) AS %s", i, searchHighlightStart, searchHighlightEnd, col.column))
+	}
+	rows, err := db.Raw("SELECT "+strings.Join(columns, ", ")+" FROM benchmarks_fts WHERE benchmarks_fts MATCH ? AND rowid IN ?", s.matchExpression(), ids).Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query search snippets: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make(map[uint][]string, len(benchmarks))
+	for rows.Next() {
+		var id uint
+		values := make([]string, len(benchmarkSearchColumns))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read search snippets: %w", err)
+		}
+		snippets[id] = values
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read search snippets: %w", err)
+	}
+
+	for i := range benchmarks {
+		values, ok := snippets[benchmarks[i].ID]
+		if !ok {
+			continue
+		}
+		for j, col := range benchmarkSearchColumns {
+			if !strings.Contains(values[j], searchHighlightStart) || !slices.Contains(s.fields, col.field) {
+				continue
+			}
+			if benchmarks[i].Highlights == nil {
+				benchmarks[i].Highlights = make(map[string]string)
+			}
+			benchmarks[i].Highlights[col.field] = values[j]
+		}
+	}
+	return nil
+}
+
+// MigrateBenchmarkSearchIndex fills the full-text search index with the existing benchmarks.
+func MigrateBenchmarkSearchIndex(db *DBInstance) error {
+	if !benchmarkSearchFTS {
+		log.Println("FTS5 is not available, skipping the search index")
+		return nil
+	}
+	if err := RebuildBenchmarkSearchIndex(db.DB); err != nil {
+		return err
+	}
+	var count int64
+	db.DB.Raw("SELECT COUNT(*) FROM benchmarks_fts").Scan(&count)
+	log.Printf("Indexed %d benchmarks for search", count)
+	return nil
+}
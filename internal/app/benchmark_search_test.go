@@ -0,0 +1,167 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseBenchmarkSearch(t *testing.T) {
+	defer func(fts bool) { benchmarkSearchFTS = fts }(benchmarkSearchFTS)
+	benchmarkSearchFTS = true
+
+	all := []string{"title", "description", "user", "run_name", "specifications"}
+	tests := []struct {
+		search string
+		fields []string
+		want   string
+	}{
+		{"rust windows", all, `"rust"* "windows"*`},
+		{`"cyberpunk 2077" ultra*`, all, `"cyberpunk 2077" "ultra"*`},
+		{`say "hi`, all, `"say"* "hi"`},
+		{`a"b OR - NEAR(x)`, all, `"a""b"* "OR"* "NEAR(x)"*`},
+		{"bore", []string{"specifications", " title", "bogus"}, `{title specifications} : ("bore"*)`},
+	}
+	for _, tt := range tests {
+		s := parseBenchmarkSearch(tt.search, tt.fields)
+		if s == nil {
+			t.Errorf("%q: expected a search", tt.search)
+			continue
+		}
+		if got := s.matchExpression(); got != tt.want {
+			t.Errorf("%q: expected %s, got %s", tt.search, tt.want, got)
+		}
+	}
+
+	if s := parseBenchmarkSearch("- * \"\"", all); s != nil {
+		t.Errorf("expected no search without words, got %+v", s)
+	}
+	if s := parseBenchmarkSearch("rust", []string{"bogus"}); s != nil {
+		t.Errorf("expected no search without valid fields, got %+v", s)
+	}
+
+	// The LIKE fallback skips words under 3 characters
+	benchmarkSearchFTS = false
+	if s := parseBenchmarkSearch(`ru "ab" rust`, all); s == nil || len(s.terms) != 1 || s.terms[0].text != "rust" {
+		t.Errorf("expected only the long word searched without FTS5, got %+v", s)
+	}
+}
+
+func TestBenchmarkFullTextSearch(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if !benchmarkSearchFTS {
+		t.Skip("SQLite was built without FTS5 (build tag sqlite_fts5)")
+	}
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks", HandleListBenchmarks(db))
+
+	user := createTestUser(db, "searcher", false)
+	other := createTestUser(db, "ranker", false)
+	for _, b := range []Benchmark{
+		{UserID: user.ID, Title: "Cyberpunk 2077 on Arch", Description: "Path tracing with the BORE scheduler"},
+		{UserID: user.ID, Title: "Kernel comparison", Description: "Cyberpunk at 1440p, then 2077 other games", RunNames: "bore, eevdf"},
+		{UserID: other.ID, Title: "Elden Ring", Description: "Ultra preset", Specifications: "Linux 6.17 bore"},
+	} {
+		db.DB.Create(&b)
+	}
+
+	search := func(query string) []Benchmark {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/benchmarks?"+query, nil))
+		var response struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: failed to list benchmarks: %d %s", query, w.Code, w.Body.String())
+		}
+		return response.Benchmarks
+	}
+	titles := func(benchmarks []Benchmark) []string {
+		out := make([]string, len(benchmarks))
+		for i, b := range benchmarks {
+			out[i] = b.Title
+		}
+		return out
+	}
+
+	t.Run("phrases and prefixes", func(t *testing.T) {
+		if got := titles(search(`search=%22cyberpunk+2077%22`)); !reflect.DeepEqual(got, []string{"Cyberpunk 2077 on Arch"}) {
+			t.Errorf("unexpected phrase matches: %v", got)
+		}
+		if got := search("search=cy+20&sort_by=title&sort_order=asc"); len(got) != 2 {
+			t.Errorf("expected both Cyberpunk benchmarks for short prefixes, got %v", titles(got))
+		}
+	})
+
+	t.Run("relevance and highlights", func(t *testing.T) {
+		got := search("search=bore&search_fields=title,description,run_name,specifications&sort_by=relevance")
+		if len(got) != 3 {
+			t.Fatalf("expected 3 matches, got %v", titles(got))
+		}
+		if got[0].Highlights["description"] != "Path tracing with the <mark>BORE</mark> scheduler" {
+			t.Errorf("unexpected highlights: %v", got[0].Highlights)
+		}
+		if got[2].Title != "Elden Ring" || got[2].Highlights["specifications"] != "Linux 6.17 <mark>bore</mark>" {
+			t.Errorf("expected the specifications match ranked last, got %v %v", titles(got), got[2].Highlights)
+		}
+		if _, ok := got[1].Highlights["title"]; ok {
+			t.Errorf("expected highlights of matching fields only, got %v", got[1].Highlights)
+		}
+	})
+
+	t.Run("index follows writes", func(t *testing.T) {
+		db.DB.Model(&Benchmark{}).Where("title = ?", "Elden Ring").Update("title", "Elden Ring Nightreign")
+		if got := search("search=nightreign"); len(got) != 1 {
+			t.Errorf("expected the renamed benchmark found, got %v", titles(got))
+		}
+		db.DB.Model(&User{}).Where("id = ?", other.ID).Update("username", "renamed")
+		if got := search("search=renamed&search_fields=user"); len(got) != 1 {
+			t.Errorf("expected the benchmark found by its owner's new name, got %v", titles(got))
+		}
+		db.DB.Where("title = ?", "Kernel comparison").Delete(&Benchmark{})
+		if got := search("search=cyberpunk"); len(got) != 1 {
+			t.Errorf("expected the deleted benchmark hidden, got %v", titles(got))
+		}
+		db.DB.Unscoped().Where("title = ?", "Kernel comparison").Delete(&Benchmark{})
+		var indexed int64
+		db.DB.Raw("SELECT COUNT(*) FROM benchmarks_fts").Scan(&indexed)
+		if indexed != 2 {
+			t.Errorf("expected the purged benchmark removed from the index, got %d rows", indexed)
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		server := newMCPServer(db, "test", "")
+		out, err := server.toolListBenchmarks(json.RawMessage(`{"search": "arch path", "sort_by": "relevance"}`))
+		if err != nil {
+			t.Fatalf("list_benchmarks failed: %v", err)
+		}
+		var response struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		if err := json.Unmarshal([]byte(out), &response); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		if len(response.Benchmarks) != 1 || response.Benchmarks[0].Highlights["title"] != "Cyberpunk 2077 on <mark>Arch</mark>" {
+			t.Errorf("unexpected result: %s", out)
+		}
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		db.DB.Exec("DELETE FROM benchmarks_fts")
+		if err := MigrateBenchmarkSearchIndex(db); err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		if got := search("search=nightreign"); len(got) != 1 {
+			t.Errorf("expected the index rebuilt, got %v", titles(got))
+		}
+	})
+}
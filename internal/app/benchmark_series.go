@@ -0,0 +1,382 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// .series files are sectioned files (see sectioned_file.go). Each chunk of a pyramid level is its
+// own section of little-endian float64 values, so a request only reads the chunks it overlaps.
+const (
+	seriesFormatVersion    = 1
+	seriesFileMagic        = "FSSERIES" // Trailer magic (8 bytes)
+	seriesChunkPoints      = 8192       // Points per compressed chunk
+	maxSeriesRequestPoints = 20000      // Upper bound for the points parameter of series requests
+)
+
+// seriesLevelTargets are the approximate point counts of the downsampled pyramid levels.
+// Full resolution is always stored as the finest level.
+var seriesLevelTargets = []int{maxDownsamplePoints, 10 * maxDownsamplePoints}
+
+// Series lookup errors
+var (
+	errSeriesRunNotFound    = errors.New("run not found")
+	errSeriesMetricNotFound = errors.New("metric not found")
+)
+
+// seriesIndex describes every chunk stored in a .series file.
+type seriesIndex struct {
+	Version int
+	Runs    []seriesRunIndex
+}
+
+// seriesRunIndex describes the pyramid of one run.
+type seriesRunIndex struct {
+	Samples int
+	// Elapsed holds the cumulative frametime (ms) before each sample, plus the total run
+	// duration as the last value (Samples+1 values). ElapsedChunkStartMs is the first value of
+	// each Elapsed chunk, so time lookups only read one chunk.
+	Elapsed             []fileSection
+	ElapsedChunkStartMs []float64
+	Metrics             map[string][]seriesLevel // camelCase metric key -> levels, coarsest first
+}
+
+// seriesLevel is one resolution of a metric. Full-resolution levels (BucketSize 1) store one
+// value per point; downsampled levels store mean, min and max per bucket.
+type seriesLevel struct {
+	BucketSize int // Raw samples per point
+	Points     int
+	Chunks     []fileSection
+}
+
+// stride returns the number of float64 values stored per point.
+func (l seriesLevel) stride() int {
+	if l.BucketSize == 1 {
+		return 1
+	}
+	return 3
+}
+
+// SeriesQuery selects a slice of a run's metric series.
+type SeriesQuery struct {
+	RunIndex int
+	Metric   string  // camelCase metric key
+	From     float64 // Range start (inclusive), in samples or milliseconds depending on Unit
+	To       float64 // Range end (exclusive); negative means the end of the run
+	Unit     string  // "index" or "ms"
+	Points   int     // Maximum number of points to return
+}
+
+// SeriesResponse is a series slice at the resolution picked for the request.
+type SeriesResponse struct {
+	Metric       string       `json:"metric"`
+	RunIndex     int          `json:"run_index"`
+	TotalSamples int          `json:"total_samples"`
+	BucketSize   int          `json:"bucket_size"` // Raw samples per point; 1 = full resolution
+	FromIndex    int          `json:"from_index"`
+	ToIndex      int          `json:"to_index"` // Exclusive
+	FromMs       float64      `json:"from_ms"`
+	ToMs         float64      `json:"to_ms"`
+	Points       [][2]float64 `json:"points"`             // [sampleIndex, value]; value is the bucket mean when downsampled
+	Envelope     [][3]float64 `json:"envelope,omitempty"` // [sampleIndex, min, max] per bucket; omitted at full resolution
+}
+
+func seriesFileName(benchmarkID uint) string {
+	return benchmarkFileName(benchmarkID, benchmarkFileSeries)
+}
+
+// seriesColumns returns the full-resolution columns of a run that have a chart series
+// (the same keys as PreCalculatedRun.Series). Efficiency columns use NaN for invalid samples.
+func seriesColumns(run *BenchmarkData) []metricEntry {
+	columns := runMetrics(run)
+	if len(run.DataFPS) > 0 {
+		columns = append(columns, metricEntry{"FPS", run.DataFPS})
+	}
+	if eff := computeEfficiencySeries(run); eff != nil {
+		columns = append(columns, efficiencyMetrics(eff)...)
+	}
+	return columns
+}
+
+// writeSeriesValues stores values (stride values per point) as chunks of seriesChunkPoints points.
+func writeSeriesValues(sw *sectionWriter, values []float64, stride int) ([]fileSection, error) {
+	chunkLen := seriesChunkPoints * stride
+	var chunks []fileSection
+	for start := 0; start < len(values); start += chunkLen {
+		chunk, err := sw.writeFloats(values[start:min(start+chunkLen, len(values))])
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// writeSeriesRun stores the elapsed-time column and the pyramid of every metric of a run.
+func writeSeriesRun(sw *sectionWriter, run *BenchmarkData) (seriesRunIndex, error) {
+	n := getRunDataPointCount(run)
+	idx := seriesRunIndex{Samples: n, Metrics: make(map[string][]seriesLevel)}
+
+	elapsed := make([]float64, n+1)
+	for i := 0; i < n; i++ {
+		elapsed[i+1] = elapsed[i] + sampleFrametime(run, i)
+	}
+	var err error
+	if idx.Elapsed, err = writeSeriesValues(sw, elapsed, 1); err != nil {
+		return idx, err
+	}
+	for start := 0; start < len(elapsed); start += seriesChunkPoints {
+		idx.ElapsedChunkStartMs = append(idx.ElapsedChunkStartMs, elapsed[start])
+	}
+
+	for _, m := range seriesColumns(run) {
+		if len(m.data) == 0 {
+			continue
+		}
+		var levels []seriesLevel
+		prevBucket := 0
+		for _, target := range seriesLevelTargets {
+			bucket := (len(m.data) + target - 1) / target
+			if bucket <= 1 || bucket == prevBucket {
+				continue
+			}
+			prevBucket = bucket
+			level := seriesLevel{BucketSize: bucket, Points: (len(m.data) + bucket - 1) / bucket}
+			if level.Chunks, err = writeSeriesValues(sw, bucketEnvelope(m.data, bucket), 3); err != nil {
+				return idx, err
+			}
+			levels = append(levels, level)
+		}
+		full := seriesLevel{BucketSize: 1, Points: len(m.data)}
+		if full.Chunks, err = writeSeriesValues(sw, m.data, 1); err != nil {
+			return idx, err
+		}
+		idx.Metrics[m.key] = append(levels, full)
+	}
+	return idx, nil
+}
+
+// bucketEnvelope returns [mean, min, max] for each bucket of data, skipping NaN samples.
+// Buckets without valid samples are NaN.
+func bucketEnvelope(data []float64, bucket int) []float64 {
+	out := make([]float64, 0, (len(data)+bucket-1)/bucket*3)
+	for start := 0; start < len(data); start += bucket {
+		end := min(start+bucket, len(data))
+		sum, count := 0.0, 0
+		minVal, maxVal := math.Inf(1), math.Inf(-1)
+		for _, v := range data[start:end] {
+			if math.IsNaN(v) {
+				continue
+			}
+			sum += v
+			count++
+			minVal = math.Min(minVal, v)
+			maxVal = math.Max(maxVal, v)
+		}
+		if count == 0 {
+			out = append(out, math.NaN(), math.NaN(), math.NaN())
+			continue
+		}
+		out = append(out, sum/float64(count), minVal, maxVal)
+	}
+	return out
+}
+
+// writeSeriesFile writes the multi-resolution series of a benchmark's runs.
+func writeSeriesFile(out io.Writer, benchmarkData []*BenchmarkData) error {
+	sw, err := newSectionWriter(out)
+	if err != nil {
+		return err
+	}
+	defer sw.Close()
+
+	index := seriesIndex{Version: seriesFormatVersion, Runs: make([]seriesRunIndex, len(benchmarkData))}
+	for i, run := range benchmarkData {
+		if index.Runs[i], err = writeSeriesRun(sw, run); err != nil {
+			return fmt.Errorf("failed to write series of run %d: %w", i, err)
+		}
+	}
+	if err := sw.finish(index, seriesFileMagic); err != nil {
+		return fmt.Errorf("failed to finish series file: %w", err)
+	}
+	return nil
+}
+
+// seriesFile is an open .series file with its decoded index.
+type seriesFile struct {
+	*sectionedFile
+	index seriesIndex
+}
+
+// openSeriesFile opens a benchmark's .series file and decodes its index.
+func openSeriesFile(benchmarkID uint) (*seriesFile, error) {
+	sf := &seriesFile{}
+	file, err := openSectionedFile(seriesFileName(benchmarkID), seriesFileMagic, &sf.index)
+	if err != nil {
+		return nil, err
+	}
+	sf.sectionedFile = file
+	if sf.index.Version != seriesFormatVersion {
+		sf.Close()
+		return nil, fmt.Errorf("unsupported series format version: %d", sf.index.Version)
+	}
+	return sf, nil
+}
+
+// readPoints returns the values of points [from, to) from chunked storage with the given stride.
+func (sf *seriesFile) readPoints(chunks []fileSection, stride, from, to int) ([]float64, error) {
+	if from >= to {
+		return nil, nil
+	}
+	values := make([]float64, 0, (to-from)*stride)
+	for c := from / seriesChunkPoints; c <= (to-1)/seriesChunkPoints; c++ {
+		if c >= len(chunks) {
+			return nil, fmt.Errorf("series chunk %d out of range", c)
+		}
+		chunk, err := sf.readFloats(chunks[c])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read series chunk: %w", err)
+		}
+		chunkStart := c * seriesChunkPoints
+		lo := max(from, chunkStart) - chunkStart
+		hi := min(to, chunkStart+seriesChunkPoints) - chunkStart
+		if hi*stride > len(chunk) {
+			return nil, fmt.Errorf("series chunk %d is truncated", c)
+		}
+		values = append(values, chunk[lo*stride:hi*stride]...)
+	}
+	return values, nil
+}
+
+// elapsedAt returns the elapsed time (ms) before sample i (i may equal Samples for the run end).
+func (sf *seriesFile) elapsedAt(run *seriesRunIndex, i int) (float64, error) {
+	values, err := sf.readPoints(run.Elapsed, 1, i, i+1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read elapsed time: %w", err)
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("elapsed time missing for sample %d", i)
+	}
+	return values[0], nil
+}
+
+// sampleAtMs returns the first sample index whose elapsed time is at least ms.
+func (sf *seriesFile) sampleAtMs(run *seriesRunIndex, ms float64) (int, error) {
+	starts := run.ElapsedChunkStartMs
+	c := sort.Search(len(starts), func(i int) bool { return starts[i] > ms }) - 1
+	if c < 0 {
+		return 0, nil
+	}
+	chunkStart := c * seriesChunkPoints
+	chunkEnd := min(chunkStart+seriesChunkPoints, run.Samples+1)
+	values, err := sf.readPoints(run.Elapsed, 1, chunkStart, chunkEnd)
+	if err != nil {
+		return 0, err
+	}
+	// Falls through to the next chunk's first sample, whose start is past ms
+	return chunkStart + sort.SearchFloat64s(values, ms), nil
+}
+
+// QuerySeries returns a slice of a run's metric series at the finest pyramid level that fits
+// within q.Points points, reading only the chunks that overlap the requested range.
+func QuerySeries(benchmarkID uint, q SeriesQuery) (*SeriesResponse, error) {
+	sf, err := openSeriesFile(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Close()
+
+	if q.RunIndex < 0 || q.RunIndex >= len(sf.index.Runs) {
+		return nil, errSeriesRunNotFound
+	}
+	run := &sf.index.Runs[q.RunIndex]
+	levels, ok := run.Metrics[q.Metric]
+	if !ok || len(levels) == 0 {
+		return nil, errSeriesMetricNotFound
+	}
+	samples := levels[len(levels)-1].Points
+
+	// Resolve the requested range to sample indices
+	from, to := 0, samples
+	if q.Unit == "ms" {
+		if from, err = sf.sampleAtMs(run, q.From); err != nil {
+			return nil, err
+		}
+		if q.To >= 0 {
+			if to, err = sf.sampleAtMs(run, q.To); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		from = int(math.Ceil(q.From))
+		if q.To >= 0 {
+			to = int(math.Ceil(q.To))
+		}
+	}
+	from = min(max(from, 0), samples)
+	to = min(max(to, from), samples)
+
+	points := q.Points
+	if points <= 0 {
+		points = maxDownsamplePoints
+	}
+	points = min(points, maxSeriesRequestPoints)
+
+	// Finest level that fits, falling back to the coarsest one
+	level := levels[0]
+	for i := len(levels) - 1; i >= 0; i-- {
+		b := levels[i].BucketSize
+		if (to+b-1)/b-from/b <= points {
+			level = levels[i]
+			break
+		}
+	}
+
+	resp := &SeriesResponse{
+		Metric:       q.Metric,
+		RunIndex:     q.RunIndex,
+		TotalSamples: samples,
+		BucketSize:   level.BucketSize,
+		FromIndex:    from,
+		ToIndex:      to,
+		Points:       [][2]float64{},
+	}
+	if resp.FromMs, err = sf.elapsedAt(run, min(from, run.Samples)); err != nil {
+		return nil, err
+	}
+	if resp.ToMs, err = sf.elapsedAt(run, min(to, run.Samples)); err != nil {
+		return nil, err
+	}
+
+	b := level.BucketSize
+	first := from / b
+	values, err := sf.readPoints(level.Chunks, level.stride(), first, (to+b-1)/b)
+	if err != nil {
+		return nil, err
+	}
+	stride := level.stride()
+	for i := 0; i+stride <= len(values); i += stride {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+		index := float64((first + i/stride) * b)
+		resp.Points = append(resp.Points, [2]float64{index, values[i]})
+		if stride == 3 {
+			resp.Envelope = append(resp.Envelope, [3]float64{index, values[i+1], values[i+2]})
+		}
+	}
+	return resp, nil
+}
+
+// seriesFileUpToDate reports whether a benchmark's .series file exists and uses the current format.
+func seriesFileUpToDate(benchmarkID uint) bool {
+	sf, err := openSeriesFile(benchmarkID)
+	if err != nil {
+		return false
+	}
+	sf.Close()
+	return true
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// longSeriesRun builds a run with n samples at 10 ms per frame and a sawtooth GPU load.
+func longSeriesRun(n int) *BenchmarkData {
+	run := &BenchmarkData{
+		Label:         "long",
+		DataFPS:       repeatValue(100, n),
+		DataFrameTime: repeatValue(10, n),
+		DataGPULoad:   make([]float64, n),
+	}
+	for i := range run.DataGPULoad {
+		run.DataGPULoad[i] = float64(i % 100)
+	}
+	return run
+}
+
+func TestBucketEnvelope(t *testing.T) {
+	got := bucketEnvelope([]float64{1, 3, math.NaN(), 5, math.NaN()}, 2)
+	want := []float64{2, 1, 3, 5, 5, 5}
+	if len(got) != 9 {
+		t.Fatalf("expected 3 buckets, got %v", got)
+	}
+	for i, v := range want {
+		if got[i] != v {
+			t.Errorf("value %d: got %v, want %v", i, got[i], v)
+		}
+	}
+	if !math.IsNaN(got[6]) {
+		t.Errorf("bucket without valid samples should be NaN, got %v", got[6:])
+	}
+}
+
+func TestQuerySeries(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	n := 50000 // 500 s at 100 FPS
+	if err := StoreBenchmarkData([]*BenchmarkData{longSeriesRun(n), longSeriesRun(100)}, 1); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+
+	t.Run("whole run uses the coarsest level", func(t *testing.T) {
+		resp, err := QuerySeries(1, SeriesQuery{Metric: "GPULoad", To: -1})
+		if err != nil {
+			t.Fatalf("QuerySeries failed: %v", err)
+		}
+		if resp.BucketSize != 25 || len(resp.Points) != 2000 || len(resp.Envelope) != 2000 {
+			t.Fatalf("expected 2000 buckets of 25 samples, got %d of %d", len(resp.Points), resp.BucketSize)
+		}
+		// First bucket covers samples 0-24 of the sawtooth
+		if resp.Points[0] != [2]float64{0, 12} || resp.Envelope[0] != [3]float64{0, 0, 24} {
+			t.Errorf("unexpected first bucket: %v %v", resp.Points[0], resp.Envelope[0])
+		}
+		if resp.TotalSamples != n || resp.ToIndex != n || resp.ToMs != float64(n)*10 {
+			t.Errorf("unexpected range: %+v", resp)
+		}
+	})
+
+	t.Run("zoomed range is full resolution", func(t *testing.T) {
+		// 10 s window deep inside the run, spanning a chunk boundary
+		resp, err := QuerySeries(1, SeriesQuery{Metric: "GPULoad", From: 80000, To: 90000, Unit: "ms", Points: 2000})
+		if err != nil {
+			t.Fatalf("QuerySeries failed: %v", err)
+		}
+		if resp.BucketSize != 1 || resp.Envelope != nil {
+			t.Fatalf("expected full resolution, got bucket size %d", resp.BucketSize)
+		}
+		if resp.FromIndex != 8000 || resp.ToIndex != 9000 || len(resp.Points) != 1000 {
+			t.Fatalf("unexpected range %d-%d with %d points", resp.FromIndex, resp.ToIndex, len(resp.Points))
+		}
+		if resp.Points[0] != [2]float64{8000, 0} || resp.Points[999] != [2]float64{8999, 99} {
+			t.Errorf("unexpected points: %v ... %v", resp.Points[0], resp.Points[999])
+		}
+		if resp.FromMs != 80000 || resp.ToMs != 90000 {
+			t.Errorf("unexpected time range: %v-%v", resp.FromMs, resp.ToMs)
+		}
+	})
+
+	t.Run("intermediate level", func(t *testing.T) {
+		resp, err := QuerySeries(1, SeriesQuery{Metric: "FPS", From: 0, To: 20000, Points: 10000})
+		if err != nil {
+			t.Fatalf("QuerySeries failed: %v", err)
+		}
+		if resp.BucketSize != 3 || len(resp.Points) != 6667 {
+			t.Errorf("expected 20k level (bucket 3), got bucket %d with %d points", resp.BucketSize, len(resp.Points))
+		}
+	})
+
+	t.Run("short run only has full resolution", func(t *testing.T) {
+		resp, err := QuerySeries(1, SeriesQuery{RunIndex: 1, Metric: "FPS", To: -1, Points: 10})
+		if err != nil {
+			t.Fatalf("QuerySeries failed: %v", err)
+		}
+		if resp.BucketSize != 1 || len(resp.Points) != 100 {
+			t.Errorf("expected full resolution fallback, got bucket %d with %d points", resp.BucketSize, len(resp.Points))
+		}
+	})
+
+	t.Run("lookup errors", func(t *testing.T) {
+		if _, err := QuerySeries(1, SeriesQuery{RunIndex: 5, Metric: "FPS", To: -1}); err != errSeriesRunNotFound {
+			t.Errorf("expected run not found, got %v", err)
+		}
+		if _, err := QuerySeries(1, SeriesQuery{Metric: "CPUTemp", To: -1}); err != errSeriesMetricNotFound {
+			t.Errorf("expected metric not found, got %v", err)
+		}
+		if _, err := QuerySeries(2, SeriesQuery{Metric: "FPS", To: -1}); !os.IsNotExist(err) {
+			t.Errorf("expected missing file error, got %v", err)
+		}
+	})
+
+	t.Run("deleted with benchmark data", func(t *testing.T) {
+		if err := DeleteBenchmarkData(1); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(benchmarksDir, seriesFileName(1))); !os.IsNotExist(err) {
+			t.Errorf("expected series file to be deleted, got %v", err)
+		}
+	})
+}
+
+func TestHandleGetBenchmarkRunSeries(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "seriesuser", false)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Series"}
+	db.DB.Create(benchmark)
+	if err := StoreBenchmarkData([]*BenchmarkData{longSeriesRun(5000)}, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks/:id/runs/:runIndex/series", HandleGetBenchmarkRunSeries(db))
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"snake_case metric", "metric=gpu_load&from=100&to=200", http.StatusOK},
+		{"missing metric", "", http.StatusBadRequest},
+		{"invalid unit", "metric=FPS&unit=s", http.StatusBadRequest},
+		{"to before from", "metric=FPS&from=10&to=5", http.StatusBadRequest},
+		{"too many points", "metric=FPS&points=50000", http.StatusBadRequest},
+		{"unknown metric", "metric=CPUTemp", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/benchmarks/%d/runs/0/series?%s", benchmark.ID, tt.query)
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, http.NoBody))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp SeriesResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal: %v", err)
+			}
+			if resp.Metric != "GPULoad" || len(resp.Points) != 100 || resp.Points[0][0] != 100 {
+				t.Errorf("unexpected response: metric %s, %d points", resp.Metric, len(resp.Points))
+			}
+		})
+	}
+}
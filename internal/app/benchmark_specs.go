@@ -0,0 +1,273 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// The specs of every run are recorded in its benchmark_runs row, normalized (see
+// normalizeSpecValue) so that the same hardware reported by different tools or drivers is one
+// value. The benchmark list can be filtered by hardware (a benchmark matches when one of its runs
+// matches every spec filter) and can count the benchmarks per spec value (facets). Filters are
+// normalized the same way and match substrings, so gpu=9070 finds "amd radeon rx 9070 xt".
+
+// Spec facet and autocomplete limits
+const (
+	specFacetLimit           = 10 // Values per facet of the benchmark list
+	defaultSpecValuesLimit   = 10
+	maxSpecValuesLimit       = 50
+	maxSpecFilterValueLength = 100
+)
+
+// benchmarkSpecFields are the spec fields of runs: their parameter names and benchmark_runs columns.
+var benchmarkSpecFields = []struct{ name, column string }{
+	{"os", "spec_os"},
+	{"cpu", "spec_cpu"},
+	{"gpu", "spec_gpu"},
+	{"ram", "spec_ram"},
+	{"kernel", "spec_kernel"},
+	{"scheduler", "spec_scheduler"},
+	{"driver", "spec_driver"},
+}
+
+// specFieldColumn returns the benchmark_runs column of a spec field, or "" if there is none.
+func specFieldColumn(name string) string {
+	for _, field := range benchmarkSpecFields {
+		if field.name == name {
+			return field.column
+		}
+	}
+	return ""
+}
+
+// specFieldNames returns the names of all spec fields, for error messages and tool schemas.
+func specFieldNames() []string {
+	names := make([]string, len(benchmarkSpecFields))
+	for i, field := range benchmarkSpecFields {
+		names[i] = field.name
+	}
+	return names
+}
+
+// Parts of spec values that don't identify the hardware, matched after lowercasing: trademark
+// marks ("Intel(R) Core(TM)"), driver tags of GPU names ("AMD Radeon RX 9070 XT (RADV GFX1201)")
+// and CPU name suffixes ("8-Core Processor", "CPU @ 3.70GHz").
+var (
+	specTrademarkPattern = regexp.MustCompile(`\((?:r|tm)\)|[®™]`)
+	specDriverTagPattern = regexp.MustCompile(`\s*\((?:radv|aco|llvm|nvk|radeonsi|zink|drm|dg2)\b[^)]*\)$`)
+	specCPUSuffixPattern = regexp.MustCompile(`(?:\s+\d+-core)?\s+processor$|\s+cpu\s*@\s*[\d.]+\s*ghz$`)
+)
+
+// normalizeSpecValue lowercases a spec value, strips trademark marks, driver tags and CPU name
+// suffixes, and collapses runs of whitespace.
+func normalizeSpecValue(value string) string {
+	value = specTrademarkPattern.ReplaceAllString(strings.ToLower(value), " ")
+	value = strings.Join(strings.Fields(value), " ")
+	value = specDriverTagPattern.ReplaceAllString(value, "")
+	return specCPUSuffixPattern.ReplaceAllString(value, "")
+}
+
+// setRunSpecs sets the normalized specs of a run record.
+func setRunSpecs(record *BenchmarkRun, run *BenchmarkData) {
+	record.SpecOS = normalizeSpecValue(run.SpecOS)
+	record.SpecCPU = normalizeSpecValue(run.SpecCPU)
+	record.SpecGPU = normalizeSpecValue(run.SpecGPU)
+	record.SpecRAM = normalizeSpecValue(run.SpecRAM)
+	record.SpecKernel = normalizeSpecValue(run.SpecLinuxKernel)
+	record.SpecScheduler = normalizeSpecValue(run.SpecLinuxScheduler)
+	record.SpecDriver = normalizeSpecValue(run.SpecDriver)
+}
+
+// specFilter restricts the benchmark list to benchmarks with a run whose spec contains value.
+type specFilter struct {
+	column string
+	value  string
+}
+
+// parseSpecFilters reads the spec filters from their parameters (e.g. gpu, kernel) with get.
+func parseSpecFilters(get func(name string) string) ([]specFilter, error) {
+	var filters []specFilter
+	for _, field := range benchmarkSpecFields {
+		value := normalizeSpecValue(get(field.name))
+		if value == "" {
+			continue
+		}
+		if len(value) > maxSpecFilterValueLength {
+			return nil, fmt.Errorf("%s filter is too long (max %d characters)", field.name, maxSpecFilterValueLength)
+		}
+		filters = append(filters, specFilter{column: field.column, value: value})
+	}
+	return filters, nil
+}
+
+// condition returns the benchmark_runs condition of the filter and its value.
+func (f specFilter) condition() (string, interface{}) {
+	// Columns come from benchmarkSpecFields
+	return f.column + " LIKE ? ESCAPE '\\'", likeContainsPattern(f.value)
+}
+
+// runFilterConditions returns the benchmark_runs conditions of spec and stat filters with their values.
+func runFilterConditions(specs []specFilter, stats []statFilter) ([]string, []interface{}) {
+	var conditions []string
+	var values []interface{}
+	for _, filter := range specs {
+		condition, value := filter.condition()
+		conditions = append(conditions, condition)
+		values = append(values, value)
+	}
+	for _, filter := range stats {
+		condition, value := filter.condition()
+		conditions = append(conditions, condition)
+		values = append(values, value)
+	}
+	return conditions, values
+}
+
+// applyRunFilters restricts a benchmarks query to benchmarks with a run matching all filters.
+func applyRunFilters(query *gorm.DB, specs []specFilter, stats []statFilter) *gorm.DB {
+	conditions, values := runFilterConditions(specs, stats)
+	if len(conditions) == 0 {
+		return query
+	}
+	return query.Where("benchmarks.id IN (SELECT benchmark_id FROM benchmark_runs WHERE "+strings.Join(conditions, " AND ")+")", values...)
+}
+
+// parseSpecFacets validates requested facet fields, e.g. from facets=gpu,cpu.
+func parseSpecFacets(fields []string) ([]string, error) {
+	var facets []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if specFieldColumn(field) == "" {
+			return nil, fmt.Errorf("invalid facet %q (valid: %s)", field, strings.Join(specFieldNames(), ", "))
+		}
+		facets = append(facets, field)
+	}
+	return facets, nil
+}
+
+// SpecValueCount is a spec value with the number of benchmarks having a run with it.
+type SpecValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// specValueCounts counts the benchmarks selected by benchmarkIDs (a subquery of IDs) per value
+// of a spec field, most common first. A non-empty contains keeps values containing it.
+func specValueCounts(db *gorm.DB, field string, benchmarkIDs *gorm.DB, contains string, limit int) ([]SpecValueCount, error) {
+	column := specFieldColumn(field)
+	if column == "" {
+		return nil, fmt.Errorf("invalid spec field: %s", field)
+	}
+	query := db.Model(&BenchmarkRun{}).
+		Select(column+" AS value, COUNT(DISTINCT benchmark_id) AS count").
+		Where("benchmark_id IN (?)", benchmarkIDs).
+		Where(column + " != ''")
+	if contains != "" {
+		query = query.Where(column+" LIKE ? ESCAPE '\\'", likeContainsPattern(contains))
+	}
+	values := []SpecValueCount{}
+	if err := query.Group(column).Order("count DESC").Order(column).Limit(limit).Scan(&values).Error; err != nil {
+		return nil, fmt.Errorf("failed to count %s values: %w", field, err)
+	}
+	return values, nil
+}
+
+// specFacets counts the benchmarks selected by benchmarkIDs per value of each facet field.
+func specFacets(db *gorm.DB, facets []string, benchmarkIDs *gorm.DB) (map[string][]SpecValueCount, error) {
+	result := make(map[string][]SpecValueCount, len(facets))
+	for _, field := range facets {
+		values, err := specValueCounts(db, field, benchmarkIDs, "", specFacetLimit)
+		if err != nil {
+			return nil, err
+		}
+		result[field] = values
+	}
+	return result, nil
+}
+
+// HandleListSpecValues returns the most common values of a spec field containing q, for autocompletion
+func HandleListSpecValues(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field := c.Param("field")
+		if specFieldColumn(field) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid spec field (valid: %s)", strings.Join(specFieldNames(), ", "))})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSpecValuesLimit)))
+		if err != nil || limit < 1 || limit > maxSpecValuesLimit {
+			limit = defaultSpecValuesLimit
+		}
+		q := normalizeSpecValue(c.Query("q"))
+		if len(q) > maxSpecFilterValueLength {
+			q = q[:maxSpecFilterValueLength]
+		}
+
+		values, err := specValueCounts(db.DB, field, db.DB.Model(&Benchmark{}).Select("id"), q, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"field": field, "values": values})
+	}
+}
+
+// MigrateBenchmarkRunSpecs records the normalized specs of every run in benchmark_runs, reading
+// only the run headers of the .bin files.
+func MigrateBenchmarkRunSpecs(db *DBInstance) error {
+	var ids []uint
+	if err := db.DB.Model(&BenchmarkRun{}).Distinct("benchmark_id").Order("benchmark_id").Pluck("benchmark_id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for _, id := range ids {
+		runs, err := readBenchmarkRunHeaders(id)
+		if err != nil {
+			log.Printf("Benchmark %d: skipping run specs, failed to read run headers: %v", id, err)
+			continue
+		}
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			for i, run := range runs {
+				var record BenchmarkRun
+				setRunSpecs(&record, run)
+				if err := tx.Model(&BenchmarkRun{}).Where("benchmark_id = ? AND run_index = ?", id, i).
+					Select("spec_os", "spec_cpu", "spec_gpu", "spec_ram", "spec_kernel", "spec_scheduler", "spec_driver").
+					Updates(&record).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record run specs of benchmark %d: %w", id, err)
+		}
+	}
+	log.Printf("Recorded run specs of %d benchmark(s)", len(ids))
+	return nil
+}
+
+// readBenchmarkRunHeaders reads the label and specs of every run of a v3 .bin file, without any data.
+func readBenchmarkRunHeaders(benchmarkID uint) ([]*BenchmarkData, error) {
+	sf, index, err := openColumnarBenchmarkFile(benchmarkID)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Close()
+
+	runs := make([]*BenchmarkData, len(index.Runs))
+	for i := range index.Runs {
+		runs[i] = &BenchmarkData{}
+		if err := sf.readGob(index.Runs[i].Header, runs[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode header of run %d: %w", i, err)
+		}
+	}
+	return runs, nil
+}
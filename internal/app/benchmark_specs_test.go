@@ -0,0 +1,165 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func specRun(label, cpu, gpu, kernel, scheduler string) *BenchmarkData {
+	return &BenchmarkData{
+		Label: label, SpecOS: "Arch Linux", SpecCPU: cpu, SpecGPU: gpu, SpecLinuxKernel: kernel, SpecLinuxScheduler: scheduler,
+		DataFPS: []float64{60, 60}, DataFrameTime: []float64{16.7, 16.7},
+	}
+}
+
+func TestBenchmarkSpecFilters(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "specuser", false)
+	storeComparisonBenchmark(t, db, user.ID, "Radeon kernels", []*BenchmarkData{
+		specRun("6.16", "AMD Ryzen 7 9800X3D", "AMD Radeon  RX 9070 XT", "6.16", "eevdf"),
+		specRun("6.17", "AMD Ryzen 7 9800X3D", "AMD Radeon RX 9070 XT", "6.17", "bore"),
+	})
+	storeComparisonBenchmark(t, db, user.ID, "GeForce", []*BenchmarkData{
+		specRun("nvidia", "AMD Ryzen 7 9800X3D", "NVIDIA GeForce RTX 5080", "6.16", "bore"),
+	})
+	storeComparisonBenchmark(t, db, user.ID, "Intel", []*BenchmarkData{
+		specRun("intel", "Intel(R) Core(TM) i7-14700K", "AMD Radeon RX 9070 XT (RADV GFX1201)", "6.15", "eevdf"),
+	})
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks", HandleListBenchmarks(db))
+	router.GET("/api/specs/:field", HandleListSpecValues(db))
+
+	list := func(query string) (titles []string, facets map[string][]SpecValueCount) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/benchmarks?sort_by=title&sort_order=asc&"+query, nil))
+		var response struct {
+			Benchmarks []Benchmark                 `json:"benchmarks"`
+			Facets     map[string][]SpecValueCount `json:"facets"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: failed to list benchmarks: %d %s", query, w.Code, w.Body.String())
+		}
+		for _, b := range response.Benchmarks {
+			titles = append(titles, b.Title)
+		}
+		return titles, response.Facets
+	}
+
+	t.Run("filters", func(t *testing.T) {
+		tests := map[string][]string{
+			"gpu=rx+9070":                {"Intel", "Radeon kernels"},
+			"gpu=radeon+rx&cpu=9800x3d":  {"Radeon kernels"},
+			"kernel=6.16&scheduler=bore": {"GeForce"}, // Both must match the same run
+			"scheduler=eevdf&kernel=6.1": {"Intel", "Radeon kernels"},
+			"gpu=100%25":                 nil,
+			"cpu=Intel(R)+Core+i7":       {"Intel"},
+		}
+		for query, want := range tests {
+			if got, _ := list(query); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %v, got %v", query, want, got)
+			}
+		}
+	})
+
+	t.Run("facets", func(t *testing.T) {
+		_, facets := list("cpu=9800x3d&facets=gpu,kernel")
+		want := map[string][]SpecValueCount{
+			"gpu":    {{"amd radeon rx 9070 xt", 1}, {"nvidia geforce rtx 5080", 1}},
+			"kernel": {{"6.16", 2}, {"6.17", 1}},
+		}
+		if !reflect.DeepEqual(facets, want) {
+			t.Errorf("expected facets %v, got %v", want, facets)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/benchmarks?facets=gpu,color", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown facet, got %d", w.Code)
+		}
+	})
+
+	t.Run("autocomplete", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/specs/gpu?q=radeon", nil))
+		var response struct {
+			Values []SpecValueCount `json:"values"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to list spec values: %d %s", w.Code, w.Body.String())
+		}
+		// The driver tag of the Intel benchmark's GPU is dropped, so both benchmarks share one value
+		if !reflect.DeepEqual(response.Values, []SpecValueCount{{"amd radeon rx 9070 xt", 2}}) {
+			t.Errorf("unexpected values: %v", response.Values)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/specs/color", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown field, got %d", w.Code)
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		server := newMCPServer(db, "test", "")
+		out, err := server.toolListBenchmarks(json.RawMessage(`{"gpu": "geforce", "facets": ["scheduler"]}`))
+		if err != nil {
+			t.Fatalf("list_benchmarks failed: %v", err)
+		}
+		var response struct {
+			Benchmarks []Benchmark                 `json:"benchmarks"`
+			Facets     map[string][]SpecValueCount `json:"facets"`
+		}
+		if err := json.Unmarshal([]byte(out), &response); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		if len(response.Benchmarks) != 1 || !reflect.DeepEqual(response.Facets["scheduler"], []SpecValueCount{{"bore", 1}}) {
+			t.Errorf("unexpected result: %s", out)
+		}
+		if _, err := server.toolListBenchmarks(json.RawMessage(`{"facets": ["color"]}`)); err == nil {
+			t.Error("expected an unknown facet rejected")
+		}
+	})
+
+	t.Run("migration", func(t *testing.T) {
+		db.DB.Model(&BenchmarkRun{}).Where("1 = 1").Updates(map[string]interface{}{"spec_gpu": "", "spec_kernel": ""})
+		if err := MigrateBenchmarkRunSpecs(db); err != nil {
+			t.Fatalf("Migration failed: %v", err)
+		}
+		var run BenchmarkRun
+		db.DB.Where("run_index = ?", 1).First(&run)
+		if run.SpecGPU != "amd radeon rx 9070 xt" || run.SpecKernel != "6.17" || run.SpecScheduler != "bore" {
+			t.Errorf("unexpected run specs: %+v", run)
+		}
+	})
+}
+
+func TestNormalizeSpecValue(t *testing.T) {
+	tests := map[string]string{
+		"  AMD Radeon  RX 9070 XT ":                      "amd radeon rx 9070 xt",
+		"AMD Radeon RX 9070 XT (RADV GFX1201)":           "amd radeon rx 9070 xt",
+		"AMD Radeon Graphics (RADV GFX1103_R1)":          "amd radeon graphics",
+		"llvmpipe (LLVM 15.0.7, 256 bits)":               "llvmpipe",
+		"NVIDIA GeForce RTX 5080":                        "nvidia geforce rtx 5080",
+		"Intel(R) Arc(tm) A770 Graphics (DG2)":           "intel arc a770 graphics",
+		"Intel(R) Core(TM) i7-8700K CPU @ 3.70GHz":       "intel core i7-8700k",
+		"AMD Ryzen 7 7800X3D 8-Core Processor":           "amd ryzen 7 7800x3d",
+		"AMD Ryzen 9 5950X 16-Core Processor           ": "amd ryzen 9 5950x",
+		"6.17.1-arch1-1":                                 "6.17.1-arch1-1",
+		"Mesa 25.2.4 (git-1a2b3c)":                       "mesa 25.2.4 (git-1a2b3c)",
+	}
+	for value, want := range tests {
+		if got := normalizeSpecValue(value); got != want {
+			t.Errorf("%q: expected %q, got %q", value, want, got)
+		}
+	}
+}
@@ -3,12 +3,33 @@ package app
 import (
 	"math"
 	"sort"
+	"time"
 )
 
 const (
 	maxDownsamplePoints = 2000 // Default LTTB downsample threshold for series data
+
+	// statsAlgorithmVersion identifies the algorithms that produced a .stats file.
+	// Bump it whenever computePreCalculatedRun or ComputeBenchmarkStats output changes so the
+	// background recompute worker regenerates outdated files.
+	// Version history:
+	// - 0: Files written before versioning (no header)
+	// - 1: Run groups, efficiency metrics, bottleneck classification, throttling events, histograms
+	// - 2: Bottleneck classification confirms CPU-bound windows with CPU load (unknown state)
+	// - 3: Histograms are binned once, on the linear stats, sharing bins with the full-range histogram
+	statsAlgorithmVersion = 3
+
+	// statsFileMagic marks the version header at the start of a .stats file
+	statsFileMagic = "FSSTATS"
 )
 
+// statsFileHeader is the first value of a versioned .stats file.
+type statsFileHeader struct {
+	Magic            string
+	AlgorithmVersion int
+	ComputedAt       time.Time
+}
+
 // MetricStats holds pre-calculated statistics for a single metric.
 // JSON tags match the frontend expectations (camelCase for WebUI consumption).
 type MetricStats struct {
@@ -30,6 +51,11 @@ type MetricStats struct {
 	Variance float64  `json:"variance"`
 	Count    int      `json:"count"`
 	Density  [][2]int `json:"density"` // [[roundedValue, count], ...]
+
+	// Binned per metric (see histogramConfigs) from the raw values, so only the linear stats
+	// carry them. The full-range histogram is omitted when the trimmed one already includes the tails.
+	Histogram     *Histogram `json:"histogram,omitempty"`
+	HistogramFull *Histogram `json:"histogramFull,omitempty"`
 }
 
 // PreCalculatedRun stores all pre-calculated data for a single benchmark run.
@@ -43,7 +69,18 @@ type PreCalculatedRun struct {
 	SpecRAM            string `json:"specRAM"`
 	SpecLinuxKernel    string `json:"specLinuxKernel,omitempty"`
 	SpecLinuxScheduler string `json:"specLinuxScheduler,omitempty"`
+	SpecDriver         string `json:"specDriver,omitempty"`
 	TotalDataPoints    int    `json:"totalDataPoints"`
+	Group              string `json:"group,omitempty"` // Run group name (explicit or from the label pattern)
+
+	// Estimated energy used over the run in joules (0 if no power sensor is available)
+	TotalEnergy float64 `json:"totalEnergy,omitempty"`
+
+	// CPU/GPU-bound classification (nil if the run has no GPU load data)
+	Bottleneck *BottleneckAnalysis `json:"bottleneck,omitempty"`
+
+	// Windows where clocks fell while temperature or power sat at a limit
+	Throttling []ThrottlingEvent `json:"throttling,omitempty"`
 
 	// Downsampled series data for line charts (LTTB, max 2000 points)
 	// metric key -> [[index, value], ...]
@@ -420,6 +457,51 @@ var metricKeyToSnake = map[string]string{
 	"GPUPower":     "gpu_power",
 	"RAMUsed":      "ram_used",
 	"SwapUsed":     "swap_used",
+	"CPUClock":     "cpu_clock",
+
+	// Derived efficiency metrics
+	"FPSPerWatt":      "fps_per_watt",
+	"FPSPerWattTotal": "fps_per_watt_total",
+	"EnergyPerFrame":  "energy_per_frame",
+}
+
+// metricEntry pairs a camelCase metric key with its raw data.
+type metricEntry struct {
+	key  string
+	data []float64
+}
+
+// runMetrics returns the standard metrics of a run (everything except FPS, which is
+// derived from frametime when available).
+func runMetrics(run *BenchmarkData) []metricEntry {
+	return []metricEntry{
+		{"FrameTime", run.DataFrameTime},
+		{"CPULoad", run.DataCPULoad},
+		{"GPULoad", run.DataGPULoad},
+		{"CPUTemp", run.DataCPUTemp},
+		{"CPUPower", run.DataCPUPower},
+		{"GPUTemp", run.DataGPUTemp},
+		{"GPUCoreClock", run.DataGPUCoreClock},
+		{"GPUMemClock", run.DataGPUMemClock},
+		{"GPUVRAMUsed", run.DataGPUVRAMUsed},
+		{"GPUPower", run.DataGPUPower},
+		{"RAMUsed", run.DataRAMUsed},
+		{"SwapUsed", run.DataSwapUsed},
+		{"CPUClock", run.DataCPUClock},
+	}
+}
+
+// runMetricData returns the raw data of a run for a camelCase metric key (including raw FPS).
+func runMetricData(run *BenchmarkData, key string) []float64 {
+	if key == "FPS" {
+		return run.DataFPS
+	}
+	for _, m := range runMetrics(run) {
+		if m.key == key {
+			return m.data
+		}
+	}
+	return nil
 }
 
 // buildSeriesData creates indexed [index, value] pairs from a raw data slice.
@@ -457,34 +539,15 @@ func computePreCalculatedRun(run *BenchmarkData) *PreCalculatedRun {
 		SpecRAM:            run.SpecRAM,
 		SpecLinuxKernel:    run.SpecLinuxKernel,
 		SpecLinuxScheduler: run.SpecLinuxScheduler,
+		SpecDriver:         run.SpecDriver,
 		TotalDataPoints:    totalPoints,
 		Series:             make(map[string][][2]float64),
 		Stats:              make(map[string]*MetricStats),
 		StatsMangoHud:      make(map[string]*MetricStats),
 	}
 
-	type metricEntry struct {
-		key  string
-		data []float64
-	}
-
-	metrics := []metricEntry{
-		{"FrameTime", run.DataFrameTime},
-		{"CPULoad", run.DataCPULoad},
-		{"GPULoad", run.DataGPULoad},
-		{"CPUTemp", run.DataCPUTemp},
-		{"CPUPower", run.DataCPUPower},
-		{"GPUTemp", run.DataGPUTemp},
-		{"GPUCoreClock", run.DataGPUCoreClock},
-		{"GPUMemClock", run.DataGPUMemClock},
-		{"GPUVRAMUsed", run.DataGPUVRAMUsed},
-		{"GPUPower", run.DataGPUPower},
-		{"RAMUsed", run.DataRAMUsed},
-		{"SwapUsed", run.DataSwapUsed},
-	}
-
 	// Compute series + stats for each standard metric
-	for _, m := range metrics {
+	for _, m := range runMetrics(run) {
 		if len(m.data) == 0 {
 			continue
 		}
@@ -496,12 +559,15 @@ func computePreCalculatedRun(run *BenchmarkData) *PreCalculatedRun {
 		// Stats for both methods
 		result.Stats[m.key] = computeMetricStatsForMethod(m.data, "linear")
 		result.StatsMangoHud[m.key] = computeMetricStatsForMethod(m.data, "mangohud")
+		addHistograms(result.Stats[m.key], m.key, m.data)
 	}
 
 	// FPS: compute from frametime when available, otherwise from raw FPS data
 	if len(run.DataFrameTime) > 0 {
 		result.Stats["FPS"] = computeFPSFromFrametimeForMethod(run.DataFrameTime, "linear")
 		result.StatsMangoHud["FPS"] = computeFPSFromFrametimeForMethod(run.DataFrameTime, "mangohud")
+		fpsValues := frametimeToFPS(run.DataFrameTime)
+		addHistograms(result.Stats["FPS"], "FPS", fpsValues)
 
 		// Series uses raw FPS data if available
 		if len(run.DataFPS) > 0 {
@@ -514,8 +580,27 @@ func computePreCalculatedRun(run *BenchmarkData) *PreCalculatedRun {
 
 		result.Stats["FPS"] = computeMetricStatsForMethod(run.DataFPS, "linear")
 		result.StatsMangoHud["FPS"] = computeMetricStatsForMethod(run.DataFPS, "mangohud")
+		addHistograms(result.Stats["FPS"], "FPS", run.DataFPS)
+	}
+
+	// Derived efficiency metrics (only when a power sensor reports real values)
+	if eff := computeEfficiencySeries(run); eff != nil {
+		result.TotalEnergy = math.Round(eff.TotalEnergy*100) / 100
+		for _, m := range efficiencyMetrics(eff) {
+			values, points := validSamples(m.data)
+			if len(values) == 0 {
+				continue
+			}
+			result.Series[m.key] = downsampleLTTB(points, maxDownsamplePoints)
+			result.Stats[m.key] = computeMetricStatsForMethod(values, "linear")
+			result.StatsMangoHud[m.key] = computeMetricStatsForMethod(values, "mangohud")
+			addHistograms(result.Stats[m.key], m.key, values)
+		}
 	}
 
+	result.Bottleneck = computeBottleneckAnalysis(run)
+	result.Throttling = computeThrottlingEvents(run)
+
 	return result
 }
 
@@ -530,7 +615,12 @@ func PreCalculatedRunToMCPSummary(run *PreCalculatedRun, maxPoints int) *Benchma
 		SpecRAM:            run.SpecRAM,
 		SpecLinuxKernel:    run.SpecLinuxKernel,
 		SpecLinuxScheduler: run.SpecLinuxScheduler,
+		SpecDriver:         run.SpecDriver,
 		TotalDataPoints:    run.TotalDataPoints,
+		Group:              run.Group,
+		TotalEnergy:        run.TotalEnergy,
+		Bottleneck:         bottleneckToMCP(run.Bottleneck),
+		Throttling:         throttlingToMCP(run.Throttling),
 		Metrics:            make(map[string]*MetricSummary),
 	}
 
@@ -540,25 +630,7 @@ func PreCalculatedRunToMCPSummary(run *PreCalculatedRun, maxPoints int) *Benchma
 			continue
 		}
 
-		ms := &MetricSummary{
-			Min:      stats.Min,
-			Max:      stats.Max,
-			Avg:      stats.Avg,
-			Median:   stats.Median,
-			P01:      stats.P01,
-			P05:      stats.P05,
-			P10:      stats.P10,
-			P25:      stats.P25,
-			P75:      stats.P75,
-			P90:      stats.P90,
-			P95:      stats.P95,
-			P97:      stats.P97,
-			P99:      stats.P99,
-			IQR:      stats.IQR,
-			StdDev:   stats.StdDev,
-			Variance: stats.Variance,
-			Count:    stats.Count,
-		}
+		ms := metricStatsToMCP(stats)
 
 		// Include downsampled data if requested
 		if maxPoints > 0 {
@@ -595,3 +667,26 @@ func PreCalculatedRunToMCPSummary(run *PreCalculatedRun, maxPoints int) *Benchma
 
 	return summary
 }
+
+// metricStatsToMCP converts pre-calculated metric stats to the MCP MetricSummary format (without data points).
+func metricStatsToMCP(stats *MetricStats) *MetricSummary {
+	return &MetricSummary{
+		Min:      stats.Min,
+		Max:      stats.Max,
+		Avg:      stats.Avg,
+		Median:   stats.Median,
+		P01:      stats.P01,
+		P05:      stats.P05,
+		P10:      stats.P10,
+		P25:      stats.P25,
+		P75:      stats.P75,
+		P90:      stats.P90,
+		P95:      stats.P95,
+		P97:      stats.P97,
+		P99:      stats.P99,
+		IQR:      stats.IQR,
+		StdDev:   stats.StdDev,
+		Variance: stats.Variance,
+		Count:    stats.Count,
+	}
+}
@@ -0,0 +1,366 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// .stats files are sectioned files (see sectioned_file.go) so clients fetching a few metrics of a
+// few runs don't pay for decoding the whole benchmark. Per run, the layout has separate sections
+// for the run header, each metric's series, and the stats and distributions of each method.
+// Files written before this layout are a single gob stream and are still readable.
+const statsSectionedMagic = "FSSTATS2" // Trailer magic (8 bytes)
+
+// Stats calculation methods, as stored in .stats sections
+const (
+	statsMethodLinear   = "linear"
+	statsMethodMangoHud = "mangohud"
+)
+
+// errStatsRunNotFound is returned when a selection names a run index the benchmark doesn't have.
+var errStatsRunNotFound = errors.New("run index out of range")
+
+// statsIndex describes the sections of a .stats file.
+type statsIndex struct {
+	Header statsFileHeader
+	Groups fileSection // gob []*RunGroupStats
+	Runs   []statsRunIndex
+}
+
+// statsRunIndex describes the sections of one run.
+type statsRunIndex struct {
+	Base    fileSection            // gob PreCalculatedRun without series and stats
+	Series  map[string]fileSection // metric -> float64 pairs (index, value, index, value, ...)
+	Stats   map[string]fileSection // method -> gob map[string]*MetricStats without distributions
+	Density map[string]fileSection // method -> gob map[string]*metricDistribution
+}
+
+// metricDistribution holds the binned fields of MetricStats, stored apart from the scalar stats.
+type metricDistribution struct {
+	Density       [][2]int
+	Histogram     *Histogram
+	HistogramFull *Histogram
+}
+
+// StatsSelection selects the parts of pre-calculated stats to read.
+type StatsSelection struct {
+	Runs    []int    // Run indices; nil selects all runs
+	Metrics []string // camelCase metric keys; nil selects all metrics
+	Methods []string // statsMethodLinear and/or statsMethodMangoHud; nil selects both
+	Series  bool     // Include downsampled series
+	Stats   bool     // Include scalar stats (min, max, avg, percentiles, ...)
+	Density bool     // Include density and histograms
+}
+
+// fullStatsSelection selects everything, matching the complete .stats contents.
+func fullStatsSelection() StatsSelection {
+	return StatsSelection{Series: true, Stats: true, Density: true}
+}
+
+func (sel StatsSelection) wantsMetric(key string) bool {
+	return sel.Metrics == nil || slices.Contains(sel.Metrics, key)
+}
+
+func (sel StatsSelection) wantsMethod(method string) bool {
+	return sel.Methods == nil || slices.Contains(sel.Methods, method)
+}
+
+// runIndices resolves the selected run indices against the number of runs.
+func (sel StatsSelection) runIndices(runCount int) ([]int, error) {
+	if sel.Runs == nil {
+		indices := make([]int, runCount)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices, nil
+	}
+	for _, i := range sel.Runs {
+		if i < 0 || i >= runCount {
+			return nil, fmt.Errorf("%w: %d (0-%d)", errStatsRunNotFound, i, runCount-1)
+		}
+	}
+	return sel.Runs, nil
+}
+
+func statsFileName(benchmarkID uint) string {
+	return benchmarkFileName(benchmarkID, benchmarkFileStats)
+}
+
+// writeStatsFile writes pre-calculated stats and run groups in the sectioned layout.
+func writeStatsFile(out io.Writer, stats []*PreCalculatedRun, groups []*RunGroupStats) error {
+	sw, err := newSectionWriter(out)
+	if err != nil {
+		return err
+	}
+	defer sw.Close()
+
+	index := statsIndex{
+		Header: statsFileHeader{
+			Magic:            statsFileMagic,
+			AlgorithmVersion: statsAlgorithmVersion,
+			ComputedAt:       time.Now().UTC(),
+		},
+		Runs: make([]statsRunIndex, len(stats)),
+	}
+	for i, run := range stats {
+		if index.Runs[i], err = writeStatsRun(sw, run); err != nil {
+			return fmt.Errorf("failed to write stats of run %d: %w", i, err)
+		}
+	}
+	if index.Groups, err = sw.writeGob(groups); err != nil {
+		return fmt.Errorf("failed to write run groups: %w", err)
+	}
+	if err := sw.finish(index, statsSectionedMagic); err != nil {
+		return fmt.Errorf("failed to finish stats file: %w", err)
+	}
+	return nil
+}
+
+// writeStatsRun splits a run into its sections.
+func writeStatsRun(sw *sectionWriter, run *PreCalculatedRun) (statsRunIndex, error) {
+	idx := statsRunIndex{
+		Series:  make(map[string]fileSection, len(run.Series)),
+		Stats:   make(map[string]fileSection, 2),
+		Density: make(map[string]fileSection, 2),
+	}
+
+	base := *run
+	base.Series, base.Stats, base.StatsMangoHud = nil, nil, nil
+	var err error
+	if idx.Base, err = sw.writeGob(&base); err != nil {
+		return idx, err
+	}
+
+	for key, points := range run.Series {
+		flat := make([]float64, 0, len(points)*2)
+		for _, p := range points {
+			flat = append(flat, p[0], p[1])
+		}
+		if idx.Series[key], err = sw.writeFloats(flat); err != nil {
+			return idx, err
+		}
+	}
+
+	for method, metrics := range map[string]map[string]*MetricStats{
+		statsMethodLinear:   run.Stats,
+		statsMethodMangoHud: run.StatsMangoHud,
+	} {
+		scalars := make(map[string]*MetricStats, len(metrics))
+		distributions := make(map[string]*metricDistribution, len(metrics))
+		for key, ms := range metrics {
+			scalar := *ms
+			scalar.Density, scalar.Histogram, scalar.HistogramFull = nil, nil, nil
+			scalars[key] = &scalar
+			distributions[key] = &metricDistribution{Density: ms.Density, Histogram: ms.Histogram, HistogramFull: ms.HistogramFull}
+		}
+		if idx.Stats[method], err = sw.writeGob(scalars); err != nil {
+			return idx, err
+		}
+		if idx.Density[method], err = sw.writeGob(distributions); err != nil {
+			return idx, err
+		}
+	}
+	return idx, nil
+}
+
+// openStatsFile opens a sectioned .stats file. Returns errNotSectioned for the legacy gob layout.
+func openStatsFile(benchmarkID uint) (*sectionedFile, *statsIndex, error) {
+	index := &statsIndex{}
+	sf, err := openSectionedFile(statsFileName(benchmarkID), statsSectionedMagic, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(index.Runs) > maxRunsPerBenchmark {
+		sf.Close()
+		return nil, nil, fmt.Errorf("stats file contains too many runs: %d", len(index.Runs))
+	}
+	return sf, index, nil
+}
+
+// readStatsSelection reads the selected parts of a benchmark's pre-calculated stats.
+// Run groups are returned for every read; legacy gob files are decoded fully and then filtered.
+func readStatsSelection(benchmarkID uint, sel StatsSelection) ([]*PreCalculatedRun, []*RunGroupStats, error) {
+	sf, index, err := openStatsFile(benchmarkID)
+	if errors.Is(err, errNotSectioned) {
+		_, stats, groups, legacyErr := readStatsFile(benchmarkID, false)
+		if legacyErr != nil {
+			return nil, nil, legacyErr
+		}
+		return filterStatsSelection(stats, groups, sel)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer sf.Close()
+
+	indices, err := sel.runIndices(len(index.Runs))
+	if err != nil {
+		return nil, nil, err
+	}
+	runs := make([]*PreCalculatedRun, len(indices))
+	for i, runIndex := range indices {
+		if runs[i], err = readStatsRun(sf, &index.Runs[runIndex], sel); err != nil {
+			return nil, nil, fmt.Errorf("failed to read run %d: %w", runIndex, err)
+		}
+	}
+
+	var groups []*RunGroupStats
+	if err := sf.readGob(index.Groups, &groups); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode run groups: %w", err)
+	}
+	return runs, filterGroupSelection(groups, sel), nil
+}
+
+// readStatsRun reassembles a run from the selected sections.
+func readStatsRun(sf *sectionedFile, idx *statsRunIndex, sel StatsSelection) (*PreCalculatedRun, error) {
+	run := &PreCalculatedRun{}
+	if err := sf.readGob(idx.Base, run); err != nil {
+		return nil, fmt.Errorf("failed to decode run header: %w", err)
+	}
+
+	if sel.Series {
+		run.Series = make(map[string][][2]float64)
+		for key, section := range idx.Series {
+			if !sel.wantsMetric(key) {
+				continue
+			}
+			flat, err := sf.readFloats(section)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s series: %w", key, err)
+			}
+			points := make([][2]float64, len(flat)/2)
+			for i := range points {
+				points[i] = [2]float64{flat[i*2], flat[i*2+1]}
+			}
+			run.Series[key] = points
+		}
+	}
+
+	if !sel.Stats && !sel.Density {
+		return run, nil
+	}
+	for _, method := range []string{statsMethodLinear, statsMethodMangoHud} {
+		if !sel.wantsMethod(method) {
+			continue
+		}
+		metrics := make(map[string]*MetricStats)
+		if sel.Stats {
+			var scalars map[string]*MetricStats
+			if err := sf.readGob(idx.Stats[method], &scalars); err != nil {
+				return nil, fmt.Errorf("failed to decode %s stats: %w", method, err)
+			}
+			for key, ms := range scalars {
+				if sel.wantsMetric(key) {
+					metrics[key] = ms
+				}
+			}
+		}
+		if sel.Density {
+			var distributions map[string]*metricDistribution
+			if err := sf.readGob(idx.Density[method], &distributions); err != nil {
+				return nil, fmt.Errorf("failed to decode %s density: %w", method, err)
+			}
+			for key, d := range distributions {
+				if !sel.wantsMetric(key) {
+					continue
+				}
+				ms, ok := metrics[key]
+				if !ok {
+					ms = &MetricStats{}
+					metrics[key] = ms
+				}
+				ms.Density, ms.Histogram, ms.HistogramFull = d.Density, d.Histogram, d.HistogramFull
+			}
+		}
+		if method == statsMethodLinear {
+			run.Stats = metrics
+		} else {
+			run.StatsMangoHud = metrics
+		}
+	}
+	return run, nil
+}
+
+// filterStatsSelection applies a selection to fully decoded stats (legacy gob files).
+func filterStatsSelection(stats []*PreCalculatedRun, groups []*RunGroupStats, sel StatsSelection) ([]*PreCalculatedRun, []*RunGroupStats, error) {
+	indices, err := sel.runIndices(len(stats))
+	if err != nil {
+		return nil, nil, err
+	}
+	runs := make([]*PreCalculatedRun, len(indices))
+	for i, runIndex := range indices {
+		run := *stats[runIndex]
+		run.Series = nil
+		if sel.Series {
+			run.Series = make(map[string][][2]float64)
+			for key, points := range stats[runIndex].Series {
+				if sel.wantsMetric(key) {
+					run.Series[key] = points
+				}
+			}
+		}
+		run.Stats = filterMetricStats(stats[runIndex].Stats, sel, statsMethodLinear)
+		run.StatsMangoHud = filterMetricStats(stats[runIndex].StatsMangoHud, sel, statsMethodMangoHud)
+		runs[i] = &run
+	}
+	return runs, filterGroupSelection(groups, sel), nil
+}
+
+// filterMetricStats applies the metric, method and include parts of a selection to a stats map.
+func filterMetricStats(metrics map[string]*MetricStats, sel StatsSelection, method string) map[string]*MetricStats {
+	if !sel.wantsMethod(method) || (!sel.Stats && !sel.Density) {
+		return nil
+	}
+	filtered := make(map[string]*MetricStats, len(metrics))
+	for key, ms := range metrics {
+		if !sel.wantsMetric(key) {
+			continue
+		}
+		copied := &MetricStats{}
+		if sel.Stats {
+			*copied = *ms
+		}
+		if sel.Density {
+			copied.Density, copied.Histogram, copied.HistogramFull = ms.Density, ms.Histogram, ms.HistogramFull
+		} else {
+			copied.Density, copied.Histogram, copied.HistogramFull = nil, nil, nil
+		}
+		filtered[key] = copied
+	}
+	return filtered
+}
+
+// filterGroupSelection applies the metric and method parts of a selection to run group aggregates.
+func filterGroupSelection(groups []*RunGroupStats, sel StatsSelection) []*RunGroupStats {
+	if sel.Metrics == nil && sel.Methods == nil {
+		return groups
+	}
+	filtered := make([]*RunGroupStats, len(groups))
+	for i, g := range groups {
+		copied := *g
+		copied.Metrics = make(map[string]*GroupMetricStats)
+		for key, m := range g.Metrics {
+			if sel.wantsMetric(key) {
+				copied.Metrics[key] = m
+			}
+		}
+		copied.Pooled = filterMetricStats(g.Pooled, StatsSelection{Metrics: sel.Metrics, Methods: sel.Methods, Stats: true, Density: true}, statsMethodLinear)
+		copied.PooledMangoHud = filterMetricStats(g.PooledMangoHud, StatsSelection{Metrics: sel.Metrics, Methods: sel.Methods, Stats: true, Density: true}, statsMethodMangoHud)
+		filtered[i] = &copied
+	}
+	return filtered
+}
+
+// statsFileUpToDate reports whether a benchmark's .stats file uses the sectioned layout and the
+// current stats algorithm version.
+func statsFileUpToDate(benchmarkID uint) bool {
+	sf, index, err := openStatsFile(benchmarkID)
+	if err != nil {
+		return false
+	}
+	sf.Close()
+	return index.Header.AlgorithmVersion >= statsAlgorithmVersion
+}
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// selectionRuns returns two grouped runs with frametime, GPU load and power data.
+func selectionRuns() []*BenchmarkData {
+	return []*BenchmarkData{
+		{Label: "A #1", DataFrameTime: []float64{10, 12, 11, 30}, DataGPULoad: []float64{90, 95, 97, 99}, DataGPUPower: []float64{150, 160, 155, 170}},
+		{Label: "A #2", DataFrameTime: []float64{9, 10, 11, 12}, DataGPULoad: []float64{80, 85, 90, 95}, DataGPUPower: []float64{140, 150, 150, 160}},
+	}
+}
+
+// writeLegacyStatsFile writes stats as a single gob stream, the layout used before sectioned files.
+func writeLegacyStatsFile(t *testing.T, benchmarkID uint, stats []*PreCalculatedRun, groups []*RunGroupStats) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(benchmarksDir, fmt.Sprintf("%d.stats", benchmarkID)))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	bufWriter := bufio.NewWriter(file)
+	enc, err := zstd.NewWriter(bufWriter)
+	if err != nil {
+		t.Fatalf("Failed to create zstd writer: %v", err)
+	}
+	gobEncoder := gob.NewEncoder(enc)
+	if err := gobEncoder.Encode(statsFileHeader{Magic: statsFileMagic, AlgorithmVersion: statsAlgorithmVersion}); err != nil {
+		t.Fatalf("Failed to encode header: %v", err)
+	}
+	if err := gobEncoder.Encode(stats); err != nil {
+		t.Fatalf("Failed to encode stats: %v", err)
+	}
+	if err := gobEncoder.Encode(statsFileGroups{Groups: groups}); err != nil {
+		t.Fatalf("Failed to encode groups: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Failed to close zstd writer: %v", err)
+	}
+	if err := bufWriter.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Failed to close file: %v", err)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestStatsFileSelection(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+	preCalc, groups := ComputeBenchmarkStats(selectionRuns(), `\s*#\d+$`)
+	if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+	writeLegacyStatsFile(t, 2, preCalc, groups)
+
+	for _, id := range []uint{1, 2} {
+		t.Run(fmt.Sprintf("benchmark %d", id), func(t *testing.T) {
+			t.Run("full selection matches stored stats", func(t *testing.T) {
+				runs, gotGroups, err := RetrievePreCalculatedStatsWithGroups(id)
+				if err != nil {
+					t.Fatalf("Failed to read stats: %v", err)
+				}
+				if mustJSON(t, runs) != mustJSON(t, preCalc) || mustJSON(t, gotGroups) != mustJSON(t, groups) {
+					t.Error("full read differs from the stored stats")
+				}
+			})
+
+			t.Run("selected metrics, method and run", func(t *testing.T) {
+				sel := StatsSelection{Runs: []int{1}, Metrics: []string{"FPS"}, Methods: []string{statsMethodLinear}, Stats: true}
+				runs, gotGroups, err := RetrievePreCalculatedStatsSelection(id, sel)
+				if err != nil {
+					t.Fatalf("Failed to read selection: %v", err)
+				}
+				if len(runs) != 1 || runs[0].Label != "A #2" {
+					t.Fatalf("expected only run 1, got %d runs", len(runs))
+				}
+				run := runs[0]
+				if len(run.Stats) != 1 || run.Stats["FPS"].Avg != preCalc[1].Stats["FPS"].Avg {
+					t.Errorf("expected FPS stats only, got %v", run.Stats)
+				}
+				if run.Stats["FPS"].Density != nil || run.Stats["FPS"].Histogram != nil {
+					t.Error("density should not be included")
+				}
+				if run.Series != nil || run.StatsMangoHud != nil {
+					t.Error("series and mangohud stats should not be included")
+				}
+				if len(gotGroups) != 1 || len(gotGroups[0].Metrics) != 1 || gotGroups[0].PooledMangoHud != nil {
+					t.Errorf("expected groups filtered to FPS and linear, got %+v", gotGroups[0])
+				}
+			})
+
+			t.Run("density without stats", func(t *testing.T) {
+				sel := StatsSelection{Metrics: []string{"GPULoad"}, Density: true, Series: true}
+				runs, _, err := RetrievePreCalculatedStatsSelection(id, sel)
+				if err != nil {
+					t.Fatalf("Failed to read selection: %v", err)
+				}
+				ms := runs[0].Stats["GPULoad"]
+				if ms == nil || ms.Density == nil || ms.Histogram == nil || ms.Avg != 0 {
+					t.Errorf("expected density with zero scalars, got %+v", ms)
+				}
+				if len(runs[0].Series) != 1 || len(runs[0].Series["GPULoad"]) != len(preCalc[0].Series["GPULoad"]) {
+					t.Errorf("expected only the GPU load series, got %d series", len(runs[0].Series))
+				}
+			})
+
+			t.Run("run out of range", func(t *testing.T) {
+				if _, err := RetrievePreCalculatedStatsRun(id, 2); err == nil {
+					t.Error("expected error for run index 2")
+				}
+			})
+		})
+	}
+
+	if !statsFileUpToDate(1) || statsFileUpToDate(2) {
+		t.Error("expected only the sectioned stats file to be up to date")
+	}
+	if version, err := ReadStatsAlgorithmVersion(2); err != nil || version != statsAlgorithmVersion {
+		t.Errorf("expected legacy file version %d, got %d (%v)", statsAlgorithmVersion, version, err)
+	}
+}
+
+func TestHandleGetBenchmarkDataSelection(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "selectionuser", false)
+	benchmark := &Benchmark{UserID: user.ID, Title: "Selection"}
+	db.DB.Create(benchmark)
+	preCalc, groups := ComputeBenchmarkStats(selectionRuns(), "")
+	if err := StorePreCalculatedStats(preCalc, groups, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.GET("/api/benchmarks/:id/data", HandleGetBenchmarkData(db))
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		runs   int
+	}{
+		{"everything", "", http.StatusOK, 2},
+		{"snake_case metrics", "metrics=fps,frame_time&methods=mangohud&include=stats", http.StatusOK, 2},
+		{"duplicate runs", "runs=1,1", http.StatusOK, 1},
+		{"unknown metric", "metrics=fps,bogus", http.StatusBadRequest, 0},
+		{"invalid method", "methods=median", http.StatusBadRequest, 0},
+		{"invalid include", "include=raw", http.StatusBadRequest, 0},
+		{"invalid run", "runs=a", http.StatusBadRequest, 0},
+		{"run out of range", "runs=0,5", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			url := fmt.Sprintf("/api/benchmarks/%d/data?%s", benchmark.ID, tt.query)
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, http.NoBody))
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var runs []map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+				t.Fatalf("Failed to unmarshal: %v", err)
+			}
+			if len(runs) != tt.runs {
+				t.Errorf("expected %d runs, got %d", tt.runs, len(runs))
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	url := fmt.Sprintf("/api/benchmarks/%d/data?metrics=FPS&methods=mangohud&include=stats", benchmark.ID)
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, http.NoBody))
+	var runs []*PreCalculatedRun
+	if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if runs[0].Series != nil || runs[0].Stats != nil || len(runs[0].StatsMangoHud) != 1 {
+		t.Errorf("expected only mangohud FPS stats, got %+v", runs[0])
+	}
+	if runs[0].StatsMangoHud["FPS"].Density != nil {
+		t.Error("density should not be included")
+	}
+}
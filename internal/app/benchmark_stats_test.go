@@ -142,6 +142,44 @@ func TestComputeDensityData(t *testing.T) {
 	})
 }
 
+func TestAddHistograms(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		stats := &MetricStats{}
+		addHistograms(stats, "GPULoad", nil)
+		if stats.Histogram != nil || stats.HistogramFull != nil {
+			t.Errorf("expected no histograms, got %+v", stats.Histogram)
+		}
+	})
+
+	t.Run("at boundaries", func(t *testing.T) {
+		stats := &MetricStats{P01: 10, P97: 20}
+		addHistograms(stats, "GPULoad", []float64{1, 10, 10, 20, 20, 30})
+		want := [][2]float64{{10, 2}, {20, 2}}
+		if len(stats.Histogram.Bins) != len(want) {
+			t.Fatalf("expected %v, got %v", want, stats.Histogram.Bins)
+		}
+		for i, bin := range want {
+			if stats.Histogram.Bins[i] != bin {
+				t.Errorf("bin %d: got %v, want %v", i, stats.Histogram.Bins[i], bin)
+			}
+		}
+		if stats.HistogramFull == nil || len(stats.HistogramFull.Bins) != 4 {
+			t.Errorf("expected full-range histogram with the tails, got %+v", stats.HistogramFull)
+		}
+	})
+
+	t.Run("bins are floored to the bin width", func(t *testing.T) {
+		stats := &MetricStats{P01: 10, P97: 11}
+		addHistograms(stats, "GPULoad", []float64{10.3, 10.7, 10.5, 11})
+		if len(stats.Histogram.Bins) != 2 || stats.Histogram.Bins[0] != [2]float64{10, 3} {
+			t.Errorf("expected [[10 3] [11 1]], got %v", stats.Histogram.Bins)
+		}
+		if stats.HistogramFull != nil {
+			t.Error("full-range histogram should be omitted when nothing is trimmed")
+		}
+	})
+}
+
 func TestComputeMetricStatsForMethod(t *testing.T) {
 	t.Run("empty returns nil", func(t *testing.T) {
 		result := computeMetricStatsForMethod(nil, "linear")
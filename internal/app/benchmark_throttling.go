@@ -0,0 +1,274 @@
+package app
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	throttleWindowMs       = 1000.0 // Elapsed time covered by one detection window
+	throttleMinDurationMs  = 2000.0 // Shorter throttling events are discarded as noise
+	throttleClockDrop      = 0.08   // Minimum clock drop (vs. the run's p95) to count as throttled
+	throttleMinGPULoad     = 80.0   // GPU load (%) below which lower clocks are idle, not throttling
+	throttleTempMargin     = 3.0    // Degrees below the run's peak temperature still counted as "at the limit"
+	throttleTempFlatness   = 3.0    // Maximum temperature swing (degrees) within a plateau window
+	throttlePowerRatio     = 0.95   // Power (vs. the run's p95) counted as "at the limit"
+	throttlePowerFlatness  = 0.10   // Maximum relative power swing within a plateau window
+	throttleModerateDrop   = 15.0   // Clock drop (%) from which an event is "moderate"
+	throttleSevereDrop     = 30.0   // Clock drop (%) from which an event is "severe"
+	throttleMaxEventsInRun = 100    // Cap on stored events per run
+)
+
+// Throttling causes and severities
+const (
+	ThrottleCauseThermal = "thermal"
+	ThrottleCausePower   = "power"
+	ThrottleCauseBoth    = "thermal+power"
+
+	ThrottleSeverityMinor    = "minor"
+	ThrottleSeverityModerate = "moderate"
+	ThrottleSeveritySevere   = "severe"
+)
+
+// ThrottlingEvent is a time range where a component's clock fell while its temperature
+// or power draw sat at a plateau.
+type ThrottlingEvent struct {
+	Component    string  `json:"component"` // "gpu" or "cpu"
+	Cause        string  `json:"cause"`     // "thermal", "power" or "thermal+power"
+	Severity     string  `json:"severity"`  // "minor", "moderate" or "severe"
+	Start        int     `json:"start"`     // First sample index
+	End          int     `json:"end"`       // Sample index after the last sample
+	StartMs      float64 `json:"startMs"`
+	EndMs        float64 `json:"endMs"`
+	ClockDropPct float64 `json:"clockDropPct"`       // Lowest window clock vs. the run's p95 clock
+	FPSDropPct   float64 `json:"fpsDropPct"`         // Average FPS during the event vs. the whole run
+	PeakTemp     float64 `json:"peakTemp,omitempty"` // Highest temperature reading during the event
+	AvgPower     float64 `json:"avgPower,omitempty"` // Mean power draw during the event
+}
+
+// throttleSignals groups the sensor columns used to detect throttling of one component.
+type throttleSignals struct {
+	component string
+	clock     []float64
+	temp      []float64
+	power     []float64
+	load      []float64 // Optional; windows below throttleMinGPULoad are skipped
+}
+
+// computeThrottlingEvents detects GPU and CPU throttling events in a run.
+// Returns nil if no component has clock data alongside temperature or power data.
+func computeThrottlingEvents(run *BenchmarkData) []ThrottlingEvent {
+	n := len(run.DataFrameTime)
+	if n == 0 {
+		n = len(run.DataFPS)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	frametimes := runFrametimes(run, n)
+	windows := splitTimeWindows(frametimes, throttleWindowMs)
+
+	var events []ThrottlingEvent
+	for _, sig := range []throttleSignals{
+		{component: "gpu", clock: run.DataGPUCoreClock, temp: run.DataGPUTemp, power: run.DataGPUPower, load: run.DataGPULoad},
+		{component: "cpu", clock: run.DataCPUClock, temp: run.DataCPUTemp, power: run.DataCPUPower},
+	} {
+		events = append(events, detectThrottling(sig, frametimes, windows)...)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Start < events[j].Start
+	})
+	if len(events) > throttleMaxEventsInRun {
+		events = events[:throttleMaxEventsInRun]
+	}
+	return events
+}
+
+// detectThrottling finds throttled windows for one component and merges them into events.
+func detectThrottling(sig throttleSignals, frametimes []float64, windows []timeWindow) []ThrottlingEvent {
+	hasTemp := sensorAvailable(sig.temp)
+	hasPower := sensorAvailable(sig.power)
+	if !sensorAvailable(sig.clock) || (!hasTemp && !hasPower) {
+		return nil
+	}
+
+	clockRef := clockReference(sig.clock)
+	tempPeak := nonZeroPercentile(sig.temp, 99)
+	powerRef := nonZeroPercentile(sig.power, 95)
+	hasLoad := sensorAvailable(sig.load)
+
+	var events []ThrottlingEvent
+	var minClock float64
+	var powerSum float64
+	var powerWindows int
+
+	for _, w := range windows {
+		cause := ""
+		clock, ok := windowMean(sig.clock, w.start, w.end)
+		if ok && clock <= (1-throttleClockDrop)*clockRef {
+			// Low load explains low clocks on its own
+			if load, ok := windowMean(sig.load, w.start, w.end); !hasLoad || (ok && load >= throttleMinGPULoad) {
+				cause = throttleCause(sig, w, hasTemp, hasPower, tempPeak, powerRef)
+			}
+		}
+
+		if cause == "" {
+			continue
+		}
+
+		last := len(events) - 1
+		extend := last >= 0 && events[last].End == w.start && events[last].Cause == cause
+		power, _ := windowMean(sig.power, w.start, w.end)
+		_, maxTemp, _ := windowRange(sig.temp, w.start, w.end)
+		if extend {
+			e := &events[last]
+			e.End = w.end
+			e.EndMs = w.endMs
+			e.PeakTemp = math.Max(e.PeakTemp, maxTemp)
+			minClock = math.Min(minClock, clock)
+		} else {
+			if last >= 0 {
+				finishThrottlingEvent(&events[last], clockRef, minClock, powerSum, powerWindows, frametimes)
+			}
+			events = append(events, ThrottlingEvent{
+				Component: sig.component,
+				Cause:     cause,
+				Start:     w.start,
+				End:       w.end,
+				StartMs:   w.startMs,
+				EndMs:     w.endMs,
+				PeakTemp:  maxTemp,
+			})
+			minClock = clock
+			powerSum, powerWindows = 0, 0
+		}
+		if hasPower {
+			powerSum += power
+			powerWindows++
+		}
+	}
+	if len(events) > 0 {
+		finishThrottlingEvent(&events[len(events)-1], clockRef, minClock, powerSum, powerWindows, frametimes)
+	}
+
+	// Drop short events
+	kept := events[:0]
+	for _, e := range events {
+		if e.EndMs-e.StartMs >= throttleMinDurationMs {
+			e.StartMs = math.Round(e.StartMs*100) / 100
+			e.EndMs = math.Round(e.EndMs*100) / 100
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// throttleCause returns why a low-clock window is throttled, or "" if neither temperature
+// nor power sits at a plateau near its limit.
+func throttleCause(sig throttleSignals, w timeWindow, hasTemp, hasPower bool, tempPeak, powerRef float64) string {
+	thermal := false
+	if hasTemp {
+		minTemp, maxTemp, ok := windowRange(sig.temp, w.start, w.end)
+		mean, _ := windowMean(sig.temp, w.start, w.end)
+		thermal = ok && mean >= tempPeak-throttleTempMargin && maxTemp-minTemp <= throttleTempFlatness
+	}
+
+	power := false
+	if hasPower {
+		minPower, maxPower, ok := windowRange(sig.power, w.start, w.end)
+		mean, _ := windowMean(sig.power, w.start, w.end)
+		power = ok && mean > 0 && mean >= throttlePowerRatio*powerRef && (maxPower-minPower) <= throttlePowerFlatness*mean
+	}
+
+	switch {
+	case thermal && power:
+		return ThrottleCauseBoth
+	case thermal:
+		return ThrottleCauseThermal
+	case power:
+		return ThrottleCausePower
+	}
+	return ""
+}
+
+// finishThrottlingEvent fills in the severity and impact figures of a merged event.
+func finishThrottlingEvent(e *ThrottlingEvent, clockRef, minClock, powerSum float64, powerWindows int, frametimes []float64) {
+	if clockRef > 0 {
+		e.ClockDropPct = math.Round((clockRef-minClock)/clockRef*10000) / 100
+	}
+	switch {
+	case e.ClockDropPct >= throttleSevereDrop:
+		e.Severity = ThrottleSeveritySevere
+	case e.ClockDropPct >= throttleModerateDrop:
+		e.Severity = ThrottleSeverityModerate
+	default:
+		e.Severity = ThrottleSeverityMinor
+	}
+
+	if powerWindows > 0 {
+		e.AvgPower = math.Round(powerSum/float64(powerWindows)*100) / 100
+	}
+
+	runFPS := averageFPS(frametimes, 0, len(frametimes))
+	eventFPS := averageFPS(frametimes, e.Start, e.End)
+	if runFPS > 0 && eventFPS < runFPS {
+		e.FPSDropPct = math.Round((runFPS-eventFPS)/runFPS*10000) / 100
+	}
+}
+
+// averageFPS returns frames per second over samples [start, end) from their total frametime.
+func averageFPS(frametimes []float64, start, end int) float64 {
+	var total float64
+	for _, ft := range frametimes[start:end] {
+		total += ft
+	}
+	if total <= 0 {
+		return 0
+	}
+	return float64(end-start) * 1000 / total
+}
+
+// windowRange returns the min and max of data[start:end], clipped to the data length.
+func windowRange(data []float64, start, end int) (float64, float64, bool) {
+	if end > len(data) {
+		end = len(data)
+	}
+	if start >= end {
+		return 0, 0, false
+	}
+	minVal, maxVal := data[start], data[start]
+	for _, v := range data[start:end] {
+		minVal = math.Min(minVal, v)
+		maxVal = math.Max(maxVal, v)
+	}
+	return minVal, maxVal, true
+}
+
+// throttlingToMCP converts throttling events to the MCP ThrottlingEventEntry format.
+func throttlingToMCP(events []ThrottlingEvent) []ThrottlingEventEntry {
+	if len(events) == 0 {
+		return nil
+	}
+	entries := make([]ThrottlingEventEntry, len(events))
+	for i, e := range events {
+		entries[i] = ThrottlingEventEntry{
+			Component:    e.Component,
+			Cause:        e.Cause,
+			Severity:     e.Severity,
+			StartIndex:   e.Start,
+			EndIndex:     e.End,
+			StartMs:      e.StartMs,
+			EndMs:        e.EndMs,
+			ClockDropPct: e.ClockDropPct,
+			FPSDropPct:   e.FPSDropPct,
+			PeakTemp:     e.PeakTemp,
+			AvgPower:     e.AvgPower,
+		}
+	}
+	return entries
+}
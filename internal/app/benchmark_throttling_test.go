@@ -0,0 +1,131 @@
+package app
+
+import (
+	"testing"
+)
+
+// throttledGPURun builds a 10 s GPU-bound run (100 FPS) whose clock drops from 2000 to
+// 1500 MHz for the last 4 s while the temperature sits at 90 °C.
+func throttledGPURun() *BenchmarkData {
+	n := 1000
+	run := &BenchmarkData{
+		DataFrameTime:    repeatValue(10, n),
+		DataGPULoad:      repeatValue(99, n),
+		DataGPUCoreClock: repeatValue(2000, n),
+		DataGPUTemp:      make([]float64, n),
+	}
+	for i := 0; i < n; i++ {
+		run.DataGPUTemp[i] = 70 + float64(i)*0.02 // heating up to 90 °C
+		if i >= 600 {
+			run.DataGPUTemp[i] = 90
+			run.DataGPUCoreClock[i] = 1500
+			run.DataFrameTime[i] = 12.5
+		}
+	}
+	return run
+}
+
+func TestComputeThrottlingEvents(t *testing.T) {
+	t.Run("thermal throttling", func(t *testing.T) {
+		events := computeThrottlingEvents(throttledGPURun())
+		if len(events) != 1 {
+			t.Fatalf("expected 1 event, got %d: %+v", len(events), events)
+		}
+		e := events[0]
+		if e.Component != "gpu" || e.Cause != ThrottleCauseThermal {
+			t.Errorf("unexpected event: %+v", e)
+		}
+		if e.Start != 600 || e.End != 1000 {
+			t.Errorf("unexpected range: %d-%d", e.Start, e.End)
+		}
+		if e.ClockDropPct != 25 || e.Severity != ThrottleSeverityModerate {
+			t.Errorf("unexpected severity: %v%% %s", e.ClockDropPct, e.Severity)
+		}
+		if e.PeakTemp != 90 || e.FPSDropPct <= 0 {
+			t.Errorf("unexpected impact: peak %v, fps drop %v", e.PeakTemp, e.FPSDropPct)
+		}
+	})
+
+	t.Run("power limit", func(t *testing.T) {
+		run := throttledGPURun()
+		run.DataGPUTemp = nil
+		run.DataGPUPower = make([]float64, len(run.DataFrameTime))
+		for i := range run.DataGPUPower {
+			run.DataGPUPower[i] = 120
+			if i >= 600 {
+				run.DataGPUPower[i] = 180
+			}
+		}
+		events := computeThrottlingEvents(run)
+		if len(events) != 1 || events[0].Cause != ThrottleCausePower || events[0].AvgPower != 180 {
+			t.Fatalf("expected one power event, got %+v", events)
+		}
+	})
+
+	t.Run("low load is not throttling", func(t *testing.T) {
+		run := throttledGPURun()
+		for i := 600; i < len(run.DataGPULoad); i++ {
+			run.DataGPULoad[i] = 40
+		}
+		if events := computeThrottlingEvents(run); len(events) != 0 {
+			t.Errorf("expected no events at low load, got %+v", events)
+		}
+	})
+
+	t.Run("clock drop without plateau", func(t *testing.T) {
+		run := throttledGPURun()
+		for i := range run.DataGPUTemp {
+			run.DataGPUTemp[i] = 60 + float64(i%20) // noisy, no plateau
+		}
+		if events := computeThrottlingEvents(run); len(events) != 0 {
+			t.Errorf("expected no events without a plateau, got %+v", events)
+		}
+	})
+
+	t.Run("cpu clock", func(t *testing.T) {
+		n := 1000
+		run := &BenchmarkData{
+			DataFrameTime: repeatValue(10, n),
+			DataCPUClock:  repeatValue(4800, n),
+			DataCPUTemp:   repeatValue(75, n),
+		}
+		for i := 200; i < 700; i++ {
+			run.DataCPUClock[i] = 3000
+			run.DataCPUTemp[i] = 95
+		}
+		events := computeThrottlingEvents(run)
+		if len(events) != 1 || events[0].Component != "cpu" || events[0].Severity != ThrottleSeveritySevere {
+			t.Fatalf("expected one severe CPU event, got %+v", events)
+		}
+	})
+}
+
+func TestThrottlingMCPAndCPUClockParsing(t *testing.T) {
+	result := computePreCalculatedRun(throttledGPURun())
+	if len(result.Throttling) != 1 {
+		t.Fatalf("expected throttling on pre-calculated run, got %+v", result.Throttling)
+	}
+	summary := PreCalculatedRunToMCPSummary(result, 0)
+	if len(summary.Throttling) != 1 || summary.Throttling[0].EndIndex != 1000 {
+		t.Errorf("unexpected MCP throttling: %+v", summary.Throttling)
+	}
+
+	content := "os,cpu,gpu,ram,kernel,driver,cpuscheduler\n" +
+		"Linux,CPU,GPU,16384,6.1,,\n" +
+		"fps,frametime,cpu_mhz\n" +
+		"60,16.6,4800\n" +
+		"59,16.9,4750\n"
+	data, err := ReadBenchmarkCSVContent(content, "cpu clock")
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(data.DataCPUClock) != 2 || data.DataCPUClock[1] != 4750 {
+		t.Errorf("expected CPU clock data, got %v", data.DataCPUClock)
+	}
+	if s := computePreCalculatedRun(data).Stats["CPUClock"]; s == nil || s.Max != 4800 {
+		t.Errorf("expected CPUClock stats, got %+v", s)
+	}
+	if metricKeyToSnake["CPUClock"] != "cpu_clock" {
+		t.Error("CPUClock missing from MCP metric keys")
+	}
+}
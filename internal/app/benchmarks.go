@@ -1,11 +1,15 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,132 +30,55 @@ func HandleListBenchmarks(db *DBInstance) gin.HandlerFunc {
 		}
 
 		var benchmarks []Benchmark
-		query := db.DB.Preload("User")
+		var filter benchmarkListFilter
 
 		// Optional filters
 		if userIDStr := c.Query("user_id"); userIDStr != "" {
 			if parsedUID, parseErr := strconv.ParseUint(userIDStr, 10, 64); parseErr == nil {
-				query = query.Where("user_id = ?", parsedUID)
+				filter.userID = uint(parsedUID)
 			} else {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
 				return
 			}
 		}
-		if search := c.Query("search"); search != "" {
+		if gameParam := c.Query("game"); gameParam != "" {
+			gameID, err := resolveGame(db.DB, gameParam)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			filter.gameID = gameID
+			filter.none = filter.none || gameID == 0
+		}
+		if searchParam := c.Query("search"); searchParam != "" {
 			// Get search fields from query parameter (comma-separated)
 			// Default to title,description to match frontend defaults
-			searchFieldsParam := c.DefaultQuery("search_fields", "title,description")
-			searchFields := strings.Split(searchFieldsParam, ",")
-
-			// Build a map of enabled search fields - validate against allowlist
-			validFields := map[string]bool{
-				"title":          true,
-				"description":    true,
-				"user":           true,
-				"run_name":       true,
-				"specifications": true,
-			}
-			enabledFields := make(map[string]bool)
-			for _, field := range searchFields {
-				field = strings.TrimSpace(field)
-				// Only add valid fields to prevent SQL injection
-				if validFields[field] {
-					enabledFields[field] = true
-				}
-			}
-
-			// Limit search query length and keyword count to prevent SQL complexity DoS
-			const maxSearchLength = 200
-			const maxKeywords = 10
-			if len(search) > maxSearchLength {
-				search = search[:maxSearchLength]
-			}
-
-			// Split search query into keywords
-			keywords := strings.Fields(search)
-			if len(keywords) > maxKeywords {
-				keywords = keywords[:maxKeywords]
-			}
-
-			// For each keyword, search in enabled fields
-			// All keywords must match (AND logic), but each keyword can match any enabled field (OR logic)
-			for _, keyword := range keywords {
-				keyword = strings.TrimSpace(keyword)
-				// Enforce minimum keyword length to avoid full-table LIKE scans
-				if len(keyword) < 3 {
-					continue
-				}
-				if keyword != "" {
-					// Escape LIKE wildcards to prevent pattern injection
-					escapedKeyword := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(keyword)
-
-					// Build OR conditions for this keyword across enabled fields
-					var orConditions []string
-					var orValues []interface{}
-
-					if enabledFields["title"] {
-						orConditions = append(orConditions, "benchmarks.title LIKE ? ESCAPE '\\'")
-						orValues = append(orValues, "%"+escapedKeyword+"%")
-					}
-					if enabledFields["description"] {
-						orConditions = append(orConditions, "benchmarks.description LIKE ? ESCAPE '\\'")
-						orValues = append(orValues, "%"+escapedKeyword+"%")
-					}
-					if enabledFields["user"] {
-						orConditions = append(orConditions, "EXISTS (SELECT 1 FROM users WHERE users.id = benchmarks.user_id AND users.username LIKE ? ESCAPE '\\')")
-						orValues = append(orValues, "%"+escapedKeyword+"%")
-					}
-					if enabledFields["run_name"] {
-						orConditions = append(orConditions, "benchmarks.run_names LIKE ? ESCAPE '\\'")
-						orValues = append(orValues, "%"+escapedKeyword+"%")
-					}
-					if enabledFields["specifications"] {
-						orConditions = append(orConditions, "benchmarks.specifications LIKE ? ESCAPE '\\'")
-						orValues = append(orValues, "%"+escapedKeyword+"%")
-					}
-
-					// Only apply search if at least one field is enabled
-					if len(orConditions) > 0 {
-						orClause := strings.Join(orConditions, " OR ")
-						query = query.Where(orClause, orValues...)
-					}
-				}
-			}
+			searchFields := strings.Split(c.DefaultQuery("search_fields", "title,description"), ",")
+			filter.search = parseBenchmarkSearch(searchParam, searchFields)
 		}
-
-		// Sorting
-		sortBy := c.DefaultQuery("sort_by", "created_at")
-		sortOrder := c.DefaultQuery("sort_order", "desc")
-
-		// Validate sort_by to prevent SQL injection
-		// Use explicit column names to avoid any possibility of injection
-		var orderClause string
-		switch sortBy {
-		case "title":
-			orderClause = "title"
-		case "created_at":
-			orderClause = "created_at"
-		case "updated_at":
-			orderClause = "updated_at"
-		default:
-			orderClause = "created_at"
+		filter.specs, err = parseSpecFilters(c.Query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		// Validate sort_order - only allow specific values
-		var ascending bool
-		if sortOrder == "asc" {
-			ascending = true
-		} else {
-			ascending = false
+		filter.stats, err = parseStatFilters(c.Query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		// Use GORM's safe order method with validated inputs
-		if ascending {
-			query = query.Order(orderClause + " ASC")
-		} else {
-			query = query.Order(orderClause + " DESC")
+		var facets []string
+		if facetsParam := c.Query("facets"); facetsParam != "" {
+			if facets, err = parseSpecFacets(strings.Split(facetsParam, ",")); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 		}
 
+		query := filter.apply(db.DB.Preload("User").Preload("Game"))
+
+		// Sorting (sort_by and sort_order are validated by orderBenchmarks)
+		query = orderBenchmarks(query, c.DefaultQuery("sort_by", "created_at"), c.DefaultQuery("sort_order", "desc"), &filter)
+
 		// Get total count
 		var total int64
 		if err := query.Model(&Benchmark{}).Count(&total).Error; err != nil {
@@ -165,6 +92,14 @@ func HandleListBenchmarks(db *DBInstance) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 			return
 		}
+		if filter.search != nil {
+			if err := filter.search.highlight(db.DB, benchmarks); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+		if err := loadRunStats(db.DB, benchmarks); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
 
 		// Populate run count and labels for each benchmark concurrently
 		// Thread safety: Each goroutine writes to a different index in the slice.
@@ -191,13 +126,22 @@ func HandleListBenchmarks(db *DBInstance) gin.HandlerFunc {
 		// Calculate total pages
 		totalPages := int((total + int64(perPage) - 1) / int64(perPage))
 
-		c.JSON(http.StatusOK, gin.H{
+		response := gin.H{
 			"benchmarks":  benchmarks,
 			"page":        page,
 			"per_page":    perPage,
 			"total":       total,
 			"total_pages": totalPages,
-		})
+		}
+		if len(facets) > 0 {
+			counts, err := specFacets(db.DB, facets, filter.apply(db.DB.Model(&Benchmark{})).Select("benchmarks.id"))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+			response["facets"] = counts
+		}
+		c.JSON(http.StatusOK, response)
 	}
 }
 
@@ -212,7 +156,7 @@ func HandleGetBenchmark(db *DBInstance) gin.HandlerFunc {
 		}
 
 		var benchmark Benchmark
-		if dbErr := db.DB.Preload("User").First(&benchmark, benchmarkID).Error; dbErr != nil {
+		if dbErr := db.DB.Preload("User").Preload("Game").First(&benchmark, benchmarkID).Error; dbErr != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
 			return
 		}
@@ -224,6 +168,7 @@ func HandleGetBenchmark(db *DBInstance) gin.HandlerFunc {
 			benchmark.RunLabels = labels
 		}
 
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
 		c.JSON(http.StatusOK, benchmark)
 	}
 }
@@ -245,15 +190,111 @@ func HandleGetBenchmarkData(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		sel, err := parseStatsSelection(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Popular benchmarks are served from the serialized response cached by the last request
+		withGroups := c.Query("groups") == "true"
+		variant := statsCacheVariant(sel, withGroups)
+		cached, token, ok := benchmarkCache.lookup(uint(benchmarkID), benchmarkCacheStats, variant)
+		if ok {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", cached.([]byte))
+			return
+		}
+
 		// Serve pre-calculated stats (no raw data sent to frontend)
-		stats, err := RetrievePreCalculatedStats(uint(benchmarkID))
+		stats, groups, err := RetrievePreCalculatedStatsSelection(uint(benchmarkID), sel)
+		if errors.Is(err, errStatsRunNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "pre-calculated stats not available"})
 			return
 		}
 
-		c.JSON(http.StatusOK, stats)
+		// Run group aggregates are opt-in to keep the default response a plain array of runs
+		var response any = stats
+		if withGroups {
+			if groups == nil {
+				groups = []*RunGroupStats{}
+			}
+			response = gin.H{"runs": stats, "groups": groups}
+		}
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode stats"})
+			return
+		}
+		benchmarkCache.store(uint(benchmarkID), benchmarkCacheStats, variant, token, body, int64(len(body)))
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+// parseStatsSelection reads the metrics, methods, runs and include query parameters of the data
+// endpoint. Each is a comma-separated list; omitted parameters select everything.
+func parseStatsSelection(c *gin.Context) (StatsSelection, error) {
+	sel := fullStatsSelection()
+
+	if metrics := c.Query("metrics"); metrics != "" {
+		for _, metric := range strings.Split(metrics, ",") {
+			metric = strings.TrimSpace(metric)
+			key := ""
+			for camel, snake := range metricKeyToSnake {
+				if metric == camel || metric == snake {
+					key = camel
+				}
+			}
+			if key == "" {
+				return sel, fmt.Errorf("unknown metric: %s", metric)
+			}
+			sel.Metrics = append(sel.Metrics, key)
+		}
+	}
+
+	if methods := c.Query("methods"); methods != "" {
+		sel.Methods = []string{}
+		for _, method := range strings.Split(methods, ",") {
+			method = strings.TrimSpace(method)
+			if method != statsMethodLinear && method != statsMethodMangoHud {
+				return sel, fmt.Errorf("methods must be linear and/or mangohud")
+			}
+			sel.Methods = append(sel.Methods, method)
+		}
+	}
+
+	if runs := c.Query("runs"); runs != "" {
+		for _, run := range strings.Split(runs, ",") {
+			runIndex, err := strconv.Atoi(strings.TrimSpace(run))
+			if err != nil || runIndex < 0 {
+				return sel, fmt.Errorf("invalid run index: %s", run)
+			}
+			sel.Runs = append(sel.Runs, runIndex)
+		}
+		slices.Sort(sel.Runs)
+		sel.Runs = slices.Compact(sel.Runs)
 	}
+
+	if include := c.Query("include"); include != "" {
+		sel.Series, sel.Stats, sel.Density = false, false, false
+		for _, part := range strings.Split(include, ",") {
+			switch strings.TrimSpace(part) {
+			case "series":
+				sel.Series = true
+			case "stats":
+				sel.Stats = true
+			case "density":
+				sel.Density = true
+			default:
+				return sel, fmt.Errorf("include must be series, stats and/or density")
+			}
+		}
+	}
+	return sel, nil
 }
 
 // HandleCreateBenchmark creates a new benchmark
@@ -292,14 +333,20 @@ func HandleCreateBenchmark(db *DBInstance) gin.HandlerFunc {
 		}
 
 		var req struct {
-			Title       string `form:"title" binding:"required,max=100"`
-			Description string `form:"description" binding:"max=5000"`
+			Title           string `form:"title" binding:"required,max=100"`
+			Description     string `form:"description" binding:"max=5000"`
+			RunGroupPattern string `form:"run_group_pattern" binding:"max=200"`
+			GameID          *uint  `form:"game_id"` // 0 for none; suggested when omitted
 		}
 
 		if err := c.ShouldBind(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
 			return
 		}
+		if err := ValidateRunGroupPattern(req.RunGroupPattern); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		form, err := c.MultipartForm()
 		if err != nil {
@@ -344,51 +391,80 @@ func HandleCreateBenchmark(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		if !checkStorageQuota(c, db, uid, 1, int64(len(benchmarkData)), int64(totalLines)) {
+			return
+		}
+
+		gameID, err := uploadGame(db.DB, req.GameID, req.Title, benchmarkData)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errGameNotFound) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Create benchmark record
 		benchmark := Benchmark{
-			UserID:      uid,
-			Title:       req.Title,
-			Description: req.Description,
+			UserID:          uid,
+			Title:           req.Title,
+			Description:     req.Description,
+			RunGroupPattern: req.RunGroupPattern,
+			GameID:          gameID,
+			Revision:        1,
 		}
 
+		// The database row and the files must both be in a backup snapshot, or neither
+		defer beginStorageMutation()()
+
 		if err := db.DB.Create(&benchmark).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create benchmark"})
 			return
 		}
 
-		// Store benchmark data
-		if err := StoreBenchmarkData(benchmarkData, benchmark.ID); err != nil {
+		// Flag runs that were uploaded before, then record the new runs ahead of their files
+		duplicates, err := findDuplicateRuns(db, benchmark.ID, benchmarkData, 0)
+		if err != nil {
+			fmt.Printf("Warning: failed to check benchmark %d for duplicate runs: %v\n", benchmark.ID, err)
+		}
+		// Pre-calculate stats for fast serving; they are stored with the benchmark data
+		preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+		if err := syncBenchmarkRuns(db, benchmark.ID, benchmarkData, preCalc); err != nil {
 			db.DB.Delete(&benchmark)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store benchmark data"})
 			return
 		}
 
-		// Pre-calculate and store stats for fast serving
-		preCalc := ComputePreCalculatedRuns(benchmarkData)
-		if err := StorePreCalculatedStats(preCalc, benchmark.ID); err != nil {
-			// Log but don't fail - stats can be regenerated on demand
-			fmt.Printf("Warning: failed to store pre-calculated stats for benchmark %d: %v\n", benchmark.ID, err)
+		if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, benchmark.ID); err != nil {
+			releaseBenchmarkRuns(db, benchmark.ID)
+			db.DB.Delete(&benchmark)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store benchmark data"})
+			return
 		}
 
 		// Extract and update searchable metadata
 		runNames, specifications := ExtractSearchableMetadata(benchmarkData)
 		benchmark.RunNames = runNames
 		benchmark.Specifications = specifications
+		setBenchmarkUsage(&benchmark, benchmarkData)
 		if err := db.DB.Save(&benchmark).Error; err != nil {
 			// Log error but don't fail - this is just for search optimization
 			fmt.Printf("Warning: failed to update searchable metadata for benchmark %d (%s): %v\n", benchmark.ID, benchmark.Title, err)
 		}
 
 		// Reload benchmark with User to return complete data
-		if err := db.DB.Preload("User").First(&benchmark, benchmark.ID).Error; err != nil {
+		if err := db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load benchmark"})
 			return
 		}
 
 		// Log benchmark creation
 		usernameStr := GetUsernameFromContext(c)
+		recordBenchmarkRevision(db, &benchmark, uid, usernameStr, revisionActionCreated)
 		LogBenchmarkCreated(uid, usernameStr, benchmark.ID, benchmark.Title, len(benchmarkData))
 
+		benchmark.Duplicates = duplicates
 		c.JSON(http.StatusCreated, benchmark)
 	}
 }
@@ -419,6 +495,9 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		// Serialize with other mutations of this benchmark
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
 		var benchmark Benchmark
 		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
@@ -431,10 +510,17 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		if !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+
 		var req struct {
-			Title       string         `json:"title" binding:"max=100"`
-			Description string         `json:"description" binding:"max=5000"`
-			Labels      map[int]string `json:"labels"` // Map of index to new label
+			Title           string         `json:"title" binding:"max=100"`
+			Description     string         `json:"description" binding:"max=5000"`
+			Labels          map[int]string `json:"labels"`            // Map of index to new label
+			Groups          map[int]string `json:"groups"`            // Map of index to explicit run group ("" clears it)
+			RunGroupPattern *string        `json:"run_group_pattern"` // Label pattern for grouping runs ("" disables it)
+			GameID          *uint          `json:"game_id"`           // Game of the benchmark (0 unlinks it)
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -447,6 +533,10 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "too many labels provided"})
 			return
 		}
+		if len(req.Groups) > maxRunsPerBenchmark {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "too many groups provided"})
+			return
+		}
 
 		// Track what fields were changed for audit logging
 		var changes []string
@@ -458,10 +548,39 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 			benchmark.Description = req.Description
 			changes = append(changes, "description")
 		}
+		patternChanged := false
+		if req.RunGroupPattern != nil && *req.RunGroupPattern != benchmark.RunGroupPattern {
+			if err := ValidateRunGroupPattern(*req.RunGroupPattern); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			benchmark.RunGroupPattern = *req.RunGroupPattern
+			changes = append(changes, "run_group_pattern")
+			patternChanged = true
+		}
+		if req.GameID != nil {
+			changed, err := setBenchmarkGame(db.DB, &benchmark, *req.GameID)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, errGameNotFound) {
+					status = http.StatusBadRequest
+				}
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			if changed {
+				changes = append(changes, "game")
+			}
+		}
 
-		// Update labels if provided
-		if len(req.Labels) > 0 {
-			changes = append(changes, "labels")
+		// Update labels and/or groups if provided
+		if len(req.Labels) > 0 || len(req.Groups) > 0 || patternChanged {
+			if len(req.Labels) > 0 {
+				changes = append(changes, "labels")
+			}
+			if len(req.Groups) > 0 {
+				changes = append(changes, "groups")
+			}
 			benchmarkData, err := RetrieveBenchmarkData(uint(benchmarkID))
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve benchmark data"})
@@ -479,15 +598,27 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 				}
 			}
 
-			// Store updated data
-			if err := StoreBenchmarkData(benchmarkData, uint(benchmarkID)); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update labels"})
-				return
+			// Update explicit run groups
+			for idx, newGroup := range req.Groups {
+				if idx >= 0 && idx < len(benchmarkData) {
+					if len(newGroup) > maxStringLength {
+						c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("group for run %d exceeds maximum length of %d characters", idx, maxStringLength)})
+						return
+					}
+					benchmarkData[idx].Group = strings.TrimSpace(newGroup)
+				}
 			}
 
-			// Recompute pre-calculated stats after label changes
-			preCalc := ComputePreCalculatedRuns(benchmarkData)
-			if storeErr := StorePreCalculatedStats(preCalc, uint(benchmarkID)); storeErr != nil {
+			// Recompute pre-calculated stats after label/group changes
+			preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+
+			// Store updated data with its stats (a pattern change alone only affects the stats)
+			if len(req.Labels) > 0 || len(req.Groups) > 0 {
+				if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, uint(benchmarkID)); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update labels"})
+					return
+				}
+			} else if storeErr := StorePreCalculatedStats(preCalc, groups, uint(benchmarkID)); storeErr != nil {
 				fmt.Printf("Warning: failed to update pre-calculated stats for benchmark %d: %v\n", benchmarkID, storeErr)
 			}
 
@@ -495,24 +626,34 @@ func HandleUpdateBenchmark(db *DBInstance) gin.HandlerFunc {
 			runNames, specifications := ExtractSearchableMetadata(benchmarkData)
 			benchmark.RunNames = runNames
 			benchmark.Specifications = specifications
+			setBenchmarkUsage(&benchmark, benchmarkData)
+
+			if len(req.Labels) > 0 {
+				if err := syncBenchmarkRuns(db, uint(benchmarkID), benchmarkData, preCalc); err != nil {
+					fmt.Printf("Warning: %v\n", err)
+				}
+			}
 
 		}
 
+		benchmark.Revision++
 		if err := db.DB.Save(&benchmark).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update benchmark"})
 			return
 		}
 
 		// Reload benchmark with User to return complete data
-		if err := db.DB.Preload("User").First(&benchmark, benchmark.ID).Error; err != nil {
+		if err := db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load benchmark"})
 			return
 		}
 
 		// Log benchmark update
 		usernameStr := GetUsernameFromContext(c)
+		recordBenchmarkRevision(db, &benchmark, uid, usernameStr, revisionActionUpdated)
 		LogBenchmarkUpdated(uid, usernameStr, benchmark.ID, benchmark.Title, changes)
 
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
 		c.JSON(http.StatusOK, benchmark)
 	}
 }
@@ -543,6 +684,9 @@ func HandleDeleteBenchmark(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		// Serialize with other mutations so a concurrent one can't rewrite the deleted files
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
 		var benchmark Benchmark
 		if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
@@ -555,25 +699,27 @@ func HandleDeleteBenchmark(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		// If-Match is optional for deletion, but a stale revision is still rejected
+		if c.GetHeader("If-Match") != "" && !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+
 		// Store title for audit log
 		title := benchmark.Title
 
-		// Delete all benchmark files (raw data, metadata, stats)
-		if err := DeleteBenchmarkData(benchmark.ID); err != nil {
-			// Log error but continue with database deletion
-			fmt.Printf("Warning: failed to delete benchmark data files: %v\n", err)
-		}
-
+		// Soft delete only: the files are kept until the trash is purged
 		if err := db.DB.Delete(&benchmark).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete benchmark"})
 			return
 		}
+		// Trashed benchmarks aren't served, so their cached payloads only take space
+		benchmarkCache.invalidate(benchmark.ID)
 
 		// Log benchmark deletion
 		usernameStr := GetUsernameFromContext(c)
 		LogBenchmarkDeleted(uid, usernameStr, benchmark.ID, title)
 
-		c.JSON(http.StatusOK, gin.H{"message": "benchmark deleted"})
+		c.JSON(http.StatusOK, gin.H{"message": "benchmark deleted", "purge_at": time.Now().UTC().Add(trashRetention)})
 	}
 }
 
@@ -643,6 +789,9 @@ func HandleDeleteBenchmarkRun(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		// Serialize with other mutations of this benchmark
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
 		// Verify benchmark exists and check ownership
 		var benchmark Benchmark
 		if dbErr := db.DB.First(&benchmark, benchmarkID).Error; dbErr != nil {
@@ -656,6 +805,10 @@ func HandleDeleteBenchmarkRun(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		if !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+
 		// Retrieve benchmark data
 		benchmarkData, err := RetrieveBenchmarkData(uint(benchmarkID))
 		if err != nil {
@@ -678,27 +831,38 @@ func HandleDeleteBenchmarkRun(db *DBInstance) gin.HandlerFunc {
 		// Capture run label before deletion for audit log
 		runLabel := benchmarkData[idx].Label
 
+		// Keep the run in the trash so it can be restored
+		trashed, err := trashRun(db, benchmark.ID, idx, benchmarkData[idx])
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to move run to the trash"})
+			return
+		}
+
 		// Remove the run at the specified index
 		benchmarkData = append(benchmarkData[:idx], benchmarkData[idx+1:]...)
 
-		// Store updated data
-		if err := StoreBenchmarkData(benchmarkData, uint(benchmarkID)); err != nil {
+		// Recompute pre-calculated stats after deleting run and store them with the updated data
+		preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+		if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, uint(benchmarkID)); err != nil {
+			if discardErr := discardTrashedRun(db, trashed); discardErr != nil {
+				fmt.Printf("Warning: %v\n", discardErr)
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update benchmark data"})
 			return
 		}
 
-		// Recompute pre-calculated stats after deleting run
-		preCalc := ComputePreCalculatedRuns(benchmarkData)
-		if storeErr := StorePreCalculatedStats(preCalc, uint(benchmarkID)); storeErr != nil {
-			fmt.Printf("Warning: failed to update pre-calculated stats for benchmark %d: %v\n", benchmarkID, storeErr)
-		}
-
 		// Update searchable metadata after deleting run
 		runNames, specifications := ExtractSearchableMetadata(benchmarkData)
 		benchmark.RunNames = runNames
 		benchmark.Specifications = specifications
+		setBenchmarkUsage(&benchmark, benchmarkData)
+		if err := syncBenchmarkRuns(db, uint(benchmarkID), benchmarkData, preCalc); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
 
-		// Update the benchmark's UpdatedAt timestamp
+		// Update the benchmark's revision and UpdatedAt timestamp
+		benchmark.Revision++
 		if err := db.DB.Save(&benchmark).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update benchmark"})
 			return
@@ -706,9 +870,11 @@ func HandleDeleteBenchmarkRun(db *DBInstance) gin.HandlerFunc {
 
 		// Log run deletion
 		usernameStr := GetUsernameFromContext(c)
+		recordBenchmarkRevision(db, &benchmark, uid, usernameStr, revisionActionRunDeleted)
 		LogBenchmarkRunDeleted(uid, usernameStr, benchmark.ID, benchmark.Title, idx, runLabel)
 
-		c.JSON(http.StatusOK, gin.H{"message": "run deleted successfully"})
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
+		c.JSON(http.StatusOK, gin.H{"message": "run deleted successfully", "revision": benchmark.Revision, "trashed_run_id": trashed.ID})
 	}
 }
 
@@ -759,6 +925,9 @@ func HandleAddBenchmarkRuns(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		// Serialize with other mutations of this benchmark
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
 		// Verify benchmark exists and check ownership
 		var benchmark Benchmark
 		if dbErr := db.DB.First(&benchmark, benchmarkID).Error; dbErr != nil {
@@ -772,6 +941,10 @@ func HandleAddBenchmarkRuns(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
+		if !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+
 		// Get uploaded files
 		form, err := c.MultipartForm()
 		if err != nil {
@@ -816,7 +989,8 @@ func HandleAddBenchmarkRuns(db *DBInstance) gin.HandlerFunc {
 		}
 
 		// Append new runs to existing data
-		existingData = append(existingData, newBenchmarkData...)
+		previousData := existingData
+		existingData = append(existingData[:len(existingData):len(existingData)], newBenchmarkData...)
 
 		// Check total runs limit after combining
 		if len(existingData) > maxRunsPerBenchmark {
@@ -835,24 +1009,39 @@ func HandleAddBenchmarkRuns(db *DBInstance) gin.HandlerFunc {
 			return
 		}
 
-		// Store combined data
-		if err := StoreBenchmarkData(existingData, uint(benchmarkID)); err != nil {
+		// Quotas count against the benchmark's owner, not an admin adding runs to it
+		if !checkStorageQuota(c, db, benchmark.UserID, 0, int64(len(newBenchmarkData)), int64(CountTotalDataLines(newBenchmarkData))) {
+			return
+		}
+
+		// Flag runs that were uploaded before, then record the new runs ahead of their files
+		duplicates, err := findDuplicateRuns(db, benchmark.ID, newBenchmarkData, len(previousData))
+		if err != nil {
+			fmt.Printf("Warning: failed to check benchmark %d for duplicate runs: %v\n", benchmark.ID, err)
+		}
+		// Recompute pre-calculated stats after adding runs; they are stored with the combined data
+		preCalc, groups := ComputeBenchmarkStats(existingData, benchmark.RunGroupPattern)
+		if err := syncBenchmarkRuns(db, benchmark.ID, existingData, preCalc); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store benchmark data"})
 			return
 		}
 
-		// Recompute pre-calculated stats after adding runs
-		preCalc := ComputePreCalculatedRuns(existingData)
-		if storeErr := StorePreCalculatedStats(preCalc, uint(benchmarkID)); storeErr != nil {
-			fmt.Printf("Warning: failed to update pre-calculated stats for benchmark %d: %v\n", benchmarkID, storeErr)
+		if err := StoreBenchmarkDataWithStats(existingData, preCalc, groups, uint(benchmarkID)); err != nil {
+			if syncErr := syncBenchmarkRuns(db, benchmark.ID, previousData, nil); syncErr != nil {
+				fmt.Printf("Warning: %v\n", syncErr)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store benchmark data"})
+			return
 		}
 
 		// Update searchable metadata after adding runs
 		runNames, specifications := ExtractSearchableMetadata(existingData)
 		benchmark.RunNames = runNames
 		benchmark.Specifications = specifications
+		setBenchmarkUsage(&benchmark, existingData)
 
-		// Update the benchmark's UpdatedAt timestamp
+		// Update the benchmark's revision and UpdatedAt timestamp
+		benchmark.Revision++
 		if err := db.DB.Save(&benchmark).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update benchmark"})
 			return
@@ -860,12 +1049,16 @@ func HandleAddBenchmarkRuns(db *DBInstance) gin.HandlerFunc {
 
 		// Log runs added
 		usernameStr := GetUsernameFromContext(c)
+		recordBenchmarkRevision(db, &benchmark, uid, usernameStr, revisionActionRunsAdded)
 		LogBenchmarkRunsAdded(uid, usernameStr, benchmark.ID, benchmark.Title, len(newBenchmarkData), len(existingData))
 
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
 		c.JSON(http.StatusOK, gin.H{
 			"message":         "runs added successfully",
 			"runs_added":      len(newBenchmarkData),
 			"total_run_count": len(existingData),
+			"revision":        benchmark.Revision,
+			"duplicates":      duplicates,
 		})
 	}
 }
@@ -904,3 +1097,132 @@ func HandleGetBenchmarkRun(db *DBInstance) gin.HandlerFunc {
 		c.JSON(http.StatusOK, run)
 	}
 }
+
+// HandleGetBenchmarkRunSeries returns a metric series of a single run for an index or time range,
+// at the finest stored resolution that fits the requested number of points
+func HandleGetBenchmarkRunSeries(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		benchmarkID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+
+		runIndex, err := strconv.Atoi(c.Param("runIndex"))
+		if err != nil || runIndex < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run index"})
+			return
+		}
+
+		query, err := parseSeriesQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query.RunIndex = runIndex
+
+		// Verify benchmark exists
+		var benchmark Benchmark
+		if dbErr := db.DB.First(&benchmark, benchmarkID).Error; dbErr != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+
+		resp, err := QuerySeries(uint(benchmarkID), query)
+		switch {
+		case err == nil:
+			c.JSON(http.StatusOK, resp)
+		case errors.Is(err, errSeriesRunNotFound), errors.Is(err, errSeriesMetricNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			// Benchmarks uploaded before series files existed get them from the recompute worker
+			if recomputer := GetStatsRecomputer(); recomputer != nil {
+				recomputer.Enqueue(uint(benchmarkID))
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "series not available"})
+		}
+	}
+}
+
+// parseSeriesQuery reads the metric, range, unit and points parameters of a series request.
+func parseSeriesQuery(c *gin.Context) (SeriesQuery, error) {
+	var q SeriesQuery
+
+	metric := c.Query("metric")
+	if metric == "" {
+		return q, fmt.Errorf("metric is required")
+	}
+	q.Metric = metric
+	for camel, snake := range metricKeyToSnake {
+		if metric == snake {
+			q.Metric = camel
+		}
+	}
+
+	var err error
+	if q.From, q.To, q.Unit, err = parseRangeParams(c); err != nil {
+		return q, err
+	}
+
+	if points := c.Query("points"); points != "" {
+		v, err := strconv.Atoi(points)
+		if err != nil || v < 1 || v > maxSeriesRequestPoints {
+			return q, fmt.Errorf("points must be between 1 and %d", maxSeriesRequestPoints)
+		}
+		q.Points = v
+	}
+	return q, nil
+}
+
+// HandleGetBenchmarkRunStats computes statistics for a section of a single run from its raw data
+func HandleGetBenchmarkRunStats(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		benchmarkID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+
+		runIndex, err := strconv.Atoi(c.Param("runIndex"))
+		if err != nil || runIndex < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run index"})
+			return
+		}
+
+		from, to, unit, err := parseRangeParams(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Verify benchmark exists
+		var benchmark Benchmark
+		if dbErr := db.DB.First(&benchmark, benchmarkID).Error; dbErr != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+
+		run, err := RetrieveBenchmarkRun(uint(benchmarkID), runIndex)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+			return
+		}
+
+		result, r, err := ComputeRangeStats(run, from, to, unit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, RangeStatsResponse{
+			RunIndex:      runIndex,
+			Label:         run.Label,
+			StatsRange:    r,
+			Samples:       r.ToIndex - r.FromIndex,
+			Stats:         result.Stats,
+			StatsMangoHud: result.StatsMangoHud,
+		})
+	}
+}
@@ -268,6 +268,7 @@ func TestHandleDeleteBenchmarkRun(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create request: %v", err)
 		}
+		req.Header.Set("If-Match", benchmarkETag(1))
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -320,6 +321,7 @@ func TestHandleDeleteBenchmarkRun(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create request: %v", err)
 		}
+		req.Header.Set("If-Match", benchmarkETag(1))
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -381,6 +383,7 @@ func TestHandleDeleteBenchmarkRun(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create request: %v", err)
 		}
+		req.Header.Set("If-Match", benchmarkETag(1))
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
@@ -616,6 +619,9 @@ func TestHandleListBenchmarksSearch(t *testing.T) {
 	})
 
 	t.Run("short keyword under 3 chars is ignored", func(t *testing.T) {
+		if benchmarkSearchFTS {
+			t.Skip("short keywords are searched with FTS5")
+		}
 		// "ru" is only 2 chars — should be ignored, returning all benchmarks
 		req, err := http.NewRequest("GET", "/api/benchmarks?search=ru", nil)
 		if err != nil {
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore stores benchmark files as named blobs. Names are plain file names such as
+// "12-g3.bin" or "12.manifest" (see benchmark_files.go). A blob is written in full and becomes
+// visible to readers atomically, when its writer is committed.
+type BlobStore interface {
+	// Open opens a blob for reading. Returns an error matching fs.ErrNotExist if there is none.
+	Open(name string) (Blob, error)
+	// Create starts writing a blob. Nothing is visible until Commit; Close discards an
+	// uncommitted blob.
+	Create(name string) (BlobWriter, error)
+	// Copy makes dst a copy of src, replacing dst. Used to carry unchanged files over to a new
+	// generation, so stores should make it cheap (hard link, server-side copy).
+	Copy(src, dst string) error
+	// Remove deletes a blob. Removing a blob that doesn't exist is not an error.
+	Remove(name string) error
+	// Stat returns the size of a blob. Returns an error matching fs.ErrNotExist if there is none.
+	Stat(name string) (int64, error)
+	// List returns every blob in the store, including uncommitted temporary ones where the store
+	// has them.
+	List() ([]BlobInfo, error)
+}
+
+// Blob is an open blob. Read streams it from the start; ReadAt reads at any offset, so sectioned
+// files only fetch the sections they need.
+type Blob interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// BlobWriter writes a new blob.
+type BlobWriter interface {
+	io.Writer
+	// Commit makes the blob durable and visible.
+	Commit() error
+	// Close releases the writer, discarding the blob unless it was committed.
+	Close() error
+}
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	Name string
+	Size int64
+}
+
+// blobStore holds the benchmark files. It is set by InitBenchmarksDir or InitBlobStore.
+var blobStore BlobStore
+
+// storageDataDir is the data directory the blob store was set up for. Local working files such
+// as backup staging and the fsck quarantine live there whatever the backend.
+var storageDataDir string
+
+// Blob store backends
+const (
+	blobStoreLocal = "local"
+	blobStoreS3    = "s3"
+)
+
+// BlobStoreConfig selects and configures the blob store backend.
+type BlobStoreConfig struct {
+	Backend string // "local" (default) or "s3"
+	S3      S3Config
+}
+
+// InitBlobStore sets up the configured blob store. The local store keeps benchmark files in
+// <dataDir>/benchmarks.
+func InitBlobStore(dataDir string, cfg BlobStoreConfig) error {
+	switch cfg.Backend {
+	case "", blobStoreLocal:
+		return InitBenchmarksDir(dataDir)
+	case blobStoreS3:
+		store, err := NewS3BlobStore(cfg.S3)
+		if err != nil {
+			return err
+		}
+		benchmarksDir = ""
+		storageDataDir = dataDir
+		blobStore = store
+		resetManifestCache()
+		return nil
+	default:
+		return fmt.Errorf("unknown blob store backend %q (expected %q or %q)", cfg.Backend, blobStoreLocal, blobStoreS3)
+	}
+}
+
+// remoteBlobStore reports whether benchmark files are kept outside the data directory.
+func remoteBlobStore() bool {
+	_, local := blobStore.(*localBlobStore)
+	return blobStore != nil && !local
+}
+
+// openBlob opens a benchmark file in the configured store.
+func openBlob(name string) (Blob, error) {
+	return blobStore.Open(name)
+}
+
+// writeBlob writes and commits a blob in one go.
+func writeBlob(name string, writeFn func(w io.Writer) error) error {
+	w, err := blobStore.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := w.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close blob %s: %v\n", name, closeErr)
+		}
+	}()
+	if err := writeFn(w); err != nil {
+		return err
+	}
+	return w.Commit()
+}
+
+// readBlob reads a whole blob into memory. Only for small blobs like manifests.
+func readBlob(name string) ([]byte, error) {
+	b, err := openBlob(name)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBlob(b)
+	return io.ReadAll(b)
+}
+
+// blobExists reports whether a blob exists. Errors other than "not found" count as existing, so
+// callers go on to open the blob and report the actual error.
+func blobExists(name string) bool {
+	_, err := blobStore.Stat(name)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// saveBlob copies a blob to a new local file. Blobs of the local store are hard-linked.
+func saveBlob(name, dst string) error {
+	if local, ok := blobStore.(*localBlobStore); ok {
+		return linkOrCopy(local.path(name), dst)
+	}
+	b, err := openBlob(name)
+	if err != nil {
+		return err
+	}
+	defer closeBlob(b)
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, b); err != nil {
+		_ = out.Close() //nolint:errcheck // Copy error takes precedence
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close() //nolint:errcheck // Sync error takes precedence
+		return err
+	}
+	return out.Close()
+}
+
+// moveBlobToFile moves a blob out of the store into a new local file.
+func moveBlobToFile(name, dst string) error {
+	if local, ok := blobStore.(*localBlobStore); ok {
+		return os.Rename(local.path(name), dst)
+	}
+	if err := saveBlob(name, dst); err != nil {
+		return err
+	}
+	return blobStore.Remove(name)
+}
+
+func closeBlob(b Blob) {
+	if err := b.Close(); err != nil {
+		fmt.Printf("Warning: failed to close blob: %v\n", err)
+	}
+}
+
+// localBlobStore keeps blobs as files in a directory. Writes go through a temporary file that is
+// fsynced and renamed into place; copies are hard links.
+type localBlobStore struct {
+	dir string
+}
+
+func newLocalBlobStore(dir string) *localBlobStore {
+	return &localBlobStore{dir: dir}
+}
+
+func (s *localBlobStore) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *localBlobStore) Open(name string) (Blob, error) {
+	file, err := os.Open(s.path(name))
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", closeErr)
+		}
+		return nil, err
+	}
+	return &localBlob{File: file, size: info.Size()}, nil
+}
+
+func (s *localBlobStore) Create(name string) (BlobWriter, error) {
+	path := s.path(name)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &localBlobWriter{file: file, dir: s.dir, path: path}, nil
+}
+
+func (s *localBlobStore) Copy(src, dst string) error {
+	if err := linkOrCopy(s.path(src), s.path(dst)); err != nil {
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+func (s *localBlobStore) Remove(name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localBlobStore) Stat(name string) (int64, error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *localBlobStore) List() ([]BlobInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]BlobInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+		blobs = append(blobs, BlobInfo{Name: entry.Name(), Size: info.Size()})
+	}
+	return blobs, nil
+}
+
+type localBlob struct {
+	*os.File
+	size int64
+}
+
+func (b *localBlob) Size() int64 {
+	return b.size
+}
+
+type localBlobWriter struct {
+	file      *os.File
+	dir       string
+	path      string
+	committed bool
+	closed    bool
+}
+
+func (w *localBlobWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+func (w *localBlobWriter) Commit() error {
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(w.path), err)
+	}
+	w.closed = true
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(w.path), err)
+	}
+	w.committed = true
+	return syncDir(w.dir)
+}
+
+func (w *localBlobWriter) Close() error {
+	if !w.closed {
+		w.closed = true
+		if err := w.file.Close(); err != nil {
+			fmt.Printf("Warning: failed to close file: %v\n", err)
+		}
+	}
+	if !w.committed {
+		removeIfExists(w.file.Name())
+	}
+	return nil
+}
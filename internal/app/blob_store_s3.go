@@ -0,0 +1,509 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Config configures the S3-compatible blob store.
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000
+	Region          string
+	Bucket          string
+	Prefix          string // Prepended to every object key, e.g. "benchmarks/"
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Address the bucket as <endpoint>/<bucket> rather than <bucket>.<endpoint>
+}
+
+const (
+	// s3ReadAheadSize is how much a small ReadAt fetches, so reading the consecutive sections of a
+	// file doesn't cost one request each.
+	s3ReadAheadSize = 1 << 20
+	// s3EmptyPayloadHash is the SHA-256 of an empty request body.
+	s3EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3RequestTimeout   = 5 * time.Minute
+)
+
+// s3BlobStore stores blobs as objects in an S3-compatible bucket, signing requests with AWS
+// Signature Version 4. Each object is written with a single PUT, which S3 makes visible
+// atomically, so the manifest remains the commit point of a generation.
+type s3BlobStore struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3BlobStore returns a blob store backed by an S3-compatible bucket.
+func NewS3BlobStore(cfg S3Config) (BlobStore, error) {
+	return newS3BlobStore(cfg)
+}
+
+func newS3BlobStore(cfg S3Config) (*s3BlobStore, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 blob store requires an endpoint and a bucket")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 blob store requires an access key ID and a secret access key")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &s3BlobStore{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// objectURL returns the URL of an object, or of the bucket for an empty key.
+func (s *s3BlobStore) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	switch {
+	case s.cfg.PathStyle && key == "":
+		u.Path += "/" + s.cfg.Bucket
+	case s.cfg.PathStyle:
+		u.Path += "/" + s.cfg.Bucket + "/" + key
+	default:
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	u.RawPath = s3URIEncode(u.Path, false)
+	u.RawQuery = s3CanonicalQuery(query)
+	return &u
+}
+
+// do sends a signed request for an object (key relative to the prefix) or, with an empty name
+// and no prefix, for the bucket.
+func (s *s3BlobStore) do(method, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash)
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 headers. Signed are the host, range and x-amz-* headers.
+func (s *s3BlobStore) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	signature, signedHeaders, scope := s3Signature(s.cfg.SecretAccessKey, s.cfg.Region, amzDate, req.Method,
+		req.URL.EscapedPath(), req.URL.RawQuery, headers, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// s3Signature computes a Signature Version 4 signature over the given (lowercase) headers.
+// Returns the signature, the signed header list and the credential scope.
+func s3Signature(secret, region, amzDate, method, canonicalURI, canonicalQuery string, headers map[string]string, payloadHash string) (signature, signedHeaders, scope string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method, canonicalURI, canonicalQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	date := amzDate[:8]
+	scope = date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := s3HMAC([]byte("AWS4"+secret), date)
+	key = s3HMAC(key, region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign)), signedHeaders, scope
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3URIEncode percent-encodes everything but unreserved characters (and slashes, unless
+// encodeSlash), as Signature Version 4 requires.
+func s3URIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery encodes a query string with sorted, fully encoded parameters.
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3ErrorResponse is the XML error body S3 returns.
+type s3ErrorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError turns a failed response into an error. Missing objects match fs.ErrNotExist.
+func (s *s3BlobStore) responseError(resp *http.Response, op, name string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Best effort error detail
+	var s3Err s3ErrorResponse
+	_ = xml.Unmarshal(body, &s3Err) //nolint:errcheck // Not every error has an XML body
+	if resp.StatusCode == http.StatusNotFound && (s3Err.Code == "" || s3Err.Code == "NoSuchKey") {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if s3Err.Code != "" {
+		return fmt.Errorf("s3 %s %s: %s: %s (%s)", op, name, resp.Status, s3Err.Code, s3Err.Message)
+	}
+	return fmt.Errorf("s3 %s %s: %s", op, name, resp.Status)
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) //nolint:errcheck // Only to reuse the connection
+	if err := resp.Body.Close(); err != nil {
+		fmt.Printf("Warning: failed to close response body: %v\n", err)
+	}
+}
+
+func (s *s3BlobStore) key(name string) string {
+	return s.cfg.Prefix + name
+}
+
+// head returns the size and ETag of an object.
+func (s *s3BlobStore) head(name string) (size int64, etag string, err error) {
+	resp, err := s.do(http.MethodHead, s.key(name), nil, nil, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return 0, "", err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", s.responseError(resp, "stat", name)
+	}
+	return resp.ContentLength, resp.Header.Get("ETag"), nil
+}
+
+func (s *s3BlobStore) Open(name string) (Blob, error) {
+	size, etag, err := s.head(name)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Blob{store: s, name: name, size: size, etag: etag}, nil
+}
+
+func (s *s3BlobStore) Create(name string) (BlobWriter, error) {
+	// Spooled to disk: a single PUT needs the length and payload hash up front
+	file, err := os.CreateTemp("", "flightlesssomething-blob-*")
+	if err != nil {
+		return nil, err
+	}
+	return &s3BlobWriter{store: s, name: name, file: file, hash: sha256.New()}, nil
+}
+
+func (s *s3BlobStore) Copy(src, dst string) error {
+	header := http.Header{"X-Amz-Copy-Source": {"/" + s.cfg.Bucket + "/" + s3URIEncode(s.key(src), false)}}
+	resp, err := s.do(http.MethodPut, s.key(dst), nil, header, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp, "copy", src)
+	}
+	// A copy can fail after the 200 status was sent; the error is then in the body
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(body, []byte("<Error>")) {
+		var s3Err s3ErrorResponse
+		_ = xml.Unmarshal(body, &s3Err) //nolint:errcheck // Reported as unknown below
+		return fmt.Errorf("s3 copy %s: %s (%s)", src, s3Err.Code, s3Err.Message)
+	}
+	return nil
+}
+
+func (s *s3BlobStore) Remove(name string) error {
+	resp, err := s.do(http.MethodDelete, s.key(name), nil, nil, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError(resp, "remove", name)
+	}
+	return nil
+}
+
+func (s *s3BlobStore) Stat(name string) (int64, error) {
+	size, _, err := s.head(name)
+	return size, err
+}
+
+// s3ListResult is the ListObjectsV2 response.
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3BlobStore) List() ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.cfg.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil, nil, 0, s3EmptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s.responseError(resp, "list", s.cfg.Bucket)
+			drainAndClose(resp)
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		drainAndClose(resp)
+		if err != nil {
+			return nil, fmt.Errorf("s3 list %s: invalid response: %w", s.cfg.Bucket, err)
+		}
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, s.cfg.Prefix)
+			// Objects in "subdirectories" below the prefix aren't benchmark files
+			if name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			blobs = append(blobs, BlobInfo{Name: name, Size: object.Size})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return blobs, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// s3Blob reads an object with ranged GETs. Every request is conditional on the ETag seen when the
+// blob was opened, so a replaced object is never read half old, half new.
+type s3Blob struct {
+	store *s3BlobStore
+	name  string
+	size  int64
+	etag  string
+
+	body   io.ReadCloser // Stream for Read, opened on first use
+	offset int64
+
+	mu        sync.Mutex
+	window    []byte // Read-ahead for ReadAt
+	windowOff int64
+}
+
+func (b *s3Blob) Size() int64 {
+	return b.size
+}
+
+// get requests bytes [from, to] of the object; to < 0 reads to the end.
+func (b *s3Blob) get(from, to int64) (*http.Response, error) {
+	rangeSpec := fmt.Sprintf("bytes=%d-", from)
+	if to >= 0 {
+		rangeSpec += fmt.Sprint(to)
+	}
+	header := http.Header{"Range": {rangeSpec}}
+	if b.etag != "" {
+		header.Set("If-Match", b.etag)
+	}
+	resp, err := b.store.do(http.MethodGet, b.store.key(b.name), nil, header, nil, 0, s3EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return resp, nil
+	case http.StatusPreconditionFailed:
+		drainAndClose(resp)
+		return nil, fmt.Errorf("s3 read %s: object changed while reading", b.name)
+	default:
+		err := b.store.responseError(resp, "read", b.name)
+		drainAndClose(resp)
+		return nil, err
+	}
+}
+
+func (b *s3Blob) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.body == nil {
+		resp, err := b.get(b.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		b.body = resp.Body
+	}
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	if errors.Is(err, io.EOF) && b.offset < b.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (b *s3Blob) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("s3 read %s: negative offset", b.name)
+	}
+	if off >= b.size {
+		return 0, io.EOF
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	end := min(off+int64(len(p)), b.size)
+	if off < b.windowOff || end > b.windowOff+int64(len(b.window)) {
+		fetchEnd := end
+		if int64(len(p)) < s3ReadAheadSize {
+			fetchEnd = min(off+s3ReadAheadSize, b.size)
+		}
+		resp, err := b.get(off, fetchEnd-1)
+		if err != nil {
+			return 0, err
+		}
+		data := make([]byte, fetchEnd-off)
+		_, err = io.ReadFull(resp.Body, data)
+		drainAndClose(resp)
+		if err != nil {
+			return 0, fmt.Errorf("s3 read %s: %w", b.name, err)
+		}
+		b.window, b.windowOff = data, off
+	}
+	n := copy(p, b.window[off-b.windowOff:end-b.windowOff])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b *s3Blob) Close() error {
+	b.window = nil
+	if b.body != nil {
+		return b.body.Close()
+	}
+	return nil
+}
+
+// s3BlobWriter spools a blob to a temporary file and uploads it on Commit.
+type s3BlobWriter struct {
+	store     *s3BlobStore
+	name      string
+	file      *os.File
+	hash      hashWriter
+	size      int64
+	committed bool
+}
+
+// hashWriter is the part of hash.Hash the writer needs.
+type hashWriter interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+func (w *s3BlobWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *s3BlobWriter) Commit() error {
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// NopCloser: the spool file is closed by Close, not by the HTTP client
+	resp, err := w.store.do(http.MethodPut, w.store.key(w.name), nil, nil, io.NopCloser(w.file), w.size, hex.EncodeToString(w.hash.Sum(nil)))
+	if err != nil {
+		return fmt.Errorf("s3 write %s: %w", w.name, err)
+	}
+	defer drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK {
+		return w.store.responseError(resp, "write", w.name)
+	}
+	w.committed = true
+	return nil
+}
+
+func (w *s3BlobWriter) Close() error {
+	closeErr := w.file.Close()
+	if err := os.Remove(w.file.Name()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("Warning: failed to remove spool file %s: %v\n", w.file.Name(), err)
+	}
+	if closeErr != nil && !errors.Is(closeErr, os.ErrClosed) {
+		return closeErr
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package app
+
+import (
+	"crypto/md5" //nolint:gosec // ETags of the fake server, like S3's
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory S3 server for path-style requests to a single bucket: PUT
+// (including copies), GET with ranges and If-Match, HEAD, DELETE and ListObjectsV2. It checks
+// that requests are signed and that uploads match their payload hash.
+type fakeS3 struct {
+	bucket   string
+	mu       sync.Mutex
+	objects  map[string][]byte
+	requests int
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	t.Helper()
+	f := &fakeS3{bucket: bucket, objects: make(map[string][]byte)}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func fakeS3Config(server *httptest.Server, bucket string) S3Config {
+	return S3Config{
+		Endpoint:        server.URL,
+		Bucket:          bucket,
+		Prefix:          "benchmarks/",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PathStyle:       true,
+	}
+}
+
+func (f *fakeS3) etag(key string) string {
+	sum := md5.Sum(f.objects[key]) //nolint:gosec // See import
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") || r.Header.Get("X-Amz-Date") == "" {
+		f.fail(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query())
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		src, err := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"+f.bucket+"/"))
+		data, ok := f.objects[src]
+		if err != nil || !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		f.objects[key] = append([]byte(nil), data...)
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.fail(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		sum := sha256.Sum256(data)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			f.fail(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch")
+			return
+		}
+		f.objects[key] = data
+		w.Header().Set("ETag", f.etag(key))
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != f.etag(key) {
+			f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		w.Header().Set("ETag", f.etag(key))
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			return
+		}
+		from, to := 0, len(data)-1
+		if spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			start, end, _ := strings.Cut(spec, "-")
+			from, _ = strconv.Atoi(start) //nolint:errcheck // Requests come from the client under test
+			if end != "" {
+				to, _ = strconv.Atoi(end) //nolint:errcheck // See above
+			}
+			to = min(to, len(data)-1)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, to, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		_, _ = w.Write(data[from : to+1]) //nolint:errcheck // Test server
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list answers ListObjectsV2 with pages of two keys, to exercise continuation.
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) && key > query.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	type object struct {
+		Key  string
+		Size int
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []object
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+	for i, key := range keys {
+		if i == 2 {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+		result.Contents = append(result.Contents, object{Key: key, Size: len(f.objects[key])})
+	}
+	_ = xml.NewEncoder(w).Encode(result) //nolint:errcheck // Test server
+}
+
+func TestS3Signature(t *testing.T) {
+	// "GET Object" example of the AWS Signature Version 4 documentation
+	headers := map[string]string{
+		"host":                 "examplebucket.s3.amazonaws.com",
+		"range":                "bytes=0-9",
+		"x-amz-content-sha256": s3EmptyPayloadHash,
+		"x-amz-date":           "20130524T000000Z",
+	}
+	signature, signedHeaders, scope := s3Signature("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", "us-east-1",
+		"20130524T000000Z", http.MethodGet, "/test.txt", "", headers, s3EmptyPayloadHash)
+	if signature != "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41" {
+		t.Errorf("unexpected signature %s", signature)
+	}
+	if signedHeaders != "host;range;x-amz-content-sha256;x-amz-date" || scope != "20130524/us-east-1/s3/aws4_request" {
+		t.Errorf("unexpected signed headers %q or scope %q", signedHeaders, scope)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	fake, server := newFakeS3(t, "fs-test")
+	store, err := newS3BlobStore(fakeS3Config(server, "fs-test"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	write := func(name, content string) {
+		t.Helper()
+		w, err := store.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		defer w.Close()
+		if _, err := io.WriteString(w, content); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := w.Commit(); err != nil {
+			t.Fatalf("Failed to commit %s: %v", name, err)
+		}
+	}
+	content := strings.Repeat("0123456789", 1000)
+	write("1-g1.bin", content)
+
+	t.Run("uncommitted writes are not visible", func(t *testing.T) {
+		w, err := store.Create("1-g2.bin")
+		if err != nil {
+			t.Fatalf("Failed to create: %v", err)
+		}
+		if _, err := io.WriteString(w, "partial"); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		if _, err := store.Stat("1-g2.bin"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("read and read at", func(t *testing.T) {
+		b, err := store.Open("1-g1.bin")
+		if err != nil {
+			t.Fatalf("Failed to open: %v", err)
+		}
+		defer closeBlob(b)
+		if b.Size() != int64(len(content)) {
+			t.Errorf("expected size %d, got %d", len(content), b.Size())
+		}
+		buf := make([]byte, 5)
+		requests := fake.requests
+		for _, off := range []int64{10, 9995, 42, 5} {
+			n, err := b.ReadAt(buf, off)
+			if n != 5 || err != nil || string(buf) != content[off:off+5] {
+				t.Errorf("ReadAt(%d) = %d %q (%v)", off, n, buf, err)
+			}
+		}
+		if fake.requests-requests != 2 {
+			t.Errorf("expected only reads before the read-ahead window to fetch again, got %d requests", fake.requests-requests)
+		}
+		if n, err := b.ReadAt(make([]byte, 10), 9995); n != 5 || !errors.Is(err, io.EOF) {
+			t.Errorf("expected short read at the end, got %d (%v)", n, err)
+		}
+		data, err := io.ReadAll(b)
+		if err != nil || string(data) != content {
+			t.Errorf("streamed read returned %d bytes (%v)", len(data), err)
+		}
+	})
+
+	t.Run("replaced object is detected", func(t *testing.T) {
+		b, err := store.Open("1-g1.bin")
+		if err != nil {
+			t.Fatalf("Failed to open: %v", err)
+		}
+		defer closeBlob(b)
+		write("1-g1.bin", "replaced")
+		if _, err := io.ReadAll(b); err == nil || !strings.Contains(err.Error(), "changed") {
+			t.Errorf("expected a changed object error, got %v", err)
+		}
+		write("1-g1.bin", content)
+	})
+
+	t.Run("copy, list and remove", func(t *testing.T) {
+		if err := store.Copy("1-g1.bin", "1-g2.bin"); err != nil {
+			t.Fatalf("Failed to copy: %v", err)
+		}
+		write("1.manifest", "{}")
+		fake.mu.Lock()
+		fake.objects["benchmarks/aside/1.bin"] = []byte("not listed")
+		fake.objects["other/2.bin"] = []byte("not listed")
+		fake.mu.Unlock()
+
+		blobs, err := store.List()
+		if err != nil {
+			t.Fatalf("Failed to list: %v", err)
+		}
+		want := []BlobInfo{{"1-g1.bin", int64(len(content))}, {"1-g2.bin", int64(len(content))}, {"1.manifest", 2}}
+		if fmt.Sprint(blobs) != fmt.Sprint(want) {
+			t.Errorf("expected %v, got %v", want, blobs)
+		}
+
+		if err := store.Remove("1-g1.bin"); err != nil {
+			t.Fatalf("Failed to remove: %v", err)
+		}
+		if err := store.Remove("1-g1.bin"); err != nil {
+			t.Errorf("removing a missing blob should succeed, got %v", err)
+		}
+		if _, err := store.Open("1-g1.bin"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected not found, got %v", err)
+		}
+		if err := store.Copy("1-g1.bin", "1-g3.bin"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected not found copying a missing blob, got %v", err)
+		}
+	})
+
+	t.Run("rejected credentials", func(t *testing.T) {
+		cfg := fakeS3Config(server, "fs-test")
+		cfg.AccessKeyID = "other-key"
+		other, err := newS3BlobStore(cfg)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		if _, err := other.Stat("1-g2.bin"); err == nil || errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected an access error, got %v", err)
+		}
+	})
+}
+
+func TestNewS3BlobStoreValidation(t *testing.T) {
+	valid := S3Config{Endpoint: "https://s3.example.com", Bucket: "b", AccessKeyID: "k", SecretAccessKey: "s"}
+	if _, err := NewS3BlobStore(valid); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	for name, modify := range map[string]func(*S3Config){
+		"no bucket":   func(c *S3Config) { c.Bucket = "" },
+		"no secret":   func(c *S3Config) { c.SecretAccessKey = "" },
+		"bad scheme":  func(c *S3Config) { c.Endpoint = "ftp://s3.example.com" },
+		"no endpoint": func(c *S3Config) { c.Endpoint = "" },
+	} {
+		cfg := valid
+		modify(&cfg)
+		if _, err := NewS3BlobStore(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
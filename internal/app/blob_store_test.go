@@ -0,0 +1,134 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// initFakeS3BlobStore points the blob store at a fresh fake S3 server.
+func initFakeS3BlobStore(t *testing.T) *fakeS3 {
+	t.Helper()
+	fake, server := newFakeS3(t, "fs-bench")
+	cfg := BlobStoreConfig{Backend: blobStoreS3, S3: fakeS3Config(server, "fs-bench")}
+	prevStore, prevDir, prevDataDir := blobStore, benchmarksDir, storageDataDir
+	t.Cleanup(func() {
+		blobStore, benchmarksDir, storageDataDir = prevStore, prevDir, prevDataDir
+		resetManifestCache()
+	})
+	if err := InitBlobStore(t.TempDir(), cfg); err != nil {
+		t.Fatalf("Failed to init blob store: %v", err)
+	}
+	return fake
+}
+
+// fakeS3Keys lists the object keys of a fake S3 server.
+func fakeS3Keys(fake *fakeS3) []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	keys := make([]string, 0, len(fake.objects))
+	for key := range fake.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestBenchmarkFilesOnS3(t *testing.T) {
+	fake := initFakeS3BlobStore(t)
+	runs := columnarRuns()
+	preCalc, groups := ComputeBenchmarkStats(runs, "")
+	if err := StoreBenchmarkDataWithStats(runs, preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+	if keys := fakeS3Keys(fake); len(keys) != 9 || !strings.HasPrefix(keys[0], "benchmarks/") {
+		t.Fatalf("expected the manifest and 4 files each of generations 1 and 2, got %v", keys)
+	}
+
+	if data, err := RetrieveBenchmarkData(1); err != nil || !reflect.DeepEqual(data, columnarRuns()) {
+		t.Errorf("data not read back from S3 (%v)", err)
+	}
+	if run, err := RetrieveBenchmarkRun(1, 1); err != nil || run.Label != "Run B" {
+		t.Errorf("unexpected run: %+v (%v)", run, err)
+	}
+	if count, labels, err := GetBenchmarkRunCount(1); err != nil || count != 2 || labels[0] != "Run A" {
+		t.Errorf("unexpected metadata: %d %v (%v)", count, labels, err)
+	}
+	if stats, err := RetrievePreCalculatedStats(1); err != nil || len(stats) != 2 {
+		t.Errorf("unexpected stats: %d runs (%v)", len(stats), err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportBenchmarkDataAsZip(1, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(zr.File) != 2 {
+		t.Errorf("expected 2 CSV files in the export (%v)", err)
+	}
+
+	t.Run("recovery removes stale objects", func(t *testing.T) {
+		fake.mu.Lock()
+		fake.objects["benchmarks/1-g1.bin"] = []byte("superseded")
+		fake.objects["benchmarks/1-g3.meta"] = []byte("uncommitted")
+		fake.mu.Unlock()
+		removed, err := RecoverBenchmarkFiles()
+		// The 4 files of generation 1 kept for readers and the uncommitted one
+		if err != nil || removed != 5 {
+			t.Errorf("expected 5 removed objects, got %d (%v)", removed, err)
+		}
+	})
+
+	t.Run("deleted with benchmark data", func(t *testing.T) {
+		if err := DeleteBenchmarkData(1); err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		if keys := fakeS3Keys(fake); len(keys) != 0 {
+			t.Errorf("expected no objects left, got %v", keys)
+		}
+	})
+}
+
+func TestBackupAndRestoreWithS3(t *testing.T) {
+	db := setupBackupTest(t)
+	var archive bytes.Buffer
+	if _, err := WriteBackup(db, &archive, "test", false); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	fake := initFakeS3BlobStore(t)
+	fake.mu.Lock()
+	fake.objects["benchmarks/9.bin"] = []byte("existing")
+	fake.mu.Unlock()
+	restoreDir := filepath.Join(t.TempDir(), "data")
+	if _, _, err := RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, false); err == nil || !strings.Contains(err.Error(), "already contains data") {
+		t.Fatalf("expected refusal with objects in the bucket, got %v", err)
+	}
+	_, previousDir, err := RestoreBackup(bytes.NewReader(archive.Bytes()), restoreDir, true)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	fake.mu.Lock()
+	_, aside := fake.objects["benchmarks/"+filepath.Base(previousDir)+"/9.bin"]
+	fake.mu.Unlock()
+	if previousDir == "" || !aside {
+		t.Errorf("expected the existing object moved aside, got %q %v", previousDir, fakeS3Keys(fake))
+	}
+	if data, err := RetrieveBenchmarkData(2); err != nil || len(data) != 2 {
+		t.Errorf("expected 2 restored runs, got %d (%v)", len(data), err)
+	}
+	if blobExists("9.bin") {
+		t.Error("existing object still in place after restore")
+	}
+}
+
+func TestInitBlobStoreUnknownBackend(t *testing.T) {
+	if err := InitBlobStore(t.TempDir(), BlobStoreConfig{Backend: "ftp"}); err == nil {
+		t.Fatal("expected an error for an unknown backend")
+	}
+}
@@ -0,0 +1,399 @@
+package app
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Runs from any benchmarks can be compared side by side, each against a baseline run. A comparison
+// is saved under an ID derived from its runs and baseline, so comparing the same runs again returns
+// the same permalink. Each run is saved with its content hash: when the runs of a benchmark are
+// deleted or reordered later, the run is found again by its hash, and reported missing once it
+// is gone (or its benchmark was deleted).
+//
+// Spec fields are compared as recorded in the run headers. The graphics driver is only recorded for
+// runs uploaded since it is parsed from MangoHud logs, so it is empty for older runs.
+
+// Comparison limits
+const (
+	minComparisonRuns = 2
+	maxComparisonRuns = 10
+)
+
+// ComparisonRun is one run of a saved comparison.
+type ComparisonRun struct {
+	BenchmarkID uint   `json:"benchmark_id"`
+	RunIndex    int    `json:"run_index"`
+	ContentHash string `json:"content_hash"`
+}
+
+// ComparisonRuns is the run list of a comparison, stored as a JSON column.
+type ComparisonRuns []ComparisonRun
+
+// Value implements driver.Valuer.
+func (r ComparisonRuns) Value() (driver.Value, error) {
+	if r == nil {
+		r = ComparisonRuns{}
+	}
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner.
+func (r *ComparisonRuns) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = ComparisonRuns{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported comparison runs type %T", value)
+	}
+	return json.Unmarshal(data, r)
+}
+
+// StatDelta is the difference of one statistic from the baseline run's.
+type StatDelta struct {
+	Diff    float64  `json:"diff"`
+	Percent *float64 `json:"percent"` // nil when the baseline value is 0
+}
+
+// ComparedRun is one run of a comparison with its stats and its deltas against the baseline.
+type ComparedRun struct {
+	BenchmarkID    uint              `json:"benchmark_id"`
+	BenchmarkTitle string            `json:"benchmark_title,omitempty"`
+	RunIndex       int               `json:"run_index"` // Current index, which can differ from the saved one
+	ContentHash    string            `json:"content_hash"`
+	Missing        bool              `json:"missing,omitempty"` // The run or its benchmark was deleted
+	Run            *PreCalculatedRun `json:"run,omitempty"`
+
+	// metric key -> statistic -> delta; nil for the baseline itself
+	Deltas         map[string]map[string]StatDelta `json:"deltas,omitempty"`
+	DeltasMangoHud map[string]map[string]StatDelta `json:"deltas_mangohud,omitempty"`
+}
+
+// SpecFieldDiff holds one spec field of every compared run.
+type SpecFieldDiff struct {
+	Values  []string `json:"values"` // In run order; empty for missing runs
+	Differs bool     `json:"differs"`
+}
+
+// ComparisonResult is a comparison with the current data of its runs.
+type ComparisonResult struct {
+	ID             string                    `json:"id"`
+	Baseline       int                       `json:"baseline"`
+	UserID         uint                      `json:"user_id"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	Runs           []*ComparedRun            `json:"runs"`
+	Specs          map[string]*SpecFieldDiff `json:"specs"`
+	DifferingSpecs []string                  `json:"differing_specs"` // Spec fields whose values differ, in field order
+}
+
+// comparisonSpecFields are the compared spec fields, in display order.
+var comparisonSpecFields = []struct {
+	key   string
+	value func(*PreCalculatedRun) string
+}{
+	{"os", func(r *PreCalculatedRun) string { return r.SpecOS }},
+	{"cpu", func(r *PreCalculatedRun) string { return r.SpecCPU }},
+	{"gpu", func(r *PreCalculatedRun) string { return r.SpecGPU }},
+	{"ram", func(r *PreCalculatedRun) string { return r.SpecRAM }},
+	{"kernel", func(r *PreCalculatedRun) string { return r.SpecLinuxKernel }},
+	{"scheduler", func(r *PreCalculatedRun) string { return r.SpecLinuxScheduler }},
+	{"driver", func(r *PreCalculatedRun) string { return r.SpecDriver }},
+}
+
+// metricStatValues returns the scalar statistics of a metric, keyed like their JSON fields.
+func metricStatValues(ms *MetricStats) map[string]float64 {
+	return map[string]float64{
+		"min": ms.Min, "max": ms.Max, "avg": ms.Avg, "median": ms.Median,
+		"p01": ms.P01, "p05": ms.P05, "p10": ms.P10, "p25": ms.P25, "p75": ms.P75,
+		"p90": ms.P90, "p95": ms.P95, "p97": ms.P97, "p99": ms.P99,
+		"iqr": ms.IQR, "stddev": ms.StdDev, "variance": ms.Variance,
+	}
+}
+
+// metricDeltas computes the deltas of every metric both runs have.
+func metricDeltas(baseline, run map[string]*MetricStats) map[string]map[string]StatDelta {
+	if len(baseline) == 0 || len(run) == 0 {
+		return nil
+	}
+	deltas := make(map[string]map[string]StatDelta)
+	for key, ms := range run {
+		base, ok := baseline[key]
+		if !ok || ms.Count == 0 || base.Count == 0 {
+			continue
+		}
+		baseValues := metricStatValues(base)
+		stats := make(map[string]StatDelta)
+		for stat, value := range metricStatValues(ms) {
+			delta := StatDelta{Diff: value - baseValues[stat]}
+			if baseValues[stat] != 0 {
+				percent := delta.Diff / baseValues[stat] * 100
+				delta.Percent = &percent
+			}
+			stats[stat] = delta
+		}
+		deltas[key] = stats
+	}
+	return deltas
+}
+
+// comparisonID derives the ID of a comparison from its runs and baseline.
+func comparisonID(runs []ComparisonRun, baseline int) string {
+	h := sha256.New()
+	for _, run := range runs {
+		fmt.Fprintf(h, "%d:%d:%s\n", run.BenchmarkID, run.RunIndex, run.ContentHash)
+	}
+	fmt.Fprintf(h, "baseline:%d", baseline)
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// createComparison saves a comparison of the given runs (benchmark ID and run index) or returns the
+// identical one saved before. On error it also returns the HTTP status to respond with.
+func createComparison(db *DBInstance, userID uint, refs []ComparisonRun, baseline int) (*Comparison, int, error) {
+	if len(refs) < minComparisonRuns || len(refs) > maxComparisonRuns {
+		return nil, http.StatusBadRequest, fmt.Errorf("a comparison needs %d to %d runs", minComparisonRuns, maxComparisonRuns)
+	}
+	if baseline < 0 || baseline >= len(refs) {
+		return nil, http.StatusBadRequest, fmt.Errorf("baseline must be a run position (0-%d)", len(refs)-1)
+	}
+
+	runs := make([]ComparisonRun, len(refs))
+	for i, ref := range refs {
+		for _, other := range refs[:i] {
+			if other.BenchmarkID == ref.BenchmarkID && other.RunIndex == ref.RunIndex {
+				return nil, http.StatusBadRequest, fmt.Errorf("run %d of benchmark %d is listed twice", ref.RunIndex, ref.BenchmarkID)
+			}
+		}
+		var benchmark Benchmark
+		if err := db.DB.First(&benchmark, ref.BenchmarkID).Error; err != nil {
+			return nil, http.StatusNotFound, fmt.Errorf("benchmark %d not found", ref.BenchmarkID)
+		}
+		var row BenchmarkRun
+		if err := db.DB.Where("benchmark_id = ? AND run_index = ?", ref.BenchmarkID, ref.RunIndex).First(&row).Error; err != nil {
+			return nil, http.StatusNotFound, fmt.Errorf("benchmark %d has no run %d", ref.BenchmarkID, ref.RunIndex)
+		}
+		runs[i] = ComparisonRun{BenchmarkID: ref.BenchmarkID, RunIndex: ref.RunIndex, ContentHash: row.ContentHash}
+	}
+
+	comparison := Comparison{ID: comparisonID(runs, baseline), UserID: userID, Runs: runs, Baseline: baseline}
+	if err := db.DB.Where(Comparison{ID: comparison.ID}).FirstOrCreate(&comparison).Error; err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to save comparison")
+	}
+	return &comparison, http.StatusOK, nil
+}
+
+// findComparison loads a saved comparison.
+func findComparison(db *DBInstance, id string) (*Comparison, error) {
+	var comparison Comparison
+	if err := db.DB.Where("id = ?", id).First(&comparison).Error; err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
+// resolveComparisonRuns finds the current index of every run of a comparison: the saved index
+// while it still holds the same content, otherwise the first run of the benchmark with that
+// content. Runs that can't be found are marked missing.
+func resolveComparisonRuns(db *DBInstance, comparison *Comparison) ([]*ComparedRun, error) {
+	titles := make(map[uint]string)
+	rows := make(map[uint][]BenchmarkRun)
+	for _, saved := range comparison.Runs {
+		if _, ok := rows[saved.BenchmarkID]; ok {
+			continue
+		}
+		var benchmark Benchmark
+		err := db.DB.First(&benchmark, saved.BenchmarkID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			rows[saved.BenchmarkID] = nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load benchmark %d: %w", saved.BenchmarkID, err)
+		}
+		titles[saved.BenchmarkID] = benchmark.Title
+		var benchmarkRows []BenchmarkRun
+		if err := db.DB.Where("benchmark_id = ?", saved.BenchmarkID).Order("run_index").Find(&benchmarkRows).Error; err != nil {
+			return nil, fmt.Errorf("failed to load runs of benchmark %d: %w", saved.BenchmarkID, err)
+		}
+		rows[saved.BenchmarkID] = benchmarkRows
+	}
+
+	runs := make([]*ComparedRun, len(comparison.Runs))
+	for i, saved := range comparison.Runs {
+		run := &ComparedRun{
+			BenchmarkID:    saved.BenchmarkID,
+			BenchmarkTitle: titles[saved.BenchmarkID],
+			RunIndex:       saved.RunIndex,
+			ContentHash:    saved.ContentHash,
+			Missing:        true,
+		}
+		benchmarkRows := rows[saved.BenchmarkID]
+		if saved.RunIndex < len(benchmarkRows) && benchmarkRows[saved.RunIndex].ContentHash == saved.ContentHash {
+			run.Missing = false
+		} else if j := slices.IndexFunc(benchmarkRows, func(r BenchmarkRun) bool { return r.ContentHash == saved.ContentHash }); j >= 0 {
+			run.RunIndex, run.Missing = benchmarkRows[j].RunIndex, false
+		}
+		runs[i] = run
+	}
+	return runs, nil
+}
+
+// loadComparison reads the selected stats of a comparison's runs and compares them with the
+// baseline run.
+func loadComparison(db *DBInstance, comparison *Comparison, sel StatsSelection) (*ComparisonResult, error) {
+	runs, err := resolveComparisonRuns(db, comparison)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read each benchmark's stats file once for all of its compared runs
+	indices := make(map[uint][]int)
+	for _, run := range runs {
+		if !run.Missing {
+			indices[run.BenchmarkID] = append(indices[run.BenchmarkID], run.RunIndex)
+		}
+	}
+	for benchmarkID, runIndices := range indices {
+		slices.Sort(runIndices)
+		runIndices = slices.Compact(runIndices)
+		benchmarkSel := sel
+		benchmarkSel.Runs = runIndices
+		stats, _, err := RetrievePreCalculatedStatsSelection(benchmarkID, benchmarkSel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stats of benchmark %d: %w", benchmarkID, err)
+		}
+		for _, run := range runs {
+			if run.BenchmarkID == benchmarkID && !run.Missing {
+				run.Run = stats[slices.Index(runIndices, run.RunIndex)]
+			}
+		}
+	}
+
+	result := &ComparisonResult{
+		ID:             comparison.ID,
+		Baseline:       comparison.Baseline,
+		UserID:         comparison.UserID,
+		CreatedAt:      comparison.CreatedAt,
+		Runs:           runs,
+		Specs:          make(map[string]*SpecFieldDiff),
+		DifferingSpecs: []string{},
+	}
+
+	if baseline := runs[comparison.Baseline].Run; baseline != nil {
+		for i, run := range runs {
+			if i == comparison.Baseline || run.Run == nil {
+				continue
+			}
+			run.Deltas = metricDeltas(baseline.Stats, run.Run.Stats)
+			run.DeltasMangoHud = metricDeltas(baseline.StatsMangoHud, run.Run.StatsMangoHud)
+		}
+	}
+
+	for _, field := range comparisonSpecFields {
+		diff := &SpecFieldDiff{Values: make([]string, len(runs))}
+		first := ""
+		for i, run := range runs {
+			if run.Run == nil {
+				continue
+			}
+			diff.Values[i] = field.value(run.Run)
+			if first == "" {
+				first = diff.Values[i]
+			} else if diff.Values[i] != first {
+				diff.Differs = true
+			}
+		}
+		result.Specs[field.key] = diff
+		if diff.Differs {
+			result.DifferingSpecs = append(result.DifferingSpecs, field.key)
+		}
+	}
+	return result, nil
+}
+
+// HandleCreateComparison saves a comparison of runs from any benchmarks and returns it with the
+// runs' stats, deltas against the baseline and differing spec fields
+func HandleCreateComparison(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, _, ok := requestingUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Runs     []ComparisonRun `json:"runs" binding:"required"`
+			Baseline int             `json:"baseline"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+			return
+		}
+		sel, err := parseComparisonSelection(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comparison, status, err := createComparison(db, uid, req.Runs, req.Baseline)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		result, err := loadComparison(db, comparison, sel)
+		if err != nil {
+			fmt.Printf("Warning: failed to load comparison %s: %v\n", comparison.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comparison"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// HandleGetComparison returns a saved comparison with the current stats of its runs
+func HandleGetComparison(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sel, err := parseComparisonSelection(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comparison, err := findComparison(db, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comparison not found"})
+			return
+		}
+		result, err := loadComparison(db, comparison, sel)
+		if err != nil {
+			fmt.Printf("Warning: failed to load comparison %s: %v\n", comparison.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comparison"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// parseComparisonSelection reads the metrics, methods and include query parameters like the data
+// endpoint. Runs are chosen by the comparison, so runs is rejected.
+func parseComparisonSelection(c *gin.Context) (StatsSelection, error) {
+	if c.Query("runs") != "" {
+		return StatsSelection{}, fmt.Errorf("runs is not supported for comparisons")
+	}
+	return parseStatsSelection(c)
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storeComparisonBenchmark creates a benchmark with the given runs and their stats.
+func storeComparisonBenchmark(t *testing.T, db *DBInstance, userID uint, title string, runs []*BenchmarkData) *Benchmark {
+	t.Helper()
+	benchmark := &Benchmark{UserID: userID, Title: title, Revision: 1}
+	db.DB.Create(benchmark)
+	replaceComparisonRuns(t, db, benchmark.ID, runs)
+	return benchmark
+}
+
+// replaceComparisonRuns rewrites a benchmark's files and run rows with the given runs.
+func replaceComparisonRuns(t *testing.T, db *DBInstance, benchmarkID uint, runs []*BenchmarkData) {
+	t.Helper()
+	stats, groups := ComputeBenchmarkStats(runs, "")
+	if err := StoreBenchmarkDataWithStats(runs, stats, groups, benchmarkID); err != nil {
+		t.Fatalf("Failed to store benchmark %d: %v", benchmarkID, err)
+	}
+	if err := syncBenchmarkRuns(db, benchmarkID, runs, stats); err != nil {
+		t.Fatalf("Failed to sync runs of benchmark %d: %v", benchmarkID, err)
+	}
+}
+
+func comparisonRun(label, os, kernel, scheduler string, fps ...float64) *BenchmarkData {
+	run := &BenchmarkData{
+		Label: label, SpecOS: os, SpecGPU: "RX 9070 XT", SpecLinuxKernel: kernel, SpecLinuxScheduler: scheduler,
+		DataFPS: fps,
+	}
+	for _, v := range fps {
+		run.DataFrameTime = append(run.DataFrameTime, 1000/v)
+	}
+	return run
+}
+
+func TestComparison(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "compareuser", false)
+	arch := storeComparisonBenchmark(t, db, user.ID, "Arch", []*BenchmarkData{
+		comparisonRun("Arch BORE", "Arch Linux", "6.17", "bore", 100, 100, 100),
+	})
+	fedora := storeComparisonBenchmark(t, db, user.ID, "Fedora", []*BenchmarkData{
+		comparisonRun("Fedora EEVDF old", "Fedora", "6.15", "eevdf", 80, 80, 80),
+		comparisonRun("Fedora EEVDF", "Fedora", "6.16", "eevdf", 90, 90, 90),
+	})
+
+	router := setupTestRouter()
+	router.POST("/api/compare", func(c *gin.Context) {
+		c.Set("UserID", user.ID)
+		HandleCreateComparison(db)(c)
+	})
+	router.GET("/api/compare/:id", HandleGetComparison(db))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/compare", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	get := func(id string) *ComparisonResult {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/compare/"+id, nil))
+		var result ComparisonResult
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to get comparison: %d %s", w.Code, w.Body.String())
+		}
+		return &result
+	}
+
+	body := `{"runs": [{"benchmark_id": 1, "run_index": 0}, {"benchmark_id": 2, "run_index": 1}], "baseline": 0}`
+	w := post(body)
+	var result ComparisonResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Failed to compare: %d %s", w.Code, w.Body.String())
+	}
+
+	t.Run("side by side", func(t *testing.T) {
+		if len(result.Runs) != 2 || result.Runs[0].BenchmarkTitle != "Arch" || result.Runs[1].Run == nil || result.Runs[1].Run.Label != "Fedora EEVDF" {
+			t.Fatalf("unexpected runs: %+v", result.Runs)
+		}
+		if result.Runs[0].Deltas != nil {
+			t.Error("expected no deltas for the baseline")
+		}
+		avg := result.Runs[1].Deltas["FPS"]["avg"]
+		if math.Abs(avg.Diff+10) > 0.01 || avg.Percent == nil || math.Abs(*avg.Percent+10) > 0.01 {
+			t.Errorf("expected FPS avg 10%% below the baseline, got %+v", avg)
+		}
+		if !reflect.DeepEqual(result.DifferingSpecs, []string{"os", "kernel", "scheduler"}) {
+			t.Errorf("unexpected differing specs: %v", result.DifferingSpecs)
+		}
+		if gpu := result.Specs["gpu"]; gpu.Differs || !reflect.DeepEqual(gpu.Values, []string{"RX 9070 XT", "RX 9070 XT"}) {
+			t.Errorf("unexpected gpu spec: %+v", gpu)
+		}
+	})
+
+	t.Run("permalink", func(t *testing.T) {
+		w := post(body)
+		var again ComparisonResult
+		if err := json.Unmarshal(w.Body.Bytes(), &again); err != nil || again.ID != result.ID {
+			t.Errorf("expected the same comparison ID, got %q and %q", result.ID, again.ID)
+		}
+		if saved := get(result.ID); saved.Runs[1].Run.Label != "Fedora EEVDF" {
+			t.Errorf("unexpected saved comparison: %+v", saved.Runs[1])
+		}
+
+		w = post(`{"runs": [{"benchmark_id": 1, "run_index": 0}, {"benchmark_id": 2, "run_index": 1}], "baseline": 1}`)
+		var other ComparisonResult
+		if err := json.Unmarshal(w.Body.Bytes(), &other); err != nil || other.ID == result.ID {
+			t.Errorf("expected another ID for another baseline, got %q", other.ID)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/compare/missing", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for an unknown comparison, got %d", w.Code)
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		server := newMCPServer(db, "test", "https://fs.example.com/")
+		out, err := server.toolCompareRuns(json.RawMessage(`{"id": "`+result.ID+`"}`), user.ID)
+		if err != nil {
+			t.Fatalf("compare_runs failed: %v", err)
+		}
+		var summary ComparisonSummary
+		if err := json.Unmarshal([]byte(out), &summary); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		if summary.URL != "https://fs.example.com/compare/"+result.ID || summary.Runs[1].Run.Metrics["fps"] == nil {
+			t.Errorf("unexpected summary: %s", out)
+		}
+		if _, ok := summary.Runs[1].Deltas["fps"]["std_dev"]; !ok {
+			t.Errorf("expected deltas with MCP metric and statistic names, got %v", summary.Runs[1].Deltas)
+		}
+		if _, err := server.toolCompareRuns(json.RawMessage(`{"id": "`+result.ID+`", "runs": [{"benchmark_id": 1}]}`), user.ID); err == nil {
+			t.Error("expected id and runs together rejected")
+		}
+	})
+
+	t.Run("runs follow their content", func(t *testing.T) {
+		replaceComparisonRuns(t, db, fedora.ID, []*BenchmarkData{
+			comparisonRun("Fedora EEVDF", "Fedora", "6.16", "eevdf", 90, 90, 90),
+		})
+		saved := get(result.ID)
+		if run := saved.Runs[1]; run.Missing || run.RunIndex != 0 || run.Run.Label != "Fedora EEVDF" {
+			t.Errorf("expected the run found at its new index, got %+v", run)
+		}
+
+		db.DB.Delete(arch)
+		saved = get(result.ID)
+		if run := saved.Runs[0]; !run.Missing || run.Run != nil {
+			t.Errorf("expected the deleted benchmark's run missing, got %+v", run)
+		}
+		if saved.Runs[1].Deltas != nil || len(saved.DifferingSpecs) != 0 {
+			t.Errorf("expected no deltas or spec differences without the baseline, got %+v %v", saved.Runs[1].Deltas, saved.DifferingSpecs)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		tests := map[string]struct {
+			body   string
+			status int
+		}{
+			"one run":           {`{"runs": [{"benchmark_id": 2, "run_index": 0}]}`, http.StatusBadRequest},
+			"baseline":          {`{"runs": [{"benchmark_id": 2, "run_index": 0}, {"benchmark_id": 2, "run_index": 0}], "baseline": 2}`, http.StatusBadRequest},
+			"duplicate run":     {`{"runs": [{"benchmark_id": 2, "run_index": 0}, {"benchmark_id": 2, "run_index": 0}]}`, http.StatusBadRequest},
+			"missing run":       {`{"runs": [{"benchmark_id": 2, "run_index": 0}, {"benchmark_id": 2, "run_index": 5}]}`, http.StatusNotFound},
+			"deleted benchmark": {`{"runs": [{"benchmark_id": 2, "run_index": 0}, {"benchmark_id": 1, "run_index": 0}]}`, http.StatusNotFound},
+		}
+		for name, tt := range tests {
+			if w := post(tt.body); w.Code != tt.status {
+				t.Errorf("%s: expected %d, got %d: %s", name, tt.status, w.Code, w.Body.String())
+			}
+		}
+	})
+}
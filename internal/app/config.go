@@ -3,7 +3,9 @@ package app
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/peterbourgon/ff/v3"
 )
@@ -21,6 +23,15 @@ type Config struct {
 	AdminUsername string
 	AdminPassword string
 
+	BlobStore BlobStoreConfig
+	DedupRuns bool
+
+	Quota StorageQuota
+
+	TrashRetention time.Duration
+
+	CacheBytes int64
+
 	Version bool
 }
 
@@ -40,6 +51,20 @@ func NewConfig() (*Config, error) {
 	fs.StringVar(&config.AdminUsername, "admin-username", "", "Admin username for authentication")
 	fs.StringVar(&config.AdminPassword, "admin-password", "", "Admin password for authentication")
 
+	registerBlobStoreFlags(fs, &config.BlobStore)
+	fs.BoolVar(&config.DedupRuns, "dedup-runs", false, "Store the data of identical runs only once, shared between benchmarks")
+
+	var quotaStorageMB int64
+	fs.Int64Var(&config.Quota.Benchmarks, "quota-benchmarks", 0, "Maximum benchmarks per user (0 = unlimited)")
+	fs.Int64Var(&quotaStorageMB, "quota-storage-mb", 0, "Maximum stored benchmark data per user in MiB (0 = unlimited)")
+	fs.Int64Var(&config.Quota.Runs, "quota-runs", 0, "Maximum runs per user across all benchmarks (0 = unlimited)")
+	fs.Int64Var(&config.Quota.DataLines, "quota-data-lines", 0, "Maximum data lines per user across all benchmarks (0 = unlimited)")
+
+	fs.DurationVar(&config.TrashRetention, "trash-retention", 30*24*time.Hour, "How long deleted benchmarks and runs are kept in the trash before they are purged")
+
+	var cacheSizeMB int64
+	fs.Int64Var(&cacheSizeMB, "cache-size-mb", defaultBenchmarkCacheBytes>>20, "Memory for cached benchmark metadata and stats in MiB (0 = disabled)")
+
 	fs.BoolVar(&config.Version, "version", false, "Print version and exit")
 
 	if err := ff.Parse(fs, os.Args[1:], ff.WithEnvVarPrefix("FS")); err != nil {
@@ -53,6 +78,20 @@ func NewConfig() (*Config, error) {
 	if config.DataDir == "" {
 		return nil, errors.New("missing data-dir argument")
 	}
+	if err := config.BlobStore.validate(); err != nil {
+		return nil, err
+	}
+	if config.Quota.Benchmarks < 0 || quotaStorageMB < 0 || config.Quota.Runs < 0 || config.Quota.DataLines < 0 {
+		return nil, errors.New("quota limits must not be negative")
+	}
+	config.Quota.StorageBytes = quotaStorageMB << 20
+	if config.TrashRetention <= 0 {
+		return nil, errors.New("trash-retention must be positive")
+	}
+	if cacheSizeMB < 0 {
+		return nil, errors.New("cache-size-mb must not be negative")
+	}
+	config.CacheBytes = cacheSizeMB << 20
 	if config.DiscordClientID == "" {
 		return nil, errors.New("missing discord-client-id argument")
 	}
@@ -80,3 +119,37 @@ func NewConfig() (*Config, error) {
 
 	return config, nil
 }
+
+// registerBlobStoreFlags adds the flags selecting where benchmark files are stored. Shared by the
+// server and the subcommands that work on its data.
+func registerBlobStoreFlags(fs *flag.FlagSet, cfg *BlobStoreConfig) {
+	fs.StringVar(&cfg.Backend, "blob-store", blobStoreLocal, "Where benchmark files are stored: local (in data-dir) or s3")
+	fs.StringVar(&cfg.S3.Endpoint, "s3-endpoint", "", "S3-compatible endpoint URL, e.g. https://s3.eu-central-1.amazonaws.com")
+	fs.StringVar(&cfg.S3.Region, "s3-region", "us-east-1", "S3 region")
+	fs.StringVar(&cfg.S3.Bucket, "s3-bucket", "", "S3 bucket for benchmark files")
+	fs.StringVar(&cfg.S3.Prefix, "s3-prefix", "", "Prefix of every object key, e.g. benchmarks/")
+	fs.StringVar(&cfg.S3.AccessKeyID, "s3-access-key-id", "", "S3 access key ID")
+	fs.StringVar(&cfg.S3.SecretAccessKey, "s3-secret-access-key", "", "S3 secret access key")
+	fs.BoolVar(&cfg.S3.PathStyle, "s3-path-style", false, "Use path-style bucket addressing (e.g. for MinIO)")
+}
+
+// validate checks the blob store flags.
+func (cfg BlobStoreConfig) validate() error {
+	switch cfg.Backend {
+	case "", blobStoreLocal:
+		return nil
+	case blobStoreS3:
+		if cfg.S3.Endpoint == "" {
+			return errors.New("missing s3-endpoint argument")
+		}
+		if cfg.S3.Bucket == "" {
+			return errors.New("missing s3-bucket argument")
+		}
+		if cfg.S3.AccessKeyID == "" || cfg.S3.SecretAccessKey == "" {
+			return errors.New("missing s3-access-key-id or s3-secret-access-key argument")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid blob-store %q (expected %s or %s)", cfg.Backend, blobStoreLocal, blobStoreS3)
+	}
+}
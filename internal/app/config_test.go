@@ -81,6 +81,24 @@ func TestNewConfig(t *testing.T) {
 			wantErr:     true,
 			errContains: "missing session-secret",
 		},
+		{
+			name: "s3 blob store",
+			args: append(append([]string(nil), validArgs...),
+				"-blob-store=s3", "-s3-endpoint=http://minio:9000", "-s3-bucket=fs",
+				"-s3-access-key-id=key", "-s3-secret-access-key=secret", "-s3-path-style"),
+		},
+		{
+			name:        "s3 blob store without bucket",
+			args:        append(append([]string(nil), validArgs...), "-blob-store=s3", "-s3-endpoint=http://minio:9000"),
+			wantErr:     true,
+			errContains: "missing s3-bucket",
+		},
+		{
+			name:        "unknown blob store",
+			args:        append(append([]string(nil), validArgs...), "-blob-store=ftp"),
+			wantErr:     true,
+			errContains: "invalid blob-store",
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,10 +103,16 @@ func InitDB(dataDir string) (*DBInstance, error) {
 
 	// Auto-migrate the schema BEFORE running data migrations
 	// This ensures columns exist before migration code tries to use them
-	if err := db.AutoMigrate(&User{}, &Benchmark{}, &APIToken{}, &SchemaVersion{}); err != nil {
+	if err := db.AutoMigrate(&User{}, &Benchmark{}, &APIToken{}, &BenchmarkRun{}, &TrashedRun{}, &BenchmarkRevision{}, &Comparison{}, &Game{}, &GameAlias{}, &SchemaVersion{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// Create the full-text search index, if SQLite supports it, before migrations fill it
+	searchIndexCreated, err := initBenchmarkSearch(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize search index: %w", err)
+	}
+
 	// Run data migrations after schema is updated
 	if version > 0 && version < currentSchemaVersion {
 		// Handle incremental migrations for future versions (Format 3+ → newer Format 3+)
@@ -165,6 +171,99 @@ func InitDB(dataDir string) (*DBInstance, error) {
 				return nil, fmt.Errorf("failed to set schema version to 5: %w", err)
 			}
 			log.Println("Successfully migrated to version 5")
+			version = 5 // Update local version for next migration step
+		}
+
+		if version == 5 {
+			log.Println("Migrating benchmark storage format from V2 to V3 (columnar)...")
+			if err := MigrateBenchmarkStorageToV3(dataDir); err != nil {
+				return nil, fmt.Errorf("failed to migrate storage format to v3: %w", err)
+			}
+			if err := setSchemaVersion(db, 6); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 6: %w", err)
+			}
+			log.Println("Successfully migrated to version 6")
+			version = 6 // Update local version for next migration step
+		}
+
+		if version == 6 {
+			log.Println("Recording content hashes of benchmark runs...")
+			if err := MigrateBenchmarkRunHashes(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to record run hashes: %w", err)
+			}
+			if err := setSchemaVersion(db, 7); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 7: %w", err)
+			}
+			log.Println("Successfully migrated to version 7")
+			version = 7 // Update local version for next migration step
+		}
+
+		if version == 7 {
+			log.Println("Recording storage usage of benchmarks...")
+			if err := MigrateBenchmarkUsage(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to record storage usage: %w", err)
+			}
+			if err := setSchemaVersion(db, 8); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 8: %w", err)
+			}
+			log.Println("Successfully migrated to version 8")
+			version = 8 // Update local version for next migration step
+		}
+
+		if version == 8 {
+			log.Println("Recording the current revision of benchmarks...")
+			if err := MigrateBenchmarkRevisions(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to record benchmark revisions: %w", err)
+			}
+			if err := setSchemaVersion(db, 9); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 9: %w", err)
+			}
+			log.Println("Successfully migrated to version 9")
+			version = 9 // Update local version for next migration step
+		}
+
+		if version == 9 {
+			log.Println("Building the full-text search index of benchmarks...")
+			if err := MigrateBenchmarkSearchIndex(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to build search index: %w", err)
+			}
+			if err := setSchemaVersion(db, 10); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 10: %w", err)
+			}
+			log.Println("Successfully migrated to version 10")
+			searchIndexCreated = false // Just built
+			version = 10               // Update local version for next migration step
+		}
+
+		if version == 10 {
+			log.Println("Recording the specs of benchmark runs...")
+			if err := MigrateBenchmarkRunSpecs(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to record run specs: %w", err)
+			}
+			if err := setSchemaVersion(db, 11); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 11: %w", err)
+			}
+			log.Println("Successfully migrated to version 11")
+			version = 11 // Update local version for next migration step
+		}
+
+		if version == 11 {
+			log.Println("Recording the stats of benchmark runs...")
+			if err := MigrateBenchmarkRunStats(&DBInstance{DB: db}); err != nil {
+				return nil, fmt.Errorf("failed to record run stats: %w", err)
+			}
+			if err := setSchemaVersion(db, 12); err != nil {
+				return nil, fmt.Errorf("failed to set schema version to 12: %w", err)
+			}
+			log.Println("Successfully migrated to version 12")
+		}
+	}
+
+	// The index was (re)created outside of the v9 → v10 migration, e.g. after running a build without FTS5
+	if searchIndexCreated {
+		log.Println("Rebuilding the full-text search index of benchmarks...")
+		if err := RebuildBenchmarkSearchIndex(db); err != nil {
+			return nil, fmt.Errorf("failed to rebuild search index: %w", err)
 		}
 	}
 
@@ -0,0 +1,585 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Games give benchmarks a structured notion of what was benchmarked. A game has a canonical name,
+// aliases (other names, abbreviations and executable names) and an optional Steam app ID. Names and
+// aliases are matched by their key, which keeps only lowercase letters and digits, so
+// "Cyberpunk 2077", "cyberpunk-2077" and the executable Cyberpunk2077 are the same. Keys are unique
+// across the names and aliases of all games. Admins manage the games; users link benchmarks to them.
+//
+// Uploads without a game get one suggested. MangoHud names its logs <executable>_<date>_<time>.csv
+// and file names become run labels, so the executables of the runs are looked up first; otherwise
+// the longest name or alias found in the title wins. Saving a game leaves existing benchmarks alone;
+// their owners link them, so every change of a benchmark goes through its revision history.
+
+const (
+	maxGameNameLength = 100
+	maxGameAliases    = 20
+
+	// Shorter names and aliases are only matched as executables, not searched for in titles
+	minGameTitleKeyLength = 4
+)
+
+// errGameNotFound is returned when a benchmark is linked to a game that does not exist.
+var errGameNotFound = errors.New("game not found")
+
+// mangoHudLogNamePattern matches the default MangoHud log name (without .csv), capturing the executable.
+var mangoHudLogNamePattern = regexp.MustCompile(`^(.+?)_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}$`)
+
+// gameKey normalizes a game name or alias for matching: lowercase letters and digits only.
+func gameKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// mangoHudExecutable returns the executable name embedded in a MangoHud log name, or "".
+func mangoHudExecutable(label string) string {
+	m := mangoHudLogNamePattern.FindStringSubmatch(strings.TrimSpace(label))
+	if m == nil {
+		return ""
+	}
+	if exe := m[1]; len(exe) > 4 && strings.EqualFold(exe[len(exe)-4:], ".exe") {
+		return exe[:len(exe)-4]
+	}
+	return m[1]
+}
+
+// gameMatcher suggests games for benchmarks from the names and aliases of all games.
+type gameMatcher struct {
+	keys      map[string]uint // Name and alias keys to game IDs
+	titleKeys []string        // Keys searched for in titles, longest first
+}
+
+// loadGameMatcher loads the names and aliases of all games.
+func loadGameMatcher(db *gorm.DB) (*gameMatcher, error) {
+	var games []Game
+	if err := db.Select("id", "key").Find(&games).Error; err != nil {
+		return nil, fmt.Errorf("failed to load games: %w", err)
+	}
+	var aliases []GameAlias
+	if err := db.Select("game_id", "key").Find(&aliases).Error; err != nil {
+		return nil, fmt.Errorf("failed to load game aliases: %w", err)
+	}
+
+	m := &gameMatcher{keys: make(map[string]uint, len(games)+len(aliases))}
+	for _, game := range games {
+		m.keys[game.Key] = game.ID
+	}
+	for _, alias := range aliases {
+		m.keys[alias.Key] = alias.GameID
+	}
+	for key := range m.keys {
+		if len(key) >= minGameTitleKeyLength {
+			m.titleKeys = append(m.titleKeys, key)
+		}
+	}
+	sort.Slice(m.titleKeys, func(i, j int) bool {
+		if len(m.titleKeys[i]) != len(m.titleKeys[j]) {
+			return len(m.titleKeys[i]) > len(m.titleKeys[j])
+		}
+		return m.titleKeys[i] < m.titleKeys[j]
+	})
+	return m, nil
+}
+
+// suggest returns the game suggested for a benchmark with the given title and run labels, and
+// where it was found ("executable" or "title"). Returns 0 if no game matches.
+func (m *gameMatcher) suggest(title string, labels []string) (uint, string) {
+	for _, label := range labels {
+		if exe := mangoHudExecutable(label); exe != "" {
+			if id, ok := m.keys[gameKey(exe)]; ok {
+				return id, "executable"
+			}
+		}
+	}
+	titleKey := gameKey(title)
+	for _, key := range m.titleKeys {
+		if strings.Contains(titleKey, key) {
+			return m.keys[key], "title"
+		}
+	}
+	return 0, ""
+}
+
+// runLabels returns the labels of runs.
+func runLabels(runs []*BenchmarkData) []string {
+	labels := make([]string, len(runs))
+	for i, run := range runs {
+		labels[i] = run.Label
+	}
+	return labels
+}
+
+// findGameByKey returns the ID of the game with a name or alias matching name, or 0 if there is none.
+func findGameByKey(db *gorm.DB, name string) (uint, error) {
+	key := gameKey(name)
+	if key == "" {
+		return 0, nil
+	}
+	var ids []uint
+	if err := db.Model(&Game{}).Where("key = ?", key).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to look up game: %w", err)
+	}
+	if len(ids) == 0 {
+		if err := db.Model(&GameAlias{}).Where("key = ?", key).Pluck("game_id", &ids).Error; err != nil {
+			return 0, fmt.Errorf("failed to look up game alias: %w", err)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+// resolveGame returns the ID of the game given by ID, name or alias, or 0 if there is none.
+func resolveGame(db *gorm.DB, value string) (uint, error) {
+	value = strings.TrimSpace(value)
+	if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+		var count int64
+		if err := db.Model(&Game{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("failed to look up game: %w", err)
+		}
+		if count == 0 {
+			return 0, nil
+		}
+		return uint(id), nil
+	}
+	return findGameByKey(db, value)
+}
+
+// setBenchmarkGame links a benchmark to a game; 0 unlinks it. Returns whether the link changed.
+func setBenchmarkGame(db *gorm.DB, benchmark *Benchmark, gameID uint) (bool, error) {
+	if gameID == 0 {
+		changed := benchmark.GameID != nil
+		benchmark.GameID, benchmark.Game = nil, nil
+		return changed, nil
+	}
+	if benchmark.GameID != nil && *benchmark.GameID == gameID {
+		return false, nil
+	}
+	var count int64
+	if err := db.Model(&Game{}).Where("id = ?", gameID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to look up game: %w", err)
+	}
+	if count == 0 {
+		return false, errGameNotFound
+	}
+	benchmark.GameID = &gameID
+	benchmark.Game = nil // Saving a stale association would restore the previous link
+	return true, nil
+}
+
+// uploadGame returns the game of a new benchmark: the requested one (0 for none), or the suggested
+// one when none was requested.
+func uploadGame(db *gorm.DB, requested *uint, title string, runs []*BenchmarkData) (*uint, error) {
+	var benchmark Benchmark
+	if requested != nil {
+		if _, err := setBenchmarkGame(db, &benchmark, *requested); err != nil {
+			return nil, err
+		}
+		return benchmark.GameID, nil
+	}
+	matcher, err := loadGameMatcher(db)
+	if err != nil {
+		fmt.Printf("Warning: failed to suggest a game: %v\n", err)
+		return nil, nil
+	}
+	if id, _ := matcher.suggest(title, runLabels(runs)); id != 0 {
+		return &id, nil
+	}
+	return nil, nil
+}
+
+// loadGameDetails sets the aliases and benchmark counts of games.
+func loadGameDetails(db *gorm.DB, games []Game) error {
+	if len(games) == 0 {
+		return nil
+	}
+	positions := make(map[uint]int, len(games))
+	ids := make([]uint, len(games))
+	for i := range games {
+		positions[games[i].ID] = i
+		ids[i] = games[i].ID
+	}
+
+	var aliases []GameAlias
+	if err := db.Where("game_id IN ?", ids).Order("id").Find(&aliases).Error; err != nil {
+		return fmt.Errorf("failed to load game aliases: %w", err)
+	}
+	for _, alias := range aliases {
+		game := &games[positions[alias.GameID]]
+		game.Aliases = append(game.Aliases, alias.Name)
+	}
+
+	var counts []struct {
+		GameID uint
+		Count  int64
+	}
+	if err := db.Model(&Benchmark{}).Select("game_id, COUNT(*) AS count").Where("game_id IN ?", ids).Group("game_id").Scan(&counts).Error; err != nil {
+		return fmt.Errorf("failed to count game benchmarks: %w", err)
+	}
+	for _, count := range counts {
+		games[positions[count.GameID]].BenchmarkCount = count.Count
+	}
+	return nil
+}
+
+// listGames returns a page of the games whose name or alias contains search, most benchmarked first.
+func listGames(db *gorm.DB, search string, page, perPage int) ([]Game, int64, error) {
+	query := db.Model(&Game{})
+	if search != "" {
+		if len(search) > maxSearchLength {
+			search = search[:maxSearchLength]
+		}
+		pattern := likeContainsPattern(search)
+		query = query.Where("name LIKE ? ESCAPE '\\' OR id IN (SELECT game_id FROM game_aliases WHERE name LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count games: %w", err)
+	}
+	games := []Game{}
+	err := query.
+		Order("(SELECT COUNT(*) FROM benchmarks WHERE benchmarks.game_id = games.id AND benchmarks.deleted_at IS NULL) DESC").
+		Order("name").
+		Offset((page - 1) * perPage).Limit(perPage).
+		Find(&games).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list games: %w", err)
+	}
+	if err := loadGameDetails(db, games); err != nil {
+		return nil, 0, err
+	}
+	return games, total, nil
+}
+
+// HandleListGames returns a page of games, optionally searched by name or alias
+func HandleListGames(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		if page > 10000 {
+			page = 10000
+		}
+		perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+		if err != nil || perPage < 1 || perPage > 100 {
+			perPage = 20
+		}
+
+		games, total, err := listGames(db.DB, c.Query("search"), page, perPage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"games":       games,
+			"page":        page,
+			"per_page":    perPage,
+			"total":       total,
+			"total_pages": int((total + int64(perPage) - 1) / int64(perPage)),
+		})
+	}
+}
+
+// HandleGetGame returns a game with its aliases and benchmark count
+func HandleGetGame(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+			return
+		}
+		games := make([]Game, 1)
+		if err := db.DB.First(&games[0], gameID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+			return
+		}
+		if err := loadGameDetails(db.DB, games); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, games[0])
+	}
+}
+
+// HandleSuggestGame suggests the game of a benchmark from its title and run labels (file names)
+func HandleSuggestGame(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		labels := c.QueryArray("label")
+		if len(labels) > maxRunsPerBenchmark {
+			labels = labels[:maxRunsPerBenchmark]
+		}
+		for i, label := range labels {
+			// Uploaded file names become labels without their extension
+			labels[i] = strings.TrimSuffix(label, ".csv")
+		}
+
+		matcher, err := loadGameMatcher(db.DB)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		id, source := matcher.suggest(c.Query("title"), labels)
+		if id == 0 {
+			c.JSON(http.StatusOK, gin.H{"game": nil})
+			return
+		}
+		games := make([]Game, 1)
+		if err := db.DB.First(&games[0], id).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if err := loadGameDetails(db.DB, games); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"game": games[0], "source": source})
+	}
+}
+
+// gameRequest is the body of creating or updating a game.
+type gameRequest struct {
+	Name       string   `json:"name"`
+	Aliases    []string `json:"aliases"`
+	SteamAppID *uint    `json:"steam_app_id"`
+}
+
+// gameConflictError is returned when a name, alias or Steam app ID belongs to another game.
+type gameConflictError struct {
+	msg string
+}
+
+func (e *gameConflictError) Error() string {
+	return e.msg
+}
+
+// validate normalizes the request and returns the game's aliases keyed by their keys.
+func (r *gameRequest) validate() (map[string]string, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || gameKey(r.Name) == "" {
+		return nil, fmt.Errorf("name must contain letters or digits")
+	}
+	if len(r.Name) > maxGameNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", maxGameNameLength)
+	}
+	if len(r.Aliases) > maxGameAliases {
+		return nil, fmt.Errorf("too many aliases (max %d)", maxGameAliases)
+	}
+	if r.SteamAppID != nil && *r.SteamAppID == 0 {
+		r.SteamAppID = nil
+	}
+
+	nameKey := gameKey(r.Name)
+	aliases := make(map[string]string, len(r.Aliases))
+	for _, alias := range r.Aliases {
+		alias = strings.TrimSpace(alias)
+		if len(alias) > maxGameNameLength {
+			return nil, fmt.Errorf("alias must be at most %d characters", maxGameNameLength)
+		}
+		key := gameKey(alias)
+		if key == "" || key == nameKey {
+			continue // Nothing to match, or the same as the name
+		}
+		if _, ok := aliases[key]; !ok {
+			aliases[key] = alias
+		}
+	}
+	return aliases, nil
+}
+
+// saveGame saves a game from a validated request with the aliases returned by validate, replacing
+// any previous aliases.
+func saveGame(db *gorm.DB, game *Game, req *gameRequest, aliases map[string]string) error {
+	game.Name = req.Name
+	game.Key = gameKey(req.Name)
+	game.SteamAppID = req.SteamAppID
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		keys := []string{game.Key}
+		for key := range aliases {
+			keys = append(keys, key)
+		}
+		var taken []Game
+		err := tx.Where("id != ? AND (key IN ? OR id IN (SELECT game_id FROM game_aliases WHERE key IN ?))", game.ID, keys, keys).
+			Limit(1).Find(&taken).Error
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return &gameConflictError{fmt.Sprintf("name or alias already belongs to game %q (ID %d)", taken[0].Name, taken[0].ID)}
+		}
+		if game.SteamAppID != nil {
+			if err := tx.Where("id != ? AND steam_app_id = ?", game.ID, *game.SteamAppID).Limit(1).Find(&taken).Error; err != nil {
+				return err
+			}
+			if len(taken) > 0 {
+				return &gameConflictError{fmt.Sprintf("Steam app ID already belongs to game %q (ID %d)", taken[0].Name, taken[0].ID)}
+			}
+		}
+
+		if err := tx.Save(game).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("game_id = ?", game.ID).Delete(&GameAlias{}).Error; err != nil {
+			return err
+		}
+		records := make([]GameAlias, 0, len(aliases))
+		for key, name := range aliases {
+			records = append(records, GameAlias{GameID: game.ID, Name: name, Key: key})
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+		if len(records) == 0 {
+			return nil
+		}
+		return tx.Create(&records).Error
+	})
+}
+
+// bindGameRequest reads and validates the body of creating or updating a game, returning its
+// aliases. Writes an error response and returns false if the request is invalid.
+func bindGameRequest(c *gin.Context, req *gameRequest) (map[string]string, bool) {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return nil, false
+	}
+	aliases, err := req.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return aliases, true
+}
+
+// respondGameSaveError writes the response for an error of saveGame.
+func respondGameSaveError(c *gin.Context, err error) {
+	var conflict *gameConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save game"})
+}
+
+// HandleCreateGame creates a game (admin only)
+func HandleCreateGame(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req gameRequest
+		aliases, ok := bindGameRequest(c, &req)
+		if !ok {
+			return
+		}
+		game := Game{}
+		if err := saveGame(db.DB, &game, &req, aliases); err != nil {
+			respondGameSaveError(c, err)
+			return
+		}
+		respondGameSaved(c, db, &game, http.StatusCreated, LogGameCreated)
+	}
+}
+
+// HandleUpdateGame replaces the name, aliases and Steam app ID of a game (admin only)
+func HandleUpdateGame(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+			return
+		}
+		var req gameRequest
+		aliases, ok := bindGameRequest(c, &req)
+		if !ok {
+			return
+		}
+		var game Game
+		if err := db.DB.First(&game, gameID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+			return
+		}
+		if err := saveGame(db.DB, &game, &req, aliases); err != nil {
+			respondGameSaveError(c, err)
+			return
+		}
+		respondGameSaved(c, db, &game, http.StatusOK, LogGameUpdated)
+	}
+}
+
+// respondGameSaved logs the change of a saved game and returns the game.
+func respondGameSaved(c *gin.Context, db *DBInstance, game *Game, status int, logChange func(uint, string, *Game)) {
+	games := []Game{*game}
+	if err := loadGameDetails(db.DB, games); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if adminUserID, exists := c.Get("UserID"); exists {
+		if uid, ok := adminUserID.(uint); ok {
+			logChange(uid, GetUsernameFromContext(c), &games[0])
+		}
+	}
+	c.JSON(status, games[0])
+}
+
+// HandleDeleteGame deletes a game and unlinks its benchmarks (admin only)
+func HandleDeleteGame(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gameID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game ID"})
+			return
+		}
+		var game Game
+		if err := db.DB.First(&game, gameID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+			return
+		}
+
+		var unlinked int64
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			// Trashed benchmarks are unlinked too, so they can't be restored with a dangling link
+			result := tx.Unscoped().Model(&Benchmark{}).Where("game_id = ?", game.ID).UpdateColumn("game_id", nil)
+			if result.Error != nil {
+				return result.Error
+			}
+			unlinked = result.RowsAffected
+			// Revisions too, so reverting to one can't link the deleted game again
+			if err := tx.Model(&BenchmarkRevision{}).Where("game_id = ?", game.ID).UpdateColumn("game_id", nil).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("game_id = ?", game.ID).Delete(&GameAlias{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&game).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete game"})
+			return
+		}
+
+		if adminUserID, exists := c.Get("UserID"); exists {
+			if uid, ok := adminUserID.(uint); ok {
+				LogGameDeleted(uid, GetUsernameFromContext(c), game.ID, game.Name, unlinked)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "game deleted"})
+	}
+}
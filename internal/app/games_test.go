@@ -0,0 +1,382 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGameKey(t *testing.T) {
+	tests := map[string]string{
+		"Cyberpunk 2077":         "cyberpunk2077",
+		"cyberpunk-2077":         "cyberpunk2077",
+		"DOOM: The Dark Ages":    "doomthedarkages",
+		"  Baldur's Gate 3  ":    "baldursgate3",
+		"Ōkami HD":               "ōkamihd",
+		"!!!":                    "",
+		"Counter-Strike 2 (CS2)": "counterstrike2cs2",
+	}
+	for name, want := range tests {
+		if got := gameKey(name); got != want {
+			t.Errorf("gameKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMangoHudExecutable(t *testing.T) {
+	tests := map[string]string{
+		"Cyberpunk2077_2025-10-01_20-15-00":     "Cyberpunk2077",
+		"eldenring.exe_2025-10-01_20-15-00":     "eldenring",
+		"Some_Game_2025-10-01_20-15-00":         "Some_Game",
+		"Cyberpunk2077_2025-10-01":              "",
+		"6.17 EEVDF #1":                         "",
+		" hl2_linux_2024-01-31_08-00-59 ":       "hl2_linux",
+		"wine64-preloader_2025-10-01_20-15-00x": "",
+	}
+	for label, want := range tests {
+		if got := mangoHudExecutable(label); got != want {
+			t.Errorf("mangoHudExecutable(%q) = %q, want %q", label, got, want)
+		}
+	}
+}
+
+func TestGames(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	uintPtr := func(v uint) *uint { return &v }
+
+	// Admins skip the upload rate limit
+	admin := createTestUser(db, "gameadmin", true)
+	router := revisionTestRouter(db, admin)
+	asAdmin := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("UserID", admin.ID)
+			c.Set("IsAdmin", true)
+			handler(c)
+		}
+	}
+	router.POST("/api/benchmarks", asAdmin(HandleCreateBenchmark(db)))
+	router.GET("/api/benchmarks", HandleListBenchmarks(db))
+	router.GET("/api/games", HandleListGames(db))
+	router.GET("/api/games/suggest", HandleSuggestGame(db))
+	router.GET("/api/games/:id", HandleGetGame(db))
+	router.POST("/api/admin/games", asAdmin(HandleCreateGame(db)))
+	router.PUT("/api/admin/games/:id", asAdmin(HandleUpdateGame(db)))
+	router.DELETE("/api/admin/games/:id", asAdmin(HandleDeleteGame(db)))
+
+	send := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("Failed to decode response: %v (%s)", err, w.Body.String())
+		}
+	}
+	create := func(title string, labels ...string) Benchmark {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createBenchmarkRequest(t, title, labels...))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+		}
+		var benchmark Benchmark
+		decode(w, &benchmark)
+		return benchmark
+	}
+	gameOf := func(benchmarkID uint) *uint {
+		t.Helper()
+		var benchmark Benchmark
+		db.DB.Unscoped().First(&benchmark, benchmarkID)
+		return benchmark.GameID
+	}
+	listTitles := func(query string) []string {
+		t.Helper()
+		w := send(http.MethodGet, "/api/benchmarks?sort_by=title&sort_order=asc&"+query, nil)
+		var response struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		decode(w, &response)
+		var titles []string
+		for _, b := range response.Benchmarks {
+			titles = append(titles, b.Title)
+		}
+		return titles
+	}
+
+	// Uploaded before the game exists
+	early := create("Night City on an old driver", "Cyberpunk2077_2025-09-01_20-15-00")
+	if early.GameID != nil {
+		t.Fatalf("expected no game without games, got %v", *early.GameID)
+	}
+
+	var cyberpunk, elden Game
+	t.Run("create", func(t *testing.T) {
+		w := send(http.MethodPost, "/api/admin/games", gameRequest{
+			Name:       " Cyberpunk 2077 ",
+			Aliases:    []string{"CP2077", "Cyberpunk2077", "cp-2077", ""},
+			SteamAppID: uintPtr(1091524),
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		decode(w, &cyberpunk)
+		if cyberpunk.Name != "Cyberpunk 2077" || !reflect.DeepEqual(cyberpunk.Aliases, []string{"CP2077"}) {
+			t.Errorf("expected the name trimmed and aliases deduplicated, got %+v", cyberpunk)
+		}
+		// Saving a game is not an edit of the benchmarks, so earlier uploads stay unlinked
+		if cyberpunk.BenchmarkCount != 0 || gameOf(early.ID) != nil {
+			t.Errorf("expected the earlier benchmark left alone, got %d benchmarks", cyberpunk.BenchmarkCount)
+		}
+
+		w = send(http.MethodPost, "/api/admin/games", gameRequest{Name: "Elden Ring", Aliases: []string{"eldenring"}})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		decode(w, &elden)
+
+		tests := []struct {
+			name string
+			req  gameRequest
+			code int
+		}{
+			{"alias taken as name", gameRequest{Name: "cp 2077"}, http.StatusConflict},
+			{"name taken as alias", gameRequest{Name: "Phantom Liberty", Aliases: []string{"CYBERPUNK 2077"}}, http.StatusConflict},
+			{"steam app ID taken", gameRequest{Name: "Phantom Liberty", SteamAppID: uintPtr(1091524)}, http.StatusConflict},
+			{"no letters", gameRequest{Name: "!!!"}, http.StatusBadRequest},
+			{"too many aliases", gameRequest{Name: "Phantom Liberty", Aliases: make([]string, maxGameAliases+1)}, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			if w := send(http.MethodPost, "/api/admin/games", tt.req); w.Code != tt.code {
+				t.Errorf("%s: expected %d, got %d: %s", tt.name, tt.code, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		path := fmt.Sprintf("/api/admin/games/%d", elden.ID)
+		w := send(http.MethodPut, path, gameRequest{Name: "ELDEN RING", Aliases: []string{"eldenring", "ER"}, SteamAppID: uintPtr(1245620)})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		decode(w, &elden)
+		if elden.Name != "ELDEN RING" || elden.SteamAppID == nil || !reflect.DeepEqual(elden.Aliases, []string{"ER"}) {
+			t.Errorf("unexpected game after update: %+v", elden)
+		}
+
+		if w := send(http.MethodPut, path, gameRequest{Name: "Elden Ring", Aliases: []string{"CP2077"}}); w.Code != http.StatusConflict {
+			t.Errorf("expected 409 for another game's alias, got %d", w.Code)
+		}
+		if w := send(http.MethodPut, "/api/admin/games/9999", gameRequest{Name: "Missing"}); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a missing game, got %d", w.Code)
+		}
+	})
+
+	t.Run("list and detail", func(t *testing.T) {
+		w := send(http.MethodGet, "/api/games", nil)
+		var response struct {
+			Games []Game `json:"games"`
+			Total int64  `json:"total"`
+		}
+		decode(w, &response)
+		if response.Total != 2 || response.Games[0].ID != cyberpunk.ID {
+			t.Errorf("expected the most benchmarked game first, got %+v", response.Games)
+		}
+
+		decode(send(http.MethodGet, "/api/games?search=cp20", nil), &response)
+		if len(response.Games) != 1 || response.Games[0].ID != cyberpunk.ID {
+			t.Errorf("expected games searched by alias, got %+v", response.Games)
+		}
+
+		var game Game
+		decode(send(http.MethodGet, fmt.Sprintf("/api/games/%d", elden.ID), nil), &game)
+		if game.Name != "ELDEN RING" || len(game.Aliases) != 1 {
+			t.Errorf("unexpected game detail: %+v", game)
+		}
+		if w := send(http.MethodGet, "/api/games/9999", nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a missing game, got %d", w.Code)
+		}
+	})
+
+	t.Run("suggest", func(t *testing.T) {
+		tests := []struct {
+			query  string
+			game   uint
+			source string
+		}{
+			{"title=Benchmark&label=Cyberpunk2077.exe_2025-10-01_20-15-00.csv", cyberpunk.ID, "executable"},
+			{"title=Elden+Ring+vs+CP2077&label=eldenring_2025-10-01_20-15-00", elden.ID, "executable"},
+			{"title=Elden+Ring+on+the+Deck&label=run+1", elden.ID, "title"},
+			{"title=ER+on+the+Deck", 0, ""}, // Short keys only match executables
+			{"title=Unrelated&label=run", 0, ""},
+		}
+		for _, tt := range tests {
+			var response struct {
+				Game   *Game  `json:"game"`
+				Source string `json:"source"`
+			}
+			decode(send(http.MethodGet, "/api/games/suggest?"+tt.query, nil), &response)
+			var got uint
+			if response.Game != nil {
+				got = response.Game.ID
+			}
+			if got != tt.game || response.Source != tt.source {
+				t.Errorf("%s: expected game %d from %q, got %d from %q", tt.query, tt.game, tt.source, got, response.Source)
+			}
+		}
+	})
+
+	var deck, nightCity Benchmark
+	t.Run("upload", func(t *testing.T) {
+		deck = create("Elden Ring on the Deck", "run 1")
+		if deck.GameID == nil || *deck.GameID != elden.ID || deck.Game == nil || deck.Game.Name != "ELDEN RING" {
+			t.Errorf("expected the suggested game linked, got %v %+v", deck.GameID, deck.Game)
+		}
+		nightCity = create("Night City at max settings", "Cyberpunk2077_2025-10-01_20-15-00")
+		if nightCity.GameID == nil || *nightCity.GameID != cyberpunk.ID {
+			t.Errorf("expected the game of the executable linked, got %v", nightCity.GameID)
+		}
+		if other := create("Unrelated", "run 1"); other.GameID != nil {
+			t.Errorf("expected no game, got %d", *other.GameID)
+		}
+
+		runs := []*BenchmarkData{{Label: "Cyberpunk2077_2025-10-01_20-15-00"}}
+		if id, err := uploadGame(db.DB, uintPtr(0), "Cyberpunk 2077", runs); err != nil || id != nil {
+			t.Errorf("expected no game when requested, got %v, %v", id, err)
+		}
+		if id, err := uploadGame(db.DB, &elden.ID, "Cyberpunk 2077", runs); err != nil || id == nil || *id != elden.ID {
+			t.Errorf("expected the requested game, got %v, %v", id, err)
+		}
+		if _, err := uploadGame(db.DB, uintPtr(9999), "", nil); err != errGameNotFound {
+			t.Errorf("expected errGameNotFound, got %v", err)
+		}
+	})
+
+	t.Run("benchmark update", func(t *testing.T) {
+		setGame := func(gameID uint) *httptest.ResponseRecorder {
+			t.Helper()
+			var benchmark Benchmark
+			db.DB.First(&benchmark, deck.ID)
+			body, _ := json.Marshal(map[string]uint{"game_id": gameID})
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/benchmarks/%d", deck.ID), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, benchmark.Revision))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := setGame(cyberpunk.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var updated Benchmark
+		decode(w, &updated)
+		if updated.Game == nil || updated.Game.ID != cyberpunk.ID || updated.Revision != deck.Revision+1 {
+			t.Errorf("expected the game changed in a new revision, got %+v", updated)
+		}
+
+		if w := setGame(9999); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown game, got %d", w.Code)
+		}
+		if w := setGame(0); w.Code != http.StatusOK || gameOf(deck.ID) != nil {
+			t.Errorf("expected the game unlinked, got %d %v", w.Code, gameOf(deck.ID))
+		}
+		if w := setGame(elden.ID); w.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("benchmark filter", func(t *testing.T) {
+		tests := map[string][]string{
+			"game=cp2077":                      {"Night City at max settings"},
+			"game=Cyberpunk+2077":              {"Night City at max settings"},
+			fmt.Sprintf("game=%d", elden.ID):   {"Elden Ring on the Deck"},
+			"game=unknown":                     nil,
+			"game=9999":                        nil,
+			"game=eldenring&search=night+city": nil,
+		}
+		for query, want := range tests {
+			if got := listTitles(query); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected %v, got %v", query, want, got)
+			}
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		server := newMCPServer(db, "test", "")
+		out, err := server.toolListGames(json.RawMessage(`{"search": "elden"}`))
+		if err != nil {
+			t.Fatalf("list_games failed: %v", err)
+		}
+		var games struct {
+			Games []Game `json:"games"`
+			Total int64  `json:"total"`
+		}
+		if err := json.Unmarshal([]byte(out), &games); err != nil || games.Total != 1 || games.Games[0].BenchmarkCount != 1 {
+			t.Errorf("unexpected list_games result: %s", out)
+		}
+
+		out, err = server.toolListBenchmarks(json.RawMessage(`{"game": "cyberpunk 2077"}`))
+		if err != nil {
+			t.Fatalf("list_benchmarks failed: %v", err)
+		}
+		var benchmarks struct {
+			Benchmarks []Benchmark `json:"benchmarks"`
+		}
+		if err := json.Unmarshal([]byte(out), &benchmarks); err != nil || len(benchmarks.Benchmarks) != 1 ||
+			benchmarks.Benchmarks[0].Game == nil || benchmarks.Benchmarks[0].Game.Name != "Cyberpunk 2077" {
+			t.Errorf("unexpected list_benchmarks result: %s", out)
+		}
+
+		out, err = server.toolUpdateBenchmark(json.RawMessage(fmt.Sprintf(`{"id": %d, "game_id": 0}`, deck.ID)), admin.ID, admin.Username, true)
+		if err != nil || gameOf(deck.ID) != nil {
+			t.Errorf("expected the game unlinked, got %v: %s", err, out)
+		}
+		if _, err := server.toolUpdateBenchmark(json.RawMessage(fmt.Sprintf(`{"id": %d, "game_id": 9999}`, deck.ID)), admin.ID, admin.Username, true); err == nil {
+			t.Error("expected an unknown game rejected")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		path := fmt.Sprintf("/api/admin/games/%d", cyberpunk.ID)
+		if w := send(http.MethodDelete, path, nil); w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if gameOf(nightCity.ID) != nil {
+			t.Error("expected the benchmarks of a deleted game unlinked")
+		}
+		var revisions int64
+		db.DB.Model(&BenchmarkRevision{}).Where("game_id = ?", cyberpunk.ID).Count(&revisions)
+		if revisions != 0 {
+			t.Errorf("expected the revisions of a deleted game unlinked, got %d", revisions)
+		}
+		var aliases int64
+		db.DB.Model(&GameAlias{}).Where("game_id = ?", cyberpunk.ID).Count(&aliases)
+		if aliases != 0 {
+			t.Errorf("expected the aliases deleted, got %d", aliases)
+		}
+		if w := send(http.MethodDelete, path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a deleted game, got %d", w.Code)
+		}
+	})
+}
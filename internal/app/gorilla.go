@@ -0,0 +1,165 @@
+package app
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// Gorilla XOR float encoding (Pelkonen et al., "Gorilla: A Fast, Scalable, In-Memory Time Series
+// Database"). Each value is XORed with the previous one; consecutive sensor readings tend to share
+// sign, exponent and high mantissa bits, so the XOR has long runs of zeros that are not stored.
+//
+// Per value after the first (stored as raw 64 bits):
+//
+//	0                                  value repeats
+//	10 <meaningful bits>               XOR fits the previous leading/trailing zero window
+//	11 <5b leading> <6b length> <bits> new window (length 64 is stored as 0)
+
+// errGorillaTruncated is returned when a Gorilla stream ends before the expected value count.
+var errGorillaTruncated = errors.New("gorilla stream truncated")
+
+type bitWriter struct {
+	buf   []byte
+	nbits uint8 // Bits used in the last byte (0 = last byte full or no bytes)
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.nbits == 0 {
+		w.buf = append(w.buf, 0)
+	}
+	if bit {
+		w.buf[len(w.buf)-1] |= 1 << (7 - w.nbits)
+	}
+	w.nbits = (w.nbits + 1) % 8
+}
+
+// writeBits writes the low n bits of v, most significant first.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(v>>uint(i)&1 == 1)
+	}
+}
+
+type bitReader struct {
+	buf []byte
+	pos int // Bit position
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= len(r.buf)*8 {
+		return false, errGorillaTruncated
+	}
+	bit := r.buf[r.pos/8]>>(7-uint(r.pos%8))&1 == 1
+	r.pos++
+	return bit, nil
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, nil
+}
+
+// encodeGorilla encodes values as a Gorilla XOR bit stream.
+func encodeGorilla(values []float64) []byte {
+	w := &bitWriter{buf: make([]byte, 0, len(values)*2)}
+	if len(values) == 0 {
+		return w.buf
+	}
+
+	prev := math.Float64bits(values[0])
+	w.writeBits(prev, 64)
+	prevLeading, prevTrailing := -1, 0
+	for _, v := range values[1:] {
+		cur := math.Float64bits(v)
+		xor := cur ^ prev
+		prev = cur
+		if xor == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+
+		leading := min(bits.LeadingZeros64(xor), 31)
+		trailing := bits.TrailingZeros64(xor)
+		if prevLeading >= 0 && leading >= prevLeading && trailing >= prevTrailing {
+			w.writeBit(false)
+			w.writeBits(xor>>uint(prevTrailing), 64-prevLeading-prevTrailing)
+			continue
+		}
+		w.writeBit(true)
+		length := 64 - leading - trailing
+		w.writeBits(uint64(leading), 5)
+		w.writeBits(uint64(length%64), 6)
+		w.writeBits(xor>>uint(trailing), length)
+		prevLeading, prevTrailing = leading, trailing
+	}
+	return w.buf
+}
+
+// decodeGorilla decodes count values from a Gorilla XOR bit stream.
+func decodeGorilla(data []byte, count int) ([]float64, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	// Every value takes at least one bit; reject counts the stream can't hold before allocating
+	if count < 0 || count-1 > len(data)*8 {
+		return nil, errGorillaTruncated
+	}
+
+	r := &bitReader{buf: data}
+	values := make([]float64, count)
+	prev, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	values[0] = math.Float64frombits(prev)
+
+	leading, trailing := 0, 0
+	for i := 1; i < count; i++ {
+		changed, err := r.readBit()
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			newWindow, err := r.readBit()
+			if err != nil {
+				return nil, err
+			}
+			if newWindow {
+				l, err := r.readBits(5)
+				if err != nil {
+					return nil, err
+				}
+				n, err := r.readBits(6)
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 {
+					n = 64
+				}
+				if int(l)+int(n) > 64 {
+					return nil, errors.New("invalid gorilla window")
+				}
+				leading, trailing = int(l), 64-int(l)-int(n)
+			}
+			meaningful, err := r.readBits(64 - leading - trailing)
+			if err != nil {
+				return nil, err
+			}
+			prev ^= meaningful << uint(trailing)
+		}
+		values[i] = math.Float64frombits(prev)
+	}
+	return values, nil
+}
@@ -143,6 +143,40 @@ type MetricSummary struct {
 	Data     []float64 `json:"data,omitempty"`
 }
 
+// BottleneckSummary holds the CPU/GPU-bound classification of a run in MCP format.
+type BottleneckSummary struct {
+	GPUBoundPct float64                  `json:"gpu_bound_pct"`
+	CPUBoundPct float64                  `json:"cpu_bound_pct"`
+	CappedPct   float64                  `json:"capped_pct"`
+	UnknownPct  float64                  `json:"unknown_pct"`
+	CapFPS      float64                  `json:"cap_fps,omitempty"`
+	Segments    []BottleneckSegmentEntry `json:"segments"`
+}
+
+// BottleneckSegmentEntry is a contiguous part of a run with a single bottleneck state in MCP format.
+type BottleneckSegmentEntry struct {
+	State      string  `json:"state"`
+	StartIndex int     `json:"start_index"`
+	EndIndex   int     `json:"end_index"`
+	StartMs    float64 `json:"start_ms"`
+	EndMs      float64 `json:"end_ms"`
+}
+
+// ThrottlingEventEntry is a thermal or power throttling event in MCP format.
+type ThrottlingEventEntry struct {
+	Component    string  `json:"component"`
+	Cause        string  `json:"cause"`
+	Severity     string  `json:"severity"`
+	StartIndex   int     `json:"start_index"`
+	EndIndex     int     `json:"end_index"`
+	StartMs      float64 `json:"start_ms"`
+	EndMs        float64 `json:"end_ms"`
+	ClockDropPct float64 `json:"clock_drop_pct"`
+	FPSDropPct   float64 `json:"fps_drop_pct"`
+	PeakTemp     float64 `json:"peak_temp,omitempty"`
+	AvgPower     float64 `json:"avg_power,omitempty"`
+}
+
 // BenchmarkDataSummary holds computed stats per metric for a benchmark run.
 // This is the primary response format — stats are always computed from full data.
 type BenchmarkDataSummary struct {
@@ -153,11 +187,37 @@ type BenchmarkDataSummary struct {
 	SpecRAM            string                    `json:"spec_ram"`
 	SpecLinuxKernel    string                    `json:"spec_linux_kernel,omitempty"`
 	SpecLinuxScheduler string                    `json:"spec_linux_scheduler,omitempty"`
+	SpecDriver         string                    `json:"spec_driver,omitempty"`
 	TotalDataPoints    int                       `json:"total_data_points"`
+	Group              string                    `json:"group,omitempty"`
+	TotalEnergy        float64                   `json:"total_energy_joules,omitempty"`
+	Bottleneck         *BottleneckSummary        `json:"bottleneck,omitempty"`
+	Throttling         []ThrottlingEventEntry    `json:"throttling,omitempty"`
+	Range              *StatsRange               `json:"range,omitempty"` // Section of the run the stats cover (get_benchmark_run with from/to)
 	DownsampledTo      int                       `json:"downsampled_to,omitempty"`
 	Metrics            map[string]*MetricSummary `json:"metrics"`
 }
 
+// ComparedRunSummary is one run of a comparison in MCP format.
+type ComparedRunSummary struct {
+	BenchmarkID    uint                            `json:"benchmark_id"`
+	BenchmarkTitle string                          `json:"benchmark_title,omitempty"`
+	RunIndex       int                             `json:"run_index"`
+	Missing        bool                            `json:"missing,omitempty"`
+	Run            *BenchmarkDataSummary           `json:"run,omitempty"`
+	Deltas         map[string]map[string]StatDelta `json:"deltas,omitempty"` // metric -> statistic -> delta vs the baseline
+}
+
+// ComparisonSummary is a run comparison in MCP format.
+type ComparisonSummary struct {
+	ID             string                    `json:"id"`
+	URL            string                    `json:"url,omitempty"`
+	Baseline       int                       `json:"baseline"`
+	Runs           []ComparedRunSummary      `json:"runs"`
+	Specs          map[string]*SpecFieldDiff `json:"specs"`
+	DifferingSpecs []string                  `json:"differing_specs"`
+}
+
 // mcpServer holds the MCP server state
 type mcpServer struct {
 	db            *DBInstance
@@ -200,17 +260,28 @@ func (s *mcpServer) defineTools() []mcpTool {
 		{
 			Name:        "list_benchmarks",
 			Title:       "List Benchmarks",
-			Description: "Search and list gaming benchmarks with pagination, search, and sorting. Returns benchmark metadata including title, description (markdown), user, run count, and timestamps. After listing, use get_benchmark_data to retrieve statistics for analysis. Response: {\"benchmarks\": [...], \"total\": N, \"page\": N, \"per_page\": N, \"total_pages\": N}. jq example: \".benchmarks | max_by(.run_count)\".",
+			Description: "Search and list gaming benchmarks with pagination, search, game, hardware filters (gpu, cpu, os, kernel, scheduler, ram, driver), run stat ranges (min/max), and sorting, e.g. by avg_fps. Returns benchmark metadata including title, description (markdown), user, game, run count, timestamps, and run_stats: the headline stats of every run (avg_fps, p01_fps, p05_fps, avg_frame_time, avg_cpu_load, avg_gpu_load, avg_cpu_power, avg_gpu_power, avg_fps_per_watt; null without data). Request facets to see which hardware values are common among the matches before narrowing down. After listing, use get_benchmark_data to retrieve statistics for analysis. Response: {\"benchmarks\": [{..., \"run_stats\": [{\"run_index\", \"label\", \"avg_fps\", \"p01_fps\", ...}]}], \"total\": N, \"page\": N, \"per_page\": N, \"total_pages\": N, \"facets\": {\"gpu\": [{\"value\", \"count\"}]}}. jq example: \".benchmarks[] | {title, best_fps: ([.run_stats[].avg_fps] | max)}\".",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"page":       map[string]interface{}{"type": "integer", "description": "Page number (default: 1)"},
 					"per_page":   map[string]interface{}{"type": "integer", "description": "Results per page, 1-100 (default: 10)"},
-					"search":     map[string]interface{}{"type": "string", "description": "Search keywords (space-separated, AND logic). Searches title, description, username, run names, and specifications."},
+					"search":     map[string]interface{}{"type": "string", "description": "Search keywords (space-separated, AND logic) and \"quoted phrases\". Searches title, description, username, run names, and specifications; words match as prefixes. Matching benchmarks include highlights: snippets of the matching fields with matches wrapped in <mark>."},
 					"user_id":    map[string]interface{}{"type": "integer", "description": "Filter by user ID"},
 					"username":   map[string]interface{}{"type": "string", "description": "Filter by exact username (case-insensitive). Use this instead of user_id when you know the username but not the ID."},
-					"sort_by":    map[string]interface{}{"type": "string", "enum": []string{"title", "created_at", "updated_at"}, "description": "Sort field (default: created_at)"},
+					"game":       map[string]interface{}{"type": "string", "description": "Filter by game: its ID, name or an alias, e.g. \"Cyberpunk 2077\" or \"cp2077\" (case, spaces and punctuation are ignored). Use list_games to find games."},
+					"sort_by":    map[string]interface{}{"type": "string", "enum": append([]string{"relevance", "title", "created_at", "updated_at"}, runStatColumns...), "description": "Sort field (default: created_at). relevance ranks search matches best first, title matches weighing most. Run stats (avg_fps, p01_fps = 1% low, avg_gpu_power in W, avg_fps_per_watt, ...) sort by the best run matching the hardware and stat filters: its highest value when descending, lowest when ascending; benchmarks without the stat come last."},
 					"sort_order": map[string]interface{}{"type": "string", "enum": []string{"asc", "desc"}, "description": "Sort order (default: desc)"},
+					"os":         map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose OS contains this (case-insensitive). All spec filters must match the same run."},
+					"cpu":        map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose CPU contains this, e.g. \"9800X3D\""},
+					"gpu":        map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose GPU contains this, e.g. \"RX 9070\""},
+					"ram":        map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose RAM contains this"},
+					"kernel":     map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose Linux kernel contains this, e.g. \"6.17\""},
+					"scheduler":  map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose CPU scheduler contains this, e.g. \"bore\""},
+					"driver":     map[string]interface{}{"type": "string", "description": "Only benchmarks with a run whose graphics driver contains this (not recorded for older runs)"},
+					"facets":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": specFieldNames()}, "description": "Spec fields to count: the response gets facets mapping each field to its 10 most common values among all matching benchmarks, as [{\"value\", \"count\"}] with count = number of benchmarks"},
+					"min":        map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "number"}, "description": "Lower bounds of run stats, e.g. {\"avg_fps\": 60}. Valid stats: " + strings.Join(runStatColumns, ", ") + ". Stat and spec filters must all match the same run."},
+					"max":        map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "number"}, "description": "Upper bounds of run stats, e.g. {\"avg_gpu_power\": 200}"},
 					"jq":         jqProperty,
 				},
 			},
@@ -237,7 +308,7 @@ func (s *mcpServer) defineTools() []mcpTool {
 		{
 			Name:        "get_benchmark_data",
 			Title:       "Get Benchmark Statistics",
-			Description: "Get benchmark metadata and computed statistics for all runs in a single call. Returns the benchmark info (title, description, user, timestamps) alongside per-metric stats: min, max, avg, median, p01, p05, p10, p25, p75, p90, p95, p97, p99, iqr, std_dev, variance, count. FPS stats are correctly derived from frametime data. Raw data points are omitted by default; set max_points > 0 to include downsampled time series. This is the primary tool for benchmark analysis — no need to call get_benchmark separately. Response: {\"benchmark\": {...}, \"runs\": [{\"label\": ..., \"metrics\": {\"fps\": {\"min\", \"max\", \"avg\", ...}, \"frametime\": {...}, ...}}]}. jq example: \".runs[] | {label, fps_avg: .metrics.fps.avg, fps_1pct: .metrics.fps.p01}\".",
+			Description: "Get benchmark metadata and computed statistics for all runs in a single call. Returns the benchmark info (title, description, user, timestamps) alongside per-metric stats: min, max, avg, median, p01, p05, p10, p25, p75, p90, p95, p97, p99, iqr, std_dev, variance, count. FPS stats are correctly derived from frametime data. Raw data points are omitted by default; set max_points > 0 to include downsampled time series. Repeated passes of one configuration can be grouped (explicitly or via the benchmark's run_group_pattern); groups carry the mean and stddev of per-run averages plus pooled stats, so compare groups rather than individual passes when present. Runs with GPU load data include a bottleneck classification (gpu_bound_pct, cpu_bound_pct, capped_pct, unknown_pct for windows where neither GPU nor CPU is saturated, detected cap_fps and time segments) answering whether the capture was CPU- or GPU-limited. Thermal/power throttling events (component, cause, severity, time range, clock_drop_pct, fps_drop_pct) explain FPS drops caused by clocks falling at a temperature or power limit. Runs with power data include efficiency metrics (fps_per_watt, fps_per_watt_total, energy_per_frame in mJ) and total_energy_joules. This is the primary tool for benchmark analysis — no need to call get_benchmark separately. Response: {\"benchmark\": {...}, \"runs\": [{\"label\": ..., \"group\": ..., \"bottleneck\": {\"gpu_bound_pct\", \"cpu_bound_pct\", \"capped_pct\", \"unknown_pct\", \"cap_fps\", \"segments\": [...]}, \"metrics\": {\"fps\": {\"min\", \"max\", \"avg\", ...}, \"frametime\": {...}, ...}}], \"groups\": [{\"name\": ..., \"run_indices\": [...], \"metrics\": {\"fps\": {\"mean_of_avgs\", \"stddev_of_avgs\", \"min_avg\", \"max_avg\", \"runs\", \"pooled\": {...}}}}]}. jq example: \".runs[] | {label, fps_avg: .metrics.fps.avg, fps_1pct: .metrics.fps.p01}\".",
 			InputSchema: map[string]interface{}{
 				"type":     "object",
 				"required": []string{"id"},
@@ -254,7 +325,7 @@ func (s *mcpServer) defineTools() []mcpTool {
 		{
 			Name:        "get_benchmark_run",
 			Title:       "Get Run Statistics",
-			Description: "Get computed statistics for a specific run within a benchmark. Same stats as get_benchmark_data but for a single run. Raw data points omitted by default. Response: flat run object with label, spec_os, spec_cpu, spec_gpu, spec_ram, total_data_points, metrics: {fps, frametime, cpu_load, gpu_load, cpu_temp, gpu_temp, ...}.",
+			Description: "Get computed statistics for a specific run within a benchmark. Same stats as get_benchmark_data but for a single run. Raw data points omitted by default. Set from/to (sample index or elapsed ms via unit) to compute all stats for just a section of the run, e.g. a boss fight; the response then includes range with the resolved from_index, to_index, from_ms and to_ms, and data point indices are relative to the section start. Response: flat run object with label, spec_os, spec_cpu, spec_gpu, spec_ram, total_data_points, metrics: {fps, frametime, cpu_load, gpu_load, cpu_temp, gpu_temp, ...}.",
 			InputSchema: map[string]interface{}{
 				"type":     "object",
 				"required": []string{"id", "run_index"},
@@ -262,6 +333,9 @@ func (s *mcpServer) defineTools() []mcpTool {
 					"id":         map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
 					"run_index":  map[string]interface{}{"type": "integer", "description": "Run index (0-based)"},
 					"max_points": map[string]interface{}{"type": "integer", "description": "Include downsampled raw data points (default: 0 = stats only). Set 1-5000 for time series data alongside stats."},
+					"from":       map[string]interface{}{"type": "number", "description": "Start of a section to compute stats for (inclusive), in samples or milliseconds depending on unit. Omit from and to for whole-run stats."},
+					"to":         map[string]interface{}{"type": "number", "description": "End of the section (exclusive). Defaults to the end of the run."},
+					"unit":       map[string]interface{}{"type": "string", "enum": []string{"index", "ms"}, "description": "Unit of from/to: sample index (default) or elapsed milliseconds"},
 					"jq":         jqProperty,
 				},
 			},
@@ -269,25 +343,176 @@ func (s *mcpServer) defineTools() []mcpTool {
 			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(true), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
 			accessLevel: toolAccessPublic,
 		},
+		{
+			Name:        "list_benchmark_revisions",
+			Title:       "List Benchmark Revisions",
+			Description: "List the edit history of a benchmark, newest revision first. Each revision is a snapshot of the title, description, run group pattern, game_id and runs (label and content hash) after a change, with the action that produced it (created, updated, runs_added, run_deleted, run_restored, reverted, or imported for the state when the history started), its author and time. Response: {\"revisions\": [...], \"revision\": N (current), \"total\": N, \"page\": N, \"per_page\": N, \"total_pages\": N}.",
+			InputSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id"},
+				"properties": map[string]interface{}{
+					"id":       map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+					"page":     map[string]interface{}{"type": "integer", "description": "Page number (default: 1)"},
+					"per_page": map[string]interface{}{"type": "integer", "description": "Results per page, 1-100 (default: 10)"},
+					"jq":       jqProperty,
+				},
+			},
+			Icons:       faIcon("clock-rotate-left"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(true), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessPublic,
+		},
+		{
+			Name:        "diff_benchmark_revisions",
+			Title:       "Compare Benchmark Revisions",
+			Description: "Show what changed between two revisions of a benchmark: title, description (with a line diff, op \"=\", \"-\" or \"+\"), run group pattern, game, and runs added, removed or relabeled (runs are matched by content hash). Defaults compare the current revision with the one before. Response: {\"from\": N, \"to\": N, \"title\": {\"from\", \"to\"}, \"description\": {\"from\", \"to\", \"lines\": [...]}, \"run_group_pattern\": {...}, \"game_id\": {\"from\", \"to\"}, \"runs_added\": [...], \"runs_removed\": [...], \"runs_relabeled\": [...]}; unchanged fields are omitted.",
+			InputSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id"},
+				"properties": map[string]interface{}{
+					"id":   map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+					"from": map[string]interface{}{"type": "integer", "description": "Older revision (default: the one before to)"},
+					"to":   map[string]interface{}{"type": "integer", "description": "Newer revision (default: the current revision)"},
+					"jq":   jqProperty,
+				},
+			},
+			Icons:       faIcon("code-compare"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(true), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessPublic,
+		},
+		{
+			Name:        "list_games",
+			Title:       "List Games",
+			Description: "List the games benchmarks can be linked to, most benchmarked first. Every game has a canonical name, aliases (abbreviations and executable names) and an optional Steam app ID. Use a game's ID or name as the game filter of list_benchmarks. Response: {\"games\": [{\"id\", \"name\", \"aliases\", \"steam_app_id\", \"benchmark_count\"}], \"total\": N, \"page\": N, \"per_page\": N, \"total_pages\": N}.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"search":   map[string]interface{}{"type": "string", "description": "Only games whose name or an alias contains this (case-insensitive)"},
+					"page":     map[string]interface{}{"type": "integer", "description": "Page number (default: 1)"},
+					"per_page": map[string]interface{}{"type": "integer", "description": "Results per page, 1-100 (default: 20)"},
+					"jq":       jqProperty,
+				},
+			},
+			Icons:       faIcon("gamepad"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(true), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessPublic,
+		},
 		{
 			Name:        "update_benchmark",
 			Title:       "Update Benchmark Metadata",
-			Description: "Update benchmark metadata (title, description) and/or run labels. Description supports markdown formatting. Requires authentication via API token. Only the benchmark owner or an admin can update.",
+			Description: "Update benchmark metadata (title, description, game), run labels and run groups. Description supports markdown formatting. Requires authentication via API token. Only the benchmark owner or an admin can update.",
 			InputSchema: map[string]interface{}{
 				"type":     "object",
 				"required": []string{"id"},
 				"properties": map[string]interface{}{
-					"id":          map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
-					"title":       map[string]interface{}{"type": "string", "description": "New title (max 100 chars)"},
-					"description": map[string]interface{}{"type": "string", "description": "New description in markdown format (max 5000 chars)"},
-					"labels":      map[string]interface{}{"type": "object", "description": "Map of run index (as string) to new label, e.g. {\"0\": \"Run A\", \"1\": \"Run B\"}", "additionalProperties": map[string]interface{}{"type": "string"}},
-					"jq":          jqProperty,
+					"id":                map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+					"title":             map[string]interface{}{"type": "string", "description": "New title (max 100 chars)"},
+					"description":       map[string]interface{}{"type": "string", "description": "New description in markdown format (max 5000 chars)"},
+					"labels":            map[string]interface{}{"type": "object", "description": "Map of run index (as string) to new label, e.g. {\"0\": \"Run A\", \"1\": \"Run B\"}", "additionalProperties": map[string]interface{}{"type": "string"}},
+					"groups":            map[string]interface{}{"type": "object", "description": "Map of run index (as string) to explicit run group name; an empty string clears it. Explicit groups override run_group_pattern.", "additionalProperties": map[string]interface{}{"type": "string"}},
+					"run_group_pattern": map[string]interface{}{"type": "string", "description": "Regular expression grouping runs by label (max 200 chars). With a capture group, the first submatch is the group name; otherwise the matched text is removed from the label, e.g. \"\\s*#\\d+$\" groups \"6.17 EEVDF #1\" and \"6.17 EEVDF #2\". Empty string disables it."},
+					"game_id":           map[string]interface{}{"type": "integer", "description": "ID of the game the benchmark is of (from list_games); 0 unlinks it"},
+					"revision":          map[string]interface{}{"type": "integer", "description": "Expected benchmark revision (from get_benchmark). When set, the update fails if the benchmark was modified since"},
+					"jq":                jqProperty,
 				},
 			},
 			Icons:       faIcon("pen"),
 			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
 			accessLevel: toolAccessAuth,
 		},
+		{
+			Name:        "revert_benchmark",
+			Title:       "Revert Benchmark Metadata",
+			Description: "Restore the title, description, run group pattern, game and run labels of an earlier revision (from list_benchmark_revisions) as a new revision. Labels are applied to the current runs with the same content hash; runs deleted since are not brought back (see restore_run). Requires authentication via API token. Only the benchmark owner or an admin can revert. Response: the updated benchmark.",
+			InputSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id", "target_revision"},
+				"properties": map[string]interface{}{
+					"id":              map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+					"target_revision": map[string]interface{}{"type": "integer", "description": "Revision to restore the metadata of"},
+					"revision":        map[string]interface{}{"type": "integer", "description": "Expected current benchmark revision. When set, the revert fails if the benchmark was modified since"},
+					"jq":              jqProperty,
+				},
+			},
+			Icons:       faIcon("clock-rotate-left"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAuth,
+		},
+		{
+			Name:        "compare_runs",
+			Title:       "Compare Runs Across Benchmarks",
+			Description: "Compare runs from any benchmarks side by side, e.g. the same game on two distributions uploaded by different users. Pass 2-10 runs as benchmark_id/run_index pairs and the position of the baseline run; every other run gets per-metric deltas against it (diff and percent for min, max, avg, median, percentiles, iqr, std_dev, variance). Spec fields (os, cpu, gpu, ram, kernel, scheduler) are listed per run with differs=true where they don't all match. The comparison is saved under a permanent id (the same runs always get the same id); pass id instead of runs to load it again. Requires authentication via API token. Response: {\"id\": ..., \"url\": ..., \"baseline\": N, \"runs\": [{\"benchmark_id\", \"benchmark_title\", \"run_index\", \"missing\", \"run\": {\"label\", \"spec_gpu\", ..., \"metrics\": {...}}, \"deltas\": {\"fps\": {\"avg\": {\"diff\", \"percent\"}, ...}}}], \"specs\": {\"kernel\": {\"values\": [...], \"differs\": true}, ...}, \"differing_specs\": [...]}. jq example: \"{differing_specs, fps: [.runs[] | {label: .run.label, avg: .run.metrics.fps.avg, delta_pct: .deltas.fps.avg.percent}]}\".",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"runs": map[string]interface{}{
+						"type":        "array",
+						"description": "Runs to compare (2-10), in display order",
+						"items": map[string]interface{}{
+							"type":     "object",
+							"required": []string{"benchmark_id", "run_index"},
+							"properties": map[string]interface{}{
+								"benchmark_id": map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+								"run_index":    map[string]interface{}{"type": "integer", "description": "Run index (0-based)"},
+							},
+						},
+					},
+					"baseline": map[string]interface{}{"type": "integer", "description": "Position in runs of the baseline run (default: 0)"},
+					"id":       map[string]interface{}{"type": "string", "description": "ID of a saved comparison to load instead of passing runs"},
+					"jq":       jqProperty,
+				},
+			},
+			Icons:       faIcon("code-compare"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAuth,
+		},
+		{
+			Name:        "list_trash",
+			Title:       "List Trash",
+			Description: "List deleted benchmarks and runs that can still be restored, most recently deleted first. Each entry has purge_at, when it is permanently deleted. Users see their own trash; admins see every user's trash, or one user's with user_id. Requires authentication via API token. Response: {\"benchmarks\": [{\"id\", \"title\", \"user_id\", \"run_count\", \"deleted_at\", \"purge_at\", ...}], \"runs\": [{\"id\", \"benchmark_id\", \"benchmark_title\", \"run_index\", \"label\", \"trashed_at\", \"purge_at\", ...}], \"retention_hours\": N}.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"user_id": map[string]interface{}{"type": "integer", "description": "Only list the trash of this user (admins only)"},
+					"jq":      jqProperty,
+				},
+			},
+			Icons:       faIcon("trash-can"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(true), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAuth,
+		},
+		{
+			Name:        "restore_benchmark",
+			Title:       "Restore Deleted Benchmark",
+			Description: "Restore a deleted benchmark from the trash (see list_trash). Counts against the owner's storage quota again. Requires authentication via API token. Only the benchmark owner or an admin can restore.",
+			InputSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id"},
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{"type": "integer", "description": "Benchmark ID"},
+					"jq": jqProperty,
+				},
+			},
+			Icons:       faIcon("trash-can-arrow-up"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAuth,
+		},
+		{
+			Name:        "restore_run",
+			Title:       "Restore Deleted Run",
+			Description: "Restore a deleted run from the trash into its benchmark, at the index it had (or last if the benchmark has fewer runs now). Takes the trashed run ID from list_trash, not the run index. The benchmark must not be in the trash itself. Requires authentication via API token. Only the benchmark owner or an admin can restore. Response: {\"benchmark_id\": N, \"run_index\": N, \"revision\": N}.",
+			InputSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []string{"id"},
+				"properties": map[string]interface{}{
+					"id":       map[string]interface{}{"type": "integer", "description": "Trashed run ID (from list_trash)"},
+					"revision": map[string]interface{}{"type": "integer", "description": "Expected benchmark revision. When set, the restore fails if the benchmark was modified since"},
+					"jq":       jqProperty,
+				},
+			},
+			Icons:       faIcon("rotate-left"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAuth,
+		},
 		{
 			Name:        "list_users",
 			Title:       "List Users",
@@ -308,13 +533,13 @@ func (s *mcpServer) defineTools() []mcpTool {
 		{
 			Name:        "delete_user",
 			Title:       "Delete User Account",
-			Description: "Delete a user account. Admin only. Cannot delete your own account. Optionally delete all user data (benchmarks). Requires authentication via API token with admin privileges.",
+			Description: "Delete a user account. Admin only. Cannot delete your own account. Optionally delete all user data (benchmarks) permanently, bypassing the trash. Requires authentication via API token with admin privileges.",
 			InputSchema: map[string]interface{}{
 				"type":     "object",
 				"required": []string{"user_id"},
 				"properties": map[string]interface{}{
 					"user_id":     map[string]interface{}{"type": "integer", "description": "User ID to delete"},
-					"delete_data": map[string]interface{}{"type": "boolean", "description": "Also delete all benchmark data files (default: false)"},
+					"delete_data": map[string]interface{}{"type": "boolean", "description": "Also permanently delete all benchmarks and their data files, including those in the trash (default: false)"},
 					"jq":          jqProperty,
 				},
 			},
@@ -325,7 +550,7 @@ func (s *mcpServer) defineTools() []mcpTool {
 		{
 			Name:        "delete_user_benchmarks",
 			Title:       "Delete User Benchmarks",
-			Description: "Delete all benchmarks belonging to a user. Admin only. Requires authentication via API token with admin privileges.",
+			Description: "Delete all benchmarks belonging to a user. They are moved to the trash and can be restored with restore_benchmark until they are purged. Admin only. Requires authentication via API token with admin privileges.",
 			InputSchema: map[string]interface{}{
 				"type":     "object",
 				"required": []string{"user_id"},
@@ -372,6 +597,22 @@ func (s *mcpServer) defineTools() []mcpTool {
 			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
 			accessLevel: toolAccessAdmin,
 		},
+		{
+			Name:        "recompute_stats",
+			Title:       "Recompute Pre-Calculated Stats",
+			Description: "Queue pre-calculated statistics for background recompute, either for one benchmark (benchmark_id) or for all benchmarks (all=true). Returns the number of benchmarks queued and the worker status, including the current stats algorithm version. Admin only. Requires authentication via API token with admin privileges.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"benchmark_id": map[string]interface{}{"type": "integer", "description": "Benchmark ID to recompute"},
+					"all":          map[string]interface{}{"type": "boolean", "description": "true to recompute every benchmark"},
+					"jq":           jqProperty,
+				},
+			},
+			Icons:       faIcon("arrows-rotate"),
+			Annotations: &mcpToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
+			accessLevel: toolAccessAdmin,
+		},
 	}
 }
 
@@ -510,8 +751,9 @@ func (s *mcpServer) handleInitialize(c *gin.Context, req *jsonrpcRequest) jsonrp
 Response shapes (important — list tools return envelopes, not bare arrays):
 - list_benchmarks: {"benchmarks": [...], "total": N, "page": N, "per_page": N, "total_pages": N}
 - list_users: {"users": [...], "total": N, "page": N, "per_page": N, "total_pages": N}
+- list_games: {"games": [...], "total": N, "page": N, "per_page": N, "total_pages": N}
 - get_benchmark_data: {"benchmark": {...}, "runs": [{label, spec_*, metrics: {fps, frametime, cpu_load, gpu_load, ...}}]}
-- get_benchmark, update_benchmark: flat benchmark object (id, title, description, user, run_count, run_labels, created_at, updated_at)
+- get_benchmark, update_benchmark: flat benchmark object (id, title, description, user, run_count, run_labels, revision, created_at, updated_at)
 - get_benchmark_run, ban_user, toggle_user_admin: flat object
 
 When writing jq filters: if the response shape is uncertain, call the tool once without jq first to inspect the structure, then apply targeted filters.
@@ -610,12 +852,28 @@ func (s *mcpServer) handleToolsCall(c *gin.Context, req *jsonrpcRequest) jsonrpc
 		result, toolErr = s.toolListBenchmarks(params.Arguments)
 	case "get_benchmark":
 		result, toolErr = s.toolGetBenchmark(params.Arguments)
+	case "list_games":
+		result, toolErr = s.toolListGames(params.Arguments)
 	case "get_benchmark_data":
 		result, toolErr = s.toolGetBenchmarkData(params.Arguments)
 	case "get_benchmark_run":
 		result, toolErr = s.toolGetBenchmarkRun(params.Arguments)
+	case "list_benchmark_revisions":
+		result, toolErr = s.toolListBenchmarkRevisions(params.Arguments)
+	case "diff_benchmark_revisions":
+		result, toolErr = s.toolDiffBenchmarkRevisions(params.Arguments)
 	case "update_benchmark":
 		result, toolErr = s.toolUpdateBenchmark(params.Arguments, userID, username, isAdmin)
+	case "revert_benchmark":
+		result, toolErr = s.toolRevertBenchmark(params.Arguments, userID, username, isAdmin)
+	case "compare_runs":
+		result, toolErr = s.toolCompareRuns(params.Arguments, userID)
+	case "list_trash":
+		result, toolErr = s.toolListTrash(params.Arguments, userID, isAdmin)
+	case "restore_benchmark":
+		result, toolErr = s.toolRestoreBenchmark(params.Arguments, userID, username, isAdmin)
+	case "restore_run":
+		result, toolErr = s.toolRestoreRun(params.Arguments, userID, username, isAdmin)
 	case "list_users":
 		result, toolErr = s.toolListUsers(params.Arguments)
 	case "delete_user":
@@ -626,6 +884,8 @@ func (s *mcpServer) handleToolsCall(c *gin.Context, req *jsonrpcRequest) jsonrpc
 		result, toolErr = s.toolBanUser(params.Arguments, userID, username)
 	case "toggle_user_admin":
 		result, toolErr = s.toolToggleUserAdmin(params.Arguments, userID, username)
+	case "recompute_stats":
+		result, toolErr = s.toolRecomputeStats(params.Arguments, userID, username)
 	default:
 		return jsonrpcResponse{
 			JSONRPC: "2.0",
@@ -798,13 +1058,24 @@ func applyJQFilter(args json.RawMessage, result string) (string, error) {
 
 func (s *mcpServer) toolListBenchmarks(args json.RawMessage) (string, error) {
 	var params struct {
-		Page      int    `json:"page"`
-		PerPage   int    `json:"per_page"`
-		Search    string `json:"search"`
-		UserID    int    `json:"user_id"`
-		Username  string `json:"username"`
-		SortBy    string `json:"sort_by"`
-		SortOrder string `json:"sort_order"`
+		Page      int                `json:"page"`
+		PerPage   int                `json:"per_page"`
+		Search    string             `json:"search"`
+		UserID    int                `json:"user_id"`
+		Username  string             `json:"username"`
+		Game      string             `json:"game"`
+		SortBy    string             `json:"sort_by"`
+		SortOrder string             `json:"sort_order"`
+		OS        string             `json:"os"`
+		CPU       string             `json:"cpu"`
+		GPU       string             `json:"gpu"`
+		RAM       string             `json:"ram"`
+		Kernel    string             `json:"kernel"`
+		Scheduler string             `json:"scheduler"`
+		Driver    string             `json:"driver"`
+		Facets    []string           `json:"facets"`
+		Min       map[string]float64 `json:"min"`
+		Max       map[string]float64 `json:"max"`
 	}
 	if args != nil {
 		if err := json.Unmarshal(args, &params); err != nil {
@@ -822,10 +1093,9 @@ func (s *mcpServer) toolListBenchmarks(args json.RawMessage) (string, error) {
 		params.PerPage = 10
 	}
 
-	query := s.db.DB.Preload("User")
-
+	var filter benchmarkListFilter
 	if params.UserID > 0 {
-		query = query.Where("user_id = ?", params.UserID)
+		filter.userID = uint(params.UserID)
 	}
 
 	// Resolve username to user_id if provided (case-insensitive exact match)
@@ -833,53 +1103,43 @@ func (s *mcpServer) toolListBenchmarks(args json.RawMessage) (string, error) {
 		var user User
 		if err := s.db.DB.Where("username = ? COLLATE NOCASE", params.Username).First(&user).Error; err != nil {
 			// Return empty results rather than revealing whether a username exists
-			query = query.Where("1 = 0")
+			filter.none = true
 		} else {
-			query = query.Where("user_id = ?", user.ID)
+			filter.userID = user.ID
 		}
 	}
 
-	if params.Search != "" {
-		// Limit search query length and keyword count to prevent SQL complexity DoS
-		const maxSearchLength = 200
-		const maxKeywords = 10
-		if len(params.Search) > maxSearchLength {
-			params.Search = params.Search[:maxSearchLength]
-		}
-		keywords := strings.Fields(params.Search)
-		if len(keywords) > maxKeywords {
-			keywords = keywords[:maxKeywords]
-		}
-		for _, keyword := range keywords {
-			keyword = strings.TrimSpace(keyword)
-			if len(keyword) < 3 {
-				continue
-			}
-			if keyword != "" {
-				// Escape LIKE wildcards to prevent pattern injection
-				escapedKeyword := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(keyword)
-				orClause := "benchmarks.title LIKE ? ESCAPE '\\' OR benchmarks.description LIKE ? ESCAPE '\\' OR EXISTS (SELECT 1 FROM users WHERE users.id = benchmarks.user_id AND users.username LIKE ? ESCAPE '\\') OR benchmarks.run_names LIKE ? ESCAPE '\\' OR benchmarks.specifications LIKE ? ESCAPE '\\'"
-				query = query.Where(orClause, "%"+escapedKeyword+"%", "%"+escapedKeyword+"%", "%"+escapedKeyword+"%", "%"+escapedKeyword+"%", "%"+escapedKeyword+"%")
-			}
+	if params.Game != "" {
+		gameID, err := resolveGame(s.db.DB, params.Game)
+		if err != nil {
+			return "", fmt.Errorf("database error")
 		}
+		filter.gameID = gameID
+		filter.none = filter.none || gameID == 0
 	}
 
-	// Sorting
-	var orderClause string
-	switch params.SortBy {
-	case "title":
-		orderClause = "title"
-	case "updated_at":
-		orderClause = "updated_at"
-	default:
-		orderClause = "created_at"
+	if params.Search != "" {
+		filter.search = parseBenchmarkSearch(params.Search, []string{"title", "description", "user", "run_name", "specifications"})
 	}
-	if params.SortOrder == "asc" {
-		orderClause += " ASC"
-	} else {
-		orderClause += " DESC"
+	specValues := map[string]string{
+		"os": params.OS, "cpu": params.CPU, "gpu": params.GPU, "ram": params.RAM,
+		"kernel": params.Kernel, "scheduler": params.Scheduler, "driver": params.Driver,
+	}
+	specs, err := parseSpecFilters(func(name string) string { return specValues[name] })
+	if err != nil {
+		return "", err
+	}
+	filter.specs = specs
+	if filter.stats, err = statRangeFilters(params.Min, params.Max); err != nil {
+		return "", err
+	}
+	facets, err := parseSpecFacets(params.Facets)
+	if err != nil {
+		return "", err
 	}
-	query = query.Order(orderClause)
+
+	query := filter.apply(s.db.DB.Preload("User").Preload("Game"))
+	query = orderBenchmarks(query, params.SortBy, params.SortOrder, &filter)
 
 	var total int64
 	if err := query.Model(&Benchmark{}).Count(&total).Error; err != nil {
@@ -891,6 +1151,14 @@ func (s *mcpServer) toolListBenchmarks(args json.RawMessage) (string, error) {
 	if err := query.Offset(offset).Limit(params.PerPage).Find(&benchmarks).Error; err != nil {
 		return "", fmt.Errorf("database error")
 	}
+	if filter.search != nil {
+		if err := filter.search.highlight(s.db.DB, benchmarks); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+	if err := loadRunStats(s.db.DB, benchmarks); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
 
 	// Populate run count and labels
 	const maxConcurrentMetaReads = 20
@@ -920,6 +1188,13 @@ func (s *mcpServer) toolListBenchmarks(args json.RawMessage) (string, error) {
 		"total":       total,
 		"total_pages": totalPages,
 	}
+	if len(facets) > 0 {
+		counts, err := specFacets(s.db.DB, facets, filter.apply(s.db.DB.Model(&Benchmark{})).Select("benchmarks.id"))
+		if err != nil {
+			return "", fmt.Errorf("database error")
+		}
+		result["facets"] = counts
+	}
 
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -940,7 +1215,7 @@ func (s *mcpServer) toolGetBenchmark(args json.RawMessage) (string, error) {
 	}
 
 	var benchmark Benchmark
-	if err := s.db.DB.Preload("User").First(&benchmark, params.ID).Error; err != nil {
+	if err := s.db.DB.Preload("User").Preload("Game").First(&benchmark, params.ID).Error; err != nil {
 		return "", fmt.Errorf("benchmark not found")
 	}
 
@@ -957,6 +1232,46 @@ func (s *mcpServer) toolGetBenchmark(args json.RawMessage) (string, error) {
 	return string(data), nil
 }
 
+func (s *mcpServer) toolListGames(args json.RawMessage) (string, error) {
+	var params struct {
+		Search  string `json:"search"`
+		Page    int    `json:"page"`
+		PerPage int    `json:"per_page"`
+	}
+	if args != nil {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Page > 10000 {
+		params.Page = 10000
+	}
+	if params.PerPage < 1 || params.PerPage > 100 {
+		params.PerPage = 20
+	}
+
+	games, total, err := listGames(s.db.DB, params.Search, params.Page, params.PerPage)
+	if err != nil {
+		return "", fmt.Errorf("database error")
+	}
+
+	result := map[string]interface{}{
+		"games":       games,
+		"page":        params.Page,
+		"per_page":    params.PerPage,
+		"total":       total,
+		"total_pages": int((total + int64(params.PerPage) - 1) / int64(params.PerPage)),
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
 func (s *mcpServer) toolGetBenchmarkData(args json.RawMessage) (string, error) {
 	var params struct {
 		ID        int `json:"id"`
@@ -979,7 +1294,7 @@ func (s *mcpServer) toolGetBenchmarkData(args json.RawMessage) (string, error) {
 
 	// Verify benchmark exists and load metadata
 	var benchmark Benchmark
-	if err := s.db.DB.Preload("User").First(&benchmark, params.ID).Error; err != nil {
+	if err := s.db.DB.Preload("User").Preload("Game").First(&benchmark, params.ID).Error; err != nil {
 		return "", fmt.Errorf("benchmark not found")
 	}
 
@@ -990,7 +1305,7 @@ func (s *mcpServer) toolGetBenchmarkData(args json.RawMessage) (string, error) {
 	}
 
 	// Use pre-calculated stats
-	preCalc, err := RetrievePreCalculatedStats(uint(params.ID))
+	preCalc, groups, err := RetrievePreCalculatedStatsWithGroups(uint(params.ID))
 	if err != nil {
 		return "", fmt.Errorf("pre-calculated stats not available")
 	}
@@ -1003,6 +1318,7 @@ func (s *mcpServer) toolGetBenchmarkData(args json.RawMessage) (string, error) {
 	result := map[string]interface{}{
 		"benchmark": benchmark,
 		"runs":      summaries,
+		"groups":    RunGroupStatsToMCP(groups),
 	}
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -1013,9 +1329,12 @@ func (s *mcpServer) toolGetBenchmarkData(args json.RawMessage) (string, error) {
 
 func (s *mcpServer) toolGetBenchmarkRun(args json.RawMessage) (string, error) {
 	var params struct {
-		ID        int `json:"id"`
-		RunIndex  int `json:"run_index"`
-		MaxPoints int `json:"max_points"`
+		ID        int      `json:"id"`
+		RunIndex  int      `json:"run_index"`
+		MaxPoints int      `json:"max_points"`
+		From      *float64 `json:"from"`
+		To        *float64 `json:"to"`
+		Unit      string   `json:"unit"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("invalid arguments: %w", err)
@@ -1041,6 +1360,19 @@ func (s *mcpServer) toolGetBenchmarkRun(args json.RawMessage) (string, error) {
 		return "", fmt.Errorf("benchmark not found")
 	}
 
+	// Section stats are computed from the raw data of the run
+	if params.From != nil || params.To != nil {
+		summary, err := mcpRangeRunSummary(uint(params.ID), params.RunIndex, params.From, params.To, params.Unit, maxPoints)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(summary)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal result: %w", err)
+		}
+		return string(data), nil
+	}
+
 	// Use pre-calculated stats for the single run
 	run, err := RetrievePreCalculatedStatsRun(uint(params.ID), params.RunIndex)
 	if err != nil {
@@ -1056,12 +1388,49 @@ func (s *mcpServer) toolGetBenchmarkRun(args json.RawMessage) (string, error) {
 	return string(data), nil
 }
 
+// mcpRangeRunSummary computes the MCP summary for a section of a run.
+func mcpRangeRunSummary(benchmarkID uint, runIndex int, fromArg, toArg *float64, unit string, maxPoints int) (*BenchmarkDataSummary, error) {
+	if unit == "" {
+		unit = "index"
+	}
+	if unit != "index" && unit != "ms" {
+		return nil, fmt.Errorf("unit must be index or ms")
+	}
+	from, to := 0.0, -1.0
+	if fromArg != nil {
+		from = *fromArg
+	}
+	if toArg != nil {
+		to = *toArg
+	}
+	if from < 0 || (toArg != nil && to < from) {
+		return nil, fmt.Errorf("invalid range")
+	}
+
+	run, err := RetrieveBenchmarkRun(benchmarkID, runIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve run: %w", err)
+	}
+	result, r, err := ComputeRangeStats(run, from, to, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := PreCalculatedRunToMCPSummary(result, maxPoints)
+	summary.Range = &r
+	return summary, nil
+}
+
 func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, username string, isAdmin bool) (string, error) {
 	var params struct {
-		ID          int               `json:"id"`
-		Title       string            `json:"title"`
-		Description string            `json:"description"`
-		Labels      map[string]string `json:"labels"`
+		ID              int               `json:"id"`
+		Title           string            `json:"title"`
+		Description     string            `json:"description"`
+		Labels          map[string]string `json:"labels"`
+		Groups          map[string]string `json:"groups"`
+		RunGroupPattern *string           `json:"run_group_pattern"`
+		GameID          *uint             `json:"game_id"`
+		Revision        *uint             `json:"revision"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("invalid arguments: %w", err)
@@ -1070,6 +1439,9 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 		return "", fmt.Errorf("id is required")
 	}
 
+	// Serialize with other mutations of this benchmark
+	defer lockBenchmarkWrites(uint(params.ID))()
+
 	var benchmark Benchmark
 	if err := s.db.DB.First(&benchmark, params.ID).Error; err != nil {
 		return "", fmt.Errorf("benchmark not found")
@@ -1080,10 +1452,18 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 		return "", fmt.Errorf("not authorized")
 	}
 
+	// Optimistic concurrency: reject the update if the benchmark changed since it was read
+	if params.Revision != nil && *params.Revision != benchmark.Revision {
+		return "", fmt.Errorf("benchmark was modified by another request (current revision %d)", benchmark.Revision)
+	}
+
 	// Guard against excessively large labels maps to prevent memory exhaustion
 	if len(params.Labels) > maxRunsPerBenchmark {
 		return "", fmt.Errorf("too many labels provided")
 	}
+	if len(params.Groups) > maxRunsPerBenchmark {
+		return "", fmt.Errorf("too many groups provided")
+	}
 
 	// Track what fields were changed for audit logging
 	var changes []string
@@ -1103,10 +1483,33 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 		benchmark.Description = params.Description
 		changes = append(changes, "description")
 	}
+	patternChanged := false
+	if params.RunGroupPattern != nil && *params.RunGroupPattern != benchmark.RunGroupPattern {
+		if err := ValidateRunGroupPattern(*params.RunGroupPattern); err != nil {
+			return "", err
+		}
+		benchmark.RunGroupPattern = *params.RunGroupPattern
+		changes = append(changes, "run_group_pattern")
+		patternChanged = true
+	}
+	if params.GameID != nil {
+		changed, err := setBenchmarkGame(s.db.DB, &benchmark, *params.GameID)
+		if err != nil {
+			return "", err
+		}
+		if changed {
+			changes = append(changes, "game")
+		}
+	}
 
-	// Update labels if provided
-	if len(params.Labels) > 0 {
-		changes = append(changes, "labels")
+	// Update labels and/or groups if provided
+	if len(params.Labels) > 0 || len(params.Groups) > 0 || patternChanged {
+		if len(params.Labels) > 0 {
+			changes = append(changes, "labels")
+		}
+		if len(params.Groups) > 0 {
+			changes = append(changes, "groups")
+		}
 		benchmarkData, err := RetrieveBenchmarkData(uint(params.ID))
 		if err != nil {
 			return "", fmt.Errorf("failed to retrieve benchmark data: %w", err)
@@ -1125,25 +1528,47 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 			}
 		}
 
-		if err := StoreBenchmarkData(benchmarkData, uint(params.ID)); err != nil {
-			return "", fmt.Errorf("failed to update labels: %w", err)
+		for idxStr, newGroup := range params.Groups {
+			idx, err := strconv.Atoi(idxStr)
+			if err != nil {
+				continue
+			}
+			if idx >= 0 && idx < len(benchmarkData) {
+				if len(newGroup) > maxStringLength {
+					return "", fmt.Errorf("group for run %d exceeds maximum length of %d characters", idx, maxStringLength)
+				}
+				benchmarkData[idx].Group = strings.TrimSpace(newGroup)
+			}
 		}
 
-		preCalc := ComputePreCalculatedRuns(benchmarkData)
-		if storeErr := StorePreCalculatedStats(preCalc, uint(params.ID)); storeErr != nil {
+		preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+
+		// A pattern change alone only affects the stats
+		if len(params.Labels) > 0 || len(params.Groups) > 0 {
+			if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, uint(params.ID)); err != nil {
+				return "", fmt.Errorf("failed to update labels: %w", err)
+			}
+		} else if storeErr := StorePreCalculatedStats(preCalc, groups, uint(params.ID)); storeErr != nil {
 			fmt.Printf("Warning: failed to update pre-calculated stats for benchmark %d: %v\n", params.ID, storeErr)
 		}
 
 		runNames, specifications := ExtractSearchableMetadata(benchmarkData)
 		benchmark.RunNames = runNames
 		benchmark.Specifications = specifications
+		setBenchmarkUsage(&benchmark, benchmarkData)
+
+		if len(params.Labels) > 0 {
+			if err := syncBenchmarkRuns(s.db, uint(params.ID), benchmarkData, preCalc); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
 
 		runtime.GC()
 	}
 
 	if len(changes) == 0 {
 		// Nothing changed — return current benchmark without writing to DB or emitting an audit entry
-		if err := s.db.DB.Preload("User").First(&benchmark, benchmark.ID).Error; err != nil {
+		if err := s.db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
 			return "", fmt.Errorf("failed to load benchmark: %w", err)
 		}
 		data, err := json.Marshal(benchmark)
@@ -1153,12 +1578,14 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 		return string(data), nil
 	}
 
+	benchmark.Revision++
 	if err := s.db.DB.Save(&benchmark).Error; err != nil {
 		return "", fmt.Errorf("failed to update benchmark: %w", err)
 	}
+	recordBenchmarkRevision(s.db, &benchmark, userID, username, revisionActionUpdated)
 
 	// Reload with user data
-	if err := s.db.DB.Preload("User").First(&benchmark, benchmark.ID).Error; err != nil {
+	if err := s.db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
 		return "", fmt.Errorf("failed to load benchmark: %w", err)
 	}
 
@@ -1171,6 +1598,352 @@ func (s *mcpServer) toolUpdateBenchmark(args json.RawMessage, userID uint, usern
 	return string(data), nil
 }
 
+func (s *mcpServer) toolListBenchmarkRevisions(args json.RawMessage) (string, error) {
+	var params struct {
+		ID      int `json:"id"`
+		Page    int `json:"page"`
+		PerPage int `json:"per_page"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PerPage < 1 || params.PerPage > 100 {
+		params.PerPage = 10
+	}
+
+	var benchmark Benchmark
+	if err := s.db.DB.First(&benchmark, params.ID).Error; err != nil {
+		return "", fmt.Errorf("benchmark not found")
+	}
+
+	query := s.db.DB.Model(&BenchmarkRevision{}).Where("benchmark_id = ?", benchmark.ID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	revisions := []BenchmarkRevision{}
+	if err := query.Order("revision DESC").Offset((params.Page - 1) * params.PerPage).Limit(params.PerPage).Find(&revisions).Error; err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"revisions":   revisions,
+		"revision":    benchmark.Revision,
+		"page":        params.Page,
+		"per_page":    params.PerPage,
+		"total":       total,
+		"total_pages": int((total + int64(params.PerPage) - 1) / int64(params.PerPage)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolDiffBenchmarkRevisions(args json.RawMessage) (string, error) {
+	var params struct {
+		ID   int  `json:"id"`
+		From uint `json:"from"`
+		To   uint `json:"to"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+
+	var benchmark Benchmark
+	if err := s.db.DB.First(&benchmark, params.ID).Error; err != nil {
+		return "", fmt.Errorf("benchmark not found")
+	}
+
+	var from, to string
+	if params.From > 0 {
+		from = strconv.FormatUint(uint64(params.From), 10)
+	}
+	if params.To > 0 {
+		to = strconv.FormatUint(uint64(params.To), 10)
+	}
+	diff, _, err := diffBenchmarkRevisionsQuery(s.db, &benchmark, from, to)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolRevertBenchmark(args json.RawMessage, userID uint, username string, isAdmin bool) (string, error) {
+	var params struct {
+		ID             int   `json:"id"`
+		TargetRevision uint  `json:"target_revision"`
+		Revision       *uint `json:"revision"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+	if params.TargetRevision == 0 {
+		return "", fmt.Errorf("target_revision is required")
+	}
+
+	// Serialize with other mutations of this benchmark
+	defer lockBenchmarkWrites(uint(params.ID))()
+
+	var benchmark Benchmark
+	if err := s.db.DB.First(&benchmark, params.ID).Error; err != nil {
+		return "", fmt.Errorf("benchmark not found")
+	}
+	if benchmark.UserID != userID && !isAdmin {
+		return "", fmt.Errorf("not authorized")
+	}
+	if params.Revision != nil && *params.Revision != benchmark.Revision {
+		return "", fmt.Errorf("benchmark was modified by another request (current revision %d)", benchmark.Revision)
+	}
+
+	target, err := findBenchmarkRevision(s.db, benchmark.ID, params.TargetRevision)
+	if err != nil {
+		return "", fmt.Errorf("revision not found")
+	}
+	changes, err := revertBenchmark(s.db, &benchmark, target)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) > 0 {
+		recordBenchmarkRevision(s.db, &benchmark, userID, username, revisionActionReverted)
+		LogBenchmarkReverted(userID, username, benchmark.ID, benchmark.Title, params.TargetRevision, changes)
+	}
+
+	if err := s.db.DB.Preload("User").Preload("Game").First(&benchmark, benchmark.ID).Error; err != nil {
+		return "", fmt.Errorf("failed to load benchmark: %w", err)
+	}
+	data, err := json.Marshal(benchmark)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolCompareRuns(args json.RawMessage, userID uint) (string, error) {
+	var params struct {
+		Runs     []ComparisonRun `json:"runs"`
+		Baseline int             `json:"baseline"`
+		ID       string          `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	var comparison *Comparison
+	switch {
+	case params.ID != "" && len(params.Runs) > 0:
+		return "", fmt.Errorf("pass either id or runs, not both")
+	case params.ID != "":
+		var err error
+		if comparison, err = findComparison(s.db, params.ID); err != nil {
+			return "", fmt.Errorf("comparison not found")
+		}
+	default:
+		var err error
+		if comparison, _, err = createComparison(s.db, userID, params.Runs, params.Baseline); err != nil {
+			return "", err
+		}
+	}
+
+	result, err := loadComparison(s.db, comparison, StatsSelection{Methods: []string{statsMethodLinear}, Stats: true})
+	if err != nil {
+		return "", err
+	}
+
+	summary := ComparisonSummary{
+		ID:             result.ID,
+		Baseline:       result.Baseline,
+		Runs:           make([]ComparedRunSummary, len(result.Runs)),
+		Specs:          result.Specs,
+		DifferingSpecs: result.DifferingSpecs,
+	}
+	if s.publicBaseURL != "" {
+		summary.URL = strings.TrimSuffix(s.publicBaseURL, "/") + "/compare/" + result.ID
+	}
+	for i, run := range result.Runs {
+		entry := ComparedRunSummary{
+			BenchmarkID:    run.BenchmarkID,
+			BenchmarkTitle: run.BenchmarkTitle,
+			RunIndex:       run.RunIndex,
+			Missing:        run.Missing,
+		}
+		if run.Run != nil {
+			entry.Run = PreCalculatedRunToMCPSummary(run.Run, 0)
+		}
+		if run.Deltas != nil {
+			// Use the metric and statistic names of the MCP run summaries
+			entry.Deltas = make(map[string]map[string]StatDelta)
+			for key, stats := range run.Deltas {
+				snakeKey, ok := metricKeyToSnake[key]
+				if !ok {
+					continue
+				}
+				stats["std_dev"] = stats["stddev"]
+				delete(stats, "stddev")
+				entry.Deltas[snakeKey] = stats
+			}
+		}
+		summary.Runs[i] = entry
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolListTrash(args json.RawMessage, userID uint, isAdmin bool) (string, error) {
+	var params struct {
+		UserID int `json:"user_id"`
+	}
+	if args != nil {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	if params.UserID < 0 {
+		return "", fmt.Errorf("invalid user_id")
+	}
+
+	allUsers := isAdmin
+	if params.UserID > 0 && isAdmin {
+		userID, allUsers = uint(params.UserID), false
+	}
+	listing, err := listTrash(s.db, userID, allUsers)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolRestoreBenchmark(args json.RawMessage, userID uint, username string, isAdmin bool) (string, error) {
+	var params struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+
+	// Serialize with the purger
+	defer lockBenchmarkWrites(uint(params.ID))()
+
+	var benchmark Benchmark
+	if err := s.db.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&benchmark, params.ID).Error; err != nil {
+		return "", fmt.Errorf("benchmark not found in the trash")
+	}
+	if benchmark.UserID != userID && !isAdmin {
+		return "", fmt.Errorf("not authorized")
+	}
+	usage, err := ownerStorageUsage(s.db, benchmark.UserID)
+	if err != nil {
+		return "", err
+	}
+	if err := usage.exceeded(1, int64(benchmark.StoredRuns), benchmark.DataLines); err != nil {
+		return "", err
+	}
+
+	if err := restoreTrashedBenchmark(s.db, &benchmark); err != nil {
+		return "", err
+	}
+
+	LogBenchmarkRestored(userID, username, benchmark.ID, benchmark.Title)
+
+	data, err := json.Marshal(benchmark)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *mcpServer) toolRestoreRun(args json.RawMessage, userID uint, username string, isAdmin bool) (string, error) {
+	var params struct {
+		ID       int   `json:"id"`
+		Revision *uint `json:"revision"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+
+	var trashed TrashedRun
+	if err := s.db.DB.First(&trashed, params.ID).Error; err != nil {
+		return "", fmt.Errorf("run not found in the trash")
+	}
+
+	// Serialize with other mutations of the benchmark, then make sure the run is still there
+	defer lockBenchmarkWrites(trashed.BenchmarkID)()
+	if err := s.db.DB.First(&trashed, params.ID).Error; err != nil {
+		return "", fmt.Errorf("run not found in the trash")
+	}
+
+	var benchmark Benchmark
+	if err := s.db.DB.Unscoped().First(&benchmark, trashed.BenchmarkID).Error; err != nil {
+		return "", fmt.Errorf("benchmark not found")
+	}
+	if benchmark.UserID != userID && !isAdmin {
+		return "", fmt.Errorf("not authorized")
+	}
+	if benchmark.DeletedAt.Valid {
+		return "", fmt.Errorf("benchmark is in the trash - restore the benchmark first")
+	}
+	if params.Revision != nil && *params.Revision != benchmark.Revision {
+		return "", fmt.Errorf("benchmark was modified by another request (current revision %d)", benchmark.Revision)
+	}
+	usage, err := ownerStorageUsage(s.db, benchmark.UserID)
+	if err != nil {
+		return "", err
+	}
+	if err := usage.exceeded(0, 1, trashed.DataLines); err != nil {
+		return "", err
+	}
+
+	runIndex, err := restoreTrashedRun(s.db, &benchmark, &trashed)
+	if err != nil {
+		return "", err
+	}
+
+	recordBenchmarkRevision(s.db, &benchmark, userID, username, revisionActionRunRestored)
+	LogBenchmarkRunRestored(userID, username, benchmark.ID, benchmark.Title, runIndex, trashed.Label)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"message":      "run restored",
+		"benchmark_id": benchmark.ID,
+		"run_index":    runIndex,
+		"revision":     benchmark.Revision,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
+
 func (s *mcpServer) toolListUsers(args json.RawMessage) (string, error) {
 	var params struct {
 		Page    int    `json:"page"`
@@ -1243,6 +2016,9 @@ func (s *mcpServer) toolListUsers(args json.RawMessage) (string, error) {
 			users[i].BenchmarkCount = int(benchCountMap[users[i].ID])
 			users[i].APITokenCount = int(tokenCountMap[users[i].ID])
 		}
+		if err := loadStorageUsage(s.db, users); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
 	}
 
 	totalPages := int((total + int64(params.PerPage) - 1) / int64(params.PerPage))
@@ -1292,17 +2068,20 @@ func (s *mcpServer) toolDeleteUser(args json.RawMessage, adminUserID uint, admin
 
 	if params.DeleteData {
 		var benchmarks []Benchmark
-		if err := s.db.DB.Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
+		if err := s.db.DB.Unscoped().Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
 			return "", fmt.Errorf("failed to find user benchmarks: %w", err)
 		}
 		for i := range benchmarks {
-			if delErr := DeleteBenchmarkData(benchmarks[i].ID); delErr != nil {
-				fmt.Printf("Warning: failed to delete data for benchmark %d\n", benchmarks[i].ID)
+			if delErr := purgeUserBenchmark(s.db, benchmarks[i].ID); delErr != nil {
+				fmt.Printf("Warning: failed to delete benchmark %d: %v\n", benchmarks[i].ID, delErr)
 			}
 		}
 	}
 
-	if err := s.db.DB.Delete(&user).Error; err != nil {
+	release := beginStorageMutation()
+	err := s.db.DB.Delete(&user).Error
+	release()
+	if err != nil {
 		return "", fmt.Errorf("failed to delete user: %w", err)
 	}
 
@@ -1336,17 +2115,14 @@ func (s *mcpServer) toolDeleteUserBenchmarks(args json.RawMessage, adminUserID u
 		return "", fmt.Errorf("user not found")
 	}
 
+	defer beginStorageMutation()()
+
 	var benchmarks []Benchmark
 	if err := s.db.DB.Where("user_id = ?", user.ID).Find(&benchmarks).Error; err != nil {
 		return "", fmt.Errorf("failed to find user benchmarks: %w", err)
 	}
 
-	for i := range benchmarks {
-		if delErr := DeleteBenchmarkData(benchmarks[i].ID); delErr != nil {
-			fmt.Printf("Warning: failed to delete data for benchmark %d\n", benchmarks[i].ID)
-		}
-	}
-
+	// Benchmarks go to the trash; their files are kept until it is purged
 	if err := s.db.DB.Where("user_id = ?", user.ID).Delete(&Benchmark{}).Error; err != nil {
 		return "", fmt.Errorf("failed to delete benchmarks: %w", err)
 	}
@@ -1452,3 +2228,39 @@ func (s *mcpServer) toolToggleUserAdmin(args json.RawMessage, adminUserID uint,
 	}
 	return string(data), nil
 }
+
+func (s *mcpServer) toolRecomputeStats(args json.RawMessage, adminUserID uint, adminUsername string) (string, error) {
+	var params struct {
+		BenchmarkID int  `json:"benchmark_id"`
+		All         bool `json:"all"`
+	}
+	if args != nil {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	if params.BenchmarkID < 0 {
+		return "", fmt.Errorf("invalid benchmark_id")
+	}
+
+	recomputer := GetStatsRecomputer()
+	if recomputer == nil {
+		return "", fmt.Errorf("stats recompute worker is not running")
+	}
+
+	queued, err := queueStatsRecompute(s.db, recomputer, uint(params.BenchmarkID), params.All)
+	if err != nil {
+		return "", err
+	}
+
+	LogStatsRecomputeRequested(adminUserID, adminUsername, uint(params.BenchmarkID), queued)
+
+	data, err := json.Marshal(map[string]interface{}{
+		"queued": queued,
+		"status": recomputer.Status(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(data), nil
+}
@@ -133,7 +133,7 @@ func TestMCPToolsList(t *testing.T) {
 
 	body := `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`
 
-	// Anonymous: should only see public tools (4)
+	// Anonymous: should only see public tools (7)
 	t.Run("anonymous sees only public tools", func(t *testing.T) {
 		w := mcpRequest(t, router, body, "")
 		if w.Code != http.StatusOK {
@@ -142,7 +142,7 @@ func TestMCPToolsList(t *testing.T) {
 		names := parseToolsList(t, w)
 		publicTools := []string{
 			"list_benchmarks", "get_benchmark", "get_benchmark_data",
-			"get_benchmark_run",
+			"get_benchmark_run", "list_benchmark_revisions", "diff_benchmark_revisions", "list_games",
 		}
 		if len(names) != len(publicTools) {
 			t.Errorf("Expected %d public tools, got %d: %v", len(publicTools), len(names), names)
@@ -170,7 +170,7 @@ func TestMCPToolsList(t *testing.T) {
 		}
 	})
 
-	// Authenticated regular user: should see public + auth tools (5)
+	// Authenticated regular user: should see public + auth tools (13)
 	t.Run("regular user sees public and auth tools", func(t *testing.T) {
 		user := createTestUser(db, "mcptoolslistuser", false)
 		apiToken := &APIToken{UserID: user.ID, Token: "toolslist-user-token-abcdef1230000000000000000000000000000000000000", Name: "ToolsList Token"}
@@ -181,8 +181,8 @@ func TestMCPToolsList(t *testing.T) {
 			t.Fatalf("Expected 200, got %d", w.Code)
 		}
 		names := parseToolsList(t, w)
-		if len(names) != 5 {
-			t.Errorf("Expected 5 tools for regular user, got %d: %v", len(names), names)
+		if len(names) != 13 {
+			t.Errorf("Expected 13 tools for regular user, got %d: %v", len(names), names)
 		}
 		// Should include auth tools
 		nameSet := make(map[string]bool)
@@ -191,7 +191,8 @@ func TestMCPToolsList(t *testing.T) {
 		}
 		for _, required := range []string{
 			"list_benchmarks", "get_benchmark", "get_benchmark_data", "get_benchmark_run",
-			"update_benchmark",
+			"list_benchmark_revisions", "diff_benchmark_revisions", "list_games",
+			"update_benchmark", "revert_benchmark", "compare_runs", "list_trash", "restore_benchmark", "restore_run",
 		} {
 			if !nameSet[required] {
 				t.Errorf("Missing auth tool: %s", required)
@@ -212,7 +213,7 @@ func TestMCPToolsList(t *testing.T) {
 		}
 	})
 
-	// Admin user: should see all tools (10)
+	// Admin user: should see all tools (19)
 	t.Run("admin sees all tools", func(t *testing.T) {
 		admin := createTestUser(db, "mcptoolslistadmin", true)
 		adminToken := &APIToken{UserID: admin.ID, Token: "toolslist-admin-token-abcdef120000000000000000000000000000000000000", Name: "ToolsList Admin"}
@@ -225,10 +226,12 @@ func TestMCPToolsList(t *testing.T) {
 		names := parseToolsList(t, w)
 		allTools := []string{
 			"list_benchmarks", "get_benchmark", "get_benchmark_data",
-			"get_benchmark_run",
-			"update_benchmark",
+			"get_benchmark_run", "list_benchmark_revisions", "diff_benchmark_revisions", "list_games",
+			"update_benchmark", "revert_benchmark", "compare_runs",
+			"list_trash", "restore_benchmark", "restore_run",
 			"list_users", "delete_user",
 			"delete_user_benchmarks", "ban_user", "toggle_user_admin",
+			"recompute_stats",
 		}
 		if len(names) != len(allTools) {
 			t.Errorf("Expected %d tools for admin, got %d: %v", len(allTools), len(names), names)
@@ -278,8 +281,17 @@ func TestMCPToolAnnotations(t *testing.T) {
 		"get_benchmark_data": {readOnly: true, destructive: false, idempotent: false, openWorld: false},
 		"get_benchmark_run":  {readOnly: true, destructive: false, idempotent: false, openWorld: false},
 
+		"list_benchmark_revisions": {readOnly: true, destructive: false, idempotent: false, openWorld: false},
+		"diff_benchmark_revisions": {readOnly: true, destructive: false, idempotent: false, openWorld: false},
+		"list_games":               {readOnly: true, destructive: false, idempotent: false, openWorld: false},
+
 		// Auth tools - write operations
-		"update_benchmark": {readOnly: false, destructive: false, idempotent: true, openWorld: false},
+		"update_benchmark":  {readOnly: false, destructive: false, idempotent: true, openWorld: false},
+		"revert_benchmark":  {readOnly: false, destructive: false, idempotent: true, openWorld: false},
+		"compare_runs":      {readOnly: false, destructive: false, idempotent: true, openWorld: false},
+		"list_trash":        {readOnly: true, destructive: false, idempotent: false, openWorld: false},
+		"restore_benchmark": {readOnly: false, destructive: false, idempotent: false, openWorld: false},
+		"restore_run":       {readOnly: false, destructive: false, idempotent: false, openWorld: false},
 
 		// Admin tools
 		"list_users":             {readOnly: true, destructive: false, idempotent: false, openWorld: false},
@@ -287,6 +299,7 @@ func TestMCPToolAnnotations(t *testing.T) {
 		"delete_user_benchmarks": {readOnly: false, destructive: true, idempotent: false, openWorld: false},
 		"ban_user":               {readOnly: false, destructive: false, idempotent: true, openWorld: false},
 		"toggle_user_admin":      {readOnly: false, destructive: false, idempotent: true, openWorld: false},
+		"recompute_stats":        {readOnly: false, destructive: false, idempotent: true, openWorld: false},
 	}
 
 	// Verify all tools are covered
@@ -481,6 +494,16 @@ func TestMCPWriteToolWithAuth(t *testing.T) {
 	if updated.Title != "Updated Title" {
 		t.Errorf("Expected 'Updated Title', got '%s'", updated.Title)
 	}
+	if updated.Revision != 2 {
+		t.Errorf("Expected revision 2, got %d", updated.Revision)
+	}
+
+	// A stale revision is rejected
+	body = `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"update_benchmark","arguments":{"id":` + idStr(b.ID) + `,"title":"Stale","revision":1}}}`
+	_, result = parseMCPToolResult(t, mcpRequest(t, router, body, apiToken.Token))
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "current revision 2") {
+		t.Errorf("Expected stale revision error, got %+v", result)
+	}
 }
 
 func TestMCPOwnershipCheck(t *testing.T) {
@@ -1255,7 +1278,7 @@ func mcpCreateBenchmarkHelper(t *testing.T, db *DBInstance, userID uint) int {
 
 	// Also store pre-calculated stats (required by new pre-calculated API)
 	preCalc := ComputePreCalculatedRuns(benchmarkData)
-	if err := StorePreCalculatedStats(preCalc, benchmark.ID); err != nil {
+	if err := StorePreCalculatedStats(preCalc, nil, benchmark.ID); err != nil {
 		t.Fatalf("Failed to store pre-calculated stats: %v", err)
 	}
 
@@ -23,8 +23,16 @@ const (
 	// - 3: Migrated benchmark storage format from V1 to V2 (streaming-friendly format) + regenerate metadata with JSON size
 	// - 4: Pre-calculate statistics for all benchmarks (.stats files) for instant loading
 	// - 5: Removed audit_logs table (audit logs moved to file-based JSON logging)
+	// - 6: Migrated benchmark storage format from V2 to V3 (columnar, delta/XOR-encoded columns)
+	// - 7: Added benchmark_runs table with per-run content hashes
+	// - 8: Added per-benchmark storage usage columns and per-user quota overrides
+	// - 9: Added benchmark_revisions table with the edit history of benchmarks
+	// - 10: Added benchmarks_fts full-text search index (requires the sqlite_fts5 build tag)
+	// - 11: Added normalized run spec columns to benchmark_runs for list filters and facets (lowercase,
+	//   without trademark marks, driver tags and CPU name suffixes)
+	// - 12: Added headline run stat columns to benchmark_runs for sorting and range filters
 	// Future versions should increment this and add migration logic in InitDB
-	currentSchemaVersion = 5
+	currentSchemaVersion = 12
 	// Maximum description length in new schema
 	maxDescriptionLength = 5000
 )
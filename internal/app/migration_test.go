@@ -451,13 +451,58 @@ func TestInitDBWithMigration(t *testing.T) {
 			t.Error("audit_logs table should have been dropped after v4→v5 migration")
 		}
 
-		// Verify schema version is now 5
+		// Verify schema version is now current (later migrations run in the same pass)
 		var version SchemaVersion
 		if queryErr := db.DB.Order("version DESC").First(&version).Error; queryErr != nil {
 			t.Fatalf("Failed to read schema version: %v", queryErr)
 		}
-		if version.Version != 5 {
-			t.Errorf("Expected schema version 5, got %d", version.Version)
+		if version.Version != currentSchemaVersion {
+			t.Errorf("Expected schema version %d, got %d", currentSchemaVersion, version.Version)
+		}
+	})
+
+	t.Run("v8 to v9 records revisions with their game", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		rawDB, err := gorm.Open(sqlite.Open(filepath.Join(tmpDir, "flightlesssomething.db")), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		if migrateErr := rawDB.AutoMigrate(&User{}, &Benchmark{}, &APIToken{}, &BenchmarkRun{}, &Game{}, &SchemaVersion{}); migrateErr != nil {
+			t.Fatalf("Failed to migrate schema: %v", migrateErr)
+		}
+		user := User{DiscordID: "v8user", Username: "v8user"}
+		rawDB.Create(&user)
+		game := Game{Name: "Elden Ring", Key: gameKey("Elden Ring")}
+		rawDB.Create(&game)
+		benchmark := Benchmark{UserID: user.ID, Title: "Existing", Revision: 3, GameID: &game.ID}
+		rawDB.Create(&benchmark)
+		if versionErr := setSchemaVersion(rawDB, 8); versionErr != nil {
+			t.Fatalf("Failed to set schema version: %v", versionErr)
+		}
+		sqlDB, dbErr := rawDB.DB()
+		if dbErr != nil {
+			t.Fatalf("Failed to get sql.DB: %v", dbErr)
+		}
+		if closeErr := sqlDB.Close(); closeErr != nil {
+			t.Fatalf("Failed to close database: %v", closeErr)
+		}
+
+		db, initErr := InitDB(tmpDir)
+		if initErr != nil {
+			t.Fatalf("Failed to initialize database: %v", initErr)
+		}
+		defer cleanupTestDB(t, db)
+
+		rev, err := findBenchmarkRevision(db, benchmark.ID, 3)
+		if err != nil || rev.GameID == nil || *rev.GameID != game.ID {
+			t.Errorf("expected the revision recorded with its game, got %+v %v", rev, err)
+		}
+		var version SchemaVersion
+		if queryErr := db.DB.Order("version DESC").First(&version).Error; queryErr != nil {
+			t.Fatalf("Failed to read schema version: %v", queryErr)
+		}
+		if version.Version != currentSchemaVersion {
+			t.Errorf("Expected schema version %d, got %d", currentSchemaVersion, version.Version)
 		}
 	})
 
@@ -27,6 +27,10 @@ type User struct {
 	BenchmarkCount    int        `gorm:"-" json:"benchmark_count,omitempty"`
 	APITokenCount     int        `gorm:"-" json:"api_token_count,omitempty"`
 
+	// QuotaOverrides replaces the server-wide storage quota per limit (see quota.go)
+	QuotaOverrides StorageQuotaOverrides `gorm:"embedded;embeddedPrefix:quota_" json:"-"`
+	Usage          *StorageUsage         `gorm:"-" json:"usage,omitempty"`
+
 	Benchmarks []Benchmark `gorm:"constraint:OnDelete:CASCADE;" json:"benchmarks,omitempty"`
 	APITokens  []APIToken  `gorm:"constraint:OnDelete:CASCADE;" json:"api_tokens,omitempty"`
 }
@@ -52,12 +56,138 @@ type Benchmark struct {
 	RunNames       string `gorm:"type:text" json:"run_names"`
 	Specifications string `gorm:"type:text" json:"specifications"`
 
+	// RunGroupPattern is an optional regular expression used to group repeated runs by label
+	RunGroupPattern string `gorm:"size:200" json:"run_group_pattern"`
+
+	// GameID links the benchmark to the game it is for (see games.go)
+	GameID *uint `gorm:"index" json:"game_id"`
+
+	// Revision is incremented on every update and returned as the ETag for optimistic concurrency
+	Revision uint `gorm:"not null;default:1" json:"revision"`
+
+	// Storage usage counted towards the owner's quota, updated whenever the files are written
+	StorageBytes int64 `gorm:"not null;default:0" json:"storage_bytes"` // Compressed .bin and .stats bytes
+	StoredRuns   int   `gorm:"not null;default:0" json:"-"`             // Run count (RunCount is read from the files)
+	DataLines    int64 `gorm:"not null;default:0" json:"data_lines"`
+
 	CreatedAtHumanized string   `gorm:"-" json:"created_at_humanized"`
 	UpdatedAtHumanized string   `gorm:"-" json:"updated_at_humanized"`
 	RunCount           int      `gorm:"-" json:"run_count,omitempty"`
 	RunLabels          []string `gorm:"-" json:"run_labels,omitempty"`
 
-	User User `gorm:"foreignKey:UserID;" json:"user,omitempty"`
+	// Highlights maps searched fields to snippets with matches wrapped in <mark>; only set in search results
+	Highlights map[string]string `gorm:"-" json:"highlights,omitempty"`
+
+	// RunStats lists the headline stats of every run; only set in list responses
+	RunStats []BenchmarkRunStats `gorm:"-" json:"run_stats,omitempty"`
+
+	// Duplicates lists uploaded runs that already existed; only set in upload responses
+	Duplicates []DuplicateRun `gorm:"-" json:"duplicates,omitempty"`
+
+	User User  `gorm:"foreignKey:UserID;" json:"user,omitempty"`
+	Game *Game `gorm:"foreignKey:GameID;" json:"game,omitempty"`
+}
+
+// Game is a game benchmarks can be linked to (see games.go)
+type Game struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Name       string    `gorm:"size:100" json:"name"`
+	Key        string    `gorm:"size:100;uniqueIndex" json:"-"` // Normalized name (see gameKey)
+	SteamAppID *uint     `gorm:"uniqueIndex" json:"steam_app_id"`
+
+	Aliases        []string `gorm:"-" json:"aliases,omitempty"`
+	BenchmarkCount int64    `gorm:"-" json:"benchmark_count,omitempty"`
+}
+
+// GameAlias is another name of a game, e.g. an abbreviation or the name of its executable
+type GameAlias struct {
+	ID     uint   `gorm:"primarykey"`
+	GameID uint   `gorm:"index"`
+	Name   string `gorm:"size:100"`
+	Key    string `gorm:"size:100;uniqueIndex"` // Normalized name, unique across games and aliases
+}
+
+// BenchmarkRun records the content hash and specs of one run of a benchmark (see benchmark_runs.go)
+type BenchmarkRun struct {
+	ID          uint   `gorm:"primarykey" json:"-"`
+	BenchmarkID uint   `gorm:"uniqueIndex:idx_benchmark_run" json:"benchmark_id"`
+	RunIndex    int    `gorm:"uniqueIndex:idx_benchmark_run" json:"run_index"`
+	Label       string `gorm:"size:100" json:"label"`
+	ContentHash string `gorm:"size:64;index" json:"content_hash"` // Hex SHA-256 of the run's specs and data
+
+	// Normalized specs of the run, for filters and facets of the benchmark list (see benchmark_specs.go)
+	SpecOS        string `gorm:"size:100" json:"spec_os"`
+	SpecCPU       string `gorm:"size:100;index" json:"spec_cpu"`
+	SpecGPU       string `gorm:"size:100;index" json:"spec_gpu"`
+	SpecRAM       string `gorm:"size:100" json:"spec_ram"`
+	SpecKernel    string `gorm:"size:100;index" json:"spec_kernel"`
+	SpecScheduler string `gorm:"size:100" json:"spec_scheduler"`
+	SpecDriver    string `gorm:"size:100" json:"spec_driver"`
+
+	// Headline stats of the run, for sorting and range filters of the benchmark list (see benchmark_run_stats.go)
+	RunStats `gorm:"embedded"`
+}
+
+// RunStats are the headline stats of a run. Stats the run has no data for are nil.
+type RunStats struct {
+	AvgFPS        *float64 `gorm:"column:avg_fps" json:"avg_fps"`
+	P01FPS        *float64 `gorm:"column:p01_fps" json:"p01_fps"` // 1% low
+	P05FPS        *float64 `gorm:"column:p05_fps" json:"p05_fps"` // 5% low
+	AvgFrameTime  *float64 `gorm:"column:avg_frame_time" json:"avg_frame_time"`
+	AvgCPULoad    *float64 `gorm:"column:avg_cpu_load" json:"avg_cpu_load"`
+	AvgGPULoad    *float64 `gorm:"column:avg_gpu_load" json:"avg_gpu_load"`
+	AvgCPUPower   *float64 `gorm:"column:avg_cpu_power" json:"avg_cpu_power"`
+	AvgGPUPower   *float64 `gorm:"column:avg_gpu_power" json:"avg_gpu_power"`
+	AvgFPSPerWatt *float64 `gorm:"column:avg_fps_per_watt" json:"avg_fps_per_watt"` // FPS per watt of GPU power
+}
+
+// BenchmarkRunStats is a run of a benchmark with its headline stats, as returned in list responses
+type BenchmarkRunStats struct {
+	RunIndex int    `json:"run_index"`
+	Label    string `json:"label"`
+	RunStats
+}
+
+// TrashedRun is a run deleted from a benchmark, kept in a trash blob until it is purged (see trash.go)
+type TrashedRun struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	BenchmarkID  uint      `gorm:"index" json:"benchmark_id"`
+	RunIndex     int       `json:"run_index"` // Index the run had when it was deleted
+	Label        string    `gorm:"size:100" json:"label"`
+	DataLines    int64     `gorm:"not null;default:0" json:"data_lines"`
+	StorageBytes int64     `gorm:"not null;default:0" json:"storage_bytes"` // Size of its trash blob
+	TrashedAt    time.Time `gorm:"index" json:"trashed_at"`
+
+	BenchmarkTitle string    `gorm:"-:migration;->" json:"benchmark_title"` // Joined from benchmarks when listing
+	UserID         uint      `gorm:"-:migration;->" json:"user_id"`         // Owner of the benchmark
+	PurgeAt        time.Time `gorm:"-" json:"purge_at"`
+}
+
+// BenchmarkRevision is a snapshot of a benchmark's metadata and run set at one revision (see benchmark_history.go)
+type BenchmarkRevision struct {
+	ID              uint         `gorm:"primarykey" json:"-"`
+	BenchmarkID     uint         `gorm:"uniqueIndex:idx_benchmark_revision" json:"benchmark_id"`
+	Revision        uint         `gorm:"uniqueIndex:idx_benchmark_revision" json:"revision"`
+	Title           string       `gorm:"size:100" json:"title"`
+	Description     string       `gorm:"size:5000" json:"description"`
+	RunGroupPattern string       `gorm:"size:200" json:"run_group_pattern"`
+	GameID          *uint        `json:"game_id"`
+	Runs            RevisionRuns `gorm:"type:text" json:"runs"`
+	Action          string       `gorm:"size:32" json:"action"` // What produced the revision, e.g. updated or run_deleted
+	UserID          uint         `json:"user_id"`               // Author of the revision
+	Username        string       `gorm:"size:32" json:"username"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// Comparison is a saved comparison of runs from any benchmarks, shared by its ID (see comparison.go)
+type Comparison struct {
+	ID        string         `gorm:"primarykey;size:16" json:"id"`
+	UserID    uint           `gorm:"index" json:"user_id"` // User who first created the comparison
+	Runs      ComparisonRuns `gorm:"type:text" json:"runs"`
+	Baseline  int            `json:"baseline"` // Index into Runs of the run the others are compared against
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 // AfterFind is a GORM hook that is called after a record is found
@@ -76,6 +206,7 @@ type BenchmarkMetadata struct {
 // BenchmarkData represents the actual benchmark data stored separately
 type BenchmarkData struct {
 	Label string
+	Group string // Explicit run group (overrides the benchmark's label pattern)
 
 	// System specs
 	SpecOS             string
@@ -84,6 +215,7 @@ type BenchmarkData struct {
 	SpecRAM            string
 	SpecLinuxKernel    string
 	SpecLinuxScheduler string
+	SpecDriver         string
 
 	// Performance data arrays
 	DataFPS          []float64
@@ -99,4 +231,5 @@ type BenchmarkData struct {
 	DataGPUPower     []float64
 	DataRAMUsed      []float64
 	DataSwapUsed     []float64
+	DataCPUClock     []float64
 }
@@ -0,0 +1,293 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gin-gonic/gin"
+)
+
+// Every benchmark row carries its storage usage: the compressed size of its .bin and .stats files,
+// its run count and its data lines. They are set whenever the files are written, so a user's usage
+// is a sum over their benchmarks. Files of the trash (deleted benchmarks and trash-run blobs) take
+// space until they are purged, so they count towards the byte quota too. Uploads are refused when they would take the owner past their
+// quota: the server-wide default from the -quota-* flags, or per-user overrides set by an admin.
+// The stored size is only known after an upload is written, so the byte quota refuses uploads
+// once it is reached rather than before; the other limits are exact.
+
+// StorageQuota limits what a user can store. Zero means unlimited.
+type StorageQuota struct {
+	Benchmarks   int64 `json:"benchmarks"`
+	StorageBytes int64 `json:"storage_bytes"`
+	Runs         int64 `json:"runs"`
+	DataLines    int64 `json:"data_lines"`
+}
+
+// StorageQuotaOverrides replaces limits of the default quota for one user. Nil fields use the
+// default, zero means unlimited.
+type StorageQuotaOverrides struct {
+	Benchmarks   *int64 `json:"benchmarks"`
+	StorageBytes *int64 `json:"storage_bytes"`
+	Runs         *int64 `json:"runs"`
+	DataLines    *int64 `json:"data_lines"`
+}
+
+// StorageUsage is what a user stores, with the quota that applies to them.
+type StorageUsage struct {
+	Benchmarks     int64                 `json:"benchmarks"`
+	StorageBytes   int64                 `json:"storage_bytes"`
+	TrashBytes     int64                 `json:"trash_bytes"` // Part of StorageBytes held by the trash
+	Runs           int64                 `json:"runs"`
+	DataLines      int64                 `json:"data_lines"`
+	Quota          StorageQuota          `json:"quota"`
+	QuotaOverrides StorageQuotaOverrides `json:"quota_overrides"`
+}
+
+// defaultStorageQuota applies to users without overrides (set from the -quota-* flags).
+var defaultStorageQuota StorageQuota
+
+// effectiveQuota returns the quota that applies to a user.
+func (u *User) effectiveQuota() StorageQuota {
+	quota := defaultStorageQuota
+	o := u.QuotaOverrides
+	for _, limit := range []struct {
+		override *int64
+		value    *int64
+	}{
+		{o.Benchmarks, &quota.Benchmarks},
+		{o.StorageBytes, &quota.StorageBytes},
+		{o.Runs, &quota.Runs},
+		{o.DataLines, &quota.DataLines},
+	} {
+		if limit.override != nil {
+			*limit.value = *limit.override
+		}
+	}
+	return quota
+}
+
+// loadStorageUsage sums the storage usage of the given users' benchmarks, adding the bytes of
+// their trash to the stored bytes.
+func loadStorageUsage(db *DBInstance, users []User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIDs := make([]uint, len(users))
+	for i := range users {
+		userIDs[i] = users[i].ID
+	}
+	var sums []struct {
+		UserID       uint
+		Benchmarks   int64
+		StorageBytes int64
+		Runs         int64
+		DataLines    int64
+	}
+	err := db.DB.Model(&Benchmark{}).
+		Select("user_id, count(*) as benchmarks, sum(storage_bytes) as storage_bytes, sum(stored_runs) as runs, sum(data_lines) as data_lines").
+		Where("user_id IN ?", userIDs).Group("user_id").Scan(&sums).Error
+	if err != nil {
+		return fmt.Errorf("failed to sum storage usage: %w", err)
+	}
+	var trashSums []struct {
+		UserID       uint
+		StorageBytes int64
+	}
+	err = db.DB.Unscoped().Model(&Benchmark{}).
+		Select("user_id, sum(storage_bytes) as storage_bytes").
+		Where("deleted_at IS NOT NULL AND user_id IN ?", userIDs).Group("user_id").Scan(&trashSums).Error
+	if err != nil {
+		return fmt.Errorf("failed to sum trash usage: %w", err)
+	}
+	var trashedRunSums []struct {
+		UserID       uint
+		StorageBytes int64
+	}
+	err = db.DB.Model(&TrashedRun{}).
+		Select("benchmarks.user_id AS user_id, sum(trashed_runs.storage_bytes) as storage_bytes").
+		Joins("JOIN benchmarks ON benchmarks.id = trashed_runs.benchmark_id").
+		Where("benchmarks.user_id IN ?", userIDs).Group("benchmarks.user_id").Scan(&trashedRunSums).Error
+	if err != nil {
+		return fmt.Errorf("failed to sum trash usage: %w", err)
+	}
+	for i := range users {
+		usage := &StorageUsage{Quota: users[i].effectiveQuota(), QuotaOverrides: users[i].QuotaOverrides}
+		for _, sum := range sums {
+			if sum.UserID == users[i].ID {
+				usage.Benchmarks, usage.StorageBytes, usage.Runs, usage.DataLines = sum.Benchmarks, sum.StorageBytes, sum.Runs, sum.DataLines
+			}
+		}
+		for _, sum := range append(trashSums, trashedRunSums...) {
+			if sum.UserID == users[i].ID {
+				usage.TrashBytes += sum.StorageBytes
+			}
+		}
+		usage.StorageBytes += usage.TrashBytes
+		users[i].Usage = usage
+	}
+	return nil
+}
+
+// ownerStorageUsage loads the storage usage and quota of a benchmark owner.
+func ownerStorageUsage(db *DBInstance, ownerID uint) (*StorageUsage, error) {
+	users := []User{{}}
+	if err := db.DB.First(&users[0], ownerID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find user %d: %w", ownerID, err)
+	}
+	if err := loadStorageUsage(db, users); err != nil {
+		return nil, err
+	}
+	return users[0].Usage, nil
+}
+
+// checkStorageQuota reports whether the owner can store benchmarks, runs and data lines on top of
+// what they already store. If not, it responds with 403 and the owner's usage.
+func checkStorageQuota(c *gin.Context, db *DBInstance, ownerID uint, benchmarks, runs, dataLines int64) bool {
+	usage, err := ownerStorageUsage(db, ownerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check storage quota"})
+		return false
+	}
+	if err := usage.exceeded(benchmarks, runs, dataLines); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "usage": usage})
+		return false
+	}
+	return true
+}
+
+// exceeded returns an error describing the first limit the additions would exceed.
+func (u *StorageUsage) exceeded(benchmarks, runs, dataLines int64) error {
+	q := u.Quota
+	switch {
+	case q.Benchmarks > 0 && u.Benchmarks+benchmarks > q.Benchmarks:
+		return fmt.Errorf("benchmark quota exceeded: %d of %d benchmarks used", u.Benchmarks, q.Benchmarks)
+	case q.Runs > 0 && u.Runs+runs > q.Runs:
+		return fmt.Errorf("run quota exceeded: %d of %d runs used, upload has %d", u.Runs, q.Runs, runs)
+	case q.DataLines > 0 && u.DataLines+dataLines > q.DataLines:
+		return fmt.Errorf("data line quota exceeded: %d of %d data lines used, upload has %d", u.DataLines, q.DataLines, dataLines)
+	case q.StorageBytes > 0 && u.StorageBytes >= q.StorageBytes:
+		return fmt.Errorf("storage quota exceeded: %s of %s used", humanize.IBytes(uint64(u.StorageBytes)), humanize.IBytes(uint64(q.StorageBytes)))
+	}
+	return nil
+}
+
+// benchmarkFileBytes returns the stored size of a benchmark's .bin and .stats files.
+func benchmarkFileBytes(benchmarkID uint) (int64, error) {
+	var total int64
+	for _, name := range []string{binFileName(benchmarkID), statsFileName(benchmarkID)} {
+		size, err := blobStore.Stat(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
+}
+
+// setBenchmarkUsage sets the usage fields of a benchmark whose files were just written with runs.
+// The caller saves the row.
+func setBenchmarkUsage(benchmark *Benchmark, runs []*BenchmarkData) {
+	benchmark.StoredRuns = len(runs)
+	benchmark.DataLines = int64(CountTotalDataLines(runs))
+	size, err := benchmarkFileBytes(benchmark.ID)
+	if err != nil {
+		fmt.Printf("Warning: failed to measure files of benchmark %d: %v\n", benchmark.ID, err)
+		return
+	}
+	benchmark.StorageBytes = size
+}
+
+// updateBenchmarkStorageBytes re-measures a benchmark's files after only its stats were written.
+func updateBenchmarkStorageBytes(db *DBInstance, benchmarkID uint) {
+	size, err := benchmarkFileBytes(benchmarkID)
+	if err == nil {
+		err = db.DB.Model(&Benchmark{}).Where("id = ?", benchmarkID).UpdateColumn("storage_bytes", size).Error
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to update storage usage of benchmark %d: %v\n", benchmarkID, err)
+	}
+}
+
+// MigrateBenchmarkUsage records the storage usage of every benchmark.
+func MigrateBenchmarkUsage(db *DBInstance) error {
+	var benchmarks []Benchmark
+	if err := db.DB.Select("id").Order("id").Find(&benchmarks).Error; err != nil {
+		return fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+	for i := range benchmarks {
+		runs, err := RetrieveBenchmarkData(benchmarks[i].ID)
+		if err != nil {
+			log.Printf("Benchmark %d: skipping storage usage, failed to read data: %v", benchmarks[i].ID, err)
+			continue
+		}
+		setBenchmarkUsage(&benchmarks[i], runs)
+		err = db.DB.Model(&benchmarks[i]).UpdateColumns(map[string]any{
+			"storage_bytes": benchmarks[i].StorageBytes,
+			"stored_runs":   benchmarks[i].StoredRuns,
+			"data_lines":    benchmarks[i].DataLines,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to record storage usage of benchmark %d: %w", benchmarks[i].ID, err)
+		}
+	}
+	log.Printf("Recorded storage usage of %d benchmark(s)", len(benchmarks))
+	return nil
+}
+
+// HandleSetUserQuota sets or clears a user's quota overrides (admin only)
+func HandleSetUserQuota(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+
+		var req StorageQuotaOverrides
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		for _, limit := range []*int64{req.Benchmarks, req.StorageBytes, req.Runs, req.DataLines} {
+			if limit != nil && *limit < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "quota limits must not be negative"})
+				return
+			}
+		}
+
+		var user User
+		if err := db.DB.First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		user.QuotaOverrides = req
+		err = db.DB.Model(&user).Select("quota_benchmarks", "quota_storage_bytes", "quota_runs", "quota_data_lines").
+			Updates(&user).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update user"})
+			return
+		}
+
+		users := []User{user}
+		if err := loadStorageUsage(db, users); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load storage usage"})
+			return
+		}
+
+		if adminUserID, exists := c.Get("UserID"); exists {
+			if uid, ok := adminUserID.(uint); ok {
+				LogUserQuotaUpdated(uid, GetUsernameFromContext(c), user.ID, user.Username, users[0].Usage.Quota)
+			}
+		}
+
+		c.JSON(http.StatusOK, users[0])
+	}
+}
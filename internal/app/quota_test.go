@@ -0,0 +1,171 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEffectiveQuota(t *testing.T) {
+	defaultStorageQuota = StorageQuota{Benchmarks: 10, StorageBytes: 1 << 20, Runs: 50}
+	defer func() { defaultStorageQuota = StorageQuota{} }()
+
+	unlimited, runs := int64(0), int64(5)
+	user := &User{QuotaOverrides: StorageQuotaOverrides{Benchmarks: &unlimited, Runs: &runs}}
+	want := StorageQuota{Benchmarks: 0, StorageBytes: 1 << 20, Runs: 5}
+	if got := user.effectiveQuota(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestStorageUsageExceeded(t *testing.T) {
+	usage := &StorageUsage{
+		Benchmarks: 2, StorageBytes: 2048, Runs: 4, DataLines: 100,
+		Quota: StorageQuota{Benchmarks: 3, StorageBytes: 4096, Runs: 6, DataLines: 200},
+	}
+	tests := []struct {
+		name                        string
+		benchmarks, runs, dataLines int64
+		want                        string
+	}{
+		{"within quota", 1, 2, 100, ""},
+		{"benchmarks", 2, 0, 0, "benchmark quota exceeded: 2 of 3 benchmarks used"},
+		{"runs", 0, 3, 0, "run quota exceeded: 4 of 6 runs used, upload has 3"},
+		{"data lines", 0, 0, 101, "data line quota exceeded: 100 of 200 data lines used, upload has 101"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := usage.exceeded(tt.benchmarks, tt.runs, tt.dataLines)
+			if tt.want == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	full := &StorageUsage{StorageBytes: 4096, Quota: StorageQuota{StorageBytes: 4096}}
+	if err := full.exceeded(0, 0, 0); err == nil || err.Error() != "storage quota exceeded: 4.0 KiB of 4.0 KiB used" {
+		t.Errorf("expected the byte quota reached, got %v", err)
+	}
+}
+
+func TestStorageQuotaEnforcement(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+	defaultStorageQuota = StorageQuota{Benchmarks: 1, Runs: 2}
+	defer func() { defaultStorageQuota = StorageQuota{} }()
+
+	// Admins skip the upload rate limit
+	user := createTestUser(db, "quotauser", true)
+	router := revisionTestRouter(db, user)
+	router.POST("/api/benchmarks", func(c *gin.Context) {
+		c.Set("UserID", user.ID)
+		HandleCreateBenchmark(db)(c)
+	})
+	router.PUT("/api/admin/users/:id/quota", HandleSetUserQuota(db))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, createBenchmarkRequest(t, "First", "a", "b"))
+	var benchmark Benchmark
+	if err := json.Unmarshal(w.Body.Bytes(), &benchmark); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+	}
+
+	t.Run("usage is recorded", func(t *testing.T) {
+		users := []User{*user}
+		if err := loadStorageUsage(db, users); err != nil {
+			t.Fatalf("Failed to load usage: %v", err)
+		}
+		usage := users[0].Usage
+		if usage.Benchmarks != 1 || usage.Runs != 2 || usage.DataLines != 4 || usage.StorageBytes <= 0 {
+			t.Errorf("unexpected usage: %+v", usage)
+		}
+		if usage.Quota != defaultStorageQuota {
+			t.Errorf("expected the default quota, got %+v", usage.Quota)
+		}
+	})
+
+	t.Run("uploads over quota are refused", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createBenchmarkRequest(t, "Second", "a"))
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "benchmark quota exceeded") {
+			t.Errorf("expected the benchmark quota enforced, got %d %s", w.Code, w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, addRunRequest(t, benchmark.ID, "c", `"1"`))
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "run quota exceeded") {
+			t.Errorf("expected the run quota enforced, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("overrides lift limits", func(t *testing.T) {
+		setQuota := func(body string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/admin/users/1/quota", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			return w
+		}
+		if w := setQuota(`{"runs": -1}`); w.Code != http.StatusBadRequest {
+			t.Errorf("expected negative limits rejected, got %d", w.Code)
+		}
+		w := setQuota(`{"runs": 0}`)
+		var resp User
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to set quota: %d %s", w.Code, w.Body.String())
+		}
+		if resp.Usage == nil || resp.Usage.Quota.Runs != 0 || resp.Usage.Quota.Benchmarks != 1 {
+			t.Errorf("unexpected usage: %+v", resp.Usage)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, addRunRequest(t, benchmark.ID, "c", `"1"`))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected runs accepted without a run limit, got %d %s", w.Code, w.Body.String())
+		}
+		var stored Benchmark
+		db.DB.First(&stored, benchmark.ID)
+		if stored.StoredRuns != 3 || stored.DataLines != 6 {
+			t.Errorf("expected usage updated, got %d runs and %d data lines", stored.StoredRuns, stored.DataLines)
+		}
+	})
+
+	t.Run("trash counts towards the byte quota", func(t *testing.T) {
+		usage := func() *StorageUsage {
+			t.Helper()
+			users := []User{*user}
+			if err := loadStorageUsage(db, users); err != nil {
+				t.Fatalf("Failed to load usage: %v", err)
+			}
+			return users[0].Usage
+		}
+		before := usage()
+		trashed, err := trashRun(db, benchmark.ID, 0, columnarRuns()[0])
+		if err != nil || trashed.StorageBytes <= 0 {
+			t.Fatalf("Failed to trash run: %+v %v", trashed, err)
+		}
+		db.DB.Delete(&Benchmark{}, benchmark.ID)
+
+		after := usage()
+		if after.Benchmarks != 0 || after.TrashBytes != before.StorageBytes+trashed.StorageBytes || after.StorageBytes != after.TrashBytes {
+			t.Errorf("expected the trash counted, got %+v (before %+v)", after, before)
+		}
+
+		defaultStorageQuota.StorageBytes = after.StorageBytes
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, createBenchmarkRequest(t, "Reupload", "a"))
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "storage quota exceeded") {
+			t.Errorf("expected the byte quota enforced with a full trash, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
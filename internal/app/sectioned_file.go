@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Sectioned file layout (.series, .stats):
+//
+//	[section][section]...[section] [index] [trailer]
+//
+// Each section is an independently zstd-compressed block, so readers only read and decompress the
+// sections they need. The index is a zstd-compressed gob value describing the sections. The
+// fixed-size trailer holds the index offset, the index length and an 8-byte magic identifying the
+// file type.
+const (
+	sectionedTrailerSize = 24       // index offset (8) + index length (8) + magic (8)
+	maxSectionIndexSize  = 64 << 20 // Guard against corrupted trailers claiming a huge index
+)
+
+// errNotSectioned is returned when a file does not end with the expected trailer, e.g. because it
+// was written in an older format.
+var errNotSectioned = errors.New("not a sectioned file")
+
+// fileSection locates a compressed section within a sectioned file.
+type fileSection struct {
+	Offset int64
+	Length int64
+}
+
+// sectionWriter appends compressed sections to a writer and tracks their offsets.
+type sectionWriter struct {
+	w      *bufio.Writer
+	enc    *zstd.Encoder
+	offset int64
+	buf    []byte
+}
+
+// newSectionWriter starts a sectioned file written to out.
+// Call finish to write the index, then Close.
+func newSectionWriter(out io.Writer) (*sectionWriter, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return nil, err
+	}
+	return &sectionWriter{w: bufio.NewWriterSize(out, 256*1024), enc: enc}, nil
+}
+
+// Close releases the encoder.
+func (sw *sectionWriter) Close() {
+	if err := sw.enc.Close(); err != nil {
+		fmt.Printf("Warning: failed to close zstd encoder: %v\n", err)
+	}
+}
+
+// writeRaw appends bytes as-is and returns their location.
+func (sw *sectionWriter) writeRaw(data []byte) (fileSection, error) {
+	n, err := sw.w.Write(data)
+	section := fileSection{Offset: sw.offset, Length: int64(n)}
+	sw.offset += int64(n)
+	return section, err
+}
+
+// writeSection compresses and appends one section.
+func (sw *sectionWriter) writeSection(data []byte) (fileSection, error) {
+	return sw.writeRaw(sw.enc.EncodeAll(data, nil))
+}
+
+// writeFloats stores values as a section of little-endian float64s.
+func (sw *sectionWriter) writeFloats(values []float64) (fileSection, error) {
+	sw.buf = sw.buf[:0]
+	for _, v := range values {
+		sw.buf = binary.LittleEndian.AppendUint64(sw.buf, math.Float64bits(v))
+	}
+	return sw.writeSection(sw.buf)
+}
+
+// writeGob stores a gob-encoded value as a section.
+func (sw *sectionWriter) writeGob(v interface{}) (fileSection, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return fileSection{}, err
+	}
+	return sw.writeSection(buf.Bytes())
+}
+
+// finish writes the index and the trailer and flushes the writer.
+func (sw *sectionWriter) finish(index interface{}, magic string) error {
+	indexSection, err := sw.writeGob(index)
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	trailer := make([]byte, 0, sectionedTrailerSize)
+	trailer = binary.LittleEndian.AppendUint64(trailer, uint64(indexSection.Offset))
+	trailer = binary.LittleEndian.AppendUint64(trailer, uint64(indexSection.Length))
+	trailer = append(trailer, magic...)
+	if _, err := sw.writeRaw(trailer); err != nil {
+		return fmt.Errorf("failed to write trailer: %w", err)
+	}
+	return sw.w.Flush()
+}
+
+// sectionedFile is an open sectioned file.
+type sectionedFile struct {
+	file Blob
+	dec  *zstd.Decoder
+}
+
+// openSectionedFile opens a sectioned blob and decodes its index into index.
+// Returns errNotSectioned if the trailer is missing or has a different magic.
+func openSectionedFile(name, magic string, index interface{}) (*sectionedFile, error) {
+	file, err := openBlob(name)
+	if err != nil {
+		return nil, err
+	}
+	sf := &sectionedFile{file: file}
+	if err := sf.readIndex(magic, index); err != nil {
+		sf.Close()
+		return nil, err
+	}
+	return sf, nil
+}
+
+func (sf *sectionedFile) readIndex(magic string, index interface{}) error {
+	size := sf.file.Size()
+	if size < sectionedTrailerSize {
+		return errNotSectioned
+	}
+	trailer := make([]byte, sectionedTrailerSize)
+	if _, err := sf.file.ReadAt(trailer, size-sectionedTrailerSize); err != nil {
+		return fmt.Errorf("failed to read trailer: %w", err)
+	}
+	if string(trailer[16:]) != magic {
+		return errNotSectioned
+	}
+	section := fileSection{
+		Offset: int64(binary.LittleEndian.Uint64(trailer[0:8])),
+		Length: int64(binary.LittleEndian.Uint64(trailer[8:16])),
+	}
+	if section.Offset < 0 || section.Length <= 0 || section.Length > maxSectionIndexSize ||
+		section.Offset+section.Length > size-sectionedTrailerSize {
+		return fmt.Errorf("invalid index location")
+	}
+
+	var err error
+	if sf.dec, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err != nil {
+		return err
+	}
+	if err := sf.readGob(section, index); err != nil {
+		return fmt.Errorf("failed to decode index: %w", err)
+	}
+	return nil
+}
+
+// Close releases the decoder and the blob.
+func (sf *sectionedFile) Close() {
+	if sf.dec != nil {
+		sf.dec.Close()
+	}
+	closeBlob(sf.file)
+}
+
+// readSection reads and decompresses one section.
+func (sf *sectionedFile) readSection(section fileSection) ([]byte, error) {
+	if section.Length < 0 || section.Length > maxSectionIndexSize {
+		return nil, fmt.Errorf("invalid section length: %d", section.Length)
+	}
+	compressed := make([]byte, section.Length)
+	if _, err := sf.file.ReadAt(compressed, section.Offset); err != nil {
+		return nil, err
+	}
+	return sf.dec.DecodeAll(compressed, nil)
+}
+
+// readFloats reads a section written by writeFloats.
+func (sf *sectionedFile) readFloats(section fileSection) ([]float64, error) {
+	raw, err := sf.readSection(section)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(raw)/8)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:]))
+	}
+	return values, nil
+}
+
+// readGob reads a section written by writeGob into v.
+func (sf *sectionedFile) readGob(section fileSection, v interface{}) error {
+	raw, err := sf.readSection(section)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(raw)).Decode(v)
+}
@@ -26,9 +26,19 @@ func Start(config *Config, version string) error {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	// Initialize benchmarks directory
-	if err := InitBenchmarksDir(config.DataDir); err != nil {
-		return fmt.Errorf("failed to initialize benchmarks directory: %w", err)
+	// Initialize benchmark file storage
+	if err := InitBlobStore(config.DataDir, config.BlobStore); err != nil {
+		return fmt.Errorf("failed to initialize blob store: %w", err)
+	}
+	runDedupEnabled = config.DedupRuns
+	defaultStorageQuota = config.Quota
+	benchmarkCache.SetCapacity(config.CacheBytes)
+
+	// Clean up half-written benchmark file generations left by a crash
+	if removed, err := RecoverBenchmarkFiles(); err != nil {
+		return fmt.Errorf("failed to recover benchmark files: %w", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d leftover benchmark file(s) from interrupted writes", removed)
 	}
 
 	// Initialize audit log directory
@@ -53,6 +63,13 @@ func Start(config *Config, version string) error {
 	// Initialize rate limiters
 	InitRateLimiters()
 
+	// Regenerate outdated pre-calculated stats in the background
+	StartStatsRecomputer(db)
+
+	// Permanently delete benchmarks and runs kept in the trash for longer than the retention period
+	trashRetention = config.TrashRetention
+	StartTrashPurger(db)
+
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
@@ -105,7 +122,17 @@ func Start(config *Config, version string) error {
 	r.GET("/api/benchmarks/:id", HandleGetBenchmark(db))
 	r.GET("/api/benchmarks/:id/data", HandleGetBenchmarkData(db))
 	r.GET("/api/benchmarks/:id/runs/:runIndex", HandleGetBenchmarkRun(db))
+	r.GET("/api/benchmarks/:id/runs/:runIndex/series", HandleGetBenchmarkRunSeries(db))
+	r.GET("/api/benchmarks/:id/runs/:runIndex/stats", HandleGetBenchmarkRunStats(db))
 	r.GET("/api/benchmarks/:id/download", HandleDownloadBenchmarkData(db))
+	r.GET("/api/benchmarks/:id/revisions", HandleListBenchmarkRevisions(db))
+	r.GET("/api/benchmarks/:id/revisions/diff", HandleDiffBenchmarkRevisions(db))
+	r.GET("/api/benchmarks/:id/revisions/:revision", HandleGetBenchmarkRevision(db))
+	r.GET("/api/compare/:id", HandleGetComparison(db))
+	r.GET("/api/specs/:field", HandleListSpecValues(db))
+	r.GET("/api/games", HandleListGames(db))
+	r.GET("/api/games/suggest", HandleSuggestGame(db))
+	r.GET("/api/games/:id", HandleGetGame(db))
 
 	// Debug calc endpoint (public, for verifying backend calculations) — rate limited per IP
 	debugCalcHandler := HandleDebugCalc()
@@ -129,6 +156,11 @@ func Start(config *Config, version string) error {
 	authorized.DELETE("/benchmarks/:id", HandleDeleteBenchmark(db))
 	authorized.DELETE("/benchmarks/:id/runs/:run_index", HandleDeleteBenchmarkRun(db))
 	authorized.POST("/benchmarks/:id/runs", HandleAddBenchmarkRuns(db))
+	authorized.POST("/benchmarks/:id/revisions/:revision/revert", HandleRevertBenchmark(db))
+	authorized.POST("/compare", HandleCreateComparison(db))
+	authorized.GET("/trash", HandleListTrash(db))
+	authorized.POST("/trash/benchmarks/:id/restore", HandleRestoreBenchmark(db))
+	authorized.POST("/trash/runs/:id/restore", HandleRestoreBenchmarkRun(db))
 
 	// API token routes
 	authorized.GET("/tokens", HandleListAPITokens(db))
@@ -143,6 +175,15 @@ func Start(config *Config, version string) error {
 	admin.DELETE("/users/:id/benchmarks", HandleDeleteUserBenchmarks(db))
 	admin.PUT("/users/:id/ban", HandleBanUser(db))
 	admin.PUT("/users/:id/admin", HandleToggleUserAdmin(db))
+	admin.PUT("/users/:id/quota", HandleSetUserQuota(db))
+	admin.GET("/stats/recompute", HandleGetStatsRecompute(db))
+	admin.POST("/stats/recompute", HandleRecomputeStats(db))
+	admin.POST("/storage/fsck", HandleStorageFsck(db))
+	admin.GET("/cache", HandleGetBenchmarkCache)
+	admin.GET("/backup", HandleBackup(db, version))
+	admin.POST("/games", HandleCreateGame(db))
+	admin.PUT("/games/:id", HandleUpdateGame(db))
+	admin.DELETE("/games/:id", HandleDeleteGame(db))
 
 	// MCP (Model Context Protocol) server
 	mcp := r.Group("/mcp")
@@ -0,0 +1,356 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statsRecomputeStartupDelay = 30 * time.Second       // Let the server settle before the initial outdated scan
+	statsRecomputeDelay        = 500 * time.Millisecond // Pause between benchmarks to keep the worker low priority
+)
+
+// errStatsSourceChanged is returned when a benchmark's data file changes while its stats are recomputed.
+var errStatsSourceChanged = errors.New("benchmark data changed during recompute")
+
+// StatsRecomputer regenerates .stats and .series files in the background, one benchmark at a time.
+type StatsRecomputer struct {
+	db *DBInstance
+
+	mu        sync.Mutex
+	queue     []uint
+	queued    map[uint]bool
+	current   uint
+	processed int
+	failed    int
+	lastError string
+	wake      chan struct{}
+}
+
+// StatsRecomputeStatus is a snapshot of the recompute worker's state.
+type StatsRecomputeStatus struct {
+	AlgorithmVersion int    `json:"algorithm_version"`
+	Queued           int    `json:"queued"`
+	Current          uint   `json:"current,omitempty"`
+	Processed        int    `json:"processed"`
+	Failed           int    `json:"failed"`
+	LastError        string `json:"last_error,omitempty"`
+}
+
+var (
+	statsRecomputer     *StatsRecomputer
+	statsRecomputerOnce sync.Once
+)
+
+// NewStatsRecomputer creates a recompute worker. Call Run to start processing the queue.
+func NewStatsRecomputer(db *DBInstance) *StatsRecomputer {
+	return &StatsRecomputer{
+		db:     db,
+		queued: make(map[uint]bool),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// StartStatsRecomputer starts the global background recompute worker. Unlike the migrations in
+// InitDB it does not block startup: outdated or missing stats files are regenerated after a delay.
+func StartStatsRecomputer(db *DBInstance) {
+	statsRecomputerOnce.Do(func() {
+		statsRecomputer = NewStatsRecomputer(db)
+		go statsRecomputer.Run(statsRecomputeStartupDelay)
+	})
+}
+
+// GetStatsRecomputer returns the global recompute worker, or nil if it has not been started.
+func GetStatsRecomputer() *StatsRecomputer {
+	return statsRecomputer
+}
+
+// Run scans for outdated stats after startupDelay, then processes queued benchmarks for the
+// lifetime of the application.
+func (r *StatsRecomputer) Run(startupDelay time.Duration) {
+	time.Sleep(startupDelay)
+	if n, err := r.ScanOutdated(); err != nil {
+		fmt.Printf("Warning: failed to scan for outdated stats: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Queued %d benchmark(s) for stats recompute (algorithm version %d)", n, statsAlgorithmVersion)
+	}
+
+	for {
+		if !r.processNext() {
+			<-r.wake
+			continue
+		}
+		// Free the loaded run data and yield to request handling between benchmarks
+		runtime.GC()
+		time.Sleep(statsRecomputeDelay)
+	}
+}
+
+// Enqueue adds a benchmark to the recompute queue. Returns false if it is already queued.
+func (r *StatsRecomputer) Enqueue(benchmarkID uint) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.queued[benchmarkID] {
+		return false
+	}
+	r.queued[benchmarkID] = true
+	r.queue = append(r.queue, benchmarkID)
+	r.signal()
+	return true
+}
+
+// EnqueueAll queues every benchmark for recompute and returns the number newly queued.
+func (r *StatsRecomputer) EnqueueAll() (int, error) {
+	var ids []uint
+	if err := r.db.DB.Model(&Benchmark{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		if r.Enqueue(id) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ScanOutdated queues benchmarks whose stats file is missing, unreadable, stored in the older
+// single-stream layout or computed with an older algorithm version, or whose series file is
+// missing or outdated. Benchmarks without a data file are skipped. Returns the number queued.
+func (r *StatsRecomputer) ScanOutdated() (int, error) {
+	var ids []uint
+	if err := r.db.DB.Model(&Benchmark{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		if _, err := blobStore.Stat(binFileName(id)); err != nil {
+			continue
+		}
+		if statsFileUpToDate(id) && seriesFileUpToDate(id) {
+			continue
+		}
+		if r.Enqueue(id) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Status returns a snapshot of the worker's state.
+func (r *StatsRecomputer) Status() StatsRecomputeStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return StatsRecomputeStatus{
+		AlgorithmVersion: statsAlgorithmVersion,
+		Queued:           len(r.queue),
+		Current:          r.current,
+		Processed:        r.processed,
+		Failed:           r.failed,
+		LastError:        r.lastError,
+	}
+}
+
+// processNext recomputes the stats of the next queued benchmark. Returns false if the queue is empty.
+func (r *StatsRecomputer) processNext() bool {
+	r.mu.Lock()
+	if len(r.queue) == 0 {
+		r.mu.Unlock()
+		return false
+	}
+	id := r.queue[0]
+	r.queue = r.queue[1:]
+	delete(r.queued, id)
+	r.current = id
+	r.mu.Unlock()
+
+	err := RecomputeBenchmarkStats(r.db, id)
+
+	r.mu.Lock()
+	r.current = 0
+	switch {
+	case err == nil:
+		r.processed++
+	case errors.Is(err, errStatsSourceChanged):
+		// The benchmark was edited mid-recompute; try again with the new data
+		if !r.queued[id] {
+			r.queued[id] = true
+			r.queue = append(r.queue, id)
+		}
+	default:
+		r.failed++
+		r.lastError = fmt.Sprintf("benchmark %d: %v", id, err)
+		fmt.Printf("Warning: failed to recompute stats for benchmark %d: %v\n", id, err)
+	}
+	r.mu.Unlock()
+	return true
+}
+
+// signal wakes the worker if it is idle. Must be called with r.mu held.
+func (r *StatsRecomputer) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RecomputeBenchmarkStats regenerates a benchmark's .stats and .series files and the headline run
+// stats in benchmark_runs from its stored run data.
+// Returns errStatsSourceChanged if the benchmark's files were rewritten while stats were being
+// computed, so a concurrent edit's freshly written stats are not overwritten with stale results.
+func RecomputeBenchmarkStats(db *DBInstance, benchmarkID uint) error {
+	var benchmark Benchmark
+	if err := db.DB.First(&benchmark, benchmarkID).Error; err != nil {
+		return fmt.Errorf("benchmark not found: %w", err)
+	}
+
+	generation, err := benchmarkGeneration(benchmarkID)
+	if err != nil {
+		return fmt.Errorf("failed to read benchmark manifest: %w", err)
+	}
+	if _, err := blobStore.Stat(binFileName(benchmarkID)); err != nil {
+		return fmt.Errorf("failed to stat benchmark data: %w", err)
+	}
+
+	benchmarkData, err := RetrieveBenchmarkData(benchmarkID)
+	if err != nil {
+		return fmt.Errorf("failed to load benchmark data: %w", err)
+	}
+	preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+
+	// Editing the run group pattern is saved to the database after the stats are rewritten
+	var patterns []string
+	if err := db.DB.Model(&Benchmark{}).Where("id = ?", benchmarkID).Pluck("run_group_pattern", &patterns).Error; err != nil {
+		return fmt.Errorf("failed to reload benchmark: %w", err)
+	}
+	if len(patterns) == 0 {
+		return fmt.Errorf("benchmark was deleted during recompute")
+	}
+	if patterns[0] != benchmark.RunGroupPattern {
+		return errStatsSourceChanged
+	}
+
+	w, err := beginBenchmarkFileWrite(benchmarkID)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if w.prevGeneration() != generation {
+		return errStatsSourceChanged
+	}
+	if _, err := blobStore.Stat(binFileName(benchmarkID)); err != nil {
+		return fmt.Errorf("benchmark data was deleted during recompute: %w", err)
+	}
+	if err := w.write(benchmarkFileStats, func(out io.Writer) error {
+		return writeStatsFile(out, preCalc, groups)
+	}); err != nil {
+		return err
+	}
+	if err := w.write(benchmarkFileSeries, func(out io.Writer) error {
+		return writeSeriesFile(out, benchmarkData)
+	}); err != nil {
+		return err
+	}
+	if err := w.commit(); err != nil {
+		return err
+	}
+	updateBenchmarkStorageBytes(db, benchmarkID)
+	return updateBenchmarkRunStats(db, benchmarkID, preCalc)
+}
+
+// HandleGetStatsRecompute returns the background stats recompute status (admin only)
+func HandleGetStatsRecompute(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		recomputer := GetStatsRecomputer()
+		if recomputer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "stats recompute worker is not running"})
+			return
+		}
+		c.JSON(http.StatusOK, recomputer.Status())
+	}
+}
+
+// HandleRecomputeStats queues one benchmark or all benchmarks for stats recompute (admin only)
+func HandleRecomputeStats(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			BenchmarkID uint `json:"benchmark_id"`
+			All         bool `json:"all"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		recomputer := GetStatsRecomputer()
+		if recomputer == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "stats recompute worker is not running"})
+			return
+		}
+
+		queued, err := queueStatsRecompute(db, recomputer, req.BenchmarkID, req.All)
+		if err != nil {
+			switch {
+			case errors.Is(err, errRecomputeTarget):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, errBenchmarkNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			}
+			return
+		}
+
+		if adminUserID, exists := c.Get("UserID"); exists {
+			if uid, ok := adminUserID.(uint); ok {
+				LogStatsRecomputeRequested(uid, GetUsernameFromContext(c), req.BenchmarkID, queued)
+			}
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"queued": queued,
+			"status": recomputer.Status(),
+		})
+	}
+}
+
+// Validation errors returned by queueStatsRecompute
+var (
+	errRecomputeTarget   = errors.New("provide either benchmark_id or all")
+	errBenchmarkNotFound = errors.New("benchmark not found")
+)
+
+// queueStatsRecompute validates a recompute request and queues the matching benchmarks.
+// Exactly one of benchmarkID or all must be set.
+func queueStatsRecompute(db *DBInstance, recomputer *StatsRecomputer, benchmarkID uint, all bool) (int, error) {
+	if all == (benchmarkID != 0) {
+		return 0, errRecomputeTarget
+	}
+	if all {
+		queued, err := recomputer.EnqueueAll()
+		if err != nil {
+			return 0, fmt.Errorf("database error: %w", err)
+		}
+		return queued, nil
+	}
+
+	var count int64
+	if err := db.DB.Model(&Benchmark{}).Where("id = ?", benchmarkID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	if count == 0 {
+		return 0, errBenchmarkNotFound
+	}
+	if recomputer.Enqueue(benchmarkID) {
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupRecomputeBenchmark stores a benchmark with run data but no stats file.
+func setupRecomputeBenchmark(t *testing.T, db *DBInstance, user *User, title string) *Benchmark {
+	t.Helper()
+	benchmark := &Benchmark{UserID: user.ID, Title: title, RunGroupPattern: `\s*#\d+$`}
+	if err := db.DB.Create(benchmark).Error; err != nil {
+		t.Fatalf("Failed to create benchmark: %v", err)
+	}
+	runs := []*BenchmarkData{
+		{Label: "A #1", DataFPS: []float64{60, 61}, DataFrameTime: []float64{16.6, 16.4}},
+		{Label: "A #2", DataFPS: []float64{62, 63}, DataFrameTime: []float64{16.1, 15.9}},
+	}
+	if err := StoreBenchmarkData(runs, benchmark.ID); err != nil {
+		t.Fatalf("Failed to store benchmark data: %v", err)
+	}
+	return benchmark
+}
+
+func TestStatsAlgorithmVersion(t *testing.T) {
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	if _, err := ReadStatsAlgorithmVersion(1); err == nil {
+		t.Error("expected error for missing stats file")
+	}
+
+	preCalc, groups := ComputeBenchmarkStats([]*BenchmarkData{{Label: "A", DataFPS: []float64{60, 61}}}, "")
+	if err := StorePreCalculatedStats(preCalc, groups, 1); err != nil {
+		t.Fatalf("Failed to store stats: %v", err)
+	}
+	version, err := ReadStatsAlgorithmVersion(1)
+	if err != nil || version != statsAlgorithmVersion {
+		t.Errorf("expected version %d, got %d (%v)", statsAlgorithmVersion, version, err)
+	}
+	runs, err := RetrievePreCalculatedStats(1)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("Failed to read versioned stats file: %d runs, %v", len(runs), err)
+	}
+}
+
+func TestStatsRecomputer(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "recomputeuser", false)
+	missing := setupRecomputeBenchmark(t, db, user, "Missing stats")
+	current := setupRecomputeBenchmark(t, db, user, "Current stats")
+	if err := RecomputeBenchmarkStats(db, current.ID); err != nil {
+		t.Fatalf("Failed to compute stats: %v", err)
+	}
+	noData := &Benchmark{UserID: user.ID, Title: "No data"}
+	db.DB.Create(noData)
+
+	r := NewStatsRecomputer(db)
+	queued, err := r.ScanOutdated()
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if queued != 1 || r.Status().Queued != 1 {
+		t.Fatalf("expected only the benchmark without stats to be queued, got %d", queued)
+	}
+	if r.Enqueue(missing.ID) {
+		t.Error("benchmark should not be queued twice")
+	}
+
+	if !r.processNext() {
+		t.Fatal("expected a queued benchmark to be processed")
+	}
+	if r.processNext() {
+		t.Error("queue should be empty")
+	}
+	status := r.Status()
+	if status.Processed != 1 || status.Failed != 0 || status.Queued != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	_, groups, err := RetrievePreCalculatedStatsWithGroups(missing.ID)
+	if err != nil {
+		t.Fatalf("Failed to read recomputed stats: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "A" {
+		t.Errorf("expected recompute to apply the run group pattern, got %+v", groups)
+	}
+	if queued, _ := r.ScanOutdated(); queued != 0 {
+		t.Errorf("expected no outdated benchmarks after recompute, got %d", queued)
+	}
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		r.Enqueue(noData.ID)
+		r.processNext()
+		if status := r.Status(); status.Failed != 1 || status.LastError == "" {
+			t.Errorf("expected failure to be recorded, got %+v", status)
+		}
+	})
+
+	t.Run("legacy stats file is outdated", func(t *testing.T) {
+		statsPath := filepath.Join(benchmarksDir, statsFileName(current.ID))
+		if err := os.Remove(statsPath); err != nil {
+			t.Fatalf("Failed to remove stats: %v", err)
+		}
+		if err := os.WriteFile(statsPath, []byte("not a stats file"), 0o644); err != nil {
+			t.Fatalf("Failed to write stats: %v", err)
+		}
+		if queued, _ := r.ScanOutdated(); queued != 1 {
+			t.Errorf("expected unreadable stats file to be queued, got %d", queued)
+		}
+	})
+}
+
+func TestHandleRecomputeStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	admin := createTestUser(db, "recomputeadmin", true)
+	first := setupRecomputeBenchmark(t, db, admin, "First")
+	setupRecomputeBenchmark(t, db, admin, "Second")
+
+	prev := statsRecomputer
+	statsRecomputer = NewStatsRecomputer(db)
+	defer func() { statsRecomputer = prev }()
+
+	router := setupTestRouter()
+	router.POST("/api/admin/stats/recompute", HandleRecomputeStats(db))
+	router.GET("/api/admin/stats/recompute", HandleGetStatsRecompute(db))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/stats/recompute", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		queued int
+	}{
+		{"single benchmark", fmt.Sprintf(`{"benchmark_id":%d}`, first.ID), http.StatusAccepted, 1},
+		{"all benchmarks", `{"all":true}`, http.StatusAccepted, 1}, // first is already queued
+		{"neither target", `{}`, http.StatusBadRequest, 0},
+		{"both targets", fmt.Sprintf(`{"benchmark_id":%d,"all":true}`, first.ID), http.StatusBadRequest, 0},
+		{"unknown benchmark", `{"benchmark_id":99999}`, http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusAccepted {
+				return
+			}
+			var resp struct {
+				Queued int                  `json:"queued"`
+				Status StatsRecomputeStatus `json:"status"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal: %v", err)
+			}
+			if resp.Queued != tt.queued || resp.Status.AlgorithmVersion != statsAlgorithmVersion {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/stats/recompute", http.NoBody))
+	var status StatsRecomputeStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to unmarshal status: %v", err)
+	}
+	if w.Code != http.StatusOK || status.Queued != 2 {
+		t.Errorf("expected 2 queued benchmarks, got %d: %+v", w.Code, status)
+	}
+}
@@ -0,0 +1,576 @@
+package app
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/peterbourgon/ff/v3"
+)
+
+// The storage checker walks the database and the blob store: every benchmark row must
+// have a readable .bin, and .meta, .stats and .series files whose run counts and labels match it.
+// Files not tied to a benchmark row (orphans) or to its current generation (stale), and shared run
+// blobs no run references (orphans), are reported.
+// With repair, derived files are regenerated from the .bin in one new generation, a corrupt
+// manifest is rebuilt from the newest generation in the store, and files that can't be read, orphans
+// and stale files are moved to <data-dir>/quarantine/<timestamp>/ instead of being deleted.
+
+// Storage issue types
+const (
+	storageIssueMissing  = "missing"  // Expected file doesn't exist
+	storageIssueCorrupt  = "corrupt"  // File can't be decoded
+	storageIssueMismatch = "mismatch" // File disagrees with the .bin on run count or labels
+	storageIssueOrphan   = "orphan"   // File of a benchmark that has no database row
+	storageIssueStale    = "stale"    // Temporary file or file outside the current generation
+	storageIssueUnknown  = "unknown"  // File the server doesn't recognize; never touched
+)
+
+// Repair actions
+const (
+	storageActionRegenerated = "regenerated"
+	storageActionQuarantined = "quarantined"
+	storageActionRebuilt     = "rebuilt"
+)
+
+// StorageIssue is one problem found by the storage checker.
+type StorageIssue struct {
+	BenchmarkID uint   `json:"benchmark_id,omitempty"`
+	File        string `json:"file"`
+	Type        string `json:"type"`
+	Detail      string `json:"detail"`
+	Action      string `json:"action,omitempty"` // Repair applied, empty when not repaired
+	Resolved    bool   `json:"resolved"`
+}
+
+// StorageCheckReport summarizes a storage check.
+type StorageCheckReport struct {
+	Repair        bool           `json:"repair"`
+	Benchmarks    int            `json:"benchmarks"`
+	Files         int            `json:"files"`
+	Issues        []StorageIssue `json:"issues"`
+	Unresolved    int            `json:"unresolved"`
+	QuarantineDir string         `json:"quarantine_dir,omitempty"`
+	StartedAt     time.Time      `json:"started_at"`
+	DurationMs    int64          `json:"duration_ms"`
+}
+
+// storageCheck holds the state of one CheckStorage run.
+type storageCheck struct {
+	db            *DBInstance
+	repair        bool
+	report        *StorageCheckReport
+	quarantineDir string
+}
+
+// CheckStorage verifies every benchmark's files and reports mismatches, orphans and corrupt files.
+// With repair, it fixes what can be fixed (see the comment at the top of this file).
+func CheckStorage(db *DBInstance, repair bool) (*StorageCheckReport, error) {
+	report := &StorageCheckReport{Repair: repair, Issues: []StorageIssue{}, StartedAt: time.Now().UTC()}
+	check := &storageCheck{
+		db:            db,
+		repair:        repair,
+		report:        report,
+		quarantineDir: filepath.Join(storageDataDir, "quarantine", report.StartedAt.Format("20060102-150405")),
+	}
+
+	// List files before loading benchmark IDs: rows are created before their files, so a benchmark
+	// uploaded during the check can't be mistaken for an orphan
+	blobs, err := blobStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark files: %w", err)
+	}
+	// Benchmarks in the trash keep their files until they are purged
+	var ids []uint
+	if err := db.DB.Unscoped().Model(&Benchmark{}).Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+
+	for _, id := range ids {
+		check.checkBenchmark(id)
+	}
+	check.checkStoredFiles(blobs, ids)
+	if repair {
+		// Quarantined files are gone without a new generation being committed
+		benchmarkCache.reset()
+	}
+
+	report.Benchmarks = len(ids)
+	report.Files = len(blobs)
+	for _, issue := range report.Issues {
+		if !issue.Resolved {
+			report.Unresolved++
+		}
+	}
+	if _, err := os.Stat(check.quarantineDir); err == nil {
+		report.QuarantineDir = check.quarantineDir
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+func (s *storageCheck) addIssue(issue StorageIssue) *StorageIssue {
+	s.report.Issues = append(s.report.Issues, issue)
+	return &s.report.Issues[len(s.report.Issues)-1]
+}
+
+// quarantine moves a file out of the blob store into the quarantine directory.
+func (s *storageCheck) quarantine(name string) error {
+	if err := os.MkdirAll(s.quarantineDir, 0o750); err != nil {
+		return err
+	}
+	return moveBlobToFile(name, filepath.Join(s.quarantineDir, name))
+}
+
+// checkBenchmark verifies the files of one benchmark row, holding its write lock so mutations
+// can't interleave with the check or the repair.
+func (s *storageCheck) checkBenchmark(benchmarkID uint) {
+	defer lockBenchmarkWrites(benchmarkID)()
+
+	if _, err := readManifest(benchmarkID); err != nil {
+		issue := s.addIssue(StorageIssue{BenchmarkID: benchmarkID, File: manifestName(benchmarkID), Type: storageIssueCorrupt, Detail: err.Error()})
+		if s.repair {
+			s.repairManifest(benchmarkID, issue)
+		}
+	}
+
+	binName := binFileName(benchmarkID)
+	if _, err := blobStore.Stat(binName); err != nil {
+		s.addIssue(StorageIssue{BenchmarkID: benchmarkID, File: binName, Type: storageIssueMissing, Detail: "benchmark data file not found"})
+		return
+	}
+	benchmarkData, err := RetrieveBenchmarkData(benchmarkID)
+	if err != nil {
+		// The derived files can't be verified or regenerated without the data
+		issue := s.addIssue(StorageIssue{BenchmarkID: benchmarkID, File: binName, Type: storageIssueCorrupt, Detail: err.Error()})
+		if s.repair {
+			if qErr := s.quarantine(binName); qErr != nil {
+				fmt.Printf("Warning: failed to quarantine %s: %v\n", binName, qErr)
+			} else {
+				issue.Action = storageActionQuarantined
+			}
+		}
+		return
+	}
+	labels := make([]string, len(benchmarkData))
+	for i, run := range benchmarkData {
+		labels[i] = run.Label
+	}
+
+	var derived []int // Indices of issues with derived files
+	var corruptNames []string
+	for _, kind := range []string{benchmarkFileMeta, benchmarkFileStats, benchmarkFileSeries} {
+		name := benchmarkFileName(benchmarkID, kind)
+		issueType, detail := checkDerivedFile(benchmarkID, kind, name, labels)
+		if issueType == "" {
+			continue
+		}
+		s.addIssue(StorageIssue{BenchmarkID: benchmarkID, File: name, Type: issueType, Detail: detail})
+		derived = append(derived, len(s.report.Issues)-1)
+		if issueType == storageIssueCorrupt {
+			corruptNames = append(corruptNames, name)
+		}
+	}
+	if len(derived) == 0 || !s.repair {
+		return
+	}
+
+	// Keep unreadable files for inspection; the regenerated generation replaces the rest
+	for _, name := range corruptNames {
+		if err := s.quarantine(name); err != nil {
+			fmt.Printf("Warning: failed to quarantine %s: %v\n", name, err)
+		}
+	}
+	if err := s.regenerateDerivedFiles(benchmarkID, benchmarkData); err != nil {
+		fmt.Printf("Warning: failed to regenerate files of benchmark %d: %v\n", benchmarkID, err)
+		return
+	}
+	for _, i := range derived {
+		s.report.Issues[i].Action = storageActionRegenerated
+		s.report.Issues[i].Resolved = true
+	}
+}
+
+// checkDerivedFile verifies a .meta, .stats or .series file against the runs of the .bin.
+// Returns an empty issue type when the file is fine.
+func checkDerivedFile(benchmarkID uint, kind, name string, labels []string) (string, string) {
+	if _, err := blobStore.Stat(name); err != nil {
+		return storageIssueMissing, kind + " file not found"
+	}
+
+	var fileLabels []string
+	switch kind {
+	case benchmarkFileMeta:
+		metadata, err := readMetadataFile(name)
+		if err != nil {
+			return storageIssueCorrupt, err.Error()
+		}
+		if metadata.RunCount != len(metadata.RunLabels) {
+			return storageIssueCorrupt, fmt.Sprintf("run count %d doesn't match %d labels", metadata.RunCount, len(metadata.RunLabels))
+		}
+		fileLabels = metadata.RunLabels
+	case benchmarkFileStats:
+		stats, _, err := readStatsSelection(benchmarkID, fullStatsSelection())
+		if err != nil {
+			return storageIssueCorrupt, err.Error()
+		}
+		fileLabels = make([]string, len(stats))
+		for i, run := range stats {
+			fileLabels[i] = run.Label
+		}
+	case benchmarkFileSeries:
+		sf, err := openSeriesFile(benchmarkID)
+		if err != nil {
+			return storageIssueCorrupt, err.Error()
+		}
+		runCount := len(sf.index.Runs)
+		sf.Close()
+		if runCount != len(labels) {
+			return storageIssueMismatch, fmt.Sprintf("%d runs, data file has %d", runCount, len(labels))
+		}
+		return "", ""
+	}
+
+	if len(fileLabels) != len(labels) {
+		return storageIssueMismatch, fmt.Sprintf("%d runs, data file has %d", len(fileLabels), len(labels))
+	}
+	if !slices.Equal(fileLabels, labels) {
+		return storageIssueMismatch, "run labels differ from the data file"
+	}
+	return "", ""
+}
+
+// readMetadataFile decodes a .meta file.
+func readMetadataFile(name string) (*BenchmarkMetadata, error) {
+	file, err := openBlob(name)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBlob(file)
+	var metadata BenchmarkMetadata
+	if err := gob.NewDecoder(file).Decode(&metadata); err != nil {
+		return nil, err
+	}
+	if metadata.RunCount < 0 || metadata.RunCount > maxRunsPerBenchmark {
+		return nil, fmt.Errorf("invalid run count: %d", metadata.RunCount)
+	}
+	return &metadata, nil
+}
+
+// regenerateDerivedFiles rewrites a benchmark's .meta, .stats and .series from its run data.
+func (s *storageCheck) regenerateDerivedFiles(benchmarkID uint, benchmarkData []*BenchmarkData) error {
+	var pattern []string
+	if err := s.db.DB.Model(&Benchmark{}).Where("id = ?", benchmarkID).Pluck("run_group_pattern", &pattern).Error; err != nil {
+		return err
+	}
+	if len(pattern) == 0 {
+		return errBenchmarkNotFound
+	}
+	preCalc, groups := ComputeBenchmarkStats(benchmarkData, pattern[0])
+
+	w, err := beginBenchmarkFileWrite(benchmarkID)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.write(benchmarkFileMeta, func(out io.Writer) error {
+		return writeBenchmarkMetadata(out, benchmarkData)
+	}); err != nil {
+		return err
+	}
+	if err := w.write(benchmarkFileStats, func(out io.Writer) error {
+		return writeStatsFile(out, preCalc, groups)
+	}); err != nil {
+		return err
+	}
+	if err := w.write(benchmarkFileSeries, func(out io.Writer) error {
+		return writeSeriesFile(out, benchmarkData)
+	}); err != nil {
+		return err
+	}
+	return w.commit()
+}
+
+// repairManifest quarantines an unreadable manifest and rebuilds it from the newest generation
+// in the store. Without generation files, the benchmark falls back to its plain file names.
+func (s *storageCheck) repairManifest(benchmarkID uint, issue *StorageIssue) {
+	unlock := lockBenchmarkFiles(benchmarkID)
+	defer unlock()
+
+	if err := s.quarantine(manifestName(benchmarkID)); err != nil {
+		fmt.Printf("Warning: failed to quarantine manifest of benchmark %d: %v\n", benchmarkID, err)
+		return
+	}
+	issue.Action = storageActionQuarantined
+	issue.Resolved = true
+
+	m, err := newestGenerationManifest(benchmarkID)
+	if err == nil && m != nil {
+		err = writeManifest(benchmarkID, m)
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to rebuild manifest of benchmark %d: %v\n", benchmarkID, err)
+		issue.Resolved = false
+	} else if m != nil {
+		issue.Action = storageActionRebuilt
+	}
+	manifestCacheMu.Lock()
+	delete(manifestCache, benchmarkID)
+	manifestCacheMu.Unlock()
+}
+
+// newestGenerationManifest builds a manifest for the newest generation that has a .bin file.
+// Returns nil if the benchmark has no generation files.
+func newestGenerationManifest(benchmarkID uint) (*benchmarkManifest, error) {
+	blobs, err := blobStore.List()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[uint64][]string)
+	for _, blob := range blobs {
+		match := generationFilePattern.FindStringSubmatch(blob.Name)
+		if match == nil || match[1] != strconv.FormatUint(uint64(benchmarkID), 10) {
+			continue
+		}
+		if generation, err := strconv.ParseUint(match[2], 10, 64); err == nil {
+			files[generation] = append(files[generation], match[3])
+		}
+	}
+
+	var newest *benchmarkManifest
+	for generation, kinds := range files {
+		if !slices.Contains(kinds, benchmarkFileData) || (newest != nil && generation < newest.Generation) {
+			continue
+		}
+		newest = &benchmarkManifest{Generation: generation, Files: kinds, CommittedAt: time.Now().UTC()}
+	}
+	return newest, nil
+}
+
+// checkStoredFiles reports files not tied to a benchmark row or to its current generation. Each
+// file is re-checked under the benchmark's file lock, so files of a write in progress are skipped.
+func (s *storageCheck) checkStoredFiles(blobs []BlobInfo, ids []uint) {
+	for _, blob := range blobs {
+		name := blob.Name
+		if match := sharedRunFilePattern.FindStringSubmatch(name); match != nil {
+			s.checkSharedRun(name, match[1])
+			continue
+		}
+		if match := trashRunFilePattern.FindStringSubmatch(name); match != nil {
+			s.checkTrashedRun(name, match[1])
+			continue
+		}
+		benchmarkID, generation, hasGeneration, ok := parseBenchmarkFileName(name)
+		if !ok {
+			s.addIssue(StorageIssue{File: name, Type: storageIssueUnknown, Detail: "not a benchmark file"})
+			continue
+		}
+		s.checkStoredFile(name, benchmarkID, generation, hasGeneration, slices.Contains(ids, benchmarkID))
+	}
+}
+
+func (s *storageCheck) checkStoredFile(name string, benchmarkID uint, generation uint64, hasGeneration, exists bool) {
+	unlock := lockBenchmarkFiles(benchmarkID)
+	defer unlock()
+
+	if _, err := blobStore.Stat(name); err != nil {
+		return // Removed by a write or a repair since the store was listed
+	}
+
+	issue := StorageIssue{BenchmarkID: benchmarkID, File: name}
+	switch m, err := readManifest(benchmarkID); {
+	case !exists:
+		issue.Type, issue.Detail = storageIssueOrphan, "no benchmark with this ID"
+	case strings.HasSuffix(name, ".tmp"):
+		issue.Type, issue.Detail = storageIssueStale, "leftover of an interrupted write"
+	case err != nil || strings.HasSuffix(name, ".manifest"):
+		return // Manifests are checked with the benchmark
+	case m != nil && slices.Contains(m.Retired, name):
+		return // Replaced generation, kept for readers until the next commit
+	case hasGeneration && (m == nil || m.Generation != generation):
+		issue.Type, issue.Detail = storageIssueStale, "not part of the current generation"
+	case !hasGeneration && m != nil:
+		issue.Type, issue.Detail = storageIssueStale, "superseded by the manifest"
+	default:
+		return
+	}
+
+	if s.repair {
+		if err := s.quarantine(name); err != nil {
+			fmt.Printf("Warning: failed to quarantine %s: %v\n", name, err)
+		} else {
+			issue.Action = storageActionQuarantined
+			issue.Resolved = true
+		}
+	}
+	s.addIssue(issue)
+}
+
+// checkSharedRun reports a shared run blob that no run record references. It holds the shared
+// run lock so a write can't start referencing the blob while it is quarantined.
+func (s *storageCheck) checkSharedRun(name, hash string) {
+	sharedRunsMu.Lock()
+	defer sharedRunsMu.Unlock()
+
+	var refs int64
+	if err := s.db.DB.Model(&BenchmarkRun{}).Where("content_hash = ?", hash).Count(&refs).Error; err != nil || refs > 0 {
+		return
+	}
+	if _, err := blobStore.Stat(name); err != nil {
+		return // Removed since the store was listed
+	}
+	issue := StorageIssue{File: name, Type: storageIssueOrphan, Detail: "shared run not referenced by any benchmark"}
+	if s.repair {
+		if err := s.quarantine(name); err != nil {
+			fmt.Printf("Warning: failed to quarantine %s: %v\n", name, err)
+		} else {
+			issue.Action = storageActionQuarantined
+			issue.Resolved = true
+		}
+	}
+	s.addIssue(issue)
+}
+
+// checkTrashedRun reports a trash blob without a trashed run record. Records are created before
+// their blobs, so a run deleted during the check is never reported.
+func (s *storageCheck) checkTrashedRun(name, id string) {
+	var count int64
+	if err := s.db.DB.Model(&TrashedRun{}).Where("id = ?", id).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if _, err := blobStore.Stat(name); err != nil {
+		return // Purged since the store was listed
+	}
+	issue := StorageIssue{File: name, Type: storageIssueOrphan, Detail: "deleted run not in the trash"}
+	if s.repair {
+		if err := s.quarantine(name); err != nil {
+			fmt.Printf("Warning: failed to quarantine %s: %v\n", name, err)
+		} else {
+			issue.Action = storageActionQuarantined
+			issue.Resolved = true
+		}
+	}
+	s.addIssue(issue)
+}
+
+// parseBenchmarkFileName extracts the benchmark ID and generation from a benchmark file name,
+// including manifests and temporary files.
+func parseBenchmarkFileName(name string) (benchmarkID uint, generation uint64, hasGeneration, ok bool) {
+	base := strings.TrimSuffix(name, ".tmp")
+	if idStr, found := strings.CutSuffix(base, ".manifest"); found {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		return uint(id), 0, false, err == nil
+	}
+	if match := generationFilePattern.FindStringSubmatch(base); match != nil {
+		id, idErr := strconv.ParseUint(match[1], 10, 32)
+		gen, genErr := strconv.ParseUint(match[2], 10, 64)
+		return uint(id), gen, true, idErr == nil && genErr == nil
+	}
+	if match := legacyFilePattern.FindStringSubmatch(base); match != nil {
+		id, err := strconv.ParseUint(match[1], 10, 32)
+		return uint(id), 0, false, err == nil
+	}
+	return 0, 0, false, false
+}
+
+// HandleStorageFsck checks benchmark storage and optionally repairs it (admin only)
+func HandleStorageFsck(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Repair bool `json:"repair"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		report, err := CheckStorage(db, req.Repair)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "storage check failed"})
+			return
+		}
+
+		if adminUserID, exists := c.Get("UserID"); exists {
+			if uid, ok := adminUserID.(uint); ok {
+				LogStorageChecked(uid, GetUsernameFromContext(c), report)
+			}
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// RunFsck runs the storage checker from the command line ("flightlesssomething fsck") and returns
+// the process exit code: 0 when no unresolved issues remain, 1 when some do, 2 on errors.
+// It must not run alongside the server: the per-benchmark locks only work within one process.
+func RunFsck(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("flightlesssomething fsck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dataDir := fs.String("data-dir", "/data", "Path where data would be stored")
+	repair := fs.Bool("repair", false, "Regenerate derived files and quarantine unreadable, orphan and stale files")
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	var blobCfg BlobStoreConfig
+	registerBlobStoreFlags(fs, &blobCfg)
+	if err := ff.Parse(fs, args, ff.WithEnvVarPrefix("FS")); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if err := InitBlobStore(*dataDir, blobCfg); err != nil {
+		fmt.Fprintf(stderr, "Failed to initialize blob store: %v\n", err)
+		return 2
+	}
+	db, err := InitDB(*dataDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to initialize database: %v\n", err)
+		return 2
+	}
+	report, err := CheckStorage(db, *repair)
+	if err != nil {
+		fmt.Fprintf(stderr, "Storage check failed: %v\n", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return 2
+		}
+	} else {
+		writeStorageCheckReport(stdout, report)
+	}
+	if report.Unresolved > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeStorageCheckReport prints a report in human-readable form.
+func writeStorageCheckReport(w io.Writer, report *StorageCheckReport) {
+	for _, issue := range report.Issues {
+		line := fmt.Sprintf("%-8s %s: %s", issue.Type, issue.File, issue.Detail)
+		if issue.Action != "" {
+			line += " [" + issue.Action + "]"
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "Checked %d benchmarks and %d files: %d issues, %d unresolved\n",
+		report.Benchmarks, report.Files, len(report.Issues), report.Unresolved)
+	if report.QuarantineDir != "" {
+		fmt.Fprintf(w, "Quarantined files moved to %s\n", report.QuarantineDir)
+	}
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// storageIssueTypes maps "file" to issue type for easy comparison.
+func storageIssueTypes(report *StorageCheckReport) map[string]string {
+	types := make(map[string]string, len(report.Issues))
+	for _, issue := range report.Issues {
+		types[issue.File] = issue.Type
+	}
+	return types
+}
+
+func TestCheckStorage(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	dataDir := t.TempDir()
+	if err := InitBenchmarksDir(dataDir); err != nil {
+		t.Fatalf("Failed to init benchmarks dir: %v", err)
+	}
+
+	user := createTestUser(db, "fsckuser", false)
+	// The last benchmark has no files at all
+	benchmarks := make([]*Benchmark, 5)
+	for i := range benchmarks {
+		benchmarks[i] = &Benchmark{UserID: user.ID, Title: "Fsck"}
+		db.DB.Create(benchmarks[i])
+	}
+	for _, b := range benchmarks[:4] {
+		runs := columnarRuns()
+		preCalc, groups := ComputeBenchmarkStats(runs, "")
+		if err := StoreBenchmarkDataWithStats(runs, preCalc, groups, b.ID); err != nil {
+			t.Fatalf("Failed to store benchmark %d: %v", b.ID, err)
+		}
+	}
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(benchmarksDir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	healthy, corruptMeta, staleStats, corruptBin := benchmarks[0].ID, benchmarks[1].ID, benchmarks[2].ID, benchmarks[3].ID
+	writeFile(benchmarkFileName(corruptMeta, benchmarkFileMeta), "trunc")
+	// Stats of one run only: readable, but the run count doesn't match
+	preCalc, groups := ComputeBenchmarkStats(columnarRuns()[:1], "")
+	if err := writeBlob(statsFileName(staleStats), func(out io.Writer) error {
+		return writeStatsFile(out, preCalc, groups)
+	}); err != nil {
+		t.Fatalf("Failed to write stats: %v", err)
+	}
+	writeFile(binFileName(corruptBin), "garbage")
+	writeFile("999-g1.bin", "orphan")
+	writeFile("999.manifest", `{"generation":1}`)
+	writeFile(generationFileName(healthy, 7, benchmarkFileMeta), "stale")
+	writeFile("notes.txt", "keep me")
+
+	report, err := CheckStorage(db, false)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	want := map[string]string{
+		"2-g1.meta":    storageIssueCorrupt,
+		"3-g1.stats":   storageIssueMismatch,
+		"4-g1.bin":     storageIssueCorrupt,
+		"5.bin":        storageIssueMissing,
+		"999-g1.bin":   storageIssueOrphan,
+		"999.manifest": storageIssueOrphan,
+		"1-g7.meta":    storageIssueStale,
+		"notes.txt":    storageIssueUnknown,
+	}
+	got := storageIssueTypes(report)
+	for file, issueType := range want {
+		if got[file] != issueType {
+			t.Errorf("%s: expected %q, got %q", file, issueType, got[file])
+		}
+	}
+	if len(report.Issues) != len(want) || report.Unresolved != len(want) {
+		t.Errorf("expected %d unresolved issues, got %d/%d: %+v", len(want), report.Unresolved, len(report.Issues), report.Issues)
+	}
+	if report.Benchmarks != 5 || report.QuarantineDir != "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	t.Run("repair", func(t *testing.T) {
+		report, err := CheckStorage(db, true)
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		// The missing and the quarantined data files, and the unknown file, need manual attention
+		if report.Unresolved != 3 {
+			t.Errorf("expected 3 unresolved issues, got %d: %+v", report.Unresolved, report.Issues)
+		}
+		for _, name := range []string{"2-g1.meta", "4-g1.bin", "999-g1.bin", "999.manifest", "1-g7.meta"} {
+			if _, err := os.Stat(filepath.Join(report.QuarantineDir, name)); err != nil {
+				t.Errorf("expected %s in quarantine: %v", name, err)
+			}
+		}
+		if count, labels, err := GetBenchmarkRunCount(corruptMeta); err != nil || count != 2 || labels[1] != "Run B" {
+			t.Errorf("metadata not regenerated: %d %v (%v)", count, labels, err)
+		}
+		if stats, err := RetrievePreCalculatedStats(staleStats); err != nil || len(stats) != 2 {
+			t.Errorf("stats not regenerated: %d runs (%v)", len(stats), err)
+		}
+
+		report, err = CheckStorage(db, false)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		want := map[string]string{"4-g1.bin": storageIssueMissing, "5.bin": storageIssueMissing, "notes.txt": storageIssueUnknown}
+		if got := storageIssueTypes(report); len(got) != len(want) {
+			t.Errorf("expected only %v after repair, got %v", want, got)
+		}
+	})
+
+	t.Run("corrupt manifest is rebuilt", func(t *testing.T) {
+		writeFile(manifestName(healthy), "{")
+		resetManifestCache()
+		report, err := CheckStorage(db, true)
+		if err != nil {
+			t.Fatalf("Repair failed: %v", err)
+		}
+		issues := storageIssueTypes(report)
+		if issues["1.manifest"] != storageIssueCorrupt || issues["1-g1.bin"] != "" {
+			t.Errorf("expected only the manifest issue for benchmark 1, got %v", issues)
+		}
+		if m, err := readManifest(healthy); err != nil || m == nil || m.Generation != 1 || len(m.Files) != 4 {
+			t.Errorf("manifest not rebuilt: %+v (%v)", m, err)
+		}
+	})
+}
+
+func TestRunFsck(t *testing.T) {
+	dataDir := t.TempDir()
+	var out, errOut bytes.Buffer
+	if code := RunFsck([]string{"--data-dir", dataDir}, &out, &errOut); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "Checked 0 benchmarks and 0 files: 0 issues, 0 unresolved") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dataDir, "benchmarks", "3.stats"), []byte("orphan"), 0o600); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+	out.Reset()
+	if code := RunFsck([]string{"--data-dir", dataDir}, &out, &errOut); code != 1 {
+		t.Errorf("expected exit code 1 with an orphan, got %d", code)
+	}
+	if !strings.Contains(out.String(), "orphan   3.stats: no benchmark with this ID") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+	out.Reset()
+	if code := RunFsck([]string{"--data-dir", dataDir, "--repair", "--json"}, &out, &errOut); code != 0 {
+		t.Errorf("expected exit code 0 after repair, got %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), `"action": "quarantined"`) {
+		t.Errorf("expected JSON report, got %s", out.String())
+	}
+}
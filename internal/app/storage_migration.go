@@ -53,15 +53,15 @@ func MigrateBenchmarkStorageToV2(dataDir string) error {
 			continue
 		}
 		
-		// Check if already in V2 format
-		isV2, err := isBenchmarkFormatV2(benchmarkID)
+		// Check if already in V2 format (or newer)
+		version, err := benchmarkStorageVersion(benchmarkID)
 		if err != nil {
 			log.Printf("Benchmark %d: ERROR - Failed to check format: %v", benchmarkID, err)
 			errorCount++
 			continue
 		}
 		
-		if isV2 {
+		if version >= storageFormatVersionV2 {
 			log.Printf("Benchmark %d: Already in V2 format - skipped", benchmarkID)
 			skipCount++
 			continue
@@ -113,14 +113,11 @@ func MigrateBenchmarkStorageToV2(dataDir string) error {
 
 // isBenchmarkFormatV2 checks if a benchmark file is in V2 format
 func isBenchmarkFormatV2(benchmarkID uint) (bool, error) {
-	filePath := filepath.Join(benchmarksDir, fmt.Sprintf("%d.bin", benchmarkID))
-	file, err := os.Open(filePath)
+	file, err := openBlob(binFileName(benchmarkID))
 	if err != nil {
 		return false, err
 	}
-	defer func() {
-		_ = file.Close() //nolint:errcheck // Defer close, error not critical in read-only operation
-	}()
+	defer closeBlob(file)
 	
 	// Set up decompression
 	zstdDecoder, err := zstd.NewReader(file, zstd.WithDecoderConcurrency(2))
@@ -138,7 +135,110 @@ func isBenchmarkFormatV2(benchmarkID uint) (bool, error) {
 	}
 	
 	// Check if version matches V2
-	return header.Version == storageFormatVersion, nil
+	return header.Version == storageFormatVersionV2, nil
+}
+
+// MigrateBenchmarkStorageToV3 migrates all benchmark data files from V2 to the columnar V3 format
+// Files already in V3 are skipped; the series and metadata files are rewritten along the way
+func MigrateBenchmarkStorageToV3(dataDir string) error {
+	log.Println("=== Starting Benchmark Storage Format Migration (V2 → V3) ===")
+
+	benchmarksDirPath := filepath.Join(dataDir, "benchmarks")
+	if _, err := os.Stat(benchmarksDirPath); os.IsNotExist(err) {
+		log.Println("No benchmarks directory found - nothing to migrate")
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(benchmarksDirPath, "*.bin"))
+	if err != nil {
+		return fmt.Errorf("failed to list benchmark files: %w", err)
+	}
+
+	if len(files) == 0 {
+		log.Println("No benchmark files found - nothing to migrate")
+		return nil
+	}
+
+	log.Printf("Found %d benchmark file(s) to check\n", len(files))
+
+	successCount := 0
+	skipCount := 0
+	errorCount := 0
+	var bytesBefore, bytesAfter int64
+
+	for _, filePath := range files {
+		basename := filepath.Base(filePath)
+		idStr := strings.TrimSuffix(basename, ".bin")
+
+		var benchmarkID uint
+		if _, err := fmt.Sscanf(idStr, "%d", &benchmarkID); err != nil {
+			log.Printf("Skipping file with invalid name: %s", basename)
+			skipCount++
+			continue
+		}
+
+		version, err := benchmarkStorageVersion(benchmarkID)
+		if err != nil {
+			log.Printf("Benchmark %d: ERROR - Failed to check format: %v", benchmarkID, err)
+			errorCount++
+			continue
+		}
+
+		if version == storageFormatVersion {
+			log.Printf("Benchmark %d: Already in V3 format - skipped", benchmarkID)
+			skipCount++
+			continue
+		}
+
+		// RetrieveBenchmarkData reads V1 and V2 files
+		benchmarkData, err := RetrieveBenchmarkData(benchmarkID)
+		if err != nil {
+			log.Printf("Benchmark %d: ERROR - Failed to load V%d data: %v", benchmarkID, version, err)
+			errorCount++
+			continue
+		}
+
+		var sizeBefore int64
+		if info, statErr := os.Stat(filePath); statErr == nil {
+			sizeBefore = info.Size()
+		}
+
+		if err := StoreBenchmarkData(benchmarkData, benchmarkID); err != nil {
+			log.Printf("Benchmark %d: ERROR - Failed to save V3: %v", benchmarkID, err)
+			errorCount++
+			continue
+		}
+
+		if info, statErr := os.Stat(filePath); statErr == nil {
+			bytesBefore += sizeBefore
+			bytesAfter += info.Size()
+		}
+
+		log.Printf("Benchmark %d: ✓ Migrated to V3 format (%d runs)", benchmarkID, len(benchmarkData))
+
+		// Clear loaded data to help GC
+		benchmarkData = nil //nolint:ineffassign // Intentional to help GC reclaim memory
+		runtime.GC()
+
+		successCount++
+	}
+
+	log.Println("\n=== Storage Migration Summary ===")
+	log.Printf("Total files found: %d", len(files))
+	log.Printf("Successfully migrated: %d", successCount)
+	log.Printf("Already V3 (skipped): %d", skipCount)
+	log.Printf("Failed: %d", errorCount)
+	if successCount > 0 {
+		log.Printf("Migrated file size: %d → %d bytes", bytesBefore, bytesAfter)
+	}
+	log.Println("==================================")
+
+	if errorCount > 0 {
+		return fmt.Errorf("storage migration completed with %d errors", errorCount)
+	}
+
+	log.Println("Storage format migration completed successfully!")
+	return nil
 }
 
 // MigratePreCalculateStats pre-calculates statistics for all existing benchmarks
@@ -181,8 +281,7 @@ func MigratePreCalculateStats(dataDir string) error {
 		}
 
 		// Check if .stats file already exists
-		statsPath := filepath.Join(benchmarksDirPath, fmt.Sprintf("%d.stats", benchmarkID))
-		if _, err := os.Stat(statsPath); err == nil {
+		if _, err := blobStore.Stat(statsFileName(benchmarkID)); err == nil {
 			log.Printf("Benchmark %d: Stats file already exists - skipped", benchmarkID)
 			skipCount++
 			continue
@@ -197,10 +296,10 @@ func MigratePreCalculateStats(dataDir string) error {
 		}
 
 		// Compute pre-calculated stats
-		preCalc := ComputePreCalculatedRuns(benchmarkData)
+		preCalc, groups := ComputeBenchmarkStats(benchmarkData, "")
 
 		// Store stats
-		if err := StorePreCalculatedStats(preCalc, benchmarkID); err != nil {
+		if err := StorePreCalculatedStats(preCalc, groups, benchmarkID); err != nil {
 			log.Printf("Benchmark %d: ERROR - Failed to save stats: %v", benchmarkID, err)
 			errorCount++
 			continue
@@ -0,0 +1,498 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deleting a benchmark only soft-deletes its row: its files, run records and quota usage stay
+// untouched, and restoring it clears deleted_at again. Trashed benchmarks and trash blobs stay on
+// disk until purged, so they keep counting towards their owner's byte quota. A deleted run is moved into a trash blob,
+// trash-run-<id>.bin, a V3 file holding just that run with all its columns (never a reference to
+// a shared run blob), with a trashed_runs row describing it. Restoring a run puts it back at its
+// old index. The purger permanently deletes benchmarks and runs that have been in the trash for
+// longer than the retention period, and records each purge in the audit log.
+
+// trashRetention is how long deleted benchmarks and runs are kept (set from -trash-retention).
+var trashRetention = 30 * 24 * time.Hour
+
+// trashPurgeInterval is how often the purger looks for expired trash.
+const trashPurgeInterval = time.Hour
+
+var trashRunFilePattern = regexp.MustCompile(`^trash-run-(\d+)\.bin$`)
+
+// errRestoreLimit is returned when restoring a run would take its benchmark past the upload limits.
+var errRestoreLimit = errors.New("cannot restore run")
+
+func trashRunName(trashedRunID uint) string {
+	return "trash-run-" + strconv.FormatUint(uint64(trashedRunID), 10) + ".bin"
+}
+
+// TrashedBenchmark is a deleted benchmark waiting in the trash.
+type TrashedBenchmark struct {
+	ID           uint      `json:"id"`
+	UserID       uint      `json:"user_id"`
+	Title        string    `json:"title"`
+	RunCount     int       `json:"run_count"`
+	DataLines    int64     `json:"data_lines"`
+	StorageBytes int64     `json:"storage_bytes"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	PurgeAt      time.Time `json:"purge_at"`
+}
+
+// TrashListing lists deleted benchmarks and runs, most recently deleted first.
+type TrashListing struct {
+	Benchmarks     []TrashedBenchmark `json:"benchmarks"`
+	Runs           []TrashedRun       `json:"runs"`
+	RetentionHours int64              `json:"retention_hours"`
+}
+
+// listTrash lists the trash of a user, or of every user when allUsers is set.
+func listTrash(db *DBInstance, userID uint, allUsers bool) (*TrashListing, error) {
+	listing := &TrashListing{Benchmarks: []TrashedBenchmark{}, Runs: []TrashedRun{}, RetentionHours: int64(trashRetention / time.Hour)}
+
+	query := db.DB.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC")
+	if !allUsers {
+		query = query.Where("user_id = ?", userID)
+	}
+	var benchmarks []Benchmark
+	if err := query.Find(&benchmarks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted benchmarks: %w", err)
+	}
+	for i := range benchmarks {
+		b := &benchmarks[i]
+		listing.Benchmarks = append(listing.Benchmarks, TrashedBenchmark{
+			ID:           b.ID,
+			UserID:       b.UserID,
+			Title:        b.Title,
+			RunCount:     b.StoredRuns,
+			DataLines:    b.DataLines,
+			StorageBytes: b.StorageBytes,
+			DeletedAt:    b.DeletedAt.Time,
+			PurgeAt:      b.DeletedAt.Time.Add(trashRetention),
+		})
+	}
+
+	runQuery := db.DB.Model(&TrashedRun{}).
+		Select("trashed_runs.*, benchmarks.title AS benchmark_title, benchmarks.user_id AS user_id").
+		Joins("JOIN benchmarks ON benchmarks.id = trashed_runs.benchmark_id").
+		Order("trashed_runs.trashed_at DESC")
+	if !allUsers {
+		runQuery = runQuery.Where("benchmarks.user_id = ?", userID)
+	}
+	if err := runQuery.Find(&listing.Runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deleted runs: %w", err)
+	}
+	for i := range listing.Runs {
+		listing.Runs[i].PurgeAt = listing.Runs[i].TrashedAt.Add(trashRetention)
+	}
+	return listing, nil
+}
+
+// trashRun moves a run about to be deleted from a benchmark into the trash. The run must have
+// all its columns loaded.
+func trashRun(db *DBInstance, benchmarkID uint, runIndex int, run *BenchmarkData) (*TrashedRun, error) {
+	trashed := &TrashedRun{
+		BenchmarkID: benchmarkID,
+		RunIndex:    runIndex,
+		Label:       run.Label,
+		DataLines:   int64(CountTotalDataLines([]*BenchmarkData{run})),
+		TrashedAt:   time.Now().UTC(),
+	}
+	if err := db.DB.Create(trashed).Error; err != nil {
+		return nil, fmt.Errorf("failed to record deleted run: %w", err)
+	}
+	err := writeBlob(trashRunName(trashed.ID), func(out io.Writer) error {
+		return writeColumnarRuns(out, []*BenchmarkData{run}, false)
+	})
+	if err != nil {
+		db.DB.Delete(trashed)
+		return nil, fmt.Errorf("failed to store deleted run: %w", err)
+	}
+	size, err := blobStore.Stat(trashRunName(trashed.ID))
+	if err == nil {
+		trashed.StorageBytes = size
+		err = db.DB.Model(trashed).UpdateColumn("storage_bytes", size).Error
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to record the size of deleted run %d: %v\n", trashed.ID, err)
+	}
+	return trashed, nil
+}
+
+// discardTrashedRun permanently deletes a run from the trash.
+func discardTrashedRun(db *DBInstance, trashed *TrashedRun) error {
+	if err := blobStore.Remove(trashRunName(trashed.ID)); err != nil {
+		return fmt.Errorf("failed to remove deleted run %d: %w", trashed.ID, err)
+	}
+	if err := db.DB.Delete(trashed).Error; err != nil {
+		return fmt.Errorf("failed to delete record of deleted run %d: %w", trashed.ID, err)
+	}
+	return nil
+}
+
+// readTrashedRun reads a run from its trash blob.
+func readTrashedRun(trashedRunID uint) (*BenchmarkData, error) {
+	index := &binIndex{}
+	sf, err := openSectionedFile(trashRunName(trashedRunID), binFileMagic, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open deleted run %d: %w", trashedRunID, err)
+	}
+	defer sf.Close()
+	if len(index.Runs) != 1 {
+		return nil, fmt.Errorf("deleted run %d has %d runs", trashedRunID, len(index.Runs))
+	}
+	return readColumnarRun(sf, &index.Runs[0], nil)
+}
+
+// restoreTrashedBenchmark takes a soft-deleted benchmark out of the trash. The caller checks
+// permissions and the owner's quota.
+func restoreTrashedBenchmark(db *DBInstance, benchmark *Benchmark) error {
+	if _, err := blobStore.Stat(binFileName(benchmark.ID)); err != nil {
+		return fmt.Errorf("failed to find data of benchmark %d: %w", benchmark.ID, err)
+	}
+	if err := db.DB.Unscoped().Model(benchmark).Update("deleted_at", nil).Error; err != nil {
+		return fmt.Errorf("failed to restore benchmark %d: %w", benchmark.ID, err)
+	}
+	benchmark.DeletedAt.Valid = false
+
+	// Stats of trashed benchmarks are skipped when the algorithm changes
+	if recomputer := GetStatsRecomputer(); recomputer != nil && !(statsFileUpToDate(benchmark.ID) && seriesFileUpToDate(benchmark.ID)) {
+		recomputer.Enqueue(benchmark.ID)
+	}
+	return nil
+}
+
+// restoreTrashedRun puts a run from the trash back into its benchmark at the index it had, or at
+// the end if the benchmark has fewer runs now. Returns the new index. The caller holds the
+// benchmark's write lock and checks permissions and the owner's quota.
+func restoreTrashedRun(db *DBInstance, benchmark *Benchmark, trashed *TrashedRun) (int, error) {
+	run, err := readTrashedRun(trashed.ID)
+	if err != nil {
+		return 0, err
+	}
+	previousData, err := RetrieveBenchmarkData(benchmark.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve benchmark data: %w", err)
+	}
+	if len(previousData) >= maxRunsPerBenchmark {
+		return 0, fmt.Errorf("%w: total runs (%d) would exceed maximum allowed (%d)", errRestoreLimit, len(previousData)+1, maxRunsPerBenchmark)
+	}
+	if lines := int64(CountTotalDataLines(previousData)) + trashed.DataLines; lines > maxTotalDataLines {
+		return 0, fmt.Errorf("%w: total data lines (%d) would exceed maximum allowed (%d)", errRestoreLimit, lines, maxTotalDataLines)
+	}
+
+	idx := min(trashed.RunIndex, len(previousData))
+	benchmarkData := slices.Insert(slices.Clone(previousData), idx, run)
+
+	// Record the run before writing files that may reference its shared blob
+	preCalc, groups := ComputeBenchmarkStats(benchmarkData, benchmark.RunGroupPattern)
+	if err := syncBenchmarkRuns(db, benchmark.ID, benchmarkData, preCalc); err != nil {
+		return 0, err
+	}
+	if err := StoreBenchmarkDataWithStats(benchmarkData, preCalc, groups, benchmark.ID); err != nil {
+		if syncErr := syncBenchmarkRuns(db, benchmark.ID, previousData, nil); syncErr != nil {
+			fmt.Printf("Warning: %v\n", syncErr)
+		}
+		return 0, fmt.Errorf("failed to store benchmark data: %w", err)
+	}
+
+	benchmark.RunNames, benchmark.Specifications = ExtractSearchableMetadata(benchmarkData)
+	setBenchmarkUsage(benchmark, benchmarkData)
+	benchmark.Revision++
+	if err := db.DB.Save(benchmark).Error; err != nil {
+		return 0, fmt.Errorf("failed to update benchmark: %w", err)
+	}
+	if err := discardTrashedRun(db, trashed); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return idx, nil
+}
+
+// purgeBenchmark permanently deletes a benchmark: its files, run records, trashed runs and row.
+// The caller holds off backups (lockBenchmarkWrites or beginStorageMutation).
+func purgeBenchmark(db *DBInstance, benchmarkID uint) error {
+	if err := DeleteBenchmarkData(benchmarkID); err != nil {
+		fmt.Printf("Warning: failed to delete data for benchmark %d: %v\n", benchmarkID, err)
+	}
+	releaseBenchmarkRuns(db, benchmarkID)
+
+	var trashed []TrashedRun
+	if err := db.DB.Where("benchmark_id = ?", benchmarkID).Find(&trashed).Error; err != nil {
+		return fmt.Errorf("failed to find deleted runs of benchmark %d: %w", benchmarkID, err)
+	}
+	for i := range trashed {
+		if err := discardTrashedRun(db, &trashed[i]); err != nil {
+			return err
+		}
+	}
+	if err := db.DB.Where("benchmark_id = ?", benchmarkID).Delete(&BenchmarkRevision{}).Error; err != nil {
+		return fmt.Errorf("failed to delete revisions of benchmark %d: %w", benchmarkID, err)
+	}
+	if err := db.DB.Unscoped().Delete(&Benchmark{}, benchmarkID).Error; err != nil {
+		return fmt.Errorf("failed to delete benchmark %d: %w", benchmarkID, err)
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes benchmarks and runs that have been in the trash for longer
+// than the retention period. Returns the number of benchmarks and runs purged.
+func PurgeExpiredTrash(db *DBInstance) (benchmarks, runs int, err error) {
+	cutoff := time.Now().Add(-trashRetention)
+
+	var expired []Benchmark
+	if err := db.DB.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Order("id").Find(&expired).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to find expired benchmarks: %w", err)
+	}
+	for i := range expired {
+		purged, err := purgeExpiredBenchmark(db, &expired[i])
+		if err != nil {
+			return benchmarks, runs, err
+		}
+		if purged {
+			LogBenchmarkPurged(expired[i].ID, expired[i].Title, expired[i].UserID, expired[i].DeletedAt.Time)
+			benchmarks++
+		}
+	}
+
+	var expiredRuns []TrashedRun
+	if err := db.DB.Where("trashed_at < ?", cutoff).Order("id").Find(&expiredRuns).Error; err != nil {
+		return benchmarks, runs, fmt.Errorf("failed to find expired runs: %w", err)
+	}
+	for i := range expiredRuns {
+		purged, err := purgeExpiredRun(db, &expiredRuns[i])
+		if err != nil {
+			return benchmarks, runs, err
+		}
+		if purged {
+			LogBenchmarkRunPurged(&expiredRuns[i])
+			runs++
+		}
+	}
+	return benchmarks, runs, nil
+}
+
+// purgeExpiredBenchmark purges a benchmark unless it was restored since it was found.
+func purgeExpiredBenchmark(db *DBInstance, benchmark *Benchmark) (bool, error) {
+	defer lockBenchmarkWrites(benchmark.ID)()
+	var count int64
+	if err := db.DB.Unscoped().Model(&Benchmark{}).Where("id = ? AND deleted_at IS NOT NULL", benchmark.ID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check benchmark %d: %w", benchmark.ID, err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+	return true, purgeBenchmark(db, benchmark.ID)
+}
+
+// purgeUserBenchmark purges a benchmark of a deleted user under its write lock, so the purge can't
+// race a mutation or restore of the same benchmark.
+func purgeUserBenchmark(db *DBInstance, benchmarkID uint) error {
+	defer lockBenchmarkWrites(benchmarkID)()
+	return purgeBenchmark(db, benchmarkID)
+}
+
+// purgeExpiredRun purges a run from the trash unless it was restored since it was found.
+func purgeExpiredRun(db *DBInstance, trashed *TrashedRun) (bool, error) {
+	defer lockBenchmarkWrites(trashed.BenchmarkID)()
+	var count int64
+	if err := db.DB.Model(&TrashedRun{}).Where("id = ?", trashed.ID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check deleted run %d: %w", trashed.ID, err)
+	}
+	if count == 0 {
+		return false, nil
+	}
+	var benchmark Benchmark
+	if err := db.DB.Unscoped().Select("id", "title", "user_id").First(&benchmark, trashed.BenchmarkID).Error; err == nil {
+		trashed.BenchmarkTitle, trashed.UserID = benchmark.Title, benchmark.UserID
+	}
+	return true, discardTrashedRun(db, trashed)
+}
+
+var trashPurgerOnce sync.Once
+
+// StartTrashPurger purges expired trash in the background, at startup and then every
+// trashPurgeInterval.
+func StartTrashPurger(db *DBInstance) {
+	trashPurgerOnce.Do(func() {
+		go func() {
+			for {
+				benchmarks, runs, err := PurgeExpiredTrash(db)
+				if err != nil {
+					fmt.Printf("Warning: failed to purge trash: %v\n", err)
+				}
+				if benchmarks > 0 || runs > 0 {
+					log.Printf("Purged %d benchmark(s) and %d run(s) from the trash", benchmarks, runs)
+				}
+				time.Sleep(trashPurgeInterval)
+			}
+		}()
+	})
+}
+
+// requestingUser returns the requesting user's ID and admin flag. If the ID is missing, it
+// responds with 500.
+func requestingUser(c *gin.Context) (userID uint, isAdmin, ok bool) {
+	value, _ := c.Get("UserID")
+	userID, ok = value.(uint)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID type"})
+		return 0, false, false
+	}
+	adminValue, _ := c.Get("IsAdmin")
+	isAdmin, _ = adminValue.(bool)
+	return userID, isAdmin, true
+}
+
+// HandleListTrash lists the requesting user's deleted benchmarks and runs. Admins see the trash of
+// every user, or of one user with ?user_id=.
+func HandleListTrash(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, adminFlag, ok := requestingUser(c)
+		if !ok {
+			return
+		}
+
+		allUsers := adminFlag
+		if userIDStr := c.Query("user_id"); userIDStr != "" && adminFlag {
+			filterID, err := strconv.ParseUint(userIDStr, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+				return
+			}
+			uid, allUsers = uint(filterID), false
+		}
+
+		listing, err := listTrash(db, uid, allUsers)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list trash"})
+			return
+		}
+		c.JSON(http.StatusOK, listing)
+	}
+}
+
+// HandleRestoreBenchmark restores a deleted benchmark from the trash
+func HandleRestoreBenchmark(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, adminFlag, ok := requestingUser(c)
+		if !ok {
+			return
+		}
+		benchmarkID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid benchmark ID"})
+			return
+		}
+
+		// Serialize with the purger
+		defer lockBenchmarkWrites(uint(benchmarkID))()
+
+		var benchmark Benchmark
+		if err := db.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&benchmark, benchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found in the trash"})
+			return
+		}
+		if benchmark.UserID != uid && !adminFlag {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not authorized"})
+			return
+		}
+		if !checkStorageQuota(c, db, benchmark.UserID, 1, int64(benchmark.StoredRuns), benchmark.DataLines) {
+			return
+		}
+
+		if err := restoreTrashedBenchmark(db, &benchmark); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore benchmark"})
+			return
+		}
+
+		LogBenchmarkRestored(uid, GetUsernameFromContext(c), benchmark.ID, benchmark.Title)
+
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
+		c.JSON(http.StatusOK, benchmark)
+	}
+}
+
+// HandleRestoreBenchmarkRun restores a deleted run from the trash into its benchmark
+func HandleRestoreBenchmarkRun(db *DBInstance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, adminFlag, ok := requestingUser(c)
+		if !ok {
+			return
+		}
+		trashedID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run ID"})
+			return
+		}
+
+		var trashed TrashedRun
+		if err := db.DB.First(&trashed, trashedID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found in the trash"})
+			return
+		}
+
+		// Serialize with other mutations of the benchmark, then make sure the run is still there
+		defer lockBenchmarkWrites(trashed.BenchmarkID)()
+		if err := db.DB.First(&trashed, trashedID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "run not found in the trash"})
+			return
+		}
+
+		var benchmark Benchmark
+		if err := db.DB.Unscoped().First(&benchmark, trashed.BenchmarkID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "benchmark not found"})
+			return
+		}
+		if benchmark.UserID != uid && !adminFlag {
+			c.JSON(http.StatusForbidden, gin.H{"error": "not authorized"})
+			return
+		}
+		if benchmark.DeletedAt.Valid {
+			c.JSON(http.StatusConflict, gin.H{"error": "benchmark is in the trash - restore the benchmark first"})
+			return
+		}
+
+		// If-Match is optional for restoring, but a stale revision is still rejected
+		if c.GetHeader("If-Match") != "" && !checkBenchmarkRevision(c, &benchmark) {
+			return
+		}
+		if !checkStorageQuota(c, db, benchmark.UserID, 0, 1, trashed.DataLines) {
+			return
+		}
+
+		runIndex, err := restoreTrashedRun(db, &benchmark, &trashed)
+		if errors.Is(err, errRestoreLimit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore run"})
+			return
+		}
+
+		username := GetUsernameFromContext(c)
+		recordBenchmarkRevision(db, &benchmark, uid, username, revisionActionRunRestored)
+		LogBenchmarkRunRestored(uid, username, benchmark.ID, benchmark.Title, runIndex, trashed.Label)
+
+		c.Header("ETag", benchmarkETag(benchmark.Revision))
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "run restored",
+			"benchmark_id": benchmark.ID,
+			"run_index":    runIndex,
+			"revision":     benchmark.Revision,
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trashTestRouter extends revisionTestRouter with uploads, deletion and the trash endpoints.
+func trashTestRouter(db *DBInstance, user *User) *gin.Engine {
+	router := revisionTestRouter(db, user)
+	asUser := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("UserID", user.ID)
+			c.Set("IsAdmin", user.IsAdmin)
+			handler(c)
+		}
+	}
+	router.POST("/api/benchmarks", asUser(HandleCreateBenchmark(db)))
+	router.DELETE("/api/benchmarks/:id", asUser(HandleDeleteBenchmark(db)))
+	router.GET("/api/trash", asUser(HandleListTrash(db)))
+	router.POST("/api/trash/benchmarks/:id/restore", asUser(HandleRestoreBenchmark(db)))
+	router.POST("/api/trash/runs/:id/restore", asUser(HandleRestoreBenchmarkRun(db)))
+	return router
+}
+
+// trashRequest serves a request without a body and returns the recorder.
+func trashRequest(router *gin.Engine, method, path, ifMatch string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, http.NoBody)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func listTestTrash(t *testing.T, router *gin.Engine) TrashListing {
+	t.Helper()
+	w := trashRequest(router, http.MethodGet, "/api/trash", "")
+	var listing TrashListing
+	if err := json.Unmarshal(w.Body.Bytes(), &listing); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Failed to list trash: %d %s", w.Code, w.Body.String())
+	}
+	return listing
+}
+
+func createTrashTestBenchmark(t *testing.T, router *gin.Engine, title string, labels ...string) Benchmark {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, createBenchmarkRequest(t, title, labels...))
+	var benchmark Benchmark
+	if err := json.Unmarshal(w.Body.Bytes(), &benchmark); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("Failed to create benchmark: %d %s", w.Code, w.Body.String())
+	}
+	return benchmark
+}
+
+func TestBenchmarkTrash(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	// Admins skip the upload rate limit
+	user := createTestUser(db, "trashuser", true)
+	router := trashTestRouter(db, user)
+	benchmark := createTrashTestBenchmark(t, router, "Trashed", "a", "b")
+	path := fmt.Sprintf("/api/benchmarks/%d", benchmark.ID)
+
+	if w := trashRequest(router, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("Failed to delete benchmark: %d %s", w.Code, w.Body.String())
+	}
+	if w := trashRequest(router, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected a deleted benchmark to be hidden, got %d", w.Code)
+	}
+	if !blobExists(binFileName(benchmark.ID)) {
+		t.Fatal("expected the files of a deleted benchmark to be kept")
+	}
+
+	listing := listTestTrash(t, router)
+	if len(listing.Benchmarks) != 1 || listing.Benchmarks[0].ID != benchmark.ID || listing.Benchmarks[0].RunCount != 2 {
+		t.Fatalf("expected the benchmark in the trash, got %+v", listing.Benchmarks)
+	}
+	if got := listing.Benchmarks[0].PurgeAt.Sub(listing.Benchmarks[0].DeletedAt); got != trashRetention {
+		t.Errorf("expected purge after the retention period, got %v", got)
+	}
+
+	t.Run("restore checks the quota", func(t *testing.T) {
+		defaultStorageQuota = StorageQuota{Runs: 1}
+		defer func() { defaultStorageQuota = StorageQuota{} }()
+		w := trashRequest(router, http.MethodPost, fmt.Sprintf("/api/trash/benchmarks/%d/restore", benchmark.ID), "")
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "run quota exceeded") {
+			t.Errorf("expected the run quota enforced, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		w := trashRequest(router, http.MethodPost, fmt.Sprintf("/api/trash/benchmarks/%d/restore", benchmark.ID), "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Failed to restore benchmark: %d %s", w.Code, w.Body.String())
+		}
+		if w := trashRequest(router, http.MethodGet, path, ""); w.Code != http.StatusOK {
+			t.Errorf("expected the restored benchmark to be visible, got %d", w.Code)
+		}
+		if data, err := RetrieveBenchmarkData(benchmark.ID); err != nil || len(data) != 2 {
+			t.Errorf("expected the runs of the restored benchmark, got %d (%v)", len(data), err)
+		}
+		if listing := listTestTrash(t, router); len(listing.Benchmarks) != 0 {
+			t.Errorf("expected an empty trash, got %+v", listing.Benchmarks)
+		}
+		w = trashRequest(router, http.MethodPost, fmt.Sprintf("/api/trash/benchmarks/%d/restore", benchmark.ID), "")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected a benchmark not in the trash to be rejected, got %d", w.Code)
+		}
+	})
+
+	t.Run("other users can't restore", func(t *testing.T) {
+		if w := trashRequest(router, http.MethodDelete, path, ""); w.Code != http.StatusOK {
+			t.Fatalf("Failed to delete benchmark: %d", w.Code)
+		}
+		other := createTestUser(db, "trashother", false)
+		otherRouter := trashTestRouter(db, other)
+		if listing := listTestTrash(t, otherRouter); len(listing.Benchmarks) != 0 {
+			t.Errorf("expected another user's trash to be empty, got %+v", listing.Benchmarks)
+		}
+		w := trashRequest(otherRouter, http.MethodPost, fmt.Sprintf("/api/trash/benchmarks/%d/restore", benchmark.ID), "")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", w.Code)
+		}
+	})
+}
+
+func TestRunTrash(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "runtrashuser", true)
+	router := trashTestRouter(db, user)
+	benchmark := createTrashTestBenchmark(t, router, "Runs", "a", "b", "c")
+
+	w := trashRequest(router, http.MethodDelete, fmt.Sprintf("/api/benchmarks/%d/runs/1", benchmark.ID), `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to delete run: %d %s", w.Code, w.Body.String())
+	}
+	listing := listTestTrash(t, router)
+	if len(listing.Runs) != 1 || listing.Runs[0].Label != "b" || listing.Runs[0].RunIndex != 1 ||
+		listing.Runs[0].BenchmarkTitle != "Runs" || listing.Runs[0].UserID != user.ID || listing.Runs[0].DataLines != 2 {
+		t.Fatalf("expected the run in the trash, got %+v", listing.Runs)
+	}
+	trashed := listing.Runs[0]
+	restorePath := fmt.Sprintf("/api/trash/runs/%d/restore", trashed.ID)
+
+	t.Run("a stale revision is rejected", func(t *testing.T) {
+		if w := trashRequest(router, http.MethodPost, restorePath, `"1"`); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("expected 412, got %d", w.Code)
+		}
+	})
+
+	t.Run("restore puts the run back at its index", func(t *testing.T) {
+		w := trashRequest(router, http.MethodPost, restorePath, `"2"`)
+		var resp struct {
+			RunIndex int  `json:"run_index"`
+			Revision uint `json:"revision"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("Failed to restore run: %d %s", w.Code, w.Body.String())
+		}
+		if resp.RunIndex != 1 || resp.Revision != 3 || w.Header().Get("ETag") != `"3"` {
+			t.Errorf("unexpected response: %+v, ETag %s", resp, w.Header().Get("ETag"))
+		}
+		data, err := RetrieveBenchmarkData(benchmark.ID)
+		if err != nil {
+			t.Fatalf("Failed to read data: %v", err)
+		}
+		var labels []string
+		for _, run := range data {
+			labels = append(labels, run.Label)
+		}
+		if !slices.Equal(labels, []string{"a", "b", "c"}) || len(data[1].DataFPS) != 2 {
+			t.Errorf("expected the run restored in place, got %v", labels)
+		}
+		if blobExists(trashRunName(trashed.ID)) || len(listTestTrash(t, router).Runs) != 0 {
+			t.Error("expected the run removed from the trash")
+		}
+	})
+
+	t.Run("runs of trashed benchmarks can't be restored", func(t *testing.T) {
+		if w := trashRequest(router, http.MethodDelete, fmt.Sprintf("/api/benchmarks/%d/runs/0", benchmark.ID), `"3"`); w.Code != http.StatusOK {
+			t.Fatalf("Failed to delete run: %d", w.Code)
+		}
+		if w := trashRequest(router, http.MethodDelete, fmt.Sprintf("/api/benchmarks/%d", benchmark.ID), ""); w.Code != http.StatusOK {
+			t.Fatalf("Failed to delete benchmark: %d", w.Code)
+		}
+		trashed := listTestTrash(t, router).Runs[0]
+		w := trashRequest(router, http.MethodPost, fmt.Sprintf("/api/trash/runs/%d/restore", trashed.ID), "")
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected 409, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := InitBenchmarksDir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to initialize benchmarks directory: %v", err)
+	}
+
+	user := createTestUser(db, "purgeuser", true)
+	router := trashTestRouter(db, user)
+	deleted := createTrashTestBenchmark(t, router, "Deleted", "a")
+	kept := createTrashTestBenchmark(t, router, "Kept", "a", "b")
+	if w := trashRequest(router, http.MethodDelete, fmt.Sprintf("/api/benchmarks/%d", deleted.ID), ""); w.Code != http.StatusOK {
+		t.Fatalf("Failed to delete benchmark: %d", w.Code)
+	}
+	if w := trashRequest(router, http.MethodDelete, fmt.Sprintf("/api/benchmarks/%d/runs/0", kept.ID), `"1"`); w.Code != http.StatusOK {
+		t.Fatalf("Failed to delete run: %d", w.Code)
+	}
+	var trashed TrashedRun
+	db.DB.First(&trashed)
+
+	t.Run("storage check accepts trashed files", func(t *testing.T) {
+		report, err := CheckStorage(db, false)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if len(report.Issues) != 0 {
+			t.Errorf("expected no issues, got %+v", report.Issues)
+		}
+	})
+
+	t.Run("entries within the retention period are kept", func(t *testing.T) {
+		benchmarks, runs, err := PurgeExpiredTrash(db)
+		if err != nil || benchmarks != 0 || runs != 0 {
+			t.Errorf("expected nothing purged, got %d benchmarks and %d runs (%v)", benchmarks, runs, err)
+		}
+	})
+
+	t.Run("expired entries are purged", func(t *testing.T) {
+		expired := time.Now().Add(-trashRetention - time.Minute)
+		db.DB.Unscoped().Model(&Benchmark{}).Where("id = ?", deleted.ID).Update("deleted_at", expired)
+		db.DB.Model(&TrashedRun{}).Where("id = ?", trashed.ID).Update("trashed_at", expired)
+
+		benchmarks, runs, err := PurgeExpiredTrash(db)
+		if err != nil || benchmarks != 1 || runs != 1 {
+			t.Fatalf("expected 1 benchmark and 1 run purged, got %d and %d (%v)", benchmarks, runs, err)
+		}
+		var count int64
+		db.DB.Unscoped().Model(&Benchmark{}).Where("id = ?", deleted.ID).Count(&count)
+		if count != 0 || blobExists(binFileName(deleted.ID)) {
+			t.Error("expected the benchmark row and files removed")
+		}
+		db.DB.Model(&BenchmarkRun{}).Where("benchmark_id = ?", deleted.ID).Count(&count)
+		if count != 0 {
+			t.Error("expected the run records of the purged benchmark removed")
+		}
+		if blobExists(trashRunName(trashed.ID)) {
+			t.Error("expected the trash blob removed")
+		}
+		if data, err := RetrieveBenchmarkData(kept.ID); err != nil || len(data) != 1 {
+			t.Errorf("expected the kept benchmark untouched (%v)", err)
+		}
+	})
+}
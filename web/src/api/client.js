@@ -9,6 +9,11 @@ class APIError extends Error {
   }
 }
 
+// If-Match header for a benchmark mutation, from the revision returned with the benchmark
+function ifMatch(revision) {
+  return { 'If-Match': `"${revision}"` }
+}
+
 async function fetchJSON(url, options = {}) {
   const headers = { ...options.headers }
   if (options.body !== undefined && options.body !== null) {
@@ -119,9 +124,10 @@ export const api = {
       return response.json()
     },
 
-    async update(id, data) {
+    async update(id, data, revision) {
       return fetchJSON(`/api/benchmarks/${encodeURIComponent(id)}`, {
         method: 'PUT',
+        headers: ifMatch(revision),
         body: JSON.stringify(data),
       })
     },
@@ -141,16 +147,18 @@ export const api = {
       return loadBenchmarkRunsIncremental(id, totalRuns, progressCallbacks)
     },
 
-    async deleteRun(id, runIndex) {
+    async deleteRun(id, runIndex, revision) {
       return fetchJSON(`/api/benchmarks/${encodeURIComponent(id)}/runs/${encodeURIComponent(runIndex)}`, {
         method: 'DELETE',
+        headers: ifMatch(revision),
       })
     },
 
-    async addRuns(id, formData) {
+    async addRuns(id, formData, revision) {
       const response = await fetch(API_BASE + `/api/benchmarks/${encodeURIComponent(id)}/runs`, {
         method: 'POST',
         credentials: 'include',
+        headers: ifMatch(revision),
         body: formData, // FormData for file uploads
       })
 
@@ -163,6 +171,39 @@ export const api = {
     },
   },
 
+  // Trash endpoints
+  trash: {
+    async list() {
+      return fetchJSON('/api/trash')
+    },
+
+    async restoreBenchmark(id) {
+      return fetchJSON(`/api/trash/benchmarks/${encodeURIComponent(id)}/restore`, {
+        method: 'POST',
+      })
+    },
+
+    async restoreRun(id) {
+      return fetchJSON(`/api/trash/runs/${encodeURIComponent(id)}/restore`, {
+        method: 'POST',
+      })
+    },
+  },
+
+  // Comparison endpoints
+  compare: {
+    async create(runs, baseline = 0) {
+      return fetchJSON('/api/compare?include=stats&methods=linear', {
+        method: 'POST',
+        body: JSON.stringify({ runs, baseline }),
+      })
+    },
+
+    async get(id) {
+      return fetchJSON(`/api/compare/${encodeURIComponent(id)}?include=stats&methods=linear`)
+    },
+  },
+
   // Admin endpoints
   admin: {
     async listUsers(page = 1, perPage = 10, search = '') {
@@ -202,6 +243,13 @@ export const api = {
         body: JSON.stringify({ is_admin: isAdmin }),
       })
     },
+
+    async setUserQuota(id, quota) {
+      return fetchJSON(`/api/admin/users/${encodeURIComponent(id)}/quota`, {
+        method: 'PUT',
+        body: JSON.stringify(quota),
+      })
+    },
   },
 
   // API Token endpoints
@@ -5,6 +5,8 @@ import BenchmarkCreate from '../views/BenchmarkCreate.vue'
 import BenchmarkDetail from '../views/BenchmarkDetail.vue'
 import APITokens from '../views/APITokens.vue'
 import Users from '../views/Users.vue'
+import Trash from '../views/Trash.vue'
+import Compare from '../views/Compare.vue'
 import DebugCalc from '../views/DebugCalc.vue'
 import { useAuthStore } from '../stores/auth'
 
@@ -52,6 +54,23 @@ const routes = [
     component: APITokens,
     meta: { requiresAuth: true }
   },
+  {
+    path: '/trash',
+    name: 'trash',
+    component: Trash,
+    meta: { requiresAuth: true }
+  },
+  {
+    path: '/compare',
+    name: 'compare-create',
+    component: Compare,
+    meta: { requiresAuth: true }
+  },
+  {
+    path: '/compare/:id',
+    name: 'compare',
+    component: Compare
+  },
   {
     path: '/admin/users',
     name: 'admin-users',
@@ -22,6 +22,7 @@ export function processRun(runData, runIndex) {
     specRAM: runData.specRAM || '',
     specLinuxKernel: runData.specLinuxKernel || '',
     specLinuxScheduler: runData.specLinuxScheduler || '',
+    specDriver: runData.specDriver || '',
     totalDataPoints: runData.totalDataPoints || 0,
 
     // Pre-computed downsampled time-series data for line charts